@@ -0,0 +1,9 @@
+// Package api holds the OpenAPI document describing the REST API of the service.
+package api
+
+import _ "embed"
+
+// Spec is the OpenAPI 3.1 document served at /openapi.json.
+//
+//go:embed openapi.json
+var Spec []byte
@@ -2,11 +2,33 @@ package main
 
 import (
 	"CustomerCRUD/pkg/server"
-	"net/http"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
 	"os"
-	"strconv"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 
+	"CustomerCRUD/pkg/config"
+	"CustomerCRUD/pkg/encryption"
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/gql"
+	"CustomerCRUD/pkg/grpcserver"
+	"CustomerCRUD/pkg/mail"
+	"CustomerCRUD/pkg/ratelimit"
 	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/cache"
+	"CustomerCRUD/pkg/repository/faults"
+	"CustomerCRUD/pkg/repository/resilient"
+	"CustomerCRUD/pkg/service"
+	"CustomerCRUD/pkg/storage"
+	"CustomerCRUD/pkg/unsubscribe"
+	"CustomerCRUD/pkg/verification"
 	"CustomerCRUD/utils"
 
 	"github.com/joho/godotenv"
@@ -14,30 +36,328 @@ import (
 )
 
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	// A .env file is only a convenience for local development, the environment wins when both are set.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatal("Error loading .env file: ", err)
 	}
 
-	isLocalDB := os.Getenv("LOCAL_DB")
-	localDBBool, err := strconv.ParseBool(isLocalDB)
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatal("Error parsing local db boolean")
+		log.Fatal(err)
 	}
+	log.Infof("effective configuration:\n%s", cfg)
 
-	db, err := repository.GetDB(localDBBool, "DATABASE_URL")
+	db, err := repository.Open(cfg.Database)
+	if err != nil {
+		log.Fatal("error opening database: ", err)
+	}
 	defer db.Close()
 
-	dbURL := os.Getenv("DATABASE_URL")
-	if err := utils.RunMigrations(dbURL); err != nil {
-		log.Fatal("error running db migrations", err)
+	migrationsEnabled := cfg.Features.RunMigrations && !cfg.Database.Local
+	if migrationsEnabled {
+		if err := utils.RunMigrationsFrom(cfg.Database.DSN, cfg.Database.MigrationsDir); err != nil {
+			log.Fatal("error running db migrations", err)
+		}
+	}
+
+	// Without encryption the repositories keep emails and phone numbers in plaintext.
+	var repoOpts []repository.Option
+	var rotator *encryption.Rotator
+	if cfg.Encryption.Enabled {
+		encryptor, err := newEncryptor(context.Background(), cfg.Encryption, db)
+		if err != nil {
+			log.Fatal("error configuring encryption: ", err)
+		}
+		repoOpts = append(repoOpts, repository.WithCipher(encryptor))
+		rotator = encryption.NewRotator(encryptor, repository.NewReencrypter(db, encryptor),
+			cfg.Encryption.ReencryptInterval, cfg.Encryption.ReencryptBatchSize)
+	}
+
+	// Without a replica every query goes to the primary.
+	var replica *repository.Replica
+	if cfg.Database.ReplicaDSN != "" {
+		replicaDB, err := repository.OpenReplica(cfg.Database)
+		if err != nil {
+			log.Fatal("error opening read replica: ", err)
+		}
+		defer replicaDB.Close()
+		replica = repository.NewReplica(replicaDB)
+		checkCtx, cancel := context.WithTimeout(context.Background(), cfg.Database.ReplicaCheckInterval)
+		replica.Check(checkCtx)
+		cancel()
+		repoOpts = append(repoOpts, repository.WithReplica(replica))
 	}
 
-	dbRepo := repository.NewCustomerRepository(db)
+	bus := events.NewBus()
+	transitions, err := service.NewTransitions(cfg.Lifecycle.Transitions)
+	if err != nil {
+		log.Fatal("error configuring the customer lifecycle: ", err)
+	}
+	var (
+		uow           repository.UnitOfWork          = repository.NewUnitOfWork(db, repoOpts...)
+		contacts      repository.ContactRepository   = repository.NewContactRepository(db, repoOpts...)
+		attributes    repository.AttributeRepository = repository.NewAttributeRepository(db)
+		customerCache *cache.Cache
+	)
+	var injector *faults.Injector
+	if cfg.Faults.Enabled {
+		// Inside the guard, so that retries and the breaker react to the injected faults.
+		log.Warnf("fault injection is enabled in %s, faults are set through /admin/faults", cfg.Server.Environment)
+		injector = faults.NewInjector()
+		uow = injector.UnitOfWork(uow)
+	}
+	var guard *resilient.Guard
+	if cfg.Resilience.Enabled {
+		guard = resilient.New(resilient.Policy{
+			Attempts:    cfg.Resilience.RetryAttempts,
+			BaseDelay:   cfg.Resilience.RetryBaseDelay,
+			MaxDelay:    cfg.Resilience.RetryMaxDelay,
+			CallTimeout: cfg.Resilience.CallTimeout,
+		}, resilient.NewBreaker(resilient.BreakerOptions{
+			Failures: cfg.Resilience.BreakerFailures,
+			OpenFor:  cfg.Resilience.BreakerOpenFor,
+		}))
+		uow = guard.UnitOfWork(uow)
+	}
+	if cfg.Cache.Enabled {
+		opts := cache.Options{
+			Size:        cfg.Cache.Size,
+			TTL:         cfg.Cache.TTL,
+			NegativeTTL: cfg.Cache.NegativeTTL,
+		}
+		if replica != nil {
+			// Clients read their own writes from the primary for that long, so the replica is
+			// assumed to catch up within it.
+			opts.ReplicaLag = cfg.Database.ReplicaStickiness
+		}
+		customerCache = cache.New(opts)
+		uow = customerCache.UnitOfWork(uow)
+		contacts = customerCache.Contacts(contacts)
+		attributes = customerCache.Attributes(attributes)
+	}
+	customers := service.NewCustomerService(uow, bus)
+	customers.SetTransitions(transitions)
+	var mailer mail.Mailer
+	if cfg.EmailVerification.Enabled() || cfg.Portal.Enabled {
+		mailer, err = newMailer(cfg.Mail)
+		if err != nil {
+			log.Fatal("error configuring mail: ", err)
+		}
+	}
+	if cfg.EmailVerification.Enabled() {
+		customers.SetEmailVerifier(verification.NewVerifier(verification.Config{
+			Secret:  cfg.EmailVerification.Secret,
+			TTL:     cfg.EmailVerification.TTL,
+			LinkURL: cfg.EmailVerification.LinkURL,
+			Limits: verification.Limits{
+				Interval: cfg.EmailVerification.ResendInterval,
+				PerDay:   cfg.EmailVerification.MaxPerDay,
+			},
+		}, mailer))
+	}
 
-	srv := server.NewServer(dbRepo)
+	srv := server.NewServer(customers)
+	srv.SetAddressRepository(repository.NewAddressRepository(db))
+	srv.SetContactRepository(contacts)
+	srv.SetAttributeRepository(attributes)
+	srv.SetTagRepository(repository.NewTagRepository(db))
+	srv.SetSegmentRepository(repository.NewSegmentRepository(db))
+	srv.SetActivityRepository(repository.NewActivityRepository(db))
+	srv.SetConsentRepository(repository.NewConsentRepository(db))
+	if cfg.Consents.UnsubscribeSecret != "" {
+		srv.SetUnsubscribeSigner(unsubscribe.NewSigner(cfg.Consents.UnsubscribeSecret))
+	}
+	if cfg.RateLimit.Enabled {
+		srv.SetRateLimiter(newRateLimiter(cfg.RateLimit), cfg.RateLimit.TrustProxy)
+	}
+	if cfg.Portal.Enabled {
+		srv.SetPortal(repository.NewSessionRepository(db), mailer, server.PortalPolicy{
+			LoginURL:       cfg.Portal.LoginURL,
+			LoginTTL:       cfg.Portal.LoginTTL,
+			SessionTTL:     cfg.Portal.SessionTTL,
+			LoginsPerHour:  cfg.Portal.LoginsPerHour,
+			InsecureCookie: cfg.Portal.InsecureCookies,
+		})
+	}
+	if cfg.Attachments.Enabled {
+		blobs, err := newBlobStore(cfg.Attachments)
+		if err != nil {
+			log.Fatal("error configuring attachment storage: ", err)
+		}
+		customers.SetBlobStore(blobs)
+		srv.SetAttachmentStore(repository.NewAttachmentRepository(db), blobs, server.AttachmentPolicy{
+			MaxSize:      int64(cfg.Attachments.MaxSize),
+			AllowedTypes: cfg.Attachments.AllowedTypes,
+		})
+	}
+	if guard != nil {
+		srv.SetCircuitBreaker(guard.Breaker())
+		srv.AddMetrics("database_breaker", func() any { return guard.Stats() })
+	}
+	if injector != nil {
+		srv.SetFaultInjector(injector)
+		srv.AddMetrics("fault_injection", func() any { return injector.Stats() })
+	}
+	if replica != nil {
+		srv.SetReplicaStickiness(cfg.Database.ReplicaStickiness)
+		srv.AddMetrics("database_replica", func() any { return replica.Stats() })
+	}
+	if customerCache != nil {
+		srv.AddMetrics("customer_cache", func() any { return customerCache.Stats() })
+	}
+	srv.AddReadinessCheck("database", db.PingContext)
+	if guard != nil {
+		srv.AddReadinessCheck("database_breaker", guard.Breaker().Check)
+	}
+	if !cfg.Database.Local {
+		srv.AddReadinessCheck("migrations", func(ctx context.Context) error {
+			return utils.CheckMigrationVersion(ctx, db, cfg.Database.MigrationsDir)
+		})
+	}
+	if cfg.GraphQL.Enabled {
+		graphqlHandler, err := gql.NewHandler(customers, gql.Limits{
+			MaxDepth:      cfg.GraphQL.MaxDepth,
+			MaxComplexity: cfg.GraphQL.MaxComplexity,
+		})
+		if err != nil {
+			log.Fatal("error building GraphQL schema: ", err)
+		}
+		srv.MountGraphQL(graphqlHandler)
+	}
 	srv.SetupRoutes()
 
-	log.Println("Server is running on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", srv.Router))
+	timeouts := server.Timeouts{
+		Read:     cfg.Server.ReadTimeout,
+		Write:    cfg.Server.WriteTimeout,
+		Idle:     cfg.Server.IdleTimeout,
+		Shutdown: cfg.Server.ShutdownGracePeriod,
+		Drain:    cfg.Server.ShutdownDrainDelay,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Waits for the servers running beside the HTTP one to finish their graceful shutdown.
+	var background sync.WaitGroup
+	defer background.Wait()
+
+	if customerCache != nil {
+		go customerCache.Watch(ctx, bus)
+	}
+	if replica != nil {
+		go replica.Watch(ctx, cfg.Database.ReplicaCheckInterval)
+	}
+
+	if rotator != nil {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			rotator.Run(ctx)
+		}()
+	}
+
+	if cfg.GRPC.Enabled {
+		grpcServer := grpcserver.NewGRPCServer(customers, bus)
+		if cfg.GRPC.Multiplex {
+			log.Printf("gRPC is multiplexed on port %d", cfg.Server.Port)
+			srv.MultiplexGRPC(grpcServer)
+		} else {
+			lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+			if err != nil {
+				log.Fatal("error listening for gRPC: ", err)
+			}
+			log.Printf("gRPC is running on port %d", cfg.GRPC.Port)
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				if err := grpcserver.Serve(ctx, grpcServer, lis, cfg.Server.ShutdownGracePeriod); err != nil {
+					log.Error("gRPC server stopped with error: ", err)
+					stop()
+				}
+			}()
+		}
+	}
+
+	log.Printf("Server is running on port %d", cfg.Server.Port)
+	if err := srv.ListenAndServe(ctx, fmt.Sprintf(":%d", cfg.Server.Port), timeouts); err != nil {
+		log.Error("server stopped with error: ", err)
+		stop()
+		return
+	}
+	log.Println("Server stopped")
+}
+
+// newBlobStore returns the store holding the contents of attachments.
+func newBlobStore(cfg config.AttachmentConfig) (storage.BlobStore, error) {
+	if cfg.Store == "s3" {
+		return storage.NewS3Store(storage.S3Options{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			Bucket:          cfg.S3.Bucket,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			PathStyle:       cfg.S3.PathStyle,
+		})
+	}
+	return storage.NewLocalStore(cfg.Dir)
+}
+
+// newMailer returns the mailer sending the emails of the service.
+func newMailer(cfg config.MailConfig) (mail.Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.From,
+		}), nil
+	case "file":
+		return mail.NewFileOutbox(cfg.OutboxDir, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+	}
+}
+
+// newRateLimiter returns the limiter of the HTTP API, which keeps its buckets in memory.
+func newRateLimiter(cfg config.RateLimitConfig) *ratelimit.Limiter {
+	policy := ratelimit.Policy{
+		Default:    ratelimit.Limit{Requests: cfg.Requests, Period: cfg.Period, Burst: cfg.Burst},
+		DailyQuota: cfg.DailyQuota,
+		Quotas:     make(map[string]int, len(cfg.Quotas)),
+	}
+	for _, r := range cfg.Routes {
+		policy.Routes = append(policy.Routes, ratelimit.Route{
+			Method: r.Method,
+			Path:   r.Path,
+			Limit:  ratelimit.Limit{Requests: r.Requests, Period: r.Period, Burst: r.Burst},
+		})
+	}
+	for _, id := range cfg.APIKeys {
+		policy.APIKeys = append(policy.APIKeys, strings.ToLower(id))
+	}
+	for id, quota := range cfg.Quotas {
+		policy.Quotas[strings.ToLower(id)] = quota
+	}
+	return ratelimit.NewLimiter(policy, ratelimit.NewMemoryStore())
+}
+
+// newEncryptor returns the encryptor of customer emails and phone numbers, with its data keys kept
+// in db.
+func newEncryptor(ctx context.Context, cfg config.EncryptionConfig, db *sql.DB) (*encryption.Encryptor, error) {
+	var provider encryption.KeyProvider
+	var err error
+	if cfg.KeyProvider == "file" {
+		provider, err = encryption.NewFileKeyProvider(cfg.KeyFile)
+	} else {
+		provider, err = encryption.NewEnvKeyProvider(cfg.MasterKeys, cfg.IndexKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encryption.NewEncryptor(ctx, provider, repository.NewDataKeyRepository(db))
 }
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS customer_addresses (
+                                                  id UUID PRIMARY KEY,
+                                                  customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                                  type TEXT NOT NULL CHECK (type IN ('billing', 'shipping', 'other')),
+                                                  line1 TEXT NOT NULL,
+                                                  line2 TEXT,
+                                                  city TEXT NOT NULL,
+                                                  region TEXT,
+                                                  postal_code TEXT,
+                                                  country CHAR(2) NOT NULL,
+                                                  is_default BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS customer_addresses_customer_id_idx ON customer_addresses (customer_id);
+
+-- At most one default address of each type per customer
+CREATE UNIQUE INDEX IF NOT EXISTS customer_addresses_default_idx
+    ON customer_addresses (customer_id, type) WHERE is_default;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS customer_phones;
+DROP TABLE IF EXISTS customer_emails;
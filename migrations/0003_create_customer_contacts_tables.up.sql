@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS customer_emails (
+                                               id UUID PRIMARY KEY,
+                                               customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                               type TEXT NOT NULL CHECK (type IN ('work', 'home', 'mobile', 'other')),
+                                               email TEXT NOT NULL,
+                                               is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+                                               verified BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Email addresses are unique across every customer, primary or not
+CREATE UNIQUE INDEX IF NOT EXISTS customer_emails_email_idx ON customer_emails (email);
+CREATE INDEX IF NOT EXISTS customer_emails_customer_id_idx ON customer_emails (customer_id);
+CREATE UNIQUE INDEX IF NOT EXISTS customer_emails_primary_idx ON customer_emails (customer_id) WHERE is_primary;
+
+CREATE TABLE IF NOT EXISTS customer_phones (
+                                               id UUID PRIMARY KEY,
+                                               customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                               type TEXT NOT NULL CHECK (type IN ('work', 'home', 'mobile', 'other')),
+                                               number TEXT NOT NULL,
+                                               is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+                                               verified BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS customer_phones_customer_id_idx ON customer_phones (customer_id);
+CREATE UNIQUE INDEX IF NOT EXISTS customer_phones_primary_idx ON customer_phones (customer_id) WHERE is_primary;
+
+-- The existing email and phone number become the primary contact points,
+-- customers.email and customers.phone_number keep mirroring them
+INSERT INTO customer_emails (id, customer_id, type, email, is_primary)
+SELECT gen_random_uuid(), id, 'other', email, TRUE FROM customers;
+
+INSERT INTO customer_phones (id, customer_id, type, number, is_primary)
+SELECT gen_random_uuid(), id, 'other', phone_number, TRUE FROM customers
+WHERE phone_number IS NOT NULL AND phone_number <> '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS customers_attributes_idx;
+ALTER TABLE customers DROP COLUMN IF EXISTS attributes;
+DROP TABLE IF EXISTS attribute_definitions;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+                                                     name TEXT PRIMARY KEY,
+                                                     definition JSONB NOT NULL
+);
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+-- Serves the equality filters of the list endpoint, which use containment
+CREATE INDEX IF NOT EXISTS customers_attributes_idx ON customers USING GIN (attributes jsonb_path_ops);
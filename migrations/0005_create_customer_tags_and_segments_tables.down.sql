@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS customer_addresses_country_idx;
+DROP TABLE IF EXISTS segments;
+DROP TABLE IF EXISTS customer_tags;
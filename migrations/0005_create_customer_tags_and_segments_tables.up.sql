@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS customer_tags (
+                                             customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                             tag TEXT NOT NULL,
+                                             PRIMARY KEY (customer_id, tag)
+);
+
+-- Serves the tag conditions of segment filters and the tag counts
+CREATE INDEX IF NOT EXISTS customer_tags_tag_idx ON customer_tags (tag, customer_id);
+
+CREATE TABLE IF NOT EXISTS segments (
+                                        id UUID PRIMARY KEY,
+                                        name TEXT NOT NULL,
+                                        description TEXT,
+                                        filter TEXT NOT NULL
+);
+
+-- Serves the country conditions of segment filters
+CREATE INDEX IF NOT EXISTS customer_addresses_country_idx ON customer_addresses (country, customer_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS customers_updated_at_idx;
+ALTER TABLE customers DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE customers DROP COLUMN IF EXISTS created_by;
+ALTER TABLE customers DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS created_at;
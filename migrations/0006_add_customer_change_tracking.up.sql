@@ -0,0 +1,8 @@
+-- Customers stored before the change tracking get the time of the migration and no actor
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS created_by TEXT;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS updated_by TEXT;
+
+-- Serves the updated_since filter and the incremental pulls sorted by updated_at
+CREATE INDEX IF NOT EXISTS customers_updated_at_idx ON customers (updated_at, id);
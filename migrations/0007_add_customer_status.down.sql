@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS customer_status_changes;
+DROP INDEX IF EXISTS customers_status_idx;
+ALTER TABLE customers DROP COLUMN IF EXISTS status;
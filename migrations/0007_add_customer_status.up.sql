@@ -0,0 +1,19 @@
+-- Customers stored before the lifecycle existed are active, new ones start as leads
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('lead', 'prospect', 'active', 'suspended', 'closed'));
+ALTER TABLE customers ALTER COLUMN status SET DEFAULT 'lead';
+
+-- Serves the status filter of the customer list
+CREATE INDEX IF NOT EXISTS customers_status_idx ON customers (status);
+
+CREATE TABLE IF NOT EXISTS customer_status_changes (
+                                                       id UUID PRIMARY KEY,
+                                                       customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                                       from_status TEXT,
+                                                       to_status TEXT NOT NULL,
+                                                       reason TEXT NOT NULL,
+                                                       changed_at TIMESTAMPTZ NOT NULL,
+                                                       changed_by TEXT
+);
+
+CREATE INDEX IF NOT EXISTS customer_status_changes_customer_id_idx ON customer_status_changes (customer_id, changed_at);
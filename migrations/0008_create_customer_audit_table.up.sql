@@ -0,0 +1,11 @@
+-- No foreign key on customer_id, the audit trail outlives the customers it describes
+CREATE TABLE IF NOT EXISTS customer_audit (
+                                              id UUID PRIMARY KEY,
+                                              customer_id UUID NOT NULL,
+                                              action TEXT NOT NULL CHECK (action IN ('created', 'updated', 'deleted')),
+                                              changes JSONB,
+                                              actor TEXT NOT NULL,
+                                              occurred_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS customer_audit_customer_id_idx ON customer_audit (customer_id, occurred_at);
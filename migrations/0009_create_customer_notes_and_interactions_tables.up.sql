@@ -0,0 +1,41 @@
+-- Deleted notes are kept, deleted_at hides them
+CREATE TABLE IF NOT EXISTS customer_notes (
+                                              id UUID PRIMARY KEY,
+                                              customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                              body TEXT NOT NULL,
+                                              author TEXT NOT NULL,
+                                              created_at TIMESTAMPTZ NOT NULL,
+                                              updated_at TIMESTAMPTZ NOT NULL,
+                                              updated_by TEXT,
+                                              deleted_at TIMESTAMPTZ,
+                                              deleted_by TEXT
+);
+
+CREATE INDEX IF NOT EXISTS customer_notes_customer_id_idx ON customer_notes (customer_id, created_at);
+
+-- Full text search on the notes that are not deleted
+CREATE INDEX IF NOT EXISTS customer_notes_body_idx
+    ON customer_notes USING GIN (to_tsvector('simple', body)) WHERE deleted_at IS NULL;
+
+-- The bodies notes had before each edit
+CREATE TABLE IF NOT EXISTS customer_note_revisions (
+                                                       id UUID PRIMARY KEY,
+                                                       note_id UUID NOT NULL REFERENCES customer_notes (id) ON DELETE CASCADE,
+                                                       body TEXT NOT NULL,
+                                                       edited_at TIMESTAMPTZ NOT NULL,
+                                                       edited_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS customer_note_revisions_note_id_idx ON customer_note_revisions (note_id, edited_at);
+
+CREATE TABLE IF NOT EXISTS customer_interactions (
+                                                     id UUID PRIMARY KEY,
+                                                     customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                                     type TEXT NOT NULL CHECK (type IN ('call', 'email', 'meeting')),
+                                                     body TEXT NOT NULL,
+                                                     occurred_at TIMESTAMPTZ NOT NULL,
+                                                     author TEXT NOT NULL,
+                                                     created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS customer_interactions_customer_id_idx ON customer_interactions (customer_id, occurred_at);
@@ -0,0 +1,14 @@
+-- The contents are kept in a blob store under storage_key
+CREATE TABLE IF NOT EXISTS customer_attachments (
+                                                    id UUID PRIMARY KEY,
+                                                    customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                                    file_name TEXT NOT NULL,
+                                                    content_type TEXT NOT NULL,
+                                                    size BIGINT NOT NULL CHECK (size >= 0),
+                                                    sha256 CHAR(64) NOT NULL,
+                                                    storage_key TEXT NOT NULL,
+                                                    uploaded_at TIMESTAMPTZ NOT NULL,
+                                                    uploaded_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS customer_attachments_customer_id_idx ON customer_attachments (customer_id, uploaded_at);
@@ -0,0 +1,8 @@
+-- The audit entries of erasures cannot be represented before this migration
+DELETE FROM customer_audit WHERE action = 'erased';
+ALTER TABLE customer_audit DROP CONSTRAINT IF EXISTS customer_audit_action_check;
+ALTER TABLE customer_audit ADD CONSTRAINT customer_audit_action_check
+    CHECK (action IN ('created', 'updated', 'deleted'));
+
+DROP TABLE IF EXISTS customer_erasures;
+DROP TABLE IF EXISTS customer_legal_holds;
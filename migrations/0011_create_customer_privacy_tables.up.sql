@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS customer_legal_holds (
+                                                    id UUID PRIMARY KEY,
+                                                    customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                                    reason TEXT NOT NULL,
+                                                    placed_at TIMESTAMPTZ NOT NULL,
+                                                    placed_by TEXT NOT NULL,
+                                                    released_at TIMESTAMPTZ,
+                                                    released_by TEXT
+);
+
+CREATE INDEX IF NOT EXISTS customer_legal_holds_customer_id_idx ON customer_legal_holds (customer_id, placed_at);
+
+-- Erased customers stay in place with anonymized fields, so that references to them keep working
+CREATE TABLE IF NOT EXISTS customer_erasures (
+                                                 customer_id UUID PRIMARY KEY REFERENCES customers (id) ON DELETE CASCADE,
+                                                 reference TEXT,
+                                                 erased_at TIMESTAMPTZ NOT NULL,
+                                                 erased_by TEXT NOT NULL
+);
+
+ALTER TABLE customer_audit DROP CONSTRAINT IF EXISTS customer_audit_action_check;
+ALTER TABLE customer_audit ADD CONSTRAINT customer_audit_action_check
+    CHECK (action IN ('created', 'updated', 'deleted', 'erased'));
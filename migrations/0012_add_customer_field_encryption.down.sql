@@ -0,0 +1,14 @@
+-- This drops the data keys, which leaves values that are still encrypted unreadable. Only roll back
+-- databases that never had encryption enabled.
+DROP TABLE IF EXISTS encryption_data_keys;
+
+DROP INDEX IF EXISTS customer_phones_number_blind_index_idx;
+ALTER TABLE customer_phones DROP COLUMN IF EXISTS number_blind_index;
+
+DROP INDEX IF EXISTS customer_emails_email_blind_index_idx;
+ALTER TABLE customer_emails DROP COLUMN IF EXISTS email_blind_index;
+
+DROP INDEX IF EXISTS customers_phone_number_blind_index_idx;
+DROP INDEX IF EXISTS customers_email_blind_index_idx;
+ALTER TABLE customers DROP COLUMN IF EXISTS phone_number_blind_index;
+ALTER TABLE customers DROP COLUMN IF EXISTS email_blind_index;
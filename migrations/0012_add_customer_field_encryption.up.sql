@@ -0,0 +1,21 @@
+-- Encrypted values differ on every write, so equality lookups and uniqueness go through a keyed
+-- hash of the plaintext stored next to them. Rows written before encryption was enabled keep a
+-- NULL index until the background re-encryption reaches them.
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_blind_index TEXT;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS phone_number_blind_index TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS customers_email_blind_index_idx ON customers (email_blind_index);
+CREATE INDEX IF NOT EXISTS customers_phone_number_blind_index_idx ON customers (phone_number_blind_index);
+
+ALTER TABLE customer_emails ADD COLUMN IF NOT EXISTS email_blind_index TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS customer_emails_email_blind_index_idx ON customer_emails (email_blind_index);
+
+ALTER TABLE customer_phones ADD COLUMN IF NOT EXISTS number_blind_index TEXT;
+CREATE INDEX IF NOT EXISTS customer_phones_number_blind_index_idx ON customer_phones (number_blind_index);
+
+-- Data keys wrapped with a master key, which itself never reaches the database
+CREATE TABLE IF NOT EXISTS encryption_data_keys (
+                                                    id TEXT PRIMARY KEY,
+                                                    master_key_id TEXT NOT NULL,
+                                                    wrapped_key BYTEA NOT NULL,
+                                                    created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS customer_consents;
+DROP TABLE IF EXISTS customer_consent_history;
@@ -0,0 +1,28 @@
+-- Every consent ever recorded, never updated
+CREATE TABLE IF NOT EXISTS customer_consent_history (
+                                                        id UUID PRIMARY KEY,
+                                                        customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                                        purpose TEXT NOT NULL,
+                                                        channel TEXT NOT NULL CHECK (channel IN ('email', 'sms', 'phone', 'post')),
+                                                        state TEXT NOT NULL CHECK (state IN ('granted', 'revoked')),
+                                                        source TEXT NOT NULL,
+                                                        policy_version TEXT,
+                                                        recorded_at TIMESTAMPTZ NOT NULL,
+                                                        recorded_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS customer_consent_history_customer_id_idx ON customer_consent_history (customer_id, recorded_at);
+
+-- The latest consent of every purpose and channel, which the contactable filter of the customer list reads
+CREATE TABLE IF NOT EXISTS customer_consents (
+                                                 customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                                 purpose TEXT NOT NULL,
+                                                 channel TEXT NOT NULL,
+                                                 id UUID NOT NULL,
+                                                 state TEXT NOT NULL,
+                                                 source TEXT NOT NULL,
+                                                 policy_version TEXT,
+                                                 recorded_at TIMESTAMPTZ NOT NULL,
+                                                 recorded_by TEXT NOT NULL,
+                                                 PRIMARY KEY (customer_id, purpose, channel)
+);
@@ -0,0 +1,22 @@
+-- Magic links signing customers in to the self-service portal, by the SHA-256 hash of their token
+CREATE TABLE IF NOT EXISTS portal_login_tokens (
+                                                   id UUID PRIMARY KEY,
+                                                   customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                                   token_hash TEXT NOT NULL UNIQUE,
+                                                   created_at TIMESTAMPTZ NOT NULL,
+                                                   expires_at TIMESTAMPTZ NOT NULL,
+                                                   used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS portal_login_tokens_customer_id_idx ON portal_login_tokens (customer_id, created_at);
+
+-- Customers signed in to the self-service portal, by the SHA-256 hash of their session token
+CREATE TABLE IF NOT EXISTS portal_sessions (
+                                               id UUID PRIMARY KEY,
+                                               customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+                                               token_hash TEXT NOT NULL UNIQUE,
+                                               created_at TIMESTAMPTZ NOT NULL,
+                                               expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS portal_sessions_customer_id_idx ON portal_sessions (customer_id);
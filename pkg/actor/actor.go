@@ -0,0 +1,43 @@
+// Package actor carries the name of whoever performs a change through a context, so that the
+// repositories can record it. The API does not authenticate callers yet, the name is taken from
+// the X-Actor header or the x-actor gRPC metadata as is.
+package actor
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// Header is the HTTP header, and lowercased the gRPC metadata key, naming the actor of a request.
+	Header = "X-Actor"
+	// Anonymous is the actor of the requests that do not name one.
+	Anonymous = "anonymous"
+	// System is the actor of the changes made outside of a request.
+	System = "system"
+
+	maxLength = 200
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx naming the actor of the changes made with it. A blank name
+// becomes Anonymous and long names are truncated.
+func NewContext(ctx context.Context, name string) context.Context {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = Anonymous
+	}
+	if len(name) > maxLength {
+		name = strings.ToValidUTF8(name[:maxLength], "")
+	}
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// FromContext returns the actor named by ctx, System when there is none.
+func FromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(contextKey{}).(string); ok {
+		return name
+	}
+	return System
+}
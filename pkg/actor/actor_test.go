@@ -0,0 +1,18 @@
+package actor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, System, FromContext(ctx))
+	assert.Equal(t, "alice@example.com", FromContext(NewContext(ctx, " alice@example.com ")))
+	assert.Equal(t, Anonymous, FromContext(NewContext(ctx, "")))
+	assert.Len(t, FromContext(NewContext(ctx, strings.Repeat("a", 300))), maxLength)
+}
@@ -0,0 +1,555 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config is the effective configuration of the service. Values are layered, each
+// layer overriding the previous one: defaults, an optional YAML or TOML file,
+// environment variables and finally command line flags.
+//
+// The env tag names the environment variable of a field. Every variable can also be
+// given as NAME_FILE pointing to a file holding the value, which is how Kubernetes
+// secrets are mounted. The flag tag names the command line flag, and fields tagged
+// secret are redacted when the configuration is printed.
+type Config struct {
+	Server      ServerConfig     `yaml:"server" toml:"server"`
+	Database    DatabaseConfig   `yaml:"database" toml:"database"`
+	GRPC        GRPCConfig       `yaml:"grpc" toml:"grpc"`
+	GraphQL     GraphQLConfig    `yaml:"graphql" toml:"graphql"`
+	Features    FeatureConfig    `yaml:"features" toml:"features"`
+	Lifecycle   LifecycleConfig  `yaml:"lifecycle" toml:"lifecycle"`
+	Attachments AttachmentConfig `yaml:"attachments" toml:"attachments"`
+	Encryption  EncryptionConfig `yaml:"encryption" toml:"encryption"`
+	Consents    ConsentConfig    `yaml:"consents" toml:"consents"`
+	Mail        MailConfig       `yaml:"mail" toml:"mail"`
+	// EmailVerification and Portal send their emails with Mail.
+	EmailVerification VerificationConfig `yaml:"email_verification" toml:"email_verification"`
+	Portal            PortalConfig       `yaml:"portal" toml:"portal"`
+	RateLimit         RateLimitConfig    `yaml:"rate_limit" toml:"rate_limit"`
+	Cache             CacheConfig        `yaml:"cache" toml:"cache"`
+	Resilience        ResilienceConfig   `yaml:"resilience" toml:"resilience"`
+	Faults            FaultConfig        `yaml:"faults" toml:"faults"`
+}
+
+type ServerConfig struct {
+	// Environment is production, staging or development. Tools meant for testing, such as fault
+	// injection, refuse to run in production.
+	Environment         string        `yaml:"environment" toml:"environment" env:"ENVIRONMENT" flag:"environment"`
+	Port                int           `yaml:"port" toml:"port" env:"PORT" flag:"port"`
+	ReadTimeout         time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"READ_TIMEOUT" flag:"read-timeout"`
+	WriteTimeout        time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"WRITE_TIMEOUT" flag:"write-timeout"`
+	IdleTimeout         time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"IDLE_TIMEOUT" flag:"idle-timeout"`
+	ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period" toml:"shutdown_grace_period" env:"SHUTDOWN_GRACE_PERIOD" flag:"shutdown-grace-period"`
+	// ShutdownDrainDelay is how long /readyz reports not ready before the server shuts down, so
+	// that load balancers stop routing to it first.
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay" toml:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY" flag:"shutdown-drain-delay"`
+}
+
+type DatabaseConfig struct {
+	DSN             string        `yaml:"dsn" toml:"dsn" env:"DATABASE_URL" flag:"database-url" secret:"true"`
+	Local           bool          `yaml:"local" toml:"local" env:"LOCAL_DB" flag:"local-db"`
+	MigrationsDir   string        `yaml:"migrations_dir" toml:"migrations_dir" env:"MIGRATIONS_DIR" flag:"migrations-dir"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" flag:"db-conn-max-idle-time"`
+	// ReplicaDSN enables reading from a read replica, see repository.Replica. Clients read their
+	// own writes from the primary for ReplicaStickiness after writing, and the replica is pinged
+	// every ReplicaCheckInterval, reads going to the primary while it is unhealthy.
+	ReplicaDSN           string        `yaml:"replica_dsn" toml:"replica_dsn" env:"DATABASE_REPLICA_URL" flag:"database-replica-url" secret:"true"`
+	ReplicaMaxOpenConns  int           `yaml:"replica_max_open_conns" toml:"replica_max_open_conns" env:"DB_REPLICA_MAX_OPEN_CONNS" flag:"db-replica-max-open-conns"`
+	ReplicaMaxIdleConns  int           `yaml:"replica_max_idle_conns" toml:"replica_max_idle_conns" env:"DB_REPLICA_MAX_IDLE_CONNS" flag:"db-replica-max-idle-conns"`
+	ReplicaStickiness    time.Duration `yaml:"replica_stickiness" toml:"replica_stickiness" env:"DB_REPLICA_STICKINESS" flag:"db-replica-stickiness"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" toml:"replica_check_interval" env:"DB_REPLICA_CHECK_INTERVAL" flag:"db-replica-check-interval"`
+}
+
+// GRPCConfig controls the gRPC API. When Multiplex is set it shares the HTTP port
+// and Port is ignored, otherwise it listens on its own port.
+type GRPCConfig struct {
+	Enabled   bool `yaml:"enabled" toml:"enabled" env:"GRPC_ENABLED" flag:"grpc-enabled"`
+	Port      int  `yaml:"port" toml:"port" env:"GRPC_PORT" flag:"grpc-port"`
+	Multiplex bool `yaml:"multiplex" toml:"multiplex" env:"GRPC_MULTIPLEX" flag:"grpc-multiplex"`
+}
+
+type GraphQLConfig struct {
+	Enabled       bool `yaml:"enabled" toml:"enabled" env:"GRAPHQL_ENABLED" flag:"graphql-enabled"`
+	MaxDepth      int  `yaml:"max_depth" toml:"max_depth" env:"GRAPHQL_MAX_DEPTH" flag:"graphql-max-depth"`
+	MaxComplexity int  `yaml:"max_complexity" toml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" flag:"graphql-max-complexity"`
+}
+
+type FeatureConfig struct {
+	RunMigrations bool `yaml:"run_migrations" toml:"run_migrations" env:"RUN_MIGRATIONS" flag:"run-migrations"`
+}
+
+// LifecycleConfig overrides the allowed customer status transitions. Every status listed in
+// Transitions gets exactly the given target statuses, the others keep the built-in ones. It is
+// only read from the config file.
+type LifecycleConfig struct {
+	Transitions map[string][]string `yaml:"transitions,omitempty" toml:"transitions"`
+}
+
+// AttachmentConfig controls customer attachments. Their contents are kept below Dir with the
+// local store, or in a bucket of an S3-compatible service with the s3 store.
+type AttachmentConfig struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled" env:"ATTACHMENTS_ENABLED" flag:"attachments-enabled"`
+	Store   string `yaml:"store" toml:"store" env:"ATTACHMENT_STORE" flag:"attachment-store"`
+	// MaxSize is the largest attachment accepted, in bytes.
+	MaxSize int `yaml:"max_size" toml:"max_size" env:"ATTACHMENT_MAX_SIZE" flag:"attachment-max-size"`
+	// AllowedTypes are the media types accepted after sniffing the content. Empty allows all.
+	AllowedTypes []string `yaml:"allowed_types" toml:"allowed_types" env:"ATTACHMENT_ALLOWED_TYPES" flag:"attachment-allowed-types"`
+	Dir          string   `yaml:"dir" toml:"dir" env:"ATTACHMENT_DIR" flag:"attachment-dir"`
+	S3           S3Config `yaml:"s3" toml:"s3"`
+}
+
+// S3Config points at a bucket of AWS S3 or an S3-compatible service such as MinIO, which
+// usually needs PathStyle.
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint" toml:"endpoint" env:"S3_ENDPOINT" flag:"s3-endpoint"`
+	Region          string `yaml:"region" toml:"region" env:"S3_REGION" flag:"s3-region"`
+	Bucket          string `yaml:"bucket" toml:"bucket" env:"S3_BUCKET" flag:"s3-bucket"`
+	AccessKeyID     string `yaml:"access_key_id" toml:"access_key_id" env:"S3_ACCESS_KEY_ID" flag:"s3-access-key-id"`
+	SecretAccessKey string `yaml:"secret_access_key" toml:"secret_access_key" env:"S3_SECRET_ACCESS_KEY" flag:"s3-secret-access-key" secret:"true"`
+	PathStyle       bool   `yaml:"path_style" toml:"path_style" env:"S3_PATH_STYLE" flag:"s3-path-style"`
+}
+
+// EncryptionConfig controls the encryption of customer emails and phone numbers at rest. The master
+// keys come from the environment with the env provider, as comma separated id:base64 pairs with the
+// current key first, or from a JSON keyring file with the file provider. Both need an index key for
+// the blind indexes, which cannot be rotated.
+type EncryptionConfig struct {
+	Enabled     bool   `yaml:"enabled" toml:"enabled" env:"ENCRYPTION_ENABLED" flag:"encryption-enabled"`
+	KeyProvider string `yaml:"key_provider" toml:"key_provider" env:"ENCRYPTION_KEY_PROVIDER" flag:"encryption-key-provider"`
+	MasterKeys  string `yaml:"master_keys" toml:"master_keys" env:"ENCRYPTION_MASTER_KEYS" flag:"encryption-master-keys" secret:"true"`
+	IndexKey    string `yaml:"index_key" toml:"index_key" env:"ENCRYPTION_INDEX_KEY" flag:"encryption-index-key" secret:"true"`
+	KeyFile     string `yaml:"key_file" toml:"key_file" env:"ENCRYPTION_KEY_FILE" flag:"encryption-key-file"`
+	// ReencryptInterval is how often values are moved to the current keys in the background.
+	ReencryptInterval  time.Duration `yaml:"reencrypt_interval" toml:"reencrypt_interval" env:"ENCRYPTION_REENCRYPT_INTERVAL" flag:"encryption-reencrypt-interval"`
+	ReencryptBatchSize int           `yaml:"reencrypt_batch_size" toml:"reencrypt_batch_size" env:"ENCRYPTION_REENCRYPT_BATCH_SIZE" flag:"encryption-reencrypt-batch-size"`
+}
+
+// ConsentConfig controls the consents of customers. Unsubscribe links are only enabled with an
+// UnsubscribeSecret, which signs their tokens and has to be the same on every instance. Changing it
+// breaks the links already sent.
+type ConsentConfig struct {
+	UnsubscribeSecret string `yaml:"unsubscribe_secret" toml:"unsubscribe_secret" env:"UNSUBSCRIBE_SECRET" flag:"unsubscribe-secret" secret:"true"`
+}
+
+// minTokenSecretLength keeps unsubscribe and email verification tokens from being forged by
+// guessing the secret.
+const minTokenSecretLength = 32
+
+// MailConfig controls how emails are sent. The smtp mailer relays them through an SMTP server, the
+// file mailer writes them to OutboxDir as .eml files instead, for local development.
+type MailConfig struct {
+	Mailer    string     `yaml:"mailer" toml:"mailer" env:"MAIL_MAILER" flag:"mail-mailer"`
+	From      string     `yaml:"from" toml:"from" env:"MAIL_FROM" flag:"mail-from"`
+	OutboxDir string     `yaml:"outbox_dir" toml:"outbox_dir" env:"MAIL_OUTBOX_DIR" flag:"mail-outbox-dir"`
+	SMTP      SMTPConfig `yaml:"smtp" toml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host" env:"SMTP_HOST" flag:"smtp-host"`
+	Port     int    `yaml:"port" toml:"port" env:"SMTP_PORT" flag:"smtp-port"`
+	Username string `yaml:"username" toml:"username" env:"SMTP_USERNAME" flag:"smtp-username"`
+	Password string `yaml:"password" toml:"password" env:"SMTP_PASSWORD" flag:"smtp-password" secret:"true"`
+}
+
+// VerificationConfig controls email verification, which is only enabled with a Secret. The secret
+// signs the tokens of the verification links and has to be the same on every instance. LinkURL is
+// the page customers open, which posts the token of its query to POST /customers/verify-email.
+type VerificationConfig struct {
+	Secret  string        `yaml:"secret" toml:"secret" env:"EMAIL_VERIFICATION_SECRET" flag:"email-verification-secret" secret:"true"`
+	LinkURL string        `yaml:"link_url" toml:"link_url" env:"EMAIL_VERIFICATION_URL" flag:"email-verification-url"`
+	TTL     time.Duration `yaml:"ttl" toml:"ttl" env:"EMAIL_VERIFICATION_TTL" flag:"email-verification-ttl"`
+	// ResendInterval and MaxPerDay limit the verification emails of a customer, zero disables a limit.
+	ResendInterval time.Duration `yaml:"resend_interval" toml:"resend_interval" env:"EMAIL_VERIFICATION_RESEND_INTERVAL" flag:"email-verification-resend-interval"`
+	MaxPerDay      int           `yaml:"max_per_day" toml:"max_per_day" env:"EMAIL_VERIFICATION_MAX_PER_DAY" flag:"email-verification-max-per-day"`
+}
+
+// Enabled reports whether email verification is enabled.
+func (c VerificationConfig) Enabled() bool {
+	return c.Secret != ""
+}
+
+// PortalConfig controls the customer self-service portal under /me. LoginURL is the page the
+// sign-in links open, which posts the token of its query to POST /me/session. LoginsPerHour
+// limits the sign-in links sent to a customer, zero disables the limit.
+type PortalConfig struct {
+	Enabled       bool          `yaml:"enabled" toml:"enabled" env:"PORTAL_ENABLED" flag:"portal-enabled"`
+	LoginURL      string        `yaml:"login_url" toml:"login_url" env:"PORTAL_LOGIN_URL" flag:"portal-login-url"`
+	LoginTTL      time.Duration `yaml:"login_ttl" toml:"login_ttl" env:"PORTAL_LOGIN_TTL" flag:"portal-login-ttl"`
+	SessionTTL    time.Duration `yaml:"session_ttl" toml:"session_ttl" env:"PORTAL_SESSION_TTL" flag:"portal-session-ttl"`
+	LoginsPerHour int           `yaml:"logins_per_hour" toml:"logins_per_hour" env:"PORTAL_LOGINS_PER_HOUR" flag:"portal-logins-per-hour"`
+	// InsecureCookies drops the Secure attribute of the session cookie, for local development over plain HTTP.
+	InsecureCookies bool `yaml:"insecure_cookies" toml:"insecure_cookies" env:"PORTAL_INSECURE_COOKIES" flag:"portal-insecure-cookies"`
+}
+
+// RateLimitConfig controls the rate limits of the HTTP API. Every client has a token bucket per
+// route holding at most Burst requests, which regains Requests every Period. Clients are told apart
+// by their X-API-Key header when the key is listed in APIKeys or Quotas, else by their IP address,
+// which is read from X-Forwarded-For with TrustProxy. Every API key may also make DailyQuota
+// requests a day, zero disabling the quota. Routes, APIKeys and Quotas are only read from the
+// config file.
+type RateLimitConfig struct {
+	Enabled    bool          `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED" flag:"rate-limit-enabled"`
+	Requests   int           `yaml:"requests" toml:"requests" env:"RATE_LIMIT_REQUESTS" flag:"rate-limit-requests"`
+	Period     time.Duration `yaml:"period" toml:"period" env:"RATE_LIMIT_PERIOD" flag:"rate-limit-period"`
+	Burst      int           `yaml:"burst" toml:"burst" env:"RATE_LIMIT_BURST" flag:"rate-limit-burst"`
+	TrustProxy bool          `yaml:"trust_proxy" toml:"trust_proxy" env:"RATE_LIMIT_TRUST_PROXY" flag:"rate-limit-trust-proxy"`
+	DailyQuota int           `yaml:"daily_quota" toml:"daily_quota" env:"RATE_LIMIT_DAILY_QUOTA" flag:"rate-limit-daily-quota"`
+	// Routes override the limit of single routes, zero Requests lifting it.
+	Routes []RouteLimitConfig `yaml:"routes,omitempty" toml:"routes"`
+	// APIKeys are the hex-encoded SHA-256 of the API keys clients may identify with, so that the
+	// keys stay out of the configuration.
+	APIKeys []string `yaml:"api_keys,omitempty" toml:"api_keys"`
+	// Quotas override DailyQuota by the hex-encoded SHA-256 of API keys, which identify clients
+	// too. Zero exempts a key.
+	Quotas map[string]int `yaml:"quotas,omitempty" toml:"quotas"`
+}
+
+// RouteLimitConfig is the limit of the route with the mux path template Path, such as
+// /customers/{id}. An empty Method matches every method.
+type RouteLimitConfig struct {
+	Method   string        `yaml:"method,omitempty" toml:"method"`
+	Path     string        `yaml:"path" toml:"path"`
+	Requests int           `yaml:"requests" toml:"requests"`
+	Period   time.Duration `yaml:"period" toml:"period"`
+	Burst    int           `yaml:"burst" toml:"burst"`
+}
+
+// CacheConfig controls the cache of customer lookups by ID and email. It holds at most Size
+// lookups for TTL, and remembers lookups that found no customer for NegativeTTL, zero disabling
+// it. The cache is invalidated by the writes and change events of its own instance only, so TTL
+// is the only bound on how long a change made by another instance of the service is not seen.
+type CacheConfig struct {
+	Enabled     bool          `yaml:"enabled" toml:"enabled" env:"CACHE_ENABLED" flag:"cache-enabled"`
+	Size        int           `yaml:"size" toml:"size" env:"CACHE_SIZE" flag:"cache-size"`
+	TTL         time.Duration `yaml:"ttl" toml:"ttl" env:"CACHE_TTL" flag:"cache-ttl"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" toml:"negative_ttl" env:"CACHE_NEGATIVE_TTL" flag:"cache-negative-ttl"`
+}
+
+// ResilienceConfig controls how the customer repository calls the database. Calls failing
+// transiently are made up to RetryAttempts times, waiting a jittered backoff from RetryBaseDelay
+// up to RetryMaxDelay in between, and every attempt is bounded by CallTimeout. After
+// BreakerFailures consecutive failed calls the circuit breaker fails calls fast for BreakerOpenFor.
+type ResilienceConfig struct {
+	Enabled         bool          `yaml:"enabled" toml:"enabled" env:"DB_RESILIENCE_ENABLED" flag:"db-resilience-enabled"`
+	RetryAttempts   int           `yaml:"retry_attempts" toml:"retry_attempts" env:"DB_RETRY_ATTEMPTS" flag:"db-retry-attempts"`
+	RetryBaseDelay  time.Duration `yaml:"retry_base_delay" toml:"retry_base_delay" env:"DB_RETRY_BASE_DELAY" flag:"db-retry-base-delay"`
+	RetryMaxDelay   time.Duration `yaml:"retry_max_delay" toml:"retry_max_delay" env:"DB_RETRY_MAX_DELAY" flag:"db-retry-max-delay"`
+	CallTimeout     time.Duration `yaml:"call_timeout" toml:"call_timeout" env:"DB_CALL_TIMEOUT" flag:"db-call-timeout"`
+	BreakerFailures int           `yaml:"breaker_failures" toml:"breaker_failures" env:"DB_BREAKER_FAILURES" flag:"db-breaker-failures"`
+	BreakerOpenFor  time.Duration `yaml:"breaker_open_for" toml:"breaker_open_for" env:"DB_BREAKER_OPEN_FOR" flag:"db-breaker-open-for"`
+}
+
+// FaultConfig enables injecting faults into the customer repository through /admin/faults, to
+// test how clients cope when the service degrades. It is refused in production.
+type FaultConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"FAULT_INJECTION_ENABLED" flag:"fault-injection-enabled"`
+}
+
+// Default returns the configuration used before any other layer is applied.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Environment:         "production",
+			Port:                8080,
+			ReadTimeout:         10 * time.Second,
+			WriteTimeout:        15 * time.Second,
+			IdleTimeout:         60 * time.Second,
+			ShutdownGracePeriod: 20 * time.Second,
+			ShutdownDrainDelay:  5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			MigrationsDir:   "./migrations",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+
+			ReplicaMaxOpenConns:  20,
+			ReplicaMaxIdleConns:  10,
+			ReplicaStickiness:    5 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Enabled: true,
+			Port:    9090,
+		},
+		GraphQL: GraphQLConfig{
+			Enabled:       true,
+			MaxDepth:      8,
+			MaxComplexity: 1000,
+		},
+		Features: FeatureConfig{
+			RunMigrations: true,
+		},
+		Attachments: AttachmentConfig{
+			Enabled: true,
+			Store:   "local",
+			MaxSize: 20 << 20,
+			AllowedTypes: []string{
+				"application/pdf", "image/jpeg", "image/png", "image/gif", "image/webp", "text/plain",
+				// Office documents are zip archives.
+				"application/zip",
+			},
+			Dir: "./attachments",
+			S3: S3Config{
+				Region: "us-east-1",
+			},
+		},
+		Encryption: EncryptionConfig{
+			KeyProvider:        "env",
+			ReencryptInterval:  time.Hour,
+			ReencryptBatchSize: 500,
+		},
+		Mail: MailConfig{
+			Mailer:    "file",
+			From:      "CustomerCRUD <noreply@localhost>",
+			OutboxDir: "./mail-outbox",
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
+		EmailVerification: VerificationConfig{
+			TTL:            48 * time.Hour,
+			ResendInterval: time.Minute,
+			MaxPerDay:      5,
+		},
+		Portal: PortalConfig{
+			LoginTTL:      15 * time.Minute,
+			SessionTTL:    24 * time.Hour,
+			LoginsPerHour: 5,
+		},
+		RateLimit: RateLimitConfig{
+			Requests: 600,
+			Period:   time.Minute,
+			Burst:    100,
+		},
+		Cache: CacheConfig{
+			Enabled:     true,
+			Size:        10000,
+			TTL:         30 * time.Second,
+			NegativeTTL: 10 * time.Second,
+		},
+		Resilience: ResilienceConfig{
+			Enabled:         true,
+			RetryAttempts:   3,
+			RetryBaseDelay:  50 * time.Millisecond,
+			RetryMaxDelay:   time.Second,
+			CallTimeout:     5 * time.Second,
+			BreakerFailures: 10,
+			BreakerOpenFor:  30 * time.Second,
+		},
+	}
+}
+
+// Validate reports every problem found in the configuration at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	switch c.Server.Environment {
+	case "production", "staging", "development":
+	default:
+		errs = append(errs, fmt.Errorf("environment %q is not production, staging or development", c.Server.Environment))
+	}
+	if c.Faults.Enabled && c.Server.Environment == "production" {
+		errs = append(errs, errors.New("fault injection must not be enabled in production"))
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server port %d is out of range", c.Server.Port))
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 {
+		errs = append(errs, errors.New("server timeouts must be positive"))
+	}
+	if c.Server.ShutdownGracePeriod <= 0 {
+		errs = append(errs, errors.New("shutdown grace period must be positive"))
+	}
+	if c.Server.ShutdownDrainDelay < 0 {
+		errs = append(errs, errors.New("shutdown drain delay must not be negative"))
+	}
+
+	if c.GRPC.Enabled && !c.GRPC.Multiplex {
+		if c.GRPC.Port < 1 || c.GRPC.Port > 65535 {
+			errs = append(errs, fmt.Errorf("gRPC port %d is out of range", c.GRPC.Port))
+		}
+		if c.GRPC.Port == c.Server.Port {
+			errs = append(errs, errors.New("gRPC port must differ from the server port unless multiplexing is enabled"))
+		}
+	}
+
+	if c.GraphQL.Enabled && (c.GraphQL.MaxDepth < 1 || c.GraphQL.MaxComplexity < 1) {
+		errs = append(errs, errors.New("GraphQL depth and complexity limits must be positive"))
+	}
+
+	if !c.Database.Local && c.Database.DSN == "" {
+		errs = append(errs, errors.New("database DSN is required unless the local database is used"))
+	}
+	if c.Database.MigrationsDir == "" && c.Features.RunMigrations {
+		errs = append(errs, errors.New("migrations directory is required when migrations are enabled"))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database pool sizes must not be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("max idle connections (%d) exceed max open connections (%d)",
+			c.Database.MaxIdleConns, c.Database.MaxOpenConns))
+	}
+	if c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("database connection lifetimes must not be negative"))
+	}
+	if c.Database.ReplicaDSN != "" {
+		if c.Database.Local {
+			errs = append(errs, errors.New("a read replica cannot be used with the local database"))
+		}
+		if c.Database.ReplicaMaxOpenConns < 0 || c.Database.ReplicaMaxIdleConns < 0 {
+			errs = append(errs, errors.New("replica pool sizes must not be negative"))
+		}
+		if c.Database.ReplicaMaxOpenConns > 0 && c.Database.ReplicaMaxIdleConns > c.Database.ReplicaMaxOpenConns {
+			errs = append(errs, fmt.Errorf("replica max idle connections (%d) exceed replica max open connections (%d)",
+				c.Database.ReplicaMaxIdleConns, c.Database.ReplicaMaxOpenConns))
+		}
+		if c.Database.ReplicaStickiness < 0 || c.Database.ReplicaCheckInterval <= 0 {
+			errs = append(errs, errors.New("replica stickiness must not be negative and the replica check interval must be positive"))
+		}
+	}
+
+	if c.Attachments.Enabled {
+		if c.Attachments.MaxSize < 1 {
+			errs = append(errs, errors.New("attachment max size must be positive"))
+		}
+		switch c.Attachments.Store {
+		case "local":
+			if c.Attachments.Dir == "" {
+				errs = append(errs, errors.New("attachment directory is required for the local store"))
+			}
+		case "s3":
+			if c.Attachments.S3.Endpoint == "" || c.Attachments.S3.Bucket == "" || c.Attachments.S3.Region == "" {
+				errs = append(errs, errors.New("S3 endpoint, bucket and region are required for the s3 attachment store"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("attachment store %q is not local or s3", c.Attachments.Store))
+		}
+	}
+
+	if c.Encryption.Enabled {
+		switch c.Encryption.KeyProvider {
+		case "env":
+			if c.Encryption.MasterKeys == "" || c.Encryption.IndexKey == "" {
+				errs = append(errs, errors.New("encryption master keys and index key are required for the env key provider"))
+			}
+		case "file":
+			if c.Encryption.KeyFile == "" {
+				errs = append(errs, errors.New("encryption key file is required for the file key provider"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("encryption key provider %q is not env or file", c.Encryption.KeyProvider))
+		}
+		if c.Encryption.ReencryptInterval <= 0 || c.Encryption.ReencryptBatchSize < 1 {
+			errs = append(errs, errors.New("re-encryption interval and batch size must be positive"))
+		}
+	}
+
+	if n := len(c.Consents.UnsubscribeSecret); n > 0 && n < minTokenSecretLength {
+		errs = append(errs, fmt.Errorf("unsubscribe secret must be at least %d characters long", minTokenSecretLength))
+	}
+
+	if c.EmailVerification.Enabled() {
+		if len(c.EmailVerification.Secret) < minTokenSecretLength {
+			errs = append(errs, fmt.Errorf("email verification secret must be at least %d characters long", minTokenSecretLength))
+		}
+		if u, err := url.Parse(c.EmailVerification.LinkURL); err != nil || !u.IsAbs() {
+			errs = append(errs, errors.New("email verification URL must be an absolute URL"))
+		}
+		if c.EmailVerification.TTL <= 0 {
+			errs = append(errs, errors.New("email verification TTL must be positive"))
+		}
+		if c.EmailVerification.ResendInterval < 0 || c.EmailVerification.MaxPerDay < 0 {
+			errs = append(errs, errors.New("email verification resend limits must not be negative"))
+		}
+	}
+
+	if c.Portal.Enabled {
+		if u, err := url.Parse(c.Portal.LoginURL); err != nil || !u.IsAbs() {
+			errs = append(errs, errors.New("portal login URL must be an absolute URL"))
+		}
+		if c.Portal.LoginTTL <= 0 || c.Portal.SessionTTL <= 0 {
+			errs = append(errs, errors.New("portal login and session TTLs must be positive"))
+		}
+		if c.Portal.LoginsPerHour < 0 {
+			errs = append(errs, errors.New("portal logins per hour must not be negative"))
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Requests < 1 || c.RateLimit.Period <= 0 || c.RateLimit.Burst < 1 {
+			errs = append(errs, errors.New("rate limit requests, period and burst must be positive"))
+		}
+		for _, r := range c.RateLimit.Routes {
+			if !strings.HasPrefix(r.Path, "/") {
+				errs = append(errs, fmt.Errorf("rate limit route path %q must start with /", r.Path))
+			}
+			if r.Requests != 0 && (r.Requests < 0 || r.Period <= 0 || r.Burst < 1) {
+				errs = append(errs, fmt.Errorf("rate limit of route %s needs positive requests, period and burst", strings.TrimSpace(r.Method+" "+r.Path)))
+			}
+		}
+		if c.RateLimit.DailyQuota < 0 {
+			errs = append(errs, errors.New("rate limit daily quota must not be negative"))
+		}
+		for _, id := range c.RateLimit.APIKeys {
+			if _, err := hex.DecodeString(id); err != nil || len(id) != 64 {
+				errs = append(errs, fmt.Errorf("rate limit API key %q needs the SHA-256 of an API key", id))
+			}
+		}
+		for id, quota := range c.RateLimit.Quotas {
+			if _, err := hex.DecodeString(id); err != nil || len(id) != 64 || quota < 0 {
+				errs = append(errs, fmt.Errorf("rate limit quota %q needs the SHA-256 of an API key and a quota of at least zero", id))
+			}
+		}
+	}
+
+	if c.Cache.Enabled {
+		if c.Cache.Size < 1 || c.Cache.TTL <= 0 {
+			errs = append(errs, errors.New("cache size and TTL must be positive"))
+		}
+		if c.Cache.NegativeTTL < 0 {
+			errs = append(errs, errors.New("cache negative TTL must not be negative"))
+		}
+	}
+
+	if c.Resilience.Enabled {
+		if c.Resilience.RetryAttempts < 1 || c.Resilience.BreakerFailures < 1 || c.Resilience.BreakerOpenFor <= 0 {
+			errs = append(errs, errors.New("database retry attempts, breaker failures and breaker open duration must be positive"))
+		}
+		if c.Resilience.RetryBaseDelay < 0 || c.Resilience.RetryMaxDelay < c.Resilience.RetryBaseDelay || c.Resilience.CallTimeout < 0 {
+			errs = append(errs, errors.New("database retry delays and call timeout must not be negative, with the max delay at least the base delay"))
+		}
+	}
+
+	if c.EmailVerification.Enabled() || c.Portal.Enabled {
+		if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+			errs = append(errs, fmt.Errorf("mail sender %q is not an email address", c.Mail.From))
+		}
+		switch c.Mail.Mailer {
+		case "smtp":
+			if c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port < 1 || c.Mail.SMTP.Port > 65535 {
+				errs = append(errs, errors.New("SMTP host and port are required for the smtp mailer"))
+			}
+		case "file":
+			if c.Mail.OutboxDir == "" {
+				errs = append(errs, errors.New("mail outbox directory is required for the file mailer"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("mailer %q is not smtp or file", c.Mail.Mailer))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envFrom(values map[string]string) LookupEnv {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, envFrom(map[string]string{"LOCAL_DB": "true"}))
+	require.NoError(t, err)
+
+	expected := Default()
+	expected.Database.Local = true
+	assert.Equal(t, expected, cfg)
+}
+
+func TestLoad_Layering(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 9000
+  read_timeout: 3s
+database:
+  dsn: postgres://file@localhost/customers
+  max_open_conns: 20
+`)
+
+	env := envFrom(map[string]string{
+		"CONFIG_FILE":       path,
+		"PORT":              "9100",
+		"DB_MAX_IDLE_CONNS": "7",
+	})
+
+	cfg, err := Load([]string{"--port", "9200", "--run-migrations=false"}, env)
+	require.NoError(t, err)
+
+	assert.Equal(t, 9200, cfg.Server.Port)
+	assert.Equal(t, 3*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, Default().Server.WriteTimeout, cfg.Server.WriteTimeout)
+	assert.Equal(t, "postgres://file@localhost/customers", cfg.Database.DSN)
+	assert.Equal(t, 20, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 7, cfg.Database.MaxIdleConns)
+	assert.False(t, cfg.Features.RunMigrations)
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[server]
+port = 9300
+idle_timeout = "2m"
+
+[database]
+local = true
+
+[lifecycle.transitions]
+closed = ["active"]
+`)
+
+	cfg, err := Load([]string{"--config", path}, envFrom(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9300, cfg.Server.Port)
+	assert.Equal(t, 2*time.Minute, cfg.Server.IdleTimeout)
+	assert.True(t, cfg.Database.Local)
+	assert.Equal(t, map[string][]string{"closed": {"active"}}, cfg.Lifecycle.Transitions)
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	path := writeFile(t, "config.yaml", "server:\n  prot: 9000\n")
+
+	_, err := Load([]string{"--config", path, "--local-db"}, envFrom(nil))
+	assert.Error(t, err)
+}
+
+func TestLoad_FileIndirection(t *testing.T) {
+	secret := writeFile(t, "dsn", "postgres://user:s3cret@db:5432/customers\n")
+
+	cfg, err := Load(nil, envFrom(map[string]string{"DATABASE_URL_FILE": secret}))
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://user:s3cret@db:5432/customers", cfg.Database.DSN)
+
+	_, err = Load(nil, envFrom(map[string]string{
+		"DATABASE_URL":      "postgres://other@db/customers",
+		"DATABASE_URL_FILE": secret,
+	}))
+	assert.Error(t, err)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(nil, envFrom(map[string]string{
+		"PORT":                      "70000",
+		"SHUTDOWN_DRAIN_DELAY":      "-1s",
+		"DB_MAX_OPEN_CONNS":         "2",
+		"DB_MAX_IDLE_CONNS":         "5",
+		"ATTACHMENT_STORE":          "s3",
+		"ENCRYPTION_ENABLED":        "true",
+		"UNSUBSCRIBE_SECRET":        "short",
+		"EMAIL_VERIFICATION_SECRET": "short",
+		"MAIL_MAILER":               "smtp",
+		"PORTAL_ENABLED":            "true",
+		"PORTAL_LOGIN_URL":          "/me/login",
+		"PORTAL_LOGINS_PER_HOUR":    "-1",
+		"RATE_LIMIT_ENABLED":        "true",
+		"RATE_LIMIT_BURST":          "0",
+		"CACHE_TTL":                 "0s",
+		"DATABASE_REPLICA_URL":      "postgres://replica",
+		"DB_REPLICA_MAX_IDLE_CONNS": "50",
+		"DB_RETRY_ATTEMPTS":         "0",
+		"FAULT_INJECTION_ENABLED":   "true",
+	}))
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "port 70000 is out of range")
+	assert.Contains(t, msg, "shutdown drain delay must not be negative")
+	assert.Contains(t, msg, "database DSN is required")
+	assert.Contains(t, msg, "max idle connections (5) exceed max open connections (2)")
+	assert.Contains(t, msg, "S3 endpoint, bucket and region are required")
+	assert.Contains(t, msg, "encryption master keys and index key are required")
+	assert.Contains(t, msg, "unsubscribe secret must be at least 32 characters long")
+	assert.Contains(t, msg, "email verification secret must be at least 32 characters long")
+	assert.Contains(t, msg, "email verification URL must be an absolute URL")
+	assert.Contains(t, msg, "SMTP host and port are required for the smtp mailer")
+	assert.Contains(t, msg, "portal login URL must be an absolute URL")
+	assert.Contains(t, msg, "portal logins per hour must not be negative")
+	assert.Contains(t, msg, "rate limit requests, period and burst must be positive")
+	assert.Contains(t, msg, "cache size and TTL must be positive")
+	assert.Contains(t, msg, "replica max idle connections (50) exceed replica max open connections (20)")
+	assert.Contains(t, msg, "database retry attempts, breaker failures and breaker open duration must be positive")
+	assert.Contains(t, msg, "fault injection must not be enabled in production")
+
+	_, err = Load(nil, envFrom(map[string]string{"ENVIRONMENT": "qa"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `environment "qa" is not production, staging or development`)
+
+	_, err = Load(nil, envFrom(map[string]string{"LOCAL_DB": "yes please"}))
+	assert.Error(t, err)
+}
+
+func TestString_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.DSN = "postgres://user:s3cret@db:5432/customers?sslmode=require"
+
+	out := cfg.String()
+	assert.NotContains(t, out, "s3cret")
+	assert.Contains(t, out, "postgres://user:****@db:5432/customers?sslmode=require")
+	assert.Contains(t, out, "read_timeout: 10s")
+
+	cfg.Database.DSN = "host=db user=user password=s3cret"
+	assert.Equal(t, "****", cfg.Redacted().Database.DSN)
+
+	cfg.Attachments.S3.SecretAccessKey = "s3cret"
+	assert.Equal(t, "****", cfg.Redacted().Attachments.S3.SecretAccessKey)
+	assert.False(t, strings.Contains(cfg.String(), "s3cret"))
+
+	cfg.Encryption.MasterKeys = "k1:s3cret"
+	cfg.Encryption.IndexKey = "s3cret"
+	redacted := cfg.Redacted()
+	assert.Equal(t, "****", redacted.Encryption.MasterKeys)
+	assert.Equal(t, "****", redacted.Encryption.IndexKey)
+
+	cfg.Consents.UnsubscribeSecret = "s3cret"
+	assert.Equal(t, "****", cfg.Redacted().Consents.UnsubscribeSecret)
+
+	cfg.Mail.SMTP.Password = "s3cret"
+	cfg.EmailVerification.Secret = "s3cret"
+	redacted = cfg.Redacted()
+	assert.Equal(t, "****", redacted.Mail.SMTP.Password)
+	assert.Equal(t, "****", redacted.EmailVerification.Secret)
+}
+
+func TestLoad_PortalMail(t *testing.T) {
+	// The portal sends its sign-in links with the mailer, also without email verification.
+	_, err := Load(nil, envFrom(map[string]string{
+		"DATABASE_URL":     "postgres://user@db/customers",
+		"PORTAL_ENABLED":   "true",
+		"PORTAL_LOGIN_URL": "https://app.example.com/sign-in",
+		"MAIL_MAILER":      "pigeon",
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `mailer "pigeon" is not smtp or file`)
+}
+
+func TestLoad_RateLimitRoutes(t *testing.T) {
+	quotaKey := strings.Repeat("ab", 32)
+	path := writeFile(t, "config.yaml", `
+database:
+  local: true
+rate_limit:
+  enabled: true
+  daily_quota: 10000
+  routes:
+    - method: GET
+      path: /customers
+      requests: 10
+      period: 1s
+      burst: 20
+  api_keys:
+    - `+strings.Repeat("cd", 32)+`
+  quotas:
+    `+quotaKey+`: 50000
+`)
+
+	cfg, err := Load([]string{"--config", path}, envFrom(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, []RouteLimitConfig{{Method: "GET", Path: "/customers", Requests: 10, Period: time.Second, Burst: 20}}, cfg.RateLimit.Routes)
+	assert.Equal(t, []string{strings.Repeat("cd", 32)}, cfg.RateLimit.APIKeys)
+	assert.Equal(t, map[string]int{quotaKey: 50000}, cfg.RateLimit.Quotas)
+	assert.Equal(t, 10000, cfg.RateLimit.DailyQuota)
+
+	path = writeFile(t, "config.yaml", `
+database:
+  local: true
+rate_limit:
+  enabled: true
+  routes:
+    - path: customers
+      requests: 10
+  api_keys:
+    - partner
+  quotas:
+    not-a-hash: 1
+`)
+	_, err = Load([]string{"--config", path}, envFrom(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `rate limit route path "customers" must start with /`)
+	assert.Contains(t, err.Error(), "rate limit of route customers needs positive requests, period and burst")
+	assert.Contains(t, err.Error(), `rate limit API key "partner" needs the SHA-256 of an API key`)
+	assert.Contains(t, err.Error(), `rate limit quota "not-a-hash" needs the SHA-256 of an API key`)
+}
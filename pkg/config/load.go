@@ -0,0 +1,282 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// fileSuffix marks an environment variable holding the path of a file with the actual value.
+	fileSuffix = "_FILE"
+	redacted   = "****"
+)
+
+// LookupEnv matches the signature of os.LookupEnv so tests can provide their own environment.
+type LookupEnv func(key string) (string, bool)
+
+// field is a leaf of Config together with the tags describing where it can be set from.
+type field struct {
+	value  reflect.Value
+	env    string
+	flag   string
+	secret bool
+	path   string
+}
+
+// Load builds the effective configuration from the defaults, the optional config file,
+// the environment and the command line arguments, then validates it. The config file is
+// given with --config or CONFIG_FILE and its format is picked by its extension.
+func Load(args []string, lookupEnv LookupEnv) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("customer-service", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file")
+
+	flagValues := map[string]*flagValue{}
+	for _, f := range fields(&cfg) {
+		if f.flag == "" {
+			continue
+		}
+		fv := &flagValue{isBool: f.value.Kind() == reflect.Bool}
+		flagValues[f.flag] = fv
+		fs.Var(fv, f.flag, "overrides "+f.path)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	path := *configFile
+	if path == "" {
+		var err error
+		if path, err = envValue(lookupEnv, "CONFIG_FILE"); err != nil {
+			return Config{}, err
+		}
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, f := range fields(&cfg) {
+		if f.env == "" {
+			continue
+		}
+		raw, err := envValue(lookupEnv, f.env)
+		if err != nil {
+			return Config{}, err
+		}
+		if raw == "" {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			return Config{}, fmt.Errorf("invalid value for %s: %w", f.env, err)
+		}
+	}
+
+	setFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	for _, f := range fields(&cfg) {
+		if !setFlags[f.flag] {
+			continue
+		}
+		if err := setValue(f.value, flagValues[f.flag].value); err != nil {
+			return Config{}, fmt.Errorf("invalid value for --%s: %w", f.flag, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// envValue reads key from the environment, following the KEY_FILE indirection when it is used.
+func envValue(lookupEnv LookupEnv, key string) (string, error) {
+	value, ok := lookupEnv(key)
+	filePath, fileOK := lookupEnv(key + fileSuffix)
+	if !fileOK || filePath == "" {
+		return value, nil
+	}
+	if ok && value != "" {
+		return "", fmt.Errorf("only one of %s and %s%s can be set", key, key, fileSuffix)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s%s: %w", key, fileSuffix, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(content))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && err != io.EOF {
+			return fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(content), cfg)
+		if err != nil {
+			return fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys in config file %s: %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q", ext)
+	}
+	return nil
+}
+
+// fields flattens the nested config structs into their leaf fields.
+func fields(cfg *Config) []field {
+	var out []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			path := strings.ToLower(sf.Name)
+			if name := strings.Split(sf.Tag.Get("yaml"), ",")[0]; name != "" {
+				path = name
+			}
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), path)
+				continue
+			}
+			out = append(out, field{
+				value:  v.Field(i),
+				env:    sf.Tag.Get("env"),
+				flag:   sf.Tag.Get("flag"),
+				secret: sf.Tag.Get("secret") == "true",
+				path:   path,
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return out
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration that is safe to log.
+func (c Config) Redacted() Config {
+	out := c
+	for _, f := range fields(&out) {
+		if !f.secret || f.value.Kind() != reflect.String || f.value.String() == "" {
+			continue
+		}
+		f.value.SetString(redact(f.value.String()))
+	}
+	return out
+}
+
+// String renders the redacted configuration as YAML.
+func (c Config) String() string {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return fmt.Sprintf("error rendering config: %v", err)
+	}
+	return buf.String()
+}
+
+// redact hides the password of connection URLs and the whole value of anything else.
+func redact(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return redacted
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	query := u.Query()
+	for key := range query {
+		if strings.Contains(strings.ToLower(key), "password") {
+			query.Set(key, redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	// Keep the mask readable instead of percent-encoded.
+	return strings.ReplaceAll(u.String(), url.QueryEscape(redacted), redacted)
+}
+
+// flagValue stores the raw flag argument so flags can be applied after the other layers.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string     { return f.value }
+func (f *flagValue) Set(s string) error { f.value = s; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.isBool }
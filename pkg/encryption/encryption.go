@@ -0,0 +1,256 @@
+// Package encryption protects personal data at rest with envelope encryption. Values are encrypted
+// with AES-256-GCM data keys, which are stored next to the data wrapped with a master key of a
+// KeyProvider. Rotating the master key only wraps the data keys again, the values themselves are
+// moved to a fresh data key in the background by a Rotator.
+//
+// Encrypted values cannot be compared in SQL, so equality lookups go through a blind index: a keyed
+// HMAC of the plaintext stored beside the ciphertext.
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// prefix marks encrypted values, which read prefix<data key ID>:<base64 nonce and ciphertext>.
+// Values without it are plaintext stored before encryption was enabled.
+const prefix = "enc:v1:"
+
+// DataKey is a data key as it is stored, wrapped with the master key MasterKeyID.
+type DataKey struct {
+	ID          string
+	MasterKeyID string
+	WrappedKey  []byte
+	CreatedAt   time.Time
+}
+
+// DataKeyStore keeps the wrapped data keys.
+type DataKeyStore interface {
+	// ListDataKeys returns every data key, oldest first.
+	ListDataKeys(ctx context.Context) ([]DataKey, error)
+	CreateDataKey(ctx context.Context, key DataKey) error
+	// UpdateDataKey stores key wrapped again with another master key.
+	UpdateDataKey(ctx context.Context, key DataKey) error
+}
+
+// Encryptor encrypts values with the current data key and decrypts them with whichever data key
+// they were encrypted with. It is safe for concurrent use.
+type Encryptor struct {
+	provider KeyProvider
+	store    DataKeyStore
+
+	mu      sync.RWMutex
+	keys    map[string]cipher.AEAD
+	current string
+}
+
+// NewEncryptor loads the data keys of store. When none of them is wrapped with the current master
+// key of provider, which is the case on first use and after the master key was rotated, a new data
+// key is created and becomes current.
+func NewEncryptor(ctx context.Context, provider KeyProvider, store DataKeyStore) (*Encryptor, error) {
+	e := &Encryptor{provider: provider, store: store}
+	if err := e.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Refresh reloads the data keys, picking up the ones created by other instances. The current data
+// key is the newest one wrapped with the current master key, so that all instances settle on the
+// same one.
+func (e *Encryptor) Refresh(ctx context.Context) error {
+	stored, err := e.store.ListDataKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing data keys: %w", err)
+	}
+
+	keys := make(map[string]cipher.AEAD, len(stored))
+	var current string
+	for _, key := range stored {
+		aead, err := e.unwrap(ctx, key)
+		if err != nil {
+			return err
+		}
+		keys[key.ID] = aead
+		if key.MasterKeyID == e.provider.CurrentKeyID() {
+			current = key.ID
+		}
+	}
+
+	if current == "" {
+		key, aead, err := e.createDataKey(ctx)
+		if err != nil {
+			return err
+		}
+		keys[key.ID] = aead
+		current = key.ID
+		log.Infof("created data key %s wrapped with master key %s", key.ID, key.MasterKeyID)
+	}
+
+	e.mu.Lock()
+	e.keys, e.current = keys, current
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *Encryptor) unwrap(ctx context.Context, key DataKey) (cipher.AEAD, error) {
+	plain, err := e.provider.UnwrapKey(ctx, key.MasterKeyID, key.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key %s with master key %s: %w", key.ID, key.MasterKeyID, err)
+	}
+	return newAEAD(plain)
+}
+
+func (e *Encryptor) createDataKey(ctx context.Context) (DataKey, cipher.AEAD, error) {
+	plain := make([]byte, KeySize)
+	if _, err := rand.Read(plain); err != nil {
+		return DataKey{}, nil, err
+	}
+	masterKeyID := e.provider.CurrentKeyID()
+	wrapped, err := e.provider.WrapKey(ctx, masterKeyID, plain)
+	if err != nil {
+		return DataKey{}, nil, fmt.Errorf("error wrapping data key: %w", err)
+	}
+	key := DataKey{
+		ID:          uuid.NewString(),
+		MasterKeyID: masterKeyID,
+		WrappedKey:  wrapped,
+		CreatedAt:   time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := e.store.CreateDataKey(ctx, key); err != nil {
+		return DataKey{}, nil, fmt.Errorf("error storing data key: %w", err)
+	}
+	aead, err := newAEAD(plain)
+	return key, aead, err
+}
+
+// RewrapDataKeys wraps the data keys still wrapped with an older master key with the current one,
+// after which the older master key can be removed from the provider. It returns how many data keys
+// it wrapped again.
+func (e *Encryptor) RewrapDataKeys(ctx context.Context) (int, error) {
+	stored, err := e.store.ListDataKeys(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error listing data keys: %w", err)
+	}
+
+	masterKeyID := e.provider.CurrentKeyID()
+	n := 0
+	for _, key := range stored {
+		if key.MasterKeyID == masterKeyID {
+			continue
+		}
+		plain, err := e.provider.UnwrapKey(ctx, key.MasterKeyID, key.WrappedKey)
+		if err != nil {
+			return n, fmt.Errorf("error unwrapping data key %s with master key %s: %w", key.ID, key.MasterKeyID, err)
+		}
+		if key.WrappedKey, err = e.provider.WrapKey(ctx, masterKeyID, plain); err != nil {
+			return n, fmt.Errorf("error wrapping data key %s: %w", key.ID, err)
+		}
+		key.MasterKeyID = masterKeyID
+		if err := e.store.UpdateDataKey(ctx, key); err != nil {
+			return n, fmt.Errorf("error storing data key %s: %w", key.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Encrypt encrypts plaintext with the current data key. The empty string stays empty, so that
+// missing optional values can still be told apart.
+func (e *Encryptor) Encrypt(_ context.Context, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	e.mu.RLock()
+	id, aead := e.current, e.keys[e.current]
+	e.mu.RUnlock()
+
+	sealed, err := seal(aead, []byte(plaintext))
+	if err != nil {
+		return "", fmt.Errorf("error encrypting value: %w", err)
+	}
+	return prefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of a value returned by Encrypt. Values stored before encryption was
+// enabled are returned as they are.
+func (e *Encryptor) Decrypt(ctx context.Context, stored string) (string, error) {
+	id, encoded, ok := parse(stored)
+	if !ok {
+		return stored, nil
+	}
+
+	e.mu.RLock()
+	aead, known := e.keys[id]
+	e.mu.RUnlock()
+	if !known {
+		// The data key was created by another instance since the keys were loaded.
+		if err := e.Refresh(ctx); err != nil {
+			return "", err
+		}
+		e.mu.RLock()
+		aead, known = e.keys[id]
+		e.mu.RUnlock()
+		if !known {
+			return "", fmt.Errorf("unknown data key %s", id)
+		}
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := open(aead, sealed)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting value: %w", err)
+	}
+	return string(plain), nil
+}
+
+// BlindIndex returns the hex encoded HMAC-SHA256 of value under the index key, empty for the empty
+// string. Equal values have equal indexes, whichever data key they are encrypted with.
+func (e *Encryptor) BlindIndex(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, e.provider.IndexKey())
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Stale reports whether a stored value should be encrypted again because it is plaintext or
+// encrypted with a data key that is no longer current.
+func (e *Encryptor) Stale(stored string) bool {
+	if stored == "" {
+		return false
+	}
+	id, _, ok := parse(stored)
+	if !ok {
+		return true
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return id != e.current
+}
+
+// parse splits an encrypted value into the ID of its data key and its encoded ciphertext.
+func parse(stored string) (id, encoded string, ok bool) {
+	rest, ok := strings.CutPrefix(stored, prefix)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
@@ -0,0 +1,228 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is a DataKeyStore keeping the data keys in memory.
+type memoryStore struct {
+	mu   sync.Mutex
+	keys []DataKey
+}
+
+func (s *memoryStore) ListDataKeys(context.Context) ([]DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DataKey(nil), s.keys...), nil
+}
+
+func (s *memoryStore) CreateDataKey(_ context.Context, key DataKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryStore) UpdateDataKey(_ context.Context, key DataKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.keys {
+		if s.keys[i].ID == key.ID {
+			s.keys[i] = key
+		}
+	}
+	return nil
+}
+
+// stubReencrypter records how it was called.
+type stubReencrypter struct {
+	batchSizes []int
+}
+
+func (s *stubReencrypter) Reencrypt(_ context.Context, batchSize int) (int, error) {
+	s.batchSizes = append(s.batchSizes, batchSize)
+	return 0, nil
+}
+
+func randomKey(t *testing.T) string {
+	key := make([]byte, KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestEncryptor_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	keys, err := NewEnvKeyProvider("k1:"+randomKey(t), randomKey(t))
+	require.NoError(t, err)
+	store := &memoryStore{}
+	e, err := NewEncryptor(ctx, keys, store)
+	require.NoError(t, err)
+	require.Len(t, store.keys, 1)
+
+	first, err := e.Encrypt(ctx, "jane@example.com")
+	require.NoError(t, err)
+	second, err := e.Encrypt(ctx, "jane@example.com")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(first, prefix+store.keys[0].ID+":"))
+	assert.NotContains(t, first, "jane")
+	assert.NotEqual(t, first, second, "every encryption uses a fresh nonce")
+
+	plain, err := e.Decrypt(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", plain)
+
+	empty, err := e.Encrypt(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	// Values stored before encryption was enabled are read as they are.
+	plain, err = e.Decrypt(ctx, "legacy@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy@example.com", plain)
+	assert.True(t, e.Stale("legacy@example.com"))
+	assert.False(t, e.Stale(first))
+	assert.False(t, e.Stale(""))
+
+	tampered := first[:len(first)-2] + "AA"
+	_, err = e.Decrypt(ctx, tampered)
+	assert.Error(t, err)
+}
+
+func TestEncryptor_BlindIndex(t *testing.T) {
+	ctx := context.Background()
+	indexKey := randomKey(t)
+	keys, err := NewEnvKeyProvider("k1:"+randomKey(t), indexKey)
+	require.NoError(t, err)
+	e, err := NewEncryptor(ctx, keys, &memoryStore{})
+	require.NoError(t, err)
+
+	index := e.BlindIndex("jane@example.com")
+	assert.Len(t, index, 64)
+	assert.Equal(t, index, e.BlindIndex("jane@example.com"))
+	assert.NotEqual(t, index, e.BlindIndex("john@example.com"))
+	assert.Empty(t, e.BlindIndex(""))
+
+	// The index only depends on the index key, not on the master or data keys.
+	other, err := NewEnvKeyProvider("k2:"+randomKey(t), indexKey)
+	require.NoError(t, err)
+	e2, err := NewEncryptor(ctx, other, &memoryStore{})
+	require.NoError(t, err)
+	assert.Equal(t, index, e2.BlindIndex("jane@example.com"))
+}
+
+func TestEncryptor_MasterKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	k1, k2, indexKey := randomKey(t), randomKey(t), randomKey(t)
+	store := &memoryStore{}
+
+	before, err := NewEnvKeyProvider("k1:"+k1, indexKey)
+	require.NoError(t, err)
+	e, err := NewEncryptor(ctx, before, store)
+	require.NoError(t, err)
+	old, err := e.Encrypt(ctx, "+15551234567")
+	require.NoError(t, err)
+
+	// k2 becomes current, k1 stays available to unwrap the existing data key.
+	after, err := NewEnvKeyProvider("k2:"+k2+", k1:"+k1, indexKey)
+	require.NoError(t, err)
+	e, err = NewEncryptor(ctx, after, store)
+	require.NoError(t, err)
+	require.Len(t, store.keys, 2)
+	assert.True(t, e.Stale(old), "values of the previous data key are moved to the new one")
+
+	plain, err := e.Decrypt(ctx, old)
+	require.NoError(t, err)
+	assert.Equal(t, "+15551234567", plain)
+
+	target := &stubReencrypter{}
+	require.NoError(t, NewRotator(e, target, 0, 25).RunOnce(ctx))
+	assert.Equal(t, []int{25}, target.batchSizes)
+	for _, key := range store.keys {
+		assert.Equal(t, "k2", key.MasterKeyID)
+	}
+
+	// Once every data key is wrapped with k2, k1 can be retired.
+	retired, err := NewEnvKeyProvider("k2:"+k2, indexKey)
+	require.NoError(t, err)
+	e, err = NewEncryptor(ctx, retired, store)
+	require.NoError(t, err)
+	plain, err = e.Decrypt(ctx, old)
+	require.NoError(t, err)
+	assert.Equal(t, "+15551234567", plain)
+}
+
+func TestEncryptor_PicksUpDataKeysOfOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	k1, k2, indexKey := randomKey(t), randomKey(t), randomKey(t)
+	store := &memoryStore{}
+
+	// A new master key is rolled out to every instance before one of them makes it current.
+	keys, err := NewEnvKeyProvider("k1:"+k1+",k2:"+k2, indexKey)
+	require.NoError(t, err)
+	reader, err := NewEncryptor(ctx, keys, store)
+	require.NoError(t, err)
+
+	rotated, err := NewEnvKeyProvider("k2:"+k2+",k1:"+k1, indexKey)
+	require.NoError(t, err)
+	writer, err := NewEncryptor(ctx, rotated, store)
+	require.NoError(t, err)
+	stored, err := writer.Encrypt(ctx, "jane@example.com")
+	require.NoError(t, err)
+
+	plain, err := reader.Decrypt(ctx, stored)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", plain)
+}
+
+func TestNewEnvKeyProvider_Invalid(t *testing.T) {
+	key := randomKey(t)
+	for name, tc := range map[string]struct{ masterKeys, indexKey string }{
+		"no master key":   {"", key},
+		"missing id":      {key, key},
+		"not base64":      {"k1:not-base64!", key},
+		"short key":       {"k1:" + base64.StdEncoding.EncodeToString([]byte("short")), key},
+		"missing index":   {"k1:" + key, ""},
+		"short index key": {"k1:" + key, base64.StdEncoding.EncodeToString([]byte("short"))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEnvKeyProvider(tc.masterKeys, tc.indexKey)
+			require.Error(t, err)
+			assert.NotContains(t, err.Error(), key, "key material stays out of errors")
+		})
+	}
+}
+
+func TestNewFileKeyProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	content := `{"current": "2026-10", "master_keys": {"2026-01": "` + randomKey(t) + `", "2026-10": "` + randomKey(t) +
+		`"}, "index_key": "` + randomKey(t) + `"}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	keys, err := NewFileKeyProvider(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10", keys.CurrentKeyID())
+
+	ctx := context.Background()
+	wrapped, err := keys.WrapKey(ctx, "2026-01", []byte("data key"))
+	require.NoError(t, err)
+	plain, err := keys.UnwrapKey(ctx, "2026-01", wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "data key", string(plain))
+	_, err = keys.UnwrapKey(ctx, "2026-10", wrapped)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"current": "2026-10", "master_keys": {}}`), 0o600))
+	_, err = NewFileKeyProvider(path)
+	assert.ErrorContains(t, err, "current master key")
+}
@@ -0,0 +1,182 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size in bytes of master, data and index keys, which are AES-256 and HMAC-SHA256 keys.
+const KeySize = 32
+
+// KeyProvider holds the master keys wrapping the data keys and the key of the blind indexes. Master
+// keys are named so that data keys wrapped with an older master key can still be unwrapped after a
+// new one became current.
+type KeyProvider interface {
+	// CurrentKeyID names the master key new data keys are wrapped with.
+	CurrentKeyID() string
+	// WrapKey encrypts a data key with the master key keyID.
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped with the master key keyID.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+	// IndexKey is the secret of the blind indexes. It cannot be rotated: the indexes of the stored
+	// values would no longer match.
+	IndexKey() []byte
+}
+
+// Keyring is a KeyProvider holding its master keys in memory.
+type Keyring struct {
+	current  string
+	masters  map[string]cipher.AEAD
+	indexKey []byte
+}
+
+var _ KeyProvider = (*Keyring)(nil)
+
+// NewEnvKeyProvider returns the keys given in the environment. masterKeys is a comma separated
+// list of id:key pairs, the first one being current, and every key, the index key included, is
+// the standard base64 encoding of KeySize random bytes, such as the output of
+// `openssl rand -base64 32`.
+func NewEnvKeyProvider(masterKeys, indexKey string) (*Keyring, error) {
+	keys := map[string]string{}
+	var current string
+	for _, pair := range strings.Split(masterKeys, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		id, key, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("master key %q is not given as id:key", truncate(pair))
+		}
+		if current == "" {
+			current = id
+		}
+		keys[id] = key
+	}
+	return newKeyring(current, keys, indexKey)
+}
+
+// keyFile is the JSON document read by NewFileKeyProvider.
+type keyFile struct {
+	Current    string            `json:"current"`
+	MasterKeys map[string]string `json:"master_keys"`
+	IndexKey   string            `json:"index_key"`
+}
+
+// NewFileKeyProvider reads the keys from a JSON file such as
+//
+//	{"current": "2026-10", "master_keys": {"2026-01": "<base64>", "2026-10": "<base64>"}, "index_key": "<base64>"}
+//
+// The file holds secrets and should only be readable by the service.
+func NewFileKeyProvider(path string) (*Keyring, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+	var file keyFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("error parsing key file %s: %w", path, err)
+	}
+	return newKeyring(file.Current, file.MasterKeys, file.IndexKey)
+}
+
+func newKeyring(current string, masterKeys map[string]string, indexKey string) (*Keyring, error) {
+	if current == "" {
+		return nil, errors.New("no current master key")
+	}
+	if _, ok := masterKeys[current]; !ok {
+		return nil, fmt.Errorf("current master key %q is missing", current)
+	}
+
+	k := &Keyring{current: current, masters: map[string]cipher.AEAD{}}
+	for id, encoded := range masterKeys {
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", id, err)
+		}
+		if k.masters[id], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if k.indexKey, err = decodeKey(indexKey); err != nil {
+		return nil, fmt.Errorf("index key: %w", err)
+	}
+	return k, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("not valid base64")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%d bytes instead of %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// truncate keeps key material out of error messages.
+func truncate(s string) string {
+	if len(s) > 8 {
+		return s[:8] + "…"
+	}
+	return s
+}
+
+func (k *Keyring) CurrentKeyID() string {
+	return k.current
+}
+
+func (k *Keyring) WrapKey(_ context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	master, ok := k.masters[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %q", keyID)
+	}
+	return seal(master, dataKey)
+}
+
+func (k *Keyring) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	master, ok := k.masters[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %q", keyID)
+	}
+	return open(master, wrapped)
+}
+
+func (k *Keyring) IndexKey() []byte {
+	return k.indexKey
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which is prepended to the result.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
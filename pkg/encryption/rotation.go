@@ -0,0 +1,65 @@
+package encryption
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Reencrypter rewrites the stored values that are Stale.
+type Reencrypter interface {
+	// Reencrypt encrypts every stale value again with the current data key, reading batchSize rows
+	// at a time, and returns how many values it rewrote.
+	Reencrypt(ctx context.Context, batchSize int) (int, error)
+}
+
+// Rotator moves stored values to the current keys in the background, so that a rotated master key
+// eventually protects every value and the older one can be retired.
+type Rotator struct {
+	encryptor *Encryptor
+	target    Reencrypter
+	interval  time.Duration
+	batchSize int
+}
+
+func NewRotator(encryptor *Encryptor, target Reencrypter, interval time.Duration, batchSize int) *Rotator {
+	return &Rotator{encryptor: encryptor, target: target, interval: interval, batchSize: batchSize}
+}
+
+// RunOnce picks up data keys created by other instances, wraps the data keys again with the
+// current master key and encrypts the stale values again.
+func (r *Rotator) RunOnce(ctx context.Context) error {
+	if err := r.encryptor.Refresh(ctx); err != nil {
+		return err
+	}
+	rewrapped, err := r.encryptor.RewrapDataKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if rewrapped > 0 {
+		log.Infof("wrapped %d data keys with the current master key", rewrapped)
+	}
+	reencrypted, err := r.target.Reencrypt(ctx, r.batchSize)
+	if reencrypted > 0 {
+		log.Infof("encrypted %d values again with the current data key", reencrypted)
+	}
+	return err
+}
+
+// Run calls RunOnce right away and then every interval until ctx is cancelled. Failures are logged
+// and retried on the next run.
+func (r *Rotator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("key rotation failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package events carries change notifications about customers between the parts of the service.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+type Type string
+
+const (
+	CustomerCreated Type = "customer.created"
+	CustomerUpdated Type = "customer.updated"
+	CustomerDeleted Type = "customer.deleted"
+	// CustomerStatusChanged is published when a customer moves to another lifecycle status.
+	CustomerStatusChanged Type = "customer.status_changed"
+	// CustomerErased is published when the personal data of a customer was erased. Customer holds
+	// the anonymized customer, downstream systems are expected to erase their copies.
+	CustomerErased Type = "customer.erased"
+)
+
+// Event describes a change to a customer. Customer holds the state after the change
+// and is nil for deletions. StatusChange is only set for status changes.
+type Event struct {
+	ID           uuid.UUID            `json:"id"`
+	Type         Type                 `json:"type"`
+	CustomerID   uuid.UUID            `json:"customer_id"`
+	Customer     *models.Customer     `json:"customer,omitempty"`
+	StatusChange *models.StatusChange `json:"status_change,omitempty"`
+	OccurredAt   time.Time            `json:"occurred_at"`
+}
+
+// New creates an event of the given type for the customer with a fresh ID and timestamp.
+func New(t Type, customerID uuid.UUID, customer *models.Customer) Event {
+	return Event{
+		ID:         uuid.New(),
+		Type:       t,
+		CustomerID: customerID,
+		Customer:   customer,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+type Subscriber interface {
+	// Subscribe returns a channel receiving every event published from now on and a function
+	// ending the subscription. The channel is closed when the subscription ends, including
+	// when the subscriber falls more than buffer events behind.
+	Subscribe(buffer int) (<-chan Event, func())
+}
+
+// Bus is an in-process publisher that fans events out to its subscribers.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: map[chan Event]struct{}{}}
+}
+
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Warnf("dropping event subscriber that fell behind on %s", event.Type)
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subscribers[ch]; ok {
+				delete(b.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	first, unsubscribeFirst := bus.Subscribe(1)
+	second, unsubscribeSecond := bus.Subscribe(1)
+	defer unsubscribeSecond()
+
+	event := New(CustomerDeleted, uuid.New(), nil)
+	bus.Publish(context.Background(), event)
+
+	assert.Equal(t, event, <-first)
+	assert.Equal(t, event, <-second)
+
+	unsubscribeFirst()
+	_, ok := <-first
+	assert.False(t, ok)
+	unsubscribeFirst()
+}
+
+func TestBus_DropsSlowSubscribers(t *testing.T) {
+	bus := NewBus()
+	ch, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	bus.Publish(context.Background(), New(CustomerCreated, uuid.New(), nil))
+	bus.Publish(context.Background(), New(CustomerCreated, uuid.New(), nil))
+
+	_, ok := <-ch
+	assert.True(t, ok)
+	_, ok = <-ch
+	assert.False(t, ok, "Expected the subscription to be closed after falling behind")
+}
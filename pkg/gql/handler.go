@@ -0,0 +1,93 @@
+package gql
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// maxQueryBytes caps the size of GraphQL request bodies.
+const maxQueryBytes = 1 << 20
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type Handler struct {
+	schema     graphql.Schema
+	repository repository.CustomerRepository
+	limits     Limits
+}
+
+// NewHandler creates the HTTP handler of the GraphQL endpoint. Queries are served for GET
+// and POST, mutations only for POST.
+func NewHandler(repo repository.CustomerRepository, limits Limits) (*Handler, error) {
+	schema, err := newSchema(repo)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: schema, repository: repo, limits: limits.withDefaults()}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, "Invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQueryBytes)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.Query == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+
+	result := h.execute(r, req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) execute(r *http.Request, req request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query)})})
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}}
+	}
+	if err := checkLimits(doc, req.OperationName, req.Variables, h.limits); err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}}
+	}
+
+	if r.Method == http.MethodGet && isMutation(doc, req.OperationName) {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError("Mutations are only allowed over POST")}}
+	}
+
+	return graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        withLoaders(r.Context(), newLoaders(h.repository)),
+	})
+}
@@ -0,0 +1,175 @@
+package gql
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func post(t *testing.T, h http.Handler, query string, variables map[string]interface{}) response {
+	t.Helper()
+	body, _ := json.Marshal(request{Query: query, Variables: variables})
+	req, err := http.NewRequest("POST", "/graphql", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var resp response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	return resp
+}
+
+func newTestHandler(t *testing.T, repo *mocks.CustomerRepository, limits Limits) *Handler {
+	h, err := NewHandler(repo, limits)
+	require.NoError(t, err)
+	return h
+}
+
+func TestCustomerQueries_AreBatched(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	h := newTestHandler(t, mockRepo, Limits{})
+
+	alice := models.Customer{ID: uuid.New(), FirstName: "Alice", LastName: "Wonderland", Email: "alice@example.com"}
+	bob := models.Customer{ID: uuid.New(), FirstName: "Bob", LastName: "Builder", Email: "bob@example.com"}
+	missing := uuid.New()
+
+	mockRepo.On("GetCustomersByIDs", mock.Anything, mock.MatchedBy(func(ids []uuid.UUID) bool {
+		return assert.ElementsMatch(t, []uuid.UUID{alice.ID, bob.ID, missing}, ids)
+	})).Return([]models.Customer{alice, bob}, nil).Once()
+	mockRepo.On("GetCustomersByEmails", mock.Anything, []string{"bob@example.com"}).Return(map[string]models.Customer{"bob@example.com": bob}, nil).Once()
+
+	resp := post(t, h, `query($a: ID!, $b: ID!, $c: ID!) {
+		a: customer(id: $a) { firstName middleName }
+		b: customer(id: $b) { firstName }
+		again: customer(id: $a) { id }
+		missing: customer(id: $c) { id }
+		byEmail: customerByEmail(email: "bob@example.com") { id email }
+	}`, map[string]interface{}{"a": alice.ID.String(), "b": bob.ID.String(), "c": missing.String()})
+
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, map[string]interface{}{"firstName": "Alice", "middleName": nil}, resp.Data["a"])
+	assert.Equal(t, map[string]interface{}{"firstName": "Bob"}, resp.Data["b"])
+	assert.Equal(t, map[string]interface{}{"id": alice.ID.String()}, resp.Data["again"])
+	assert.Nil(t, resp.Data["missing"])
+	assert.Equal(t, map[string]interface{}{"id": bob.ID.String(), "email": "bob@example.com"}, resp.Data["byEmail"])
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCustomersConnection(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	h := newTestHandler(t, mockRepo, Limits{})
+
+	customers := []models.Customer{
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), FirstName: "A", LastName: "A", Email: "a@example.com"},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), FirstName: "B", LastName: "B", Email: "b@example.com"},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), FirstName: "C", LastName: "C", Email: "c@example.com"},
+	}
+	mockRepo.On("ListCustomers", mock.Anything, repository.ListOptions{Limit: 3}).Return(customers, nil).Once()
+	mockRepo.On("ListCustomers", mock.Anything, repository.ListOptions{Limit: 3, After: customers[1].ID}).Return(customers[2:], nil).Once()
+
+	query := `query($after: String) {
+		customers(first: 2, after: $after) { edges { cursor node { firstName } } pageInfo { hasNextPage endCursor } }
+	}`
+
+	first := post(t, h, query, nil)
+	require.Empty(t, first.Errors)
+	conn := first.Data["customers"].(map[string]interface{})
+	assert.Len(t, conn["edges"], 2)
+	pageInfo := conn["pageInfo"].(map[string]interface{})
+	assert.Equal(t, true, pageInfo["hasNextPage"])
+	assert.Equal(t, encodeCursor(customers[1].ID), pageInfo["endCursor"])
+
+	second := post(t, h, query, map[string]interface{}{"after": pageInfo["endCursor"]})
+	require.Empty(t, second.Errors)
+	conn = second.Data["customers"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"cursor": encodeCursor(customers[2].ID), "node": map[string]interface{}{"firstName": "C"}},
+	}, conn["edges"])
+	assert.Equal(t, false, conn["pageInfo"].(map[string]interface{})["hasNextPage"])
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLimits(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	h := newTestHandler(t, mockRepo, Limits{MaxDepth: 3, MaxComplexity: 50})
+
+	resp := post(t, h, `{ customers { edges { node { id } } } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "query depth 4 exceeds the limit of 3", resp.Errors[0].Message)
+
+	resp = post(t, h, `query($n: Int) { customers(first: $n) { ...page } } fragment page on CustomerConnection { edges { cursor } }`,
+		map[string]interface{}{"n": 30})
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "query complexity 61 exceeds the limit of 50", resp.Errors[0].Message)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMutations(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	h := newTestHandler(t, mockRepo, Limits{})
+
+	mockRepo.On("CreateCustomer", mock.Anything, mock.MatchedBy(func(c *models.Customer) bool {
+		return c.FirstName == "Bob" && c.PhoneNumber == "1324" && c.ID != uuid.Nil
+	})).Return(nil)
+
+	id := uuid.New()
+	mockRepo.On("DeleteCustomer", mock.Anything, id).Return(nil)
+
+	resp := post(t, h, `mutation { createCustomer(input: {firstName: "Bob", lastName: "Builder", email: "bob@example.com", phoneNumber: "1324"}) { id phoneNumber } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, "1324", resp.Data["createCustomer"].(map[string]interface{})["phoneNumber"])
+
+	resp = post(t, h, `mutation { createCustomer(input: {firstName: "", lastName: "Builder", email: "bob@example.com"}) { id } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "First name, last name, and email are required", resp.Errors[0].Message)
+
+	resp = post(t, h, `mutation($id: ID!) { deleteCustomer(id: $id) }`, map[string]interface{}{"id": id.String()})
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, true, resp.Data["deleteCustomer"])
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGet_RejectsMutations(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	h := newTestHandler(t, mockRepo, Limits{})
+
+	query := url.Values{"query": {`mutation { deleteCustomer(id: "7f8b4d2e-8d4a-4f65-9bd4-2a8f6b6b0c11") }`}}
+	req, err := http.NewRequest("GET", "/graphql?"+query.Encode(), nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	var resp response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "Mutations are only allowed over POST", resp.Errors[0].Message)
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,153 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound the cost of a query before it is executed.
+type Limits struct {
+	// MaxDepth is the deepest field nesting allowed, top level fields being at depth 1.
+	MaxDepth int
+	// MaxComplexity caps the estimated number of resolved fields. Every field costs one and
+	// the cost of the selections below a paginated field is multiplied by its page size.
+	MaxComplexity int
+}
+
+// DefaultLimits are used for every zero value in the Limits passed to NewHandler.
+var DefaultLimits = Limits{MaxDepth: 8, MaxComplexity: 1000}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultLimits.MaxDepth
+	}
+	if l.MaxComplexity <= 0 {
+		l.MaxComplexity = DefaultLimits.MaxComplexity
+	}
+	return l
+}
+
+// paginatedFields maps the fields returning a page of results to their default page size.
+var paginatedFields = map[string]int{
+	"customers": defaultPageSize,
+}
+
+// checkLimits measures the operation that will be executed and rejects it when it is too deep or too complex.
+func checkLimits(doc *ast.Document, operationName string, variables map[string]interface{}, limits Limits) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				operation = d
+			}
+		}
+	}
+	if operation == nil {
+		// Let the executor report the missing operation.
+		return nil
+	}
+
+	m := measurer{fragments: fragments, variables: variables, visiting: map[string]bool{}}
+	depth, complexity := m.selectionSet(operation.SelectionSet, 1)
+
+	if depth > limits.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, limits.MaxDepth)
+	}
+	if complexity > limits.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity)
+	}
+	return nil
+}
+
+type measurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// visiting guards against fragment cycles, which validation reports later.
+	visiting map[string]bool
+}
+
+// selectionSet returns the maximum depth reached below set and the complexity of set.
+func (m measurer) selectionSet(set *ast.SelectionSet, depth int) (int, int) {
+	if set == nil {
+		return depth - 1, 0
+	}
+
+	maxDepth, complexity := depth, 0
+	for _, selection := range set.Selections {
+		var d, c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			childDepth, childComplexity := m.selectionSet(s.SelectionSet, depth+1)
+			d, c = childDepth, 1+childComplexity*m.multiplier(s)
+			if d < depth {
+				d = depth
+			}
+		case *ast.InlineFragment:
+			d, c = m.selectionSet(s.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visiting[name] {
+				continue
+			}
+			m.visiting[name] = true
+			d, c = m.selectionSet(fragment.SelectionSet, depth)
+			delete(m.visiting, name)
+		}
+		if d > maxDepth {
+			maxDepth = d
+		}
+		complexity += c
+	}
+	return maxDepth, complexity
+}
+
+// multiplier is the page size of paginated fields and one for every other field.
+func (m measurer) multiplier(field *ast.Field) int {
+	pageSize, paginated := paginatedFields[field.Name.Value]
+	if !paginated {
+		return 1
+	}
+
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil {
+				pageSize = n
+			}
+		case *ast.Variable:
+			switch n := m.variables[v.Name.Value].(type) {
+			case float64:
+				pageSize = int(n)
+			case int:
+				pageSize = n
+			}
+		}
+	}
+	if pageSize < 1 {
+		return 1
+	}
+	return pageSize
+}
+
+func isMutation(doc *ast.Document, operationName string) bool {
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return op.Operation == ast.OperationTypeMutation
+		}
+	}
+	return false
+}
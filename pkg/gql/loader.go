@@ -0,0 +1,112 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+)
+
+// loader batches customer lookups made while resolving one request. Resolvers register
+// their key with load and get a thunk back; the first thunk to be called fetches every
+// key registered so far with a single repository call. graphql-go calls the thunks only
+// after resolving the whole level of the query, so sibling fields end up in one batch.
+type loader[K comparable] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]*models.Customer, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	results map[K]*models.Customer
+	errs    map[K]error
+}
+
+func newLoader[K comparable](fetch func(ctx context.Context, keys []K) (map[K]*models.Customer, error)) *loader[K] {
+	return &loader[K]{
+		fetch:   fetch,
+		queued:  map[K]bool{},
+		results: map[K]*models.Customer{},
+		errs:    map[K]error{},
+	}
+}
+
+func (l *loader[K]) load(ctx context.Context, key K) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, done := l.results[key]; !done && !l.queued[key] {
+		l.pending = append(l.pending, key)
+		l.queued[key] = true
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+
+			found, err := l.fetch(ctx, keys)
+			for _, k := range keys {
+				delete(l.queued, k)
+				l.results[k] = found[k]
+				l.errs[k] = err
+			}
+		}
+
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		if c := l.results[key]; c != nil {
+			return c, nil
+		}
+		// A nil *models.Customer inside an interface would not be treated as null.
+		return nil, nil
+	}
+}
+
+// loaders holds the per-request batch loaders.
+type loaders struct {
+	byID    *loader[uuid.UUID]
+	byEmail *loader[string]
+}
+
+func newLoaders(repo repository.CustomerRepository) *loaders {
+	return &loaders{
+		byID: newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.Customer, error) {
+			customers, err := repo.GetCustomersByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			out := make(map[uuid.UUID]*models.Customer, len(customers))
+			for i := range customers {
+				out[customers[i].ID] = &customers[i]
+			}
+			return out, nil
+		}),
+		byEmail: newLoader(func(ctx context.Context, emails []string) (map[string]*models.Customer, error) {
+			customers, err := repo.GetCustomersByEmails(ctx, emails)
+			if err != nil {
+				return nil, err
+			}
+			out := make(map[string]*models.Customer, len(customers))
+			for email, c := range customers {
+				out[email] = &c
+			}
+			return out, nil
+		}),
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
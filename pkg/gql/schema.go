@@ -0,0 +1,348 @@
+// Package gql serves a GraphQL API over the customer repository.
+package gql
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/service"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	cursorPrefix    = "customer:"
+)
+
+type resolver struct {
+	repository repository.CustomerRepository
+}
+
+// connection is the page of customers returned by the customers query.
+type connection struct {
+	customers   []models.Customer
+	hasNextPage bool
+}
+
+func customerField(resolve func(c *models.Customer) interface{}, t graphql.Output) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return resolve(p.Source.(*models.Customer)), nil
+		},
+	}
+}
+
+// optional turns empty strings into null for nullable fields.
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func newSchema(repo repository.CustomerRepository) (graphql.Schema, error) {
+	r := &resolver{repository: repo}
+
+	customerType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Customer",
+		Fields: graphql.Fields{
+			"id":            customerField(func(c *models.Customer) interface{} { return c.ID.String() }, graphql.NewNonNull(graphql.ID)),
+			"firstName":     customerField(func(c *models.Customer) interface{} { return c.FirstName }, graphql.NewNonNull(graphql.String)),
+			"middleName":    customerField(func(c *models.Customer) interface{} { return optional(c.MiddleName) }, graphql.String),
+			"lastName":      customerField(func(c *models.Customer) interface{} { return c.LastName }, graphql.NewNonNull(graphql.String)),
+			"email":         customerField(func(c *models.Customer) interface{} { return c.Email }, graphql.NewNonNull(graphql.String)),
+			"phoneNumber":   customerField(func(c *models.Customer) interface{} { return optional(c.PhoneNumber) }, graphql.String),
+			"emailVerified": customerField(func(c *models.Customer) interface{} { return c.EmailVerified }, graphql.NewNonNull(graphql.Boolean)),
+			"status":        customerField(func(c *models.Customer) interface{} { return string(c.Status) }, graphql.NewNonNull(graphql.String)),
+			"createdAt":     customerField(func(c *models.Customer) interface{} { return c.CreatedAt }, graphql.NewNonNull(graphql.DateTime)),
+			"updatedAt":     customerField(func(c *models.Customer) interface{} { return c.UpdatedAt }, graphql.NewNonNull(graphql.DateTime)),
+			"createdBy":     customerField(func(c *models.Customer) interface{} { return optional(c.CreatedBy) }, graphql.String),
+			"updatedBy":     customerField(func(c *models.Customer) interface{} { return optional(c.UpdatedBy) }, graphql.String),
+		},
+	})
+
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CustomerEdge",
+		Fields: graphql.Fields{
+			"cursor": customerField(func(c *models.Customer) interface{} { return encodeCursor(c.ID) }, graphql.NewNonNull(graphql.String)),
+			"node":   customerField(func(c *models.Customer) interface{} { return c }, graphql.NewNonNull(customerType)),
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*connection).hasNextPage, nil
+				},
+			},
+			"endCursor": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					conn := p.Source.(*connection)
+					if len(conn.customers) == 0 {
+						return nil, nil
+					}
+					return encodeCursor(conn.customers[len(conn.customers)-1].ID), nil
+				},
+			},
+		},
+	})
+
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CustomerConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					conn := p.Source.(*connection)
+					edges := make([]interface{}, len(conn.customers))
+					for i := range conn.customers {
+						edges[i] = &conn.customers[i]
+					}
+					return edges, nil
+				},
+			},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(pageInfoType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	inputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CustomerInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"firstName":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"middleName":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"lastName":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"email":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"phoneNumber": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"customer": &graphql.Field{
+				Type: customerType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: r.customer,
+			},
+			"customerByEmail": &graphql.Field{
+				Type: customerType,
+				Args: graphql.FieldConfigArgument{
+					"email": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: r.customerByEmail,
+			},
+			"customers": &graphql.Field{
+				Type: graphql.NewNonNull(connectionType),
+				Args: graphql.FieldConfigArgument{
+					"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+					"after": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: r.customers,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createCustomer": &graphql.Field{
+				Type: graphql.NewNonNull(customerType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(inputType)},
+				},
+				Resolve: r.createCustomer,
+			},
+			"updateCustomer": &graphql.Field{
+				Type: graphql.NewNonNull(customerType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(inputType)},
+				},
+				Resolve: r.updateCustomer,
+			},
+			"deleteCustomer": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: r.deleteCustomer,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func (r *resolver) customer(p graphql.ResolveParams) (interface{}, error) {
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, errors.New("Invalid customer ID")
+	}
+	return loadersFrom(p.Context).byID.load(p.Context, id), nil
+}
+
+func (r *resolver) customerByEmail(p graphql.ResolveParams) (interface{}, error) {
+	return loadersFrom(p.Context).byEmail.load(p.Context, p.Args["email"].(string)), nil
+}
+
+func (r *resolver) customers(p graphql.ResolveParams) (interface{}, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 0 || first > maxPageSize {
+		return nil, fmt.Errorf("first must be between 0 and %d", maxPageSize)
+	}
+
+	var after uuid.UUID
+	if cursor, ok := p.Args["after"].(string); ok {
+		var err error
+		if after, err = decodeCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch one extra customer to know whether there is a next page.
+	customers, err := r.repository.ListCustomers(p.Context, repository.ListOptions{Limit: first + 1, After: after})
+	if err != nil {
+		log.Errorf("error listing customers: %v", err)
+		return nil, errors.New("Problem when retrieving customers, please try again later")
+	}
+
+	conn := &connection{customers: customers}
+	if len(customers) > first {
+		conn.customers = customers[:first]
+		conn.hasNextPage = true
+	}
+	return conn, nil
+}
+
+func customerFromInput(args map[string]interface{}) (models.Customer, error) {
+	input := args["input"].(map[string]interface{})
+	str := func(key string) string {
+		v, _ := input[key].(string)
+		return v
+	}
+
+	c := models.Customer{
+		FirstName:   str("firstName"),
+		MiddleName:  str("middleName"),
+		LastName:    str("lastName"),
+		Email:       str("email"),
+		PhoneNumber: str("phoneNumber"),
+	}
+	if c.Email == "" || c.FirstName == "" || c.LastName == "" {
+		return c, errors.New("First name, last name, and email are required")
+	}
+	return c, nil
+}
+
+func (r *resolver) createCustomer(p graphql.ResolveParams) (interface{}, error) {
+	c, err := customerFromInput(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ID = uuid.New()
+	if err := r.repository.CreateCustomer(p.Context, &c); err != nil {
+		return nil, mutationError(err, "Failed to create customer")
+	}
+	return &c, nil
+}
+
+func (r *resolver) updateCustomer(p graphql.ResolveParams) (interface{}, error) {
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, errors.New("Invalid customer ID")
+	}
+	c, err := customerFromInput(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ID = id
+	if err := r.repository.UpdateCustomer(p.Context, &c); err != nil {
+		return nil, mutationError(err, "Failed to update customer")
+	}
+	return &c, nil
+}
+
+func (r *resolver) deleteCustomer(p graphql.ResolveParams) (interface{}, error) {
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, errors.New("Invalid customer ID")
+	}
+
+	if err := r.repository.DeleteCustomer(p.Context, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return nil, mutationError(err, "Failed to delete customer")
+	}
+	return true, nil
+}
+
+// mutationError turns an error of a customer write into the message shown to clients.
+// Unexpected errors are logged and reported as failed.
+func mutationError(err error, failed string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("Customer not found")
+	}
+	if errors.Is(err, repository.ErrDuplicate) {
+		return errors.New("Email already in use")
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return errors.New("Not allowed to change this customer")
+	}
+	var holdErr *service.LegalHoldError
+	if errors.As(err, &holdErr) {
+		return errors.New("Customer is under legal hold")
+	}
+	if errors.Is(err, service.ErrErased) {
+		return errors.New("Customer was erased")
+	}
+	var attrErr *models.AttributeError
+	if errors.As(err, &attrErr) {
+		return errors.New("Invalid attributes: " + strings.Join(attrErr.Problems, "; "))
+	}
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		return errors.New("Invalid customer: " + validationErr.Error())
+	}
+	log.Errorf("%s: %v", failed, err)
+	return errors.New(failed)
+}
+
+func encodeCursor(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + id.String()))
+}
+
+func decodeCursor(cursor string) (uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return uuid.Nil, errors.New("Invalid cursor")
+	}
+	id, err := uuid.Parse(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil {
+		return uuid.Nil, errors.New("Invalid cursor")
+	}
+	return id, nil
+}
@@ -0,0 +1,40 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"CustomerCRUD/pkg/actor"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// actorContext names the actor of the changes made by a call after its x-actor metadata.
+func actorContext(ctx context.Context) context.Context {
+	var name string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(strings.ToLower(actor.Header)); len(values) > 0 {
+			name = values[0]
+		}
+	}
+	return actor.NewContext(ctx, name)
+}
+
+func unaryActor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(actorContext(ctx), req)
+}
+
+func streamActor(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, actorStream{ServerStream: stream, ctx: actorContext(stream.Context())})
+}
+
+// actorStream overrides the context of a stream with the one naming its actor.
+type actorStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s actorStream) Context() context.Context {
+	return s.ctx
+}
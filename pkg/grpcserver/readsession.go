@@ -0,0 +1,15 @@
+package grpcserver
+
+import (
+	"context"
+
+	"CustomerCRUD/pkg/repository"
+
+	"google.golang.org/grpc"
+)
+
+// unaryReadSession starts the read session of every call, so that a call reads its own writes
+// from the primary when reads go to a read replica.
+func unaryReadSession(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(repository.WithReadSession(ctx, false), req)
+}
@@ -0,0 +1,270 @@
+// Package grpcserver serves the CustomerService gRPC API on top of the customer repository.
+package grpcserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	customerv1 "CustomerCRUD/pkg/pb/customer/v1"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/resilient"
+	"CustomerCRUD/pkg/service"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchBuffer is how many events a watcher may fall behind before its stream is ended.
+const watchBuffer = 64
+
+type Server struct {
+	customerv1.UnimplementedCustomerServiceServer
+
+	repository repository.CustomerRepository
+	events     events.Subscriber
+}
+
+func NewServer(repository repository.CustomerRepository, subscriber events.Subscriber) *Server {
+	return &Server{
+		repository: repository,
+		events:     subscriber,
+	}
+}
+
+// NewGRPCServer creates a gRPC server with the CustomerService registered. The x-actor metadata
+// of the calls names the actor of their changes.
+func NewGRPCServer(repository repository.CustomerRepository, subscriber events.Subscriber, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryActor, unaryReadSession),
+		grpc.ChainStreamInterceptor(streamActor),
+	}, opts...)
+	g := grpc.NewServer(opts...)
+	customerv1.RegisterCustomerServiceServer(g, NewServer(repository, subscriber))
+	return g
+}
+
+// Serve serves g on lis until ctx is cancelled, then stops it gracefully within the grace period.
+func Serve(ctx context.Context, g *grpc.Server, lis net.Listener, grace time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- g.Serve(lis)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		g.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(grace):
+		log.Warn("gRPC graceful stop timed out, closing remaining streams")
+		g.Stop()
+	}
+	return <-serveErr
+}
+
+func (s *Server) GetCustomer(ctx context.Context, req *customerv1.GetCustomerRequest) (*customerv1.Customer, error) {
+	var customer *models.Customer
+	var err error
+
+	switch lookup := req.Lookup.(type) {
+	case *customerv1.GetCustomerRequest_Id:
+		id, parseErr := parseID(lookup.Id)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		customer, err = s.repository.GetCustomerByID(ctx, id)
+	case *customerv1.GetCustomerRequest_Email:
+		customer, err = s.repository.GetCustomerByEmail(ctx, lookup.Email)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "id or email is required")
+	}
+
+	if err != nil {
+		return nil, toStatus(err, "Failed to retrieve customer")
+	}
+	return toProto(customer), nil
+}
+
+func (s *Server) ListCustomers(req *customerv1.ListCustomersRequest, stream grpc.ServerStreamingServer[customerv1.Customer]) error {
+	customers, err := s.repository.GetAllCustomers(stream.Context())
+	if err != nil {
+		return toStatus(err, "Problem when retrieving customers, please try again later")
+	}
+
+	for i := range customers {
+		if err := stream.Send(toProto(&customers[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) CreateCustomer(ctx context.Context, req *customerv1.CreateCustomerRequest) (*customerv1.Customer, error) {
+	c := fromProto(req.GetCustomer())
+	if err := validate(c); err != nil {
+		return nil, err
+	}
+
+	c.ID = uuid.New()
+	if err := s.repository.CreateCustomer(ctx, &c); err != nil {
+		return nil, toStatus(err, "Failed to create customer")
+	}
+	return toProto(&c), nil
+}
+
+func (s *Server) UpdateCustomer(ctx context.Context, req *customerv1.UpdateCustomerRequest) (*customerv1.Customer, error) {
+	id, err := parseID(req.GetCustomer().GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	c := fromProto(req.GetCustomer())
+	c.ID = id
+	if err := s.repository.UpdateCustomer(ctx, &c); err != nil {
+		return nil, toStatus(err, "Failed to update customer")
+	}
+	return toProto(&c), nil
+}
+
+func (s *Server) DeleteCustomer(ctx context.Context, req *customerv1.DeleteCustomerRequest) (*emptypb.Empty, error) {
+	id, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.DeleteCustomer(ctx, id); err != nil {
+		return nil, toStatus(err, "Failed to delete customer")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) WatchCustomers(req *customerv1.WatchCustomersRequest, stream grpc.ServerStreamingServer[customerv1.CustomerEvent]) error {
+	ch, unsubscribe := s.events.Subscribe(watchBuffer)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind, restart the watch")
+			}
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func parseID(raw string) (uuid.UUID, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "Invalid customer ID")
+	}
+	return id, nil
+}
+
+func validate(c models.Customer) error {
+	if c.Email == "" || c.FirstName == "" || c.LastName == "" {
+		return status.Error(codes.InvalidArgument, "First name, last name, and email are required")
+	}
+	return nil
+}
+
+// toStatus maps repository errors the same way the REST handlers map them to HTTP statuses.
+func toStatus(err error, msg string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return status.Error(codes.NotFound, "Customer not found")
+	}
+	if errors.Is(err, repository.ErrDuplicate) {
+		return status.Error(codes.AlreadyExists, "Email already in use")
+	}
+	var attrErr *models.AttributeError
+	if errors.As(err, &attrErr) {
+		return status.Error(codes.InvalidArgument, "Invalid attributes: "+strings.Join(attrErr.Problems, "; "))
+	}
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		return status.Error(codes.InvalidArgument, "Invalid customer: "+validationErr.Error())
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return status.Error(codes.PermissionDenied, "Not allowed to change this customer")
+	}
+	var holdErr *service.LegalHoldError
+	if errors.As(err, &holdErr) {
+		return status.Error(codes.FailedPrecondition, "Customer is under legal hold")
+	}
+	if errors.Is(err, service.ErrErased) {
+		return status.Error(codes.FailedPrecondition, "Customer was erased")
+	}
+	if errors.Is(err, resilient.ErrCircuitOpen) {
+		return status.Error(codes.Unavailable, "Database unavailable, please retry later")
+	}
+	log.Errorf("%s: %v", msg, err)
+	return status.Error(codes.Internal, msg)
+}
+
+func toProto(c *models.Customer) *customerv1.Customer {
+	return &customerv1.Customer{
+		Id:          c.ID.String(),
+		FirstName:   c.FirstName,
+		MiddleName:  c.MiddleName,
+		LastName:    c.LastName,
+		Email:       c.Email,
+		PhoneNumber: c.PhoneNumber,
+	}
+}
+
+func fromProto(c *customerv1.Customer) models.Customer {
+	return models.Customer{
+		FirstName:   c.GetFirstName(),
+		MiddleName:  c.GetMiddleName(),
+		LastName:    c.GetLastName(),
+		Email:       c.GetEmail(),
+		PhoneNumber: c.GetPhoneNumber(),
+	}
+}
+
+var eventTypes = map[events.Type]customerv1.CustomerEvent_Type{
+	events.CustomerCreated: customerv1.CustomerEvent_TYPE_CREATED,
+	events.CustomerUpdated: customerv1.CustomerEvent_TYPE_UPDATED,
+	events.CustomerDeleted: customerv1.CustomerEvent_TYPE_DELETED,
+	// The proto has no status change type, watchers see the new status as an update.
+	events.CustomerStatusChanged: customerv1.CustomerEvent_TYPE_UPDATED,
+	// Nor an erasure type, watchers replace the customer with its anonymized fields.
+	events.CustomerErased: customerv1.CustomerEvent_TYPE_UPDATED,
+}
+
+func eventToProto(e events.Event) *customerv1.CustomerEvent {
+	out := &customerv1.CustomerEvent{
+		Id:         e.ID.String(),
+		Type:       eventTypes[e.Type],
+		CustomerId: e.CustomerID.String(),
+		OccurredAt: timestamppb.New(e.OccurredAt),
+	}
+	if e.Customer != nil {
+		out.Customer = toProto(e.Customer)
+	}
+	return out
+}
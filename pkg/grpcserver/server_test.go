@@ -0,0 +1,225 @@
+package grpcserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	customerv1 "CustomerCRUD/pkg/pb/customer/v1"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the CustomerService over an in-memory bufconn listener.
+func newTestClient(t *testing.T, repo repository.CustomerRepository, bus *events.Bus) customerv1.CustomerServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	g := NewGRPCServer(repo, bus)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return customerv1.NewCustomerServiceClient(conn)
+}
+
+func TestGetCustomer(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	client := newTestClient(t, mockRepo, events.NewBus())
+
+	id := uuid.New()
+	expected := &models.Customer{ID: id, FirstName: "Alice", LastName: "Wonderland", Email: "alice@example.com"}
+	mockRepo.On("GetCustomerByID", mock.Anything, id).Return(expected, nil)
+	mockRepo.On("GetCustomerByEmail", mock.Anything, "alice@example.com").Return(expected, nil)
+
+	byID, err := client.GetCustomer(context.Background(), &customerv1.GetCustomerRequest{Lookup: &customerv1.GetCustomerRequest_Id{Id: id.String()}})
+	require.NoError(t, err)
+	assert.Equal(t, id.String(), byID.Id)
+	assert.Equal(t, "Alice", byID.FirstName)
+
+	byEmail, err := client.GetCustomer(context.Background(), &customerv1.GetCustomerRequest{Lookup: &customerv1.GetCustomerRequest_Email{Email: "alice@example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, id.String(), byEmail.Id)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetCustomer_Errors(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	client := newTestClient(t, mockRepo, events.NewBus())
+
+	missing := uuid.New()
+	broken := uuid.New()
+	mockRepo.On("GetCustomerByID", mock.Anything, missing).Return(nil, sql.ErrNoRows)
+	mockRepo.On("GetCustomerByID", mock.Anything, broken).Return(nil, errors.New("database error"))
+
+	tests := []struct {
+		name string
+		req  *customerv1.GetCustomerRequest
+		code codes.Code
+	}{
+		{"no lookup", &customerv1.GetCustomerRequest{}, codes.InvalidArgument},
+		{"invalid id", &customerv1.GetCustomerRequest{Lookup: &customerv1.GetCustomerRequest_Id{Id: "invalid-uuid"}}, codes.InvalidArgument},
+		{"not found", &customerv1.GetCustomerRequest{Lookup: &customerv1.GetCustomerRequest_Id{Id: missing.String()}}, codes.NotFound},
+		{"database error", &customerv1.GetCustomerRequest{Lookup: &customerv1.GetCustomerRequest_Id{Id: broken.String()}}, codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetCustomer(context.Background(), tt.req)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListCustomers(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	client := newTestClient(t, mockRepo, events.NewBus())
+
+	expected := []models.Customer{
+		{ID: uuid.New(), FirstName: "John", LastName: "Doe", Email: "john.doe@example.com"},
+		{ID: uuid.New(), FirstName: "Jane", LastName: "Smith", Email: "jane.smith@example.com"},
+	}
+	mockRepo.On("GetAllCustomers", mock.Anything).Return(expected, nil)
+
+	stream, err := client.ListCustomers(context.Background(), &customerv1.ListCustomersRequest{})
+	require.NoError(t, err)
+
+	var ids []string
+	for {
+		c, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, c.Id)
+	}
+	assert.Equal(t, []string{expected[0].ID.String(), expected[1].ID.String()}, ids)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateCustomer(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	client := newTestClient(t, mockRepo, events.NewBus())
+
+	mockRepo.On("CreateCustomer", mock.Anything, mock.MatchedBy(func(c *models.Customer) bool {
+		return c.FirstName == "Bob" && c.ID != uuid.Nil
+	})).Return(nil)
+
+	created, err := client.CreateCustomer(context.Background(), &customerv1.CreateCustomerRequest{
+		Customer: &customerv1.Customer{FirstName: "Bob", LastName: "Builder", Email: "bob.builder@example.com"},
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil.String(), created.Id)
+
+	_, err = client.CreateCustomer(context.Background(), &customerv1.CreateCustomerRequest{
+		Customer: &customerv1.Customer{Email: "missing.fields@example.com"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateAndDeleteCustomer(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	client := newTestClient(t, mockRepo, events.NewBus())
+
+	id := uuid.New()
+	updated := models.Customer{ID: id, FirstName: "Updated", LastName: "User", Email: "updated.user@example.com"}
+	mockRepo.On("UpdateCustomer", mock.Anything, &updated).Return(nil)
+	mockRepo.On("DeleteCustomer", mock.Anything, id).Return(errors.New("database error"))
+
+	got, err := client.UpdateCustomer(context.Background(), &customerv1.UpdateCustomerRequest{
+		Customer: &customerv1.Customer{Id: id.String(), FirstName: "Updated", LastName: "User", Email: "updated.user@example.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Updated", got.FirstName)
+
+	_, err = client.DeleteCustomer(context.Background(), &customerv1.DeleteCustomerRequest{Id: id.String()})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWatchCustomers(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	bus := events.NewBus()
+	customers := service.NewCustomerService(repository.Untransacted(repository.Repositories{Customers: mockRepo}), bus)
+	client := newTestClient(t, customers, bus)
+
+	id := uuid.New()
+	mockRepo.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id}, nil)
+	mockRepo.On("DeleteCustomer", mock.Anything, id).Return(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchCustomers(ctx, &customerv1.WatchCustomersRequest{})
+	require.NoError(t, err)
+
+	// The subscription is registered once the server handler runs, so retry the write until it is observed.
+	received := make(chan *customerv1.CustomerEvent, 1)
+	go func() {
+		event, err := stream.Recv()
+		if err == nil {
+			received <- event
+		}
+	}()
+
+	for {
+		_, err := client.DeleteCustomer(ctx, &customerv1.DeleteCustomerRequest{Id: id.String()})
+		require.NoError(t, err)
+
+		select {
+		case event := <-received:
+			assert.Equal(t, customerv1.CustomerEvent_TYPE_DELETED, event.Type)
+			assert.Equal(t, id.String(), event.CustomerId)
+			assert.Nil(t, event.Customer)
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("Expected a customer event")
+		}
+	}
+}
+
+func TestActorMetadata(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	client := newTestClient(t, mockRepo, events.NewBus())
+
+	id := uuid.New()
+	mockRepo.On("DeleteCustomer", mock.MatchedBy(func(ctx context.Context) bool {
+		return actor.FromContext(ctx) == "billing-sync"
+	}), id).Return(nil)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-actor", "billing-sync")
+	_, err := client.DeleteCustomer(ctx, &customerv1.DeleteCustomerRequest{Id: id.String()})
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,77 @@
+package mail
+
+import (
+	"context"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var message = Message{To: "Jane Doe <jane@example.com>", Subject: "Vérifiez votre adresse", Body: "Hello,\nplease verify.\n"}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", Port: 587, Username: "crm", Password: "secret", From: "CRM <noreply@example.com>"})
+	var addr, from string
+	var to []string
+	var data []byte
+	m.send = func(a string, auth smtp.Auth, f string, t []string, msg []byte) error {
+		addr, from, to, data = a, f, t, msg
+		return nil
+	}
+
+	require.NoError(t, m.Send(context.Background(), message))
+	assert.Equal(t, "smtp.example.com:587", addr)
+	assert.Equal(t, "noreply@example.com", from)
+	assert.Equal(t, []string{"jane@example.com"}, to)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, message.Subject, subject)
+	assert.Equal(t, "CRM <noreply@example.com>", parsed.Header.Get("From"))
+	assert.Equal(t, "quoted-printable", parsed.Header.Get("Content-Transfer-Encoding"))
+}
+
+func TestSMTPMailer_InvalidMessage(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "localhost", Port: 25, From: "noreply@example.com"})
+	m.send = func(string, smtp.Auth, string, []string, []byte) error {
+		t.Fatal("an invalid message was sent")
+		return nil
+	}
+
+	assert.Error(t, m.Send(context.Background(), Message{To: "not an address", Subject: "Hi"}))
+	assert.Error(t, m.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hi\r\nBcc: eve@example.com"}))
+}
+
+func TestOutbox(t *testing.T) {
+	o := NewOutbox()
+	require.NoError(t, o.Send(context.Background(), message))
+	assert.Equal(t, []Message{message}, o.Messages())
+}
+
+func TestFileOutbox(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	o, err := NewFileOutbox(dir, "noreply@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, o.Send(context.Background(), message))
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), ".eml"))
+
+	f, err := os.Open(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	defer f.Close()
+	parsed, err := mail.ReadMessage(f)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe <jane@example.com>", parsed.Header.Get("To"))
+}
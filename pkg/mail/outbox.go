@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox keeps the messages sent in memory, for tests.
+type Outbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+func (o *Outbox) Send(ctx context.Context, msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
+
+// FileOutbox writes every message to a .eml file of a directory instead of sending it, for
+// local development. The files open in any mail client.
+type FileOutbox struct {
+	dir  string
+	from string
+}
+
+// NewFileOutbox returns an outbox writing to dir, which is created when missing.
+func NewFileOutbox(dir, from string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating mail outbox: %w", err)
+	}
+	return &FileOutbox{dir: dir, from: from}, nil
+}
+
+func (o *FileOutbox) Send(ctx context.Context, msg Message) error {
+	at := time.Now()
+	data, err := compose(o.from, msg, at)
+	if err != nil {
+		return err
+	}
+	// Names sort in the order the messages were sent.
+	name := fmt.Sprintf("%s-%s.eml", at.UTC().Format("20060102T150405.000000"), uuid.NewString())
+	if err := os.WriteFile(filepath.Join(o.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("error writing mail to outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig describes the SMTP server messages are relayed through.
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN when Username is set, which net/smtp only
+	// allows over TLS or to localhost.
+	Username string
+	Password string
+	// From is the sender address of every message.
+	From string
+}
+
+// SMTPMailer sends messages through an SMTP server, upgrading the connection with STARTTLS when
+// the server supports it.
+type SMTPMailer struct {
+	cfg  SMTPConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg, send: smtp.SendMail}
+}
+
+// Send relays msg. smtp.SendMail does not take a context, a cancelled ctx only stops messages
+// that were not sent yet.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := compose(m.cfg.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.cfg.From, err)
+	}
+	to, _ := mail.ParseAddress(msg.To)
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	if err := m.send(addr, auth, from.Address, []string{to.Address}, data); err != nil {
+		return fmt.Errorf("error sending mail through %s: %w", addr, err)
+	}
+	return nil
+}
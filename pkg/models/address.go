@@ -0,0 +1,147 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type AddressType string
+
+const (
+	AddressBilling  AddressType = "billing"
+	AddressShipping AddressType = "shipping"
+	AddressOther    AddressType = "other"
+)
+
+func (t AddressType) Valid() bool {
+	switch t {
+	case AddressBilling, AddressShipping, AddressOther:
+		return true
+	}
+	return false
+}
+
+type Address struct {
+	ID         uuid.UUID   `json:"id"`
+	CustomerID uuid.UUID   `json:"customer_id"`
+	Type       AddressType `json:"type"`
+	Line1      string      `json:"line1"`
+	Line2      string      `json:"line2,omitempty"`
+	City       string      `json:"city"`
+	Region     string      `json:"region,omitempty"`
+	PostalCode string      `json:"postal_code,omitempty"`
+	Country    string      `json:"country"`
+	IsDefault  bool        `json:"is_default"`
+}
+
+// Normalize trims the free-text fields and upper-cases the country and postal code.
+func (a *Address) Normalize() {
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.Region = strings.TrimSpace(a.Region)
+	a.PostalCode = strings.ToUpper(strings.TrimSpace(a.PostalCode))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+}
+
+// Validate checks the required fields, the country code and the postal code format of the country.
+func (a Address) Validate() error {
+	var errs []error
+	if !a.Type.Valid() {
+		errs = append(errs, fmt.Errorf("type must be one of %s, %s or %s", AddressBilling, AddressShipping, AddressOther))
+	}
+	if a.Line1 == "" {
+		errs = append(errs, errors.New("line1 is required"))
+	}
+	if a.City == "" {
+		errs = append(errs, errors.New("city is required"))
+	}
+	if !IsCountryCode(a.Country) {
+		errs = append(errs, errors.New("country must be an ISO 3166-1 alpha-2 code"))
+	} else if err := ValidatePostalCode(a.Country, a.PostalCode); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// countryCodes holds the officially assigned ISO 3166-1 alpha-2 codes.
+var countryCodes = map[string]bool{}
+
+func init() {
+	for _, code := range strings.Fields(`
+		AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+		CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR
+		GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM IN IO IQ IR IS IT JE JM JO
+		JP KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR
+		MS MT MU MV MW MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR PS PT PW PY QA RE RO
+		RS RU RW SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR TT TV
+		TW TZ UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW`) {
+		countryCodes[code] = true
+	}
+}
+
+func IsCountryCode(code string) bool {
+	return countryCodes[code]
+}
+
+// postalCodeFormats holds the postal code format of the countries we validate strictly.
+// Other countries only get a generic sanity check.
+var postalCodeFormats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BG": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] ?\d[ABCEGHJ-NPRSTV-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"CZ": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FI": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"GR": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"IE": regexp.MustCompile(`^[AC-FHKNPRTV-Y]\d[\dW] ?[0-9AC-FHKNPRTV-Y]{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"RO": regexp.MustCompile(`^\d{6}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// countriesWithoutPostalCodes may leave the postal code empty.
+var countriesWithoutPostalCodes = map[string]bool{
+	"AE": true, "AG": true, "AO": true, "BS": true, "BZ": true, "FJ": true, "HK": true, "JM": true, "KI": true,
+	"MO": true, "QA": true, "SC": true, "TV": true, "UG": true, "ZW": true,
+}
+
+var genericPostalCode = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+
+// ValidatePostalCode checks a normalized postal code against the format used in country.
+func ValidatePostalCode(country, postalCode string) error {
+	if postalCode == "" {
+		if countriesWithoutPostalCodes[country] {
+			return nil
+		}
+		return fmt.Errorf("postal code is required for %s", country)
+	}
+
+	format, ok := postalCodeFormats[country]
+	if !ok {
+		format = genericPostalCode
+	}
+	if !format.MatchString(postalCode) {
+		return fmt.Errorf("postal code %q is not valid for %s", postalCode, country)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePostalCode(t *testing.T) {
+	tests := []struct {
+		country    string
+		postalCode string
+		valid      bool
+	}{
+		{"US", "12345", true},
+		{"US", "12345-6789", true},
+		{"US", "1234", false},
+		{"GB", "SW1A 1AA", true},
+		{"GB", "12345", false},
+		{"CA", "K1A 0B1", true},
+		{"NL", "1012AB", true},
+		{"DE", "1011", false},
+		{"HK", "", true},
+		{"DE", "", false},
+		{"KE", "00100", true},
+		{"KE", "#1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country+" "+tt.postalCode, func(t *testing.T) {
+			err := ValidatePostalCode(tt.country, tt.postalCode)
+			assert.Equal(t, tt.valid, err == nil, "unexpected result: %v", err)
+		})
+	}
+}
+
+func TestAddressValidate(t *testing.T) {
+	a := Address{Type: "home", Line1: " ", City: "Paris", PostalCode: "75001", Country: "fr"}
+	a.Normalize()
+
+	err := a.Validate()
+	assert.EqualError(t, err, "type must be one of billing, shipping or other\nline1 is required")
+
+	a.Type = AddressShipping
+	a.Line1 = "1 Rue de Rivoli"
+	assert.NoError(t, a.Validate())
+	assert.Equal(t, "FR", a.Country)
+}
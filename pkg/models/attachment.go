@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment describes a file attached to a customer, such as a contract or an ID scan. The
+// content itself lives in a blob store under Key.
+type Attachment struct {
+	ID         uuid.UUID `json:"id"`
+	CustomerID uuid.UUID `json:"customer_id"`
+	FileName   string    `json:"file_name"`
+	// ContentType is sniffed from the content, the type claimed by the uploader is ignored.
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// SHA256 is the hex encoded checksum of the content.
+	SHA256     string    `json:"sha256"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	UploadedBy string    `json:"uploaded_by"`
+	Key        string    `json:"-"`
+}
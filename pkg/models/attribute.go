@@ -0,0 +1,300 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AttributeType is the type of the values of a custom attribute.
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeInteger AttributeType = "integer"
+	AttributeBoolean AttributeType = "boolean"
+	// AttributeDate values are ISO 8601 calendar dates such as 1990-04-23.
+	AttributeDate AttributeType = "date"
+)
+
+const dateLayout = "2006-01-02"
+
+func (t AttributeType) Valid() bool {
+	switch t {
+	case AttributeString, AttributeNumber, AttributeInteger, AttributeBoolean, AttributeDate:
+		return true
+	}
+	return false
+}
+
+// Numeric tells whether values of the type are JSON numbers.
+func (t AttributeType) Numeric() bool {
+	return t == AttributeNumber || t == AttributeInteger
+}
+
+var attributeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// AttributeDefinition describes a custom customer attribute defined at runtime. The constraints
+// that do not apply to the type must be left empty.
+type AttributeDefinition struct {
+	Name        string        `json:"name"`
+	Label       string        `json:"label"`
+	Description string        `json:"description,omitempty"`
+	Type        AttributeType `json:"type"`
+	Required    bool          `json:"required"`
+	// Enum lists the allowed values of string and numeric attributes.
+	Enum []any `json:"enum,omitempty"`
+	// Minimum and Maximum bound numeric attributes, inclusively.
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+	// MinLength, MaxLength and Pattern constrain string attributes.
+	MinLength *int   `json:"min_length,omitempty"`
+	MaxLength *int   `json:"max_length,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+}
+
+// Validate checks that the definition is consistent, so that values can be validated against it.
+func (d AttributeDefinition) Validate() error {
+	var errs []error
+	if !attributeName.MatchString(d.Name) {
+		errs = append(errs, errors.New("name must start with a lowercase letter followed by up to 62 lowercase letters, digits or underscores"))
+	}
+	if strings.TrimSpace(d.Label) == "" {
+		errs = append(errs, errors.New("label is required"))
+	}
+	if !d.Type.Valid() {
+		errs = append(errs, fmt.Errorf("type must be one of %s, %s, %s, %s or %s",
+			AttributeString, AttributeNumber, AttributeInteger, AttributeBoolean, AttributeDate))
+		return errors.Join(errs...)
+	}
+
+	if d.Type != AttributeString && (d.MinLength != nil || d.MaxLength != nil || d.Pattern != "") {
+		errs = append(errs, errors.New("min_length, max_length and pattern only apply to string attributes"))
+	}
+	if !d.Type.Numeric() && (d.Minimum != nil || d.Maximum != nil) {
+		errs = append(errs, errors.New("minimum and maximum only apply to number and integer attributes"))
+	}
+	if len(d.Enum) > 0 && d.Type != AttributeString && !d.Type.Numeric() {
+		errs = append(errs, errors.New("enum only applies to string, number and integer attributes"))
+	}
+
+	if d.Minimum != nil && d.Maximum != nil && *d.Minimum > *d.Maximum {
+		errs = append(errs, errors.New("minimum must not exceed maximum"))
+	}
+	if (d.MinLength != nil && *d.MinLength < 0) || (d.MaxLength != nil && *d.MaxLength < 0) {
+		errs = append(errs, errors.New("min_length and max_length must not be negative"))
+	}
+	if d.MinLength != nil && d.MaxLength != nil && *d.MinLength > *d.MaxLength {
+		errs = append(errs, errors.New("min_length must not exceed max_length"))
+	}
+	if d.Pattern != "" {
+		if _, err := regexp.Compile(d.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("pattern is not a valid regular expression: %v", err))
+		}
+	}
+	if d.Type == AttributeString || d.Type.Numeric() {
+		for _, v := range d.Enum {
+			if err := d.checkType(v); err != nil {
+				errs = append(errs, fmt.Errorf("enum value %v: %v", v, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkType reports values whose JSON type does not match the attribute type.
+func (d AttributeDefinition) checkType(v any) error {
+	switch d.Type {
+	case AttributeString:
+		if _, ok := v.(string); !ok {
+			return errors.New("must be a string")
+		}
+	case AttributeNumber:
+		if _, ok := v.(float64); !ok {
+			return errors.New("must be a number")
+		}
+	case AttributeInteger:
+		if n, ok := v.(float64); !ok || n != math.Trunc(n) {
+			return errors.New("must be an integer")
+		}
+	case AttributeBoolean:
+		if _, ok := v.(bool); !ok {
+			return errors.New("must be a boolean")
+		}
+	case AttributeDate:
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("must be a date such as 1990-04-23")
+		}
+		if _, err := time.Parse(dateLayout, s); err != nil {
+			return errors.New("must be a date such as 1990-04-23")
+		}
+	}
+	return nil
+}
+
+// ValidateValue checks a value decoded from JSON against the type and constraints of the attribute.
+func (d AttributeDefinition) ValidateValue(v any) error {
+	if err := d.checkType(v); err != nil {
+		return err
+	}
+
+	if len(d.Enum) > 0 {
+		found := false
+		for _, e := range d.Enum {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("must be one of %v", d.Enum)
+		}
+	}
+
+	if n, ok := v.(float64); ok {
+		if d.Minimum != nil && n < *d.Minimum {
+			return fmt.Errorf("must be at least %v", *d.Minimum)
+		}
+		if d.Maximum != nil && n > *d.Maximum {
+			return fmt.Errorf("must be at most %v", *d.Maximum)
+		}
+	}
+
+	if s, ok := v.(string); ok && d.Type == AttributeString {
+		length := utf8.RuneCountInString(s)
+		if d.MinLength != nil && length < *d.MinLength {
+			return fmt.Errorf("must be at least %d characters long", *d.MinLength)
+		}
+		if d.MaxLength != nil && length > *d.MaxLength {
+			return fmt.Errorf("must be at most %d characters long", *d.MaxLength)
+		}
+		if d.Pattern != "" {
+			if re, err := regexp.Compile(d.Pattern); err != nil || !re.MatchString(s) {
+				return fmt.Errorf("must match pattern %s", d.Pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseValue converts the text form of a value, as found in a query string, to its JSON form.
+func (d AttributeDefinition) ParseValue(raw string) (any, error) {
+	var v any = raw
+	switch d.Type {
+	case AttributeNumber, AttributeInteger:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		v = n
+	case AttributeBoolean:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("must be a boolean")
+		}
+		v = b
+	}
+	if err := d.checkType(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// AttributeError lists every problem found validating the custom attributes of a customer.
+type AttributeError struct {
+	Problems []string
+}
+
+func (e *AttributeError) Error() string {
+	return "invalid attributes: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateAttributes checks values against the definitions. Unknown attributes are rejected and
+// required ones must be present. The problems are sorted by attribute name.
+func ValidateAttributes(defs []AttributeDefinition, values map[string]any) error {
+	byName := make(map[string]AttributeDefinition, len(defs))
+	for _, d := range defs {
+		byName[d.Name] = d
+	}
+
+	var problems []string
+	for name, v := range values {
+		d, ok := byName[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not a defined attribute", name))
+			continue
+		}
+		if err := d.ValidateValue(v); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %v", name, err))
+		}
+	}
+	for _, d := range defs {
+		if _, ok := values[d.Name]; d.Required && !ok {
+			problems = append(problems, fmt.Sprintf("%s is required", d.Name))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return &AttributeError{Problems: problems}
+}
+
+// AttributesJSONSchema describes the attributes object as a JSON Schema, for UIs to render forms from.
+func AttributesJSONSchema(defs []AttributeDefinition) map[string]any {
+	properties := make(map[string]any, len(defs))
+	required := []string{}
+	for _, d := range defs {
+		p := map[string]any{"title": d.Label}
+		switch d.Type {
+		case AttributeDate:
+			p["type"] = "string"
+			p["format"] = "date"
+		default:
+			p["type"] = string(d.Type)
+		}
+		if d.Description != "" {
+			p["description"] = d.Description
+		}
+		if len(d.Enum) > 0 {
+			p["enum"] = d.Enum
+		}
+		if d.Minimum != nil {
+			p["minimum"] = *d.Minimum
+		}
+		if d.Maximum != nil {
+			p["maximum"] = *d.Maximum
+		}
+		if d.MinLength != nil {
+			p["minLength"] = *d.MinLength
+		}
+		if d.MaxLength != nil {
+			p["maxLength"] = *d.MaxLength
+		}
+		if d.Pattern != "" {
+			p["pattern"] = d.Pattern
+		}
+		properties[d.Name] = p
+		if d.Required {
+			required = append(required, d.Name)
+		}
+	}
+
+	return map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Customer attributes",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           properties,
+		"required":             required,
+	}
+}
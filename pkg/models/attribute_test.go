@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func float(v float64) *float64 { return &v }
+
+func TestAttributeDefinitionValidate(t *testing.T) {
+	valid := AttributeDefinition{Name: "loyalty_tier", Label: "Loyalty tier", Type: AttributeString, Enum: []any{"gold", "silver"}}
+	assert.NoError(t, valid.Validate())
+
+	invalid := AttributeDefinition{Name: "Loyalty-Tier", Label: "Tier", Type: AttributeBoolean, Minimum: float(1), Pattern: "^a"}
+	assert.EqualError(t, invalid.Validate(),
+		"name must start with a lowercase letter followed by up to 62 lowercase letters, digits or underscores\n"+
+			"min_length, max_length and pattern only apply to string attributes\n"+
+			"minimum and maximum only apply to number and integer attributes")
+
+	wrongEnum := AttributeDefinition{Name: "score", Label: "Score", Type: AttributeInteger, Enum: []any{1.0, "two"}}
+	assert.EqualError(t, wrongEnum.Validate(), "enum value two: must be an integer")
+}
+
+func TestValidateAttributes(t *testing.T) {
+	defs := []AttributeDefinition{
+		{Name: "account_manager", Label: "Account manager", Type: AttributeString, Required: true},
+		{Name: "birthday", Label: "Birthday", Type: AttributeDate},
+		{Name: "loyalty_tier", Label: "Loyalty tier", Type: AttributeString, Enum: []any{"gold", "silver"}},
+		{Name: "orders", Label: "Orders", Type: AttributeInteger, Minimum: float(0)},
+	}
+
+	assert.NoError(t, ValidateAttributes(defs, map[string]any{
+		"account_manager": "Ann",
+		"birthday":        "1990-04-23",
+		"loyalty_tier":    "gold",
+		"orders":          3.0,
+	}))
+
+	err := ValidateAttributes(defs, map[string]any{
+		"birthday":     "23/04/1990",
+		"loyalty_tier": "bronze",
+		"orders":       -1.0,
+		"nickname":     "Bob",
+	})
+	var attrErr *AttributeError
+	require.ErrorAs(t, err, &attrErr)
+	assert.Equal(t, []string{
+		"account_manager is required",
+		"birthday must be a date such as 1990-04-23",
+		"loyalty_tier must be one of [gold silver]",
+		"nickname is not a defined attribute",
+		"orders must be at least 0",
+	}, attrErr.Problems)
+}
+
+func TestAttributeDefinitionParseValue(t *testing.T) {
+	number := AttributeDefinition{Name: "score", Type: AttributeNumber}
+	v, err := number.ParseValue("4.5")
+	assert.NoError(t, err)
+	assert.Equal(t, 4.5, v)
+
+	boolean := AttributeDefinition{Name: "vip", Type: AttributeBoolean}
+	_, err = boolean.ParseValue("maybe")
+	assert.EqualError(t, err, "must be a boolean")
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction is what happened to a customer in an audit entry.
+type AuditAction string
+
+const (
+	AuditCreated AuditAction = "created"
+	AuditUpdated AuditAction = "updated"
+	AuditDeleted AuditAction = "deleted"
+	// AuditErased records the erasure of the personal data of a customer, see Erasure.
+	AuditErased AuditAction = "erased"
+)
+
+// AuditEntry records a write to a customer. Entries outlive the customer they describe.
+type AuditEntry struct {
+	ID         uuid.UUID   `json:"id"`
+	CustomerID uuid.UUID   `json:"customer_id"`
+	Action     AuditAction `json:"action"`
+	// Changes holds the fields an update changed, keyed by their JSON name. Attributes are keyed
+	// as attributes.<name>.
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+	Actor      string                 `json:"actor"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// FieldChange holds the value of a field before and after an update. A nil value means unset.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// CustomerChanges returns the fields that differ between two versions of a customer. Fields
+// maintained by the repository, such as timestamps, and the included resources are ignored.
+func CustomerChanges(before, after Customer) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	field := func(name, from, to string) {
+		if from != to {
+			changes[name] = FieldChange{From: optionalValue(from), To: optionalValue(to)}
+		}
+	}
+	field("first_name", before.FirstName, after.FirstName)
+	field("middle_name", before.MiddleName, after.MiddleName)
+	field("last_name", before.LastName, after.LastName)
+	field("email", before.Email, after.Email)
+	field("phone_number", before.PhoneNumber, after.PhoneNumber)
+	field("status", string(before.Status), string(after.Status))
+	if before.EmailVerified != after.EmailVerified {
+		changes["email_verified"] = FieldChange{From: before.EmailVerified, To: after.EmailVerified}
+	}
+
+	for name, from := range before.Attributes {
+		if to, ok := after.Attributes[name]; !ok || !reflect.DeepEqual(from, to) {
+			changes["attributes."+name] = FieldChange{From: from, To: to}
+		}
+	}
+	for name, to := range after.Attributes {
+		if _, ok := before.Attributes[name]; !ok {
+			changes["attributes."+name] = FieldChange{To: to}
+		}
+	}
+	return changes
+}
+
+func optionalValue(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ConsentChannel is how a customer may be contacted.
+type ConsentChannel string
+
+const (
+	ChannelEmail ConsentChannel = "email"
+	ChannelSMS   ConsentChannel = "sms"
+	ChannelPhone ConsentChannel = "phone"
+	ChannelPost  ConsentChannel = "post"
+)
+
+// ConsentChannels lists every channel.
+var ConsentChannels = []ConsentChannel{ChannelEmail, ChannelSMS, ChannelPhone, ChannelPost}
+
+func (c ConsentChannel) Valid() bool {
+	for _, channel := range ConsentChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// ConsentState is whether a customer agreed to be contacted.
+type ConsentState string
+
+const (
+	ConsentGranted ConsentState = "granted"
+	ConsentRevoked ConsentState = "revoked"
+)
+
+func (s ConsentState) Valid() bool {
+	return s == ConsentGranted || s == ConsentRevoked
+}
+
+var purposePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+const (
+	maxSourceLength        = 100
+	maxPolicyVersionLength = 50
+)
+
+// UnsubscribeSource is the source of the consents revoked through an unsubscribe link.
+const UnsubscribeSource = "unsubscribe_link"
+
+// Consent records that a customer granted or revoked the use of a channel for a purpose, such as
+// marketing or product updates. Consents are never changed: a new one is recorded on top, and
+// the latest one of a purpose and channel is the current one.
+type Consent struct {
+	ID         uuid.UUID      `json:"id"`
+	CustomerID uuid.UUID      `json:"customer_id"`
+	Purpose    string         `json:"purpose"`
+	Channel    ConsentChannel `json:"channel"`
+	State      ConsentState   `json:"state"`
+	// Source tells where the consent was collected, such as a signup form or a call.
+	Source string `json:"source"`
+	// PolicyVersion is the version of the privacy policy the customer agreed to.
+	PolicyVersion string `json:"policy_version,omitempty"`
+	// RecordedAt and RecordedBy are maintained by the repository, see package actor.
+	RecordedAt time.Time `json:"recorded_at"`
+	RecordedBy string    `json:"recorded_by"`
+}
+
+// Granted reports whether the customer may be contacted.
+func (c Consent) Granted() bool {
+	return c.State == ConsentGranted
+}
+
+// NormalizePurpose lowercases a purpose so that Marketing and marketing are the same purpose.
+func NormalizePurpose(purpose string) string {
+	return strings.ToLower(strings.TrimSpace(purpose))
+}
+
+// ValidatePurpose checks a normalized purpose.
+func ValidatePurpose(purpose string) error {
+	if !purposePattern.MatchString(purpose) {
+		return fmt.Errorf("purpose %q must start with a letter or digit followed by up to 63 letters, digits, underscores or hyphens", purpose)
+	}
+	return nil
+}
+
+func (c *Consent) Normalize() {
+	c.Purpose = NormalizePurpose(c.Purpose)
+	c.Channel = ConsentChannel(strings.ToLower(strings.TrimSpace(string(c.Channel))))
+	c.State = ConsentState(strings.ToLower(strings.TrimSpace(string(c.State))))
+	c.Source = strings.TrimSpace(c.Source)
+	c.PolicyVersion = strings.TrimSpace(c.PolicyVersion)
+}
+
+// Validate checks the fields set by clients. A grant has to name the policy version it was given under.
+func (c Consent) Validate() error {
+	var errs []error
+	if err := ValidatePurpose(c.Purpose); err != nil {
+		errs = append(errs, err)
+	}
+	if !c.Channel.Valid() {
+		errs = append(errs, fmt.Errorf("channel %q is not email, sms, phone or post", c.Channel))
+	}
+	if !c.State.Valid() {
+		errs = append(errs, fmt.Errorf("state %q is not granted or revoked", c.State))
+	}
+	if c.Source == "" {
+		errs = append(errs, errors.New("source is required"))
+	} else if utf8.RuneCountInString(c.Source) > maxSourceLength {
+		errs = append(errs, fmt.Errorf("source must be at most %d characters long", maxSourceLength))
+	}
+	if c.State == ConsentGranted && c.PolicyVersion == "" {
+		errs = append(errs, errors.New("policy_version is required when consent is granted"))
+	} else if utf8.RuneCountInString(c.PolicyVersion) > maxPolicyVersionLength {
+		errs = append(errs, fmt.Errorf("policy_version must be at most %d characters long", maxPolicyVersionLength))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsentValidate(t *testing.T) {
+	c := Consent{Purpose: " Marketing ", Channel: "Email", State: " granted", Source: " signup_form ", PolicyVersion: " 2026-03 "}
+	c.Normalize()
+
+	assert.Equal(t, "marketing", c.Purpose)
+	assert.Equal(t, ChannelEmail, c.Channel)
+	assert.Equal(t, ConsentGranted, c.State)
+	assert.Equal(t, "signup_form", c.Source)
+	assert.Equal(t, "2026-03", c.PolicyVersion)
+	assert.NoError(t, c.Validate())
+
+	assert.NoError(t, Consent{Purpose: "marketing", Channel: ChannelSMS, State: ConsentRevoked, Source: "call"}.Validate())
+	assert.EqualError(t, Consent{Purpose: "not a purpose", Channel: "fax", State: "maybe"}.Validate(),
+		"purpose \"not a purpose\" must start with a letter or digit followed by up to 63 letters, digits, underscores or hyphens\n"+
+			"channel \"fax\" is not email, sms, phone or post\nstate \"maybe\" is not granted or revoked\nsource is required")
+	assert.EqualError(t, Consent{Purpose: "marketing", Channel: ChannelPost, State: ConsentGranted, Source: "form"}.Validate(),
+		"policy_version is required when consent is granted")
+	assert.EqualError(t, Consent{Purpose: "marketing", Channel: ChannelPost, State: ConsentRevoked, Source: strings.Repeat("x", 101)}.Validate(),
+		"source must be at most 100 characters long")
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ContactType tells what a contact point is used for.
+type ContactType string
+
+const (
+	ContactWork   ContactType = "work"
+	ContactHome   ContactType = "home"
+	ContactMobile ContactType = "mobile"
+	// ContactOther is given to the contact points migrated from the customer record.
+	ContactOther ContactType = "other"
+)
+
+func (t ContactType) Valid() bool {
+	switch t {
+	case ContactWork, ContactHome, ContactMobile, ContactOther:
+		return true
+	}
+	return false
+}
+
+// Email is one of the email addresses of a customer. Addresses are unique across all
+// customers, and the primary one is mirrored in Customer.Email.
+type Email struct {
+	ID         uuid.UUID   `json:"id"`
+	CustomerID uuid.UUID   `json:"customer_id"`
+	Type       ContactType `json:"type"`
+	Email      string      `json:"email"`
+	IsPrimary  bool        `json:"is_primary"`
+	Verified   bool        `json:"verified"`
+}
+
+// Phone is one of the phone numbers of a customer. The primary one is mirrored in Customer.PhoneNumber.
+type Phone struct {
+	ID         uuid.UUID   `json:"id"`
+	CustomerID uuid.UUID   `json:"customer_id"`
+	Type       ContactType `json:"type"`
+	Number     string      `json:"number"`
+	IsPrimary  bool        `json:"is_primary"`
+	Verified   bool        `json:"verified"`
+}
+
+func (e *Email) Normalize() {
+	e.Email = strings.TrimSpace(e.Email)
+}
+
+func (e Email) Validate() error {
+	var errs []error
+	if !e.Type.Valid() {
+		errs = append(errs, contactTypeError())
+	}
+	if addr, err := mail.ParseAddress(e.Email); err != nil || addr.Address != e.Email {
+		errs = append(errs, errors.New("email must be a valid email address"))
+	}
+	return errors.Join(errs...)
+}
+
+// phoneSeparators are stripped from phone numbers before they are validated.
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+var phoneNumber = regexp.MustCompile(`^\+?[0-9]{4,15}$`)
+
+// Normalize removes the separators commonly used to group the digits of a number.
+func (p *Phone) Normalize() {
+	p.Number = phoneSeparators.Replace(strings.TrimSpace(p.Number))
+}
+
+func (p Phone) Validate() error {
+	var errs []error
+	if !p.Type.Valid() {
+		errs = append(errs, contactTypeError())
+	}
+	if !phoneNumber.MatchString(p.Number) {
+		errs = append(errs, errors.New("number must hold 4 to 15 digits with an optional leading +"))
+	}
+	return errors.Join(errs...)
+}
+
+func contactTypeError() error {
+	return fmt.Errorf("type must be one of %s, %s, %s or %s", ContactWork, ContactHome, ContactMobile, ContactOther)
+}
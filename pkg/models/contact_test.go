@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhoneNormalize(t *testing.T) {
+	p := Phone{Type: ContactMobile, Number: " +1 (555) 010-9999 "}
+	p.Normalize()
+
+	assert.Equal(t, "+15550109999", p.Number)
+	assert.NoError(t, p.Validate())
+}
+
+func TestContactValidate(t *testing.T) {
+	assert.EqualError(t, Email{Type: "fax", Email: "jane@example.com"}.Validate(),
+		"type must be one of work, home, mobile or other")
+	assert.EqualError(t, Email{Type: ContactWork, Email: "Jane <jane@example.com>"}.Validate(),
+		"email must be a valid email address")
+	assert.EqualError(t, Phone{Type: ContactHome, Number: "12ab"}.Validate(),
+		"number must hold 4 to 15 digits with an optional leading +")
+}
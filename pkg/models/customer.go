@@ -1,6 +1,16 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrRequiredFields is returned by Validate for customers without a first name, last name or email.
+var ErrRequiredFields = errors.New("first name, last name, and email are required")
 
 type Customer struct {
 	ID          uuid.UUID `json:"id"`
@@ -9,4 +19,46 @@ type Customer struct {
 	LastName    string    `json:"last_name"`
 	Email       string    `json:"email"`
 	PhoneNumber string    `json:"phone_number,omitempty"`
+	// EmailVerified tells whether the customer proved to own Email, see package verification. It is
+	// the verified flag of the primary email and is reset when the email changes.
+	EmailVerified bool `json:"email_verified"`
+	// Status is only changed through status transitions, see StatusChange.
+	Status Status `json:"status"`
+	// Attributes holds the values of the custom attributes, see AttributeDefinition.
+	Attributes map[string]any `json:"attributes,omitempty"`
+
+	// CreatedAt, UpdatedAt, CreatedBy and UpdatedBy are maintained by the repository, see package actor.
+	// Customers stored before they were tracked have no CreatedBy.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+
+	// Addresses, Emails, Phones and Tags are only loaded when requested with ?include=.
+	Addresses []Address `json:"addresses,omitempty"`
+	Emails    []Email   `json:"emails,omitempty"`
+	Phones    []Phone   `json:"phones,omitempty"`
+	// Tags are free-form labels, see NormalizeTag.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Normalize trims the fields a customer can be written with.
+func (c *Customer) Normalize() {
+	c.FirstName = strings.TrimSpace(c.FirstName)
+	c.MiddleName = strings.TrimSpace(c.MiddleName)
+	c.LastName = strings.TrimSpace(c.LastName)
+	c.Email = strings.TrimSpace(c.Email)
+	c.PhoneNumber = strings.TrimSpace(c.PhoneNumber)
+}
+
+// Validate checks the fields a customer can be written with. The attributes are checked against
+// their definitions, see ValidateAttributes.
+func (c Customer) Validate() error {
+	var errs []error
+	if c.FirstName == "" || c.LastName == "" || c.Email == "" {
+		errs = append(errs, ErrRequiredFields)
+	} else if addr, err := mail.ParseAddress(c.Email); err != nil || addr.Address != c.Email {
+		errs = append(errs, errors.New("email must be a valid email address"))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const maxBodyLength = 10000
+
+// Note is a free-text note an agent keeps on a customer. Edits keep the previous bodies as
+// NoteRevisions and deleted notes are only hidden, so nothing written on a customer is lost.
+type Note struct {
+	ID         uuid.UUID `json:"id"`
+	CustomerID uuid.UUID `json:"customer_id"`
+	Body       string    `json:"body"`
+	// Author, CreatedAt, UpdatedAt and UpdatedBy are maintained by the server, see package actor.
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+}
+
+// NoteRevision is a body a note had before an edit, which EditedBy made at EditedAt.
+type NoteRevision struct {
+	ID       uuid.UUID `json:"id"`
+	NoteID   uuid.UUID `json:"note_id"`
+	Body     string    `json:"body"`
+	EditedAt time.Time `json:"edited_at"`
+	EditedBy string    `json:"edited_by"`
+}
+
+func (n *Note) Normalize() {
+	n.Body = strings.TrimSpace(n.Body)
+}
+
+func (n Note) Validate() error {
+	return validateBody(n.Body)
+}
+
+// InteractionType is the channel an interaction with a customer took place on.
+type InteractionType string
+
+const (
+	InteractionCall    InteractionType = "call"
+	InteractionEmail   InteractionType = "email"
+	InteractionMeeting InteractionType = "meeting"
+)
+
+func (t InteractionType) Valid() bool {
+	switch t {
+	case InteractionCall, InteractionEmail, InteractionMeeting:
+		return true
+	}
+	return false
+}
+
+// Interaction logs a call, email or meeting with a customer. Interactions are never changed.
+type Interaction struct {
+	ID         uuid.UUID       `json:"id"`
+	CustomerID uuid.UUID       `json:"customer_id"`
+	Type       InteractionType `json:"type"`
+	Body       string          `json:"body"`
+	// OccurredAt is when the interaction took place and defaults to when it was logged.
+	OccurredAt time.Time `json:"occurred_at"`
+	// Author and CreatedAt are maintained by the server, see package actor.
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (i *Interaction) Normalize() {
+	i.Type = InteractionType(strings.ToLower(strings.TrimSpace(string(i.Type))))
+	i.Body = strings.TrimSpace(i.Body)
+}
+
+func (i Interaction) Validate() error {
+	var errs []error
+	if !i.Type.Valid() {
+		errs = append(errs, fmt.Errorf("type must be one of %s, %s or %s", InteractionCall, InteractionEmail, InteractionMeeting))
+	}
+	if err := validateBody(i.Body); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func validateBody(body string) error {
+	if body == "" {
+		return errors.New("body is required")
+	}
+	if utf8.RuneCountInString(body) > maxBodyLength {
+		return fmt.Errorf("body must be at most %d characters long", maxBodyLength)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoteValidate(t *testing.T) {
+	n := Note{Body: "  Prefers email over calls \n"}
+	n.Normalize()
+
+	assert.Equal(t, "Prefers email over calls", n.Body)
+	assert.NoError(t, n.Validate())
+
+	assert.EqualError(t, Note{}.Validate(), "body is required")
+	assert.EqualError(t, Note{Body: strings.Repeat("x", 10001)}.Validate(), "body must be at most 10000 characters long")
+}
+
+func TestInteractionValidate(t *testing.T) {
+	i := Interaction{Type: " Meeting ", Body: " Quarterly review "}
+	i.Normalize()
+
+	assert.Equal(t, InteractionMeeting, i.Type)
+	assert.NoError(t, i.Validate())
+
+	assert.EqualError(t, Interaction{Type: "fax"}.Validate(), "type must be one of call, email or meeting\nbody is required")
+}
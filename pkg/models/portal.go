@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginToken is the token of a magic link signing a customer in to the self-service portal. Only
+// the hash of the token is stored, and it can be used once.
+type LoginToken struct {
+	ID         uuid.UUID
+	CustomerID uuid.UUID
+	TokenHash  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// PortalSession is a customer signed in to the self-service portal. Only the hash of its token is
+// stored, the token itself is kept by the browser of the customer in a cookie.
+type PortalSession struct {
+	ID         uuid.UUID
+	CustomerID uuid.UUID
+	TokenHash  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
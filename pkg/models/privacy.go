@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Erased replaces the free-text fields and recorded values an erasure removes.
+const Erased = "[erased]"
+
+const maxReferenceLength = 200
+
+// LegalHold keeps the data of a customer from being erased, for instance during litigation or an
+// investigation. Released holds are kept as a record.
+type LegalHold struct {
+	ID         uuid.UUID `json:"id"`
+	CustomerID uuid.UUID `json:"customer_id"`
+	Reason     string    `json:"reason"`
+	// PlacedAt, PlacedBy, ReleasedAt and ReleasedBy are maintained by the repository, see package actor.
+	PlacedAt   time.Time  `json:"placed_at"`
+	PlacedBy   string     `json:"placed_by"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+	ReleasedBy string     `json:"released_by,omitempty"`
+}
+
+// Active reports whether the hold still blocks erasure.
+func (h LegalHold) Active() bool {
+	return h.ReleasedAt == nil
+}
+
+func (h *LegalHold) Normalize() {
+	h.Reason = strings.TrimSpace(h.Reason)
+}
+
+func (h LegalHold) Validate() error {
+	if h.Reason == "" {
+		return errors.New("reason is required")
+	}
+	if utf8.RuneCountInString(h.Reason) > maxReasonLength {
+		return fmt.Errorf("reason must be at most %d characters long", maxReasonLength)
+	}
+	return nil
+}
+
+// Erasure records that the personal data of a customer was erased. Reference points at the
+// request of the data subject, such as a ticket number.
+type Erasure struct {
+	CustomerID uuid.UUID `json:"customer_id"`
+	Reference  string    `json:"reference,omitempty"`
+	// ErasedAt and ErasedBy are maintained by the repository, see package actor.
+	ErasedAt time.Time `json:"erased_at"`
+	ErasedBy string    `json:"erased_by"`
+}
+
+func (e *Erasure) Normalize() {
+	e.Reference = strings.TrimSpace(e.Reference)
+}
+
+func (e Erasure) Validate() error {
+	if utf8.RuneCountInString(e.Reference) > maxReferenceLength {
+		return fmt.Errorf("reference must be at most %d characters long", maxReferenceLength)
+	}
+	return nil
+}
+
+// AnonymizedCustomer returns the fields an erased customer keeps in place of its personal data.
+// The email stays unique and valid, in the reserved .invalid domain.
+func AnonymizedCustomer(customerID uuid.UUID) Customer {
+	return Customer{
+		ID:        customerID,
+		FirstName: "Erased",
+		LastName:  "Customer",
+		Email:     "erased-" + customerID.String() + "@erased.invalid",
+	}
+}
+
+// RedactChanges returns the audit changes with their values replaced by Erased. The names of the
+// changed fields and the status values, which are not personal, are kept.
+func RedactChanges(changes map[string]FieldChange) map[string]FieldChange {
+	if changes == nil {
+		return nil
+	}
+	out := make(map[string]FieldChange, len(changes))
+	for name, change := range changes {
+		if name != "status" {
+			change = FieldChange{From: redactValue(change.From), To: redactValue(change.To)}
+		}
+		out[name] = change
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	if v == nil {
+		return nil
+	}
+	return Erased
+}
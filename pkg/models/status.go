@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Status is the lifecycle state of a customer. The allowed transitions between the statuses are
+// configured and enforced by the service layer.
+type Status string
+
+const (
+	StatusLead      Status = "lead"
+	StatusProspect  Status = "prospect"
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
+	StatusClosed    Status = "closed"
+)
+
+// InitialStatus is the status new customers start in.
+const InitialStatus = StatusLead
+
+// Statuses lists every status in lifecycle order.
+var Statuses = []Status{StatusLead, StatusProspect, StatusActive, StatusSuspended, StatusClosed}
+
+func (s Status) Valid() bool {
+	for _, status := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+const maxReasonLength = 500
+
+// StatusChange records a transition of a customer from one status to another. From is empty
+// for the status a customer was created with.
+type StatusChange struct {
+	ID         uuid.UUID `json:"id"`
+	CustomerID uuid.UUID `json:"customer_id"`
+	From       Status    `json:"from,omitempty"`
+	To         Status    `json:"to"`
+	Reason     string    `json:"reason"`
+	ChangedAt  time.Time `json:"changed_at"`
+	ChangedBy  string    `json:"changed_by,omitempty"`
+}
+
+func (c *StatusChange) Normalize() {
+	c.To = Status(strings.ToLower(strings.TrimSpace(string(c.To))))
+	c.Reason = strings.TrimSpace(c.Reason)
+}
+
+// Validate checks the requested status and reason. Whether the transition is allowed is up to the service layer.
+func (c StatusChange) Validate() error {
+	var errs []error
+	if !c.To.Valid() {
+		errs = append(errs, fmt.Errorf("status must be one of %s, %s, %s, %s or %s",
+			StatusLead, StatusProspect, StatusActive, StatusSuspended, StatusClosed))
+	}
+	if c.Reason == "" {
+		errs = append(errs, errors.New("reason is required"))
+	} else if utf8.RuneCountInString(c.Reason) > maxReasonLength {
+		errs = append(errs, fmt.Errorf("reason must be at most %d characters long", maxReasonLength))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusChangeValidate(t *testing.T) {
+	c := StatusChange{To: " Active ", Reason: " Paid the first invoice "}
+	c.Normalize()
+
+	assert.Equal(t, StatusActive, c.To)
+	assert.Equal(t, "Paid the first invoice", c.Reason)
+	assert.NoError(t, c.Validate())
+
+	assert.EqualError(t, StatusChange{To: "archived"}.Validate(),
+		"status must be one of lead, prospect, active, suspended or closed\nreason is required")
+	assert.EqualError(t, StatusChange{To: StatusClosed, Reason: strings.Repeat("x", 501)}.Validate(),
+		"reason must be at most 500 characters long")
+}
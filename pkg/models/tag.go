@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// NormalizeTag lowercases a tag so that VIP and vip are the same tag.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// ValidateTag checks a normalized tag.
+func ValidateTag(tag string) error {
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("tag %q must start with a letter or digit followed by up to 63 letters, digits, underscores or hyphens", tag)
+	}
+	return nil
+}
+
+// Tag is a tag in use together with the number of customers carrying it.
+type Tag struct {
+	Name      string `json:"name"`
+	Customers int    `json:"customers"`
+}
+
+// Segment is a saved group of customers. Its members are the customers matching Filter at the
+// time it is evaluated, see package segment for the filter syntax.
+type Segment struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Filter      string    `json:"filter"`
+}
+
+func (s *Segment) Normalize() {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Filter = strings.TrimSpace(s.Filter)
+}
+
+// Validate checks the required fields. The filter is parsed separately by package segment.
+func (s Segment) Validate() error {
+	var errs []error
+	if s.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if s.Filter == "" {
+		errs = append(errs, errors.New("filter is required"))
+	}
+	return errors.Join(errs...)
+}
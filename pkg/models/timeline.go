@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TimelineKind tells where a timeline entry comes from.
+type TimelineKind string
+
+const (
+	TimelineNote         TimelineKind = "note"
+	TimelineInteraction  TimelineKind = "interaction"
+	TimelineAudit        TimelineKind = "audit"
+	TimelineStatusChange TimelineKind = "status_change"
+)
+
+// TimelineEntry is a note, interaction, audit entry or status change of a customer, flattened so
+// that they can be listed together. ID is the ID of the underlying record.
+type TimelineEntry struct {
+	ID    uuid.UUID    `json:"id"`
+	Kind  TimelineKind `json:"kind"`
+	At    time.Time    `json:"at"`
+	Actor string       `json:"actor,omitempty"`
+	// Type is the interaction type or the audit action.
+	Type string `json:"type,omitempty"`
+	// Body is the note or interaction body, or the reason of a status change.
+	Body string `json:"body,omitempty"`
+	// Changes holds the fields an update changed, or the status of a status change.
+	Changes map[string]FieldChange `json:"changes,omitempty"`
+}
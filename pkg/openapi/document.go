@@ -0,0 +1,181 @@
+// Package openapi loads the OpenAPI document of the service and validates requests against it.
+// Only the subset of OpenAPI 3.1 and JSON Schema used by api/openapi.json is supported.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Methods lists the HTTP methods an OpenAPI path item can describe.
+var Methods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Components struct {
+	Schemas    map[string]*Schema   `json:"schemas"`
+	Parameters map[string]Parameter `json:"parameters"`
+}
+
+// PathItem holds the operations of a path keyed by upper-case HTTP method.
+type PathItem struct {
+	Parameters []Parameter
+	Operations map[string]*Operation
+}
+
+func (p *PathItem) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	p.Operations = map[string]*Operation{}
+	if params, ok := raw["parameters"]; ok {
+		if err := json.Unmarshal(params, &p.Parameters); err != nil {
+			return err
+		}
+	}
+	for _, method := range Methods {
+		op, ok := raw[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+		var o Operation
+		if err := json.Unmarshal(op, &o); err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		p.Operations[method] = &o
+	}
+	return nil
+}
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []Parameter  `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema, limited to the keywords the service uses.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 Types              `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []interface{}      `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Pattern              string             `json:"pattern"`
+	ReadOnly             bool               `json:"readOnly"`
+}
+
+// Types is the JSON Schema type keyword, which is either a single type or a list of them.
+type Types []string
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+// Additional is the additionalProperties keyword, either a boolean or a schema.
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// Load parses an OpenAPI document.
+func Load(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.1") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// Operation returns the operation registered for method on the path template, such as "/customers/{id}".
+func (d *Document) Operation(method, path string) (*Operation, bool) {
+	item, ok := d.Paths[path]
+	if !ok {
+		return nil, false
+	}
+	op, ok := item.Operations[strings.ToUpper(method)]
+	return op, ok
+}
+
+// OperationParameters returns the parameters of the operation merged with the ones of its path,
+// with references resolved.
+func (d *Document) OperationParameters(path string, op *Operation) ([]Parameter, error) {
+	var params []Parameter
+	for _, p := range append(append([]Parameter{}, d.Paths[path].Parameters...), op.Parameters...) {
+		if p.Ref != "" {
+			resolved, ok := d.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+			if !ok {
+				return nil, fmt.Errorf("unresolved parameter reference %s", p.Ref)
+			}
+			p = resolved
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// Resolve follows the $ref of a schema to the component schema it points to.
+func (d *Document) Resolve(s *Schema) (*Schema, error) {
+	for s != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("unresolved schema reference %s", s.Ref)
+		}
+		s = resolved
+	}
+	return s, nil
+}
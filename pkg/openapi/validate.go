@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Validate checks a decoded JSON value against the schema and returns every violation found.
+// Locations are reported as JSON pointers, the root being "/".
+func (d *Document) Validate(value interface{}, schema *Schema) []string {
+	var errs []string
+	d.validate(value, schema, "", &errs)
+	return errs
+}
+
+func (d *Document) validate(value interface{}, schema *Schema, at string, errs *[]string) {
+	schema, err := d.Resolve(schema)
+	if err != nil {
+		*errs = append(*errs, err.Error())
+		return
+	}
+	if schema == nil {
+		return
+	}
+
+	fail := func(format string, args ...interface{}) {
+		location := at
+		if location == "" {
+			location = "/"
+		}
+		*errs = append(*errs, location+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(schema.Type) > 0 && !matchesAnyType(value, schema.Type) {
+		fail("must be of type %s", strings.Join(schema.Type, " or "))
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, e := range schema.Enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %v", schema.Enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("must be at most %d characters long", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err != nil || !re.MatchString(v) {
+				fail("must match pattern %s", schema.Pattern)
+			}
+		}
+		if msg := checkFormat(schema.Format, v); msg != "" {
+			fail("%s", msg)
+		}
+	case float64:
+		if schema.Minimum != nil && v < *schema.Minimum {
+			fail("must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && v > *schema.Maximum {
+			fail("must be at most %v", *schema.Maximum)
+		}
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			fail("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			fail("must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range v {
+			d.validate(item, schema.Items, at+"/"+strconv.Itoa(i), errs)
+		}
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if prop, ok := schema.Properties[name]; ok {
+				d.validate(v[name], prop, at+"/"+name, errs)
+				continue
+			}
+			if schema.AdditionalProperties == nil {
+				continue
+			}
+			if !schema.AdditionalProperties.Allowed {
+				fail("unknown property %q", name)
+				continue
+			}
+			d.validate(v[name], schema.AdditionalProperties.Schema, at+"/"+name, errs)
+		}
+	}
+}
+
+func matchesAnyType(value interface{}, types []string) bool {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(value interface{}, t string) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+func checkFormat(format, value string) string {
+	switch format {
+	case "uuid":
+		if _, err := uuid.Parse(value); err != nil {
+			return "must be a valid uuid"
+		}
+	case "email":
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			return "must be a valid email address"
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return "must be a date formatted as YYYY-MM-DD"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return "must be an RFC 3339 date-time"
+		}
+	}
+	return ""
+}
+
+// ParseParameter converts a raw query or path value to the JSON type its schema expects,
+// so it can be validated like a body value.
+func ParseParameter(raw string, schema *Schema) interface{} {
+	if schema == nil {
+		return raw
+	}
+	for _, t := range schema.Type {
+		switch t {
+		case "integer", "number":
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				return f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b
+			}
+		}
+	}
+	return raw
+}
@@ -0,0 +1,62 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `{
+  "openapi": "3.1.0",
+  "paths": {},
+  "components": {
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["id", "tags"],
+        "properties": {
+          "id": {"type": "string", "format": "uuid"},
+          "count": {"type": ["integer", "null"], "minimum": 0},
+          "kind": {"type": "string", "enum": ["a", "b"]},
+          "tags": {"type": "array", "maxItems": 2, "items": {"type": "string", "minLength": 1}},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+        }
+      }
+    }
+  }
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	doc, err := Load([]byte(testSpec))
+	require.NoError(t, err)
+	schema := &Schema{Ref: "#/components/schemas/Item"}
+
+	assert.Empty(t, doc.Validate(decode(t, `{"id":"7f8b4d2e-8d4a-4f65-9bd4-2a8f6b6b0c11","count":null,"kind":"a","tags":["x"],"labels":{"k":"v"}}`), schema))
+
+	errs := doc.Validate(decode(t, `{"id":"nope","count":1.5,"kind":"c","tags":["", "y", "z"],"labels":{"k":1},"extra":true}`), schema)
+	assert.Equal(t, []string{
+		"/count: must be of type integer or null",
+		`/: unknown property "extra"`,
+		"/id: must be a valid uuid",
+		"/kind: must be one of [a b]",
+		"/labels/k: must be of type string",
+		"/tags: must have at most 2 items",
+		"/tags/0: must be at least 1 characters long",
+	}, errs)
+
+	assert.Equal(t, []string{`/: missing required property "id"`, `/: missing required property "tags"`}, doc.Validate(decode(t, `{}`), schema))
+}
+
+func TestLoad_RejectsOtherVersions(t *testing.T) {
+	_, err := Load([]byte(`{"openapi":"3.0.3","paths":{}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the MemoryStore drops full buckets and past counters.
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+type memoryCounter struct {
+	count int
+	reset time.Time
+}
+
+// MemoryStore keeps the buckets and counters in memory, so each instance of the service limits
+// its clients on its own.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	counters  map[string]*memoryCounter
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:      time.Now,
+		buckets:  map[string]*memoryBucket{},
+		counters: map[string]*memoryCounter{},
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, at time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+
+	var current *Bucket
+	if b, ok := s.buckets[key]; ok {
+		current = &b.Bucket
+	}
+	next, res := limit.Take(current, at)
+	s.buckets[key] = &memoryBucket{Bucket: next, limit: limit}
+	return res, nil
+}
+
+func (s *MemoryStore) Count(_ context.Context, key string, reset time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+
+	c, ok := s.counters[key]
+	if !ok || !s.now().Before(c.reset) {
+		c = &memoryCounter{reset: reset}
+		s.counters[key] = c
+	}
+	c.count++
+	return c.count, nil
+}
+
+// sweep drops the buckets that filled up again, which are the same as missing ones, and the
+// counters that restarted. The caller holds the lock.
+func (s *MemoryStore) sweep() {
+	now := s.now()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	for key, b := range s.buckets {
+		if b.limit.tokens(&b.Bucket, now) >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, c := range s.counters {
+		if !now.Before(c.reset) {
+			delete(s.counters, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,188 @@
+// Package ratelimit limits how many requests clients make, with a token bucket per client and
+// route and a daily quota per API key.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket holding at most Burst tokens, which regains Requests tokens every
+// Period. Every request takes a token. A Limit without Requests is unlimited.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Unlimited reports whether l lets every request through.
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// Bucket is the state of a token bucket as kept by a Store.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// Reset is how long the bucket takes to fill up again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token when the request was not allowed.
+	RetryAfter time.Duration
+}
+
+// Take takes a token from b at the given time, a missing bucket being full. Stores call it to
+// implement Store.Take.
+func (l Limit) Take(b *Bucket, at time.Time) (Bucket, Result) {
+	capacity, perSecond := float64(l.Burst), l.perSecond()
+	tokens := l.tokens(b, at)
+	res := Result{Allowed: tokens >= 1}
+	if res.Allowed {
+		tokens--
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / perSecond)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = seconds((capacity - tokens) / perSecond)
+	return Bucket{Tokens: tokens, Updated: at}, res
+}
+
+// tokens returns the tokens in b at the given time.
+func (l Limit) tokens(b *Bucket, at time.Time) float64 {
+	if b == nil {
+		return float64(l.Burst)
+	}
+	return math.Min(float64(l.Burst), b.Tokens+at.Sub(b.Updated).Seconds()*l.perSecond())
+}
+
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// Store keeps the token buckets and quota counters. The MemoryStore keeps them per instance of the
+// service, a store shared by the replicas, such as one backed by Redis, limits clients across them.
+type Store interface {
+	// Take takes a token from the bucket of key with the limit at the given time.
+	Take(ctx context.Context, key string, limit Limit, at time.Time) (Result, error)
+	// Count adds a request to the counter of key, which restarts at reset, and returns the count
+	// including it.
+	Count(ctx context.Context, key string, reset time.Time) (int, error)
+}
+
+// Route overrides the limit of the requests to a route. Path is its mux path template, such as
+// /customers/{id}, and an empty Method matches every method.
+type Route struct {
+	Method string
+	Path   string
+	Limit  Limit
+}
+
+// Policy configures a Limiter.
+type Policy struct {
+	// Default limits every route without a Route of its own.
+	Default Limit
+	Routes  []Route
+	// APIKeys are the IDs of the API keys clients may identify with, besides the keys of Quotas.
+	APIKeys []string
+	// DailyQuota bounds the requests of every API key per UTC day, zero disables it.
+	DailyQuota int
+	// Quotas override DailyQuota by API key ID, zero exempting a key.
+	Quotas map[string]int
+}
+
+// Request names the client and route of a request. APIKeyID identifies the API key of the client,
+// empty without one, and Client the client itself, which may be the same.
+type Request struct {
+	Client   string
+	APIKeyID string
+	Method   string
+	Route    string
+}
+
+// Decision is the outcome of Allow. When Limited, Limit and the Remaining and Reset of the result
+// describe the token bucket of the request.
+type Decision struct {
+	Result
+	Limited bool
+	Limit   Limit
+	// QuotaExceeded tells that the daily quota of the API key was used up, rather than the bucket.
+	QuotaExceeded bool
+}
+
+// Limiter decides which requests are allowed by a Policy.
+type Limiter struct {
+	policy Policy
+	store  Store
+	now    func() time.Time
+}
+
+func NewLimiter(policy Policy, store Store) *Limiter {
+	return &Limiter{policy: policy, store: store, now: time.Now}
+}
+
+// Knows reports whether the API key with the given ID is configured. Clients can send any key, so
+// only configured ones are trusted to identify a client.
+func (l *Limiter) Knows(apiKeyID string) bool {
+	if _, ok := l.policy.Quotas[apiKeyID]; ok {
+		return true
+	}
+	return slices.Contains(l.policy.APIKeys, apiKeyID)
+}
+
+// Allow takes a token for the request and counts it against the quota of its API key. Requests
+// turned away by their bucket do not count against the quota.
+func (l *Limiter) Allow(ctx context.Context, req Request) (Decision, error) {
+	at := l.now()
+	limit, bucket := l.policy.Default, "*"
+	for _, r := range l.policy.Routes {
+		if r.Path == req.Route && (r.Method == "" || strings.EqualFold(r.Method, req.Method)) {
+			limit, bucket = r.Limit, r.Method+" "+r.Path
+			break
+		}
+	}
+
+	d := Decision{Result: Result{Allowed: true}}
+	if !limit.Unlimited() {
+		res, err := l.store.Take(ctx, "rate|"+req.Client+"|"+bucket, limit, at)
+		if err != nil {
+			return d, err
+		}
+		d = Decision{Result: res, Limited: true, Limit: limit}
+		if !res.Allowed {
+			return d, nil
+		}
+	}
+
+	quota := l.policy.DailyQuota
+	if q, ok := l.policy.Quotas[req.APIKeyID]; ok {
+		quota = q
+	}
+	if req.APIKeyID == "" || quota <= 0 {
+		return d, nil
+	}
+	day := at.UTC().Truncate(24 * time.Hour)
+	reset := day.Add(24 * time.Hour)
+	n, err := l.store.Count(ctx, "quota|"+req.APIKeyID+"|"+day.Format(time.DateOnly), reset)
+	if err != nil {
+		return d, err
+	}
+	if n > quota {
+		d.Allowed = false
+		d.QuotaExceeded = true
+		d.RetryAfter = reset.Sub(at)
+	}
+	return d, nil
+}
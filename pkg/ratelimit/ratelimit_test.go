@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimit_Take(t *testing.T) {
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 2}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	b, res := limit.Take(nil, start)
+	assert.Equal(t, Result{Allowed: true, Remaining: 1, Reset: time.Second}, res)
+	b, res = limit.Take(&b, start)
+	assert.Equal(t, Result{Allowed: true, Remaining: 0, Reset: 2 * time.Second}, res)
+	b, res = limit.Take(&b, start.Add(500*time.Millisecond))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	// A token per second comes back, up to the burst.
+	_, res = limit.Take(&b, start.Add(time.Hour))
+	assert.Equal(t, Result{Allowed: true, Remaining: 1, Reset: time.Second}, res)
+}
+
+func newTestLimiter(policy Policy, now *time.Time) *Limiter {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	l := NewLimiter(policy, store)
+	l.now = store.now
+	return l
+}
+
+func TestLimiter_Routes(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(Policy{
+		Default: Limit{Requests: 10, Period: time.Second, Burst: 10},
+		Routes: []Route{
+			{Method: "GET", Path: "/customers", Limit: Limit{Requests: 1, Period: time.Minute, Burst: 1}},
+			{Path: "/healthz"},
+		},
+	}, &now)
+	ctx := context.Background()
+	list := Request{Client: "ip:10.0.0.1", Method: "GET", Route: "/customers"}
+
+	d, err := l.Allow(ctx, list)
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 1, d.Limit.Burst)
+
+	d, err = l.Allow(ctx, list)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, time.Minute, d.RetryAfter)
+
+	// Other routes, clients and methods have buckets of their own.
+	for _, req := range []Request{
+		{Client: "ip:10.0.0.1", Method: "POST", Route: "/customers"},
+		{Client: "ip:10.0.0.2", Method: "GET", Route: "/customers"},
+	} {
+		d, err = l.Allow(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed, req)
+	}
+
+	for i := 0; i < 20; i++ {
+		d, err = l.Allow(ctx, Request{Client: "ip:10.0.0.1", Method: "GET", Route: "/healthz"})
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.False(t, d.Limited)
+	}
+}
+
+func TestLimiter_DailyQuota(t *testing.T) {
+	now := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	l := newTestLimiter(Policy{
+		Default:    Limit{Requests: 1, Period: time.Second, Burst: 1},
+		DailyQuota: 2,
+		Quotas:     map[string]int{"partner": 3, "internal": 0},
+	}, &now)
+	ctx := context.Background()
+
+	allowed := func(apiKeyID string, n int) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			now = now.Add(time.Second)
+			d, err := l.Allow(ctx, Request{Client: "key:" + apiKeyID, APIKeyID: apiKeyID, Method: "GET", Route: "/customers"})
+			require.NoError(t, err)
+			if d.Allowed {
+				count++
+			} else {
+				assert.True(t, d.QuotaExceeded)
+			}
+		}
+		return count
+	}
+
+	assert.Equal(t, 2, allowed("default", 5))
+	assert.Equal(t, 3, allowed("partner", 5))
+	assert.Equal(t, 5, allowed("internal", 5))
+
+	d, err := l.Allow(ctx, Request{Client: "key:default", APIKeyID: "default", Method: "GET", Route: "/customers"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC).Sub(now), d.RetryAfter)
+
+	// The quota restarts at midnight UTC.
+	now = time.Date(2026, 3, 2, 0, 0, 1, 0, time.UTC)
+	assert.Equal(t, 2, allowed("default", 5))
+}
+
+func TestLimiter_Knows(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(Policy{
+		Default: Limit{Requests: 1, Period: time.Second, Burst: 1},
+		APIKeys: []string{"partner"},
+		Quotas:  map[string]int{"internal": 0},
+	}, &now)
+
+	assert.True(t, l.Knows("partner"))
+	assert.True(t, l.Knows("internal"))
+	assert.False(t, l.Knows("unknown"))
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 1, Period: time.Minute, Burst: 5}
+	ctx := context.Background()
+
+	_, err := store.Take(ctx, "a", limit, now)
+	require.NoError(t, err)
+	_, err = store.Count(ctx, "q", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = store.Take(ctx, "b", limit, now)
+	require.NoError(t, err)
+	assert.Contains(t, store.buckets, "b")
+	assert.NotContains(t, store.buckets, "a", "a full bucket is dropped")
+	assert.Contains(t, store.counters, "q")
+
+	now = now.Add(2 * time.Hour)
+	_, err = store.Take(ctx, "b", limit, now)
+	require.NoError(t, err)
+	assert.NotContains(t, store.counters, "q")
+}
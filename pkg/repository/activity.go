@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// NoteQuery selects the notes to list. Deleted notes are never listed.
+type NoteQuery struct {
+	// CustomerID, when set, keeps the notes of that customer. Otherwise the notes of all customers are searched.
+	CustomerID uuid.UUID
+	// Search, when set, keeps the notes whose body contains all of its words.
+	Search string
+	// Limit caps the number of notes, 0 means no limit.
+	Limit int
+}
+
+// TimelineQuery selects a page of the timeline of a customer, newest first.
+type TimelineQuery struct {
+	CustomerID uuid.UUID
+	// Before and BeforeID, when set, continue after the last entry of the previous page.
+	Before   time.Time
+	BeforeID uuid.UUID
+	Limit    int
+}
+
+// ActivityRepository stores what happens around a customer besides its own fields: the notes agents
+// keep and the calls, emails and meetings they log. It also merges them with the audit trail and the
+// status changes into the timeline of the customer.
+type ActivityRepository interface {
+	// ListNotes returns the notes selected by query, newest first.
+	ListNotes(ctx context.Context, query NoteQuery) ([]models.Note, error)
+	GetNote(ctx context.Context, customerID, noteID uuid.UUID) (*models.Note, error)
+	// CreateNote stores note and sets its author and timestamps.
+	CreateNote(ctx context.Context, note *models.Note) error
+	// UpdateNote replaces the body of note, keeping the previous one as a revision, and fills in the
+	// other fields from the stored note.
+	UpdateNote(ctx context.Context, note *models.Note) error
+	// DeleteNote hides the note. Its body and revisions are kept.
+	DeleteNote(ctx context.Context, customerID, noteID uuid.UUID) error
+	// ListNoteRevisions returns the previous bodies of the note, oldest first.
+	ListNoteRevisions(ctx context.Context, customerID, noteID uuid.UUID) ([]models.NoteRevision, error)
+
+	// ListInteractions returns the interactions of the customer, most recent first.
+	ListInteractions(ctx context.Context, customerID uuid.UUID) ([]models.Interaction, error)
+	GetInteraction(ctx context.Context, customerID, interactionID uuid.UUID) (*models.Interaction, error)
+	// CreateInteraction stores interaction and sets its author and creation time, and its
+	// occurrence time when it is zero.
+	CreateInteraction(ctx context.Context, interaction *models.Interaction) error
+
+	// ListTimeline returns a page of the notes, interactions, audit entries and status changes of a
+	// customer, newest first.
+	ListTimeline(ctx context.Context, query TimelineQuery) ([]models.TimelineEntry, error)
+}
+
+type activityRepository struct {
+	db      conn
+	dialect dialect
+}
+
+func NewActivityRepository(db *sql.DB) ActivityRepository {
+	return &activityRepository{db: conn{db: db}, dialect: dialectOf(db)}
+}
+
+const noteColumns = "id, customer_id, body, author, created_at, updated_at, updated_by"
+
+func scanNote(row rowScanner) (models.Note, error) {
+	var n models.Note
+	var updatedBy sql.NullString
+	err := row.Scan(&n.ID, &n.CustomerID, &n.Body, &n.Author, &n.CreatedAt, &n.UpdatedAt, &updatedBy)
+	n.CreatedAt, n.UpdatedAt = n.CreatedAt.UTC(), n.UpdatedAt.UTC()
+	n.UpdatedBy = updatedBy.String
+	return n, err
+}
+
+// searchCondition matches the notes containing all the words of search. PostgreSQL uses its full
+// text search on whole words, SQLite matches the words anywhere in the body.
+func (r activityRepository) searchCondition(search string, args []any) (string, []any) {
+	if r.dialect == postgresDialect {
+		args = append(args, search)
+		return fmt.Sprintf("to_tsvector('simple', body) @@ plainto_tsquery('simple', $%d)", len(args)), args
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	var conditions []string
+	for _, word := range strings.Fields(search) {
+		args = append(args, "%"+escaper.Replace(word)+"%")
+		conditions = append(conditions, fmt.Sprintf(`body LIKE $%d ESCAPE '\'`, len(args)))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (r activityRepository) ListNotes(ctx context.Context, query NoteQuery) ([]models.Note, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []any
+	if query.CustomerID != uuid.Nil {
+		args = append(args, query.CustomerID)
+		conditions = append(conditions, fmt.Sprintf("customer_id = $%d", len(args)))
+	}
+	if strings.TrimSpace(query.Search) != "" {
+		var condition string
+		condition, args = r.searchCondition(query.Search, args)
+		conditions = append(conditions, condition)
+	}
+
+	stmt := "SELECT " + noteColumns + " FROM customer_notes WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY created_at DESC, id DESC"
+	if query.Limit > 0 {
+		args = append(args, query.Limit)
+		stmt += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning note rows: %w", err)
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+func (r activityRepository) GetNote(ctx context.Context, customerID, noteID uuid.UUID) (*models.Note, error) {
+	n, err := scanNote(r.db.QueryRowContext(ctx,
+		"SELECT "+noteColumns+" FROM customer_notes WHERE customer_id = $1 AND id = $2 AND deleted_at IS NULL",
+		customerID, noteID))
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r activityRepository) CreateNote(ctx context.Context, note *models.Note) error {
+	at, by := now(), actor.FromContext(ctx)
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO customer_notes (id, customer_id, body, author, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $5)",
+		note.ID, note.CustomerID, note.Body, by, at)
+	if err != nil {
+		return fmt.Errorf("error inserting note: %w", err)
+	}
+	note.Author, note.CreatedAt, note.UpdatedAt, note.UpdatedBy = by, at, at, ""
+	return nil
+}
+
+func (r activityRepository) UpdateNote(ctx context.Context, note *models.Note) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stored, err := scanNote(tx.QueryRowContext(ctx,
+		"SELECT "+noteColumns+" FROM customer_notes WHERE customer_id = $1 AND id = $2 AND deleted_at IS NULL",
+		note.CustomerID, note.ID))
+	if err != nil {
+		return err
+	}
+	if stored.Body == note.Body {
+		*note = stored
+		return nil
+	}
+
+	at, by := now(), actor.FromContext(ctx)
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO customer_note_revisions (id, note_id, body, edited_at, edited_by) VALUES ($1, $2, $3, $4, $5)",
+		uuid.New(), note.ID, stored.Body, at, by)
+	if err != nil {
+		return fmt.Errorf("error recording note revision: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE customer_notes SET body = $1, updated_at = $2, updated_by = $3 WHERE id = $4",
+		note.Body, at, by, note.ID)
+	if err != nil {
+		return fmt.Errorf("error updating note: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	stored.Body, stored.UpdatedAt, stored.UpdatedBy = note.Body, at, by
+	*note = stored
+	return nil
+}
+
+func (r activityRepository) DeleteNote(ctx context.Context, customerID, noteID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE customer_notes SET deleted_at = $1, deleted_by = $2 WHERE customer_id = $3 AND id = $4 AND deleted_at IS NULL",
+		now(), actor.FromContext(ctx), customerID, noteID)
+	if err != nil {
+		return fmt.Errorf("error deleting note: %w", err)
+	}
+	return expectAffected(res)
+}
+
+func (r activityRepository) ListNoteRevisions(ctx context.Context, customerID, noteID uuid.UUID) ([]models.NoteRevision, error) {
+	if _, err := r.GetNote(ctx, customerID, noteID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, note_id, body, edited_at, edited_by FROM customer_note_revisions WHERE note_id = $1 ORDER BY edited_at, id",
+		noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.NoteRevision
+	for rows.Next() {
+		var rev models.NoteRevision
+		if err := rows.Scan(&rev.ID, &rev.NoteID, &rev.Body, &rev.EditedAt, &rev.EditedBy); err != nil {
+			return nil, fmt.Errorf("error scanning note revision rows: %w", err)
+		}
+		rev.EditedAt = rev.EditedAt.UTC()
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+const interactionColumns = "id, customer_id, type, body, occurred_at, author, created_at"
+
+func scanInteraction(row rowScanner) (models.Interaction, error) {
+	var i models.Interaction
+	err := row.Scan(&i.ID, &i.CustomerID, &i.Type, &i.Body, &i.OccurredAt, &i.Author, &i.CreatedAt)
+	i.OccurredAt, i.CreatedAt = i.OccurredAt.UTC(), i.CreatedAt.UTC()
+	return i, err
+}
+
+func (r activityRepository) ListInteractions(ctx context.Context, customerID uuid.UUID) ([]models.Interaction, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+interactionColumns+" FROM customer_interactions WHERE customer_id = $1 ORDER BY occurred_at DESC, id DESC",
+		customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interactions []models.Interaction
+	for rows.Next() {
+		i, err := scanInteraction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning interaction rows: %w", err)
+		}
+		interactions = append(interactions, i)
+	}
+	return interactions, rows.Err()
+}
+
+func (r activityRepository) GetInteraction(ctx context.Context, customerID, interactionID uuid.UUID) (*models.Interaction, error) {
+	i, err := scanInteraction(r.db.QueryRowContext(ctx,
+		"SELECT "+interactionColumns+" FROM customer_interactions WHERE customer_id = $1 AND id = $2",
+		customerID, interactionID))
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r activityRepository) CreateInteraction(ctx context.Context, interaction *models.Interaction) error {
+	at, by := now(), actor.FromContext(ctx)
+	occurredAt := interaction.OccurredAt.UTC().Truncate(time.Microsecond)
+	if interaction.OccurredAt.IsZero() {
+		occurredAt = at
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO customer_interactions ("+interactionColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		interaction.ID, interaction.CustomerID, interaction.Type, interaction.Body, occurredAt, by, at)
+	if err != nil {
+		return fmt.Errorf("error inserting interaction: %w", err)
+	}
+	interaction.OccurredAt, interaction.Author, interaction.CreatedAt = occurredAt, by, at
+	return nil
+}
+
+// timelineSources flattens every record of the timeline to the same columns. The first source
+// names them, and being a plain column its timestamp keeps the type SQLite decodes it with.
+const timelineSources = `
+SELECT 'note' AS kind, id, created_at AS at, author AS actor, NULL AS type, body, NULL AS changes,
+       NULL AS from_status, NULL AS to_status
+  FROM customer_notes WHERE customer_id = $1 AND deleted_at IS NULL
+UNION ALL
+SELECT 'interaction', id, occurred_at, author, type, body, NULL, NULL, NULL
+  FROM customer_interactions WHERE customer_id = $1
+UNION ALL
+SELECT 'audit', id, occurred_at, actor, action, NULL, changes, NULL, NULL
+  FROM customer_audit WHERE customer_id = $1
+UNION ALL
+SELECT 'status_change', id, changed_at, changed_by, NULL, reason, NULL, from_status, to_status
+  FROM customer_status_changes WHERE customer_id = $1`
+
+func (r activityRepository) ListTimeline(ctx context.Context, query TimelineQuery) ([]models.TimelineEntry, error) {
+	args := []any{query.CustomerID}
+	stmt := "SELECT kind, id, at, actor, type, body, changes, from_status, to_status FROM (" + timelineSources + ") AS timeline"
+	if !query.Before.IsZero() {
+		args = append(args, query.Before.UTC(), query.BeforeID)
+		stmt += " WHERE at < $2 OR (at = $2 AND id < $3)"
+	}
+	args = append(args, query.Limit)
+	stmt += fmt.Sprintf(" ORDER BY at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.TimelineEntry
+	for rows.Next() {
+		var e models.TimelineEntry
+		var actorName, typ, body, from, to sql.NullString
+		var changes []byte
+		if err := rows.Scan(&e.Kind, &e.ID, &e.At, &actorName, &typ, &body, &changes, &from, &to); err != nil {
+			return nil, fmt.Errorf("error scanning timeline rows: %w", err)
+		}
+		e.At = e.At.UTC()
+		e.Actor, e.Type, e.Body = actorName.String, typ.String, body.String
+		if changes != nil {
+			if err := json.Unmarshal(changes, &e.Changes); err != nil {
+				return nil, fmt.Errorf("error decoding audit changes: %w", err)
+			}
+		}
+		if e.Kind == models.TimelineStatusChange {
+			// The initial status has no previous one, like an unset field.
+			change := models.FieldChange{To: to.String}
+			if from.Valid {
+				change.From = from.String
+			}
+			e.Changes = map[string]models.FieldChange{"status": change}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
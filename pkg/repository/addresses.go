@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+type AddressRepository interface {
+	ListAddresses(ctx context.Context, customerID uuid.UUID) ([]models.Address, error)
+	ListAddressesByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Address, error)
+	GetAddress(ctx context.Context, customerID, addressID uuid.UUID) (*models.Address, error)
+	CreateAddress(ctx context.Context, address models.Address) error
+	UpdateAddress(ctx context.Context, address models.Address) error
+	DeleteAddress(ctx context.Context, customerID, addressID uuid.UUID) error
+}
+
+type addressRepository struct {
+	db *sql.DB
+}
+
+func NewAddressRepository(db *sql.DB) AddressRepository {
+	return &addressRepository{db: db}
+}
+
+const addressColumns = "id, customer_id, type, line1, line2, city, region, postal_code, country, is_default"
+
+func scanAddress(row rowScanner) (models.Address, error) {
+	var a models.Address
+	var line2, region, postalCode sql.NullString
+	err := row.Scan(&a.ID, &a.CustomerID, &a.Type, &a.Line1, &line2, &a.City, &region, &postalCode, &a.Country, &a.IsDefault)
+	a.Line2 = line2.String
+	a.Region = region.String
+	a.PostalCode = postalCode.String
+	return a, err
+}
+
+func (r addressRepository) queryAddresses(ctx context.Context, query string, args ...any) ([]models.Address, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []models.Address
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning address rows: %w", err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+func (r addressRepository) ListAddresses(ctx context.Context, customerID uuid.UUID) ([]models.Address, error) {
+	return r.queryAddresses(ctx,
+		"SELECT "+addressColumns+" FROM customer_addresses WHERE customer_id = $1 ORDER BY type, is_default DESC, id",
+		customerID)
+}
+
+func (r addressRepository) ListAddressesByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Address, error) {
+	out := make(map[uuid.UUID][]models.Address, len(customerIDs))
+	if len(customerIDs) == 0 {
+		return out, nil
+	}
+
+	args := make([]any, len(customerIDs))
+	for i, id := range customerIDs {
+		args[i] = id
+	}
+	addresses, err := r.queryAddresses(ctx,
+		"SELECT "+addressColumns+" FROM customer_addresses WHERE customer_id IN ("+placeholders(1, len(args))+
+			") ORDER BY type, is_default DESC, id", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range addresses {
+		out[a.CustomerID] = append(out[a.CustomerID], a)
+	}
+	return out, nil
+}
+
+func (r addressRepository) GetAddress(ctx context.Context, customerID, addressID uuid.UUID) (*models.Address, error) {
+	row := r.db.QueryRowContext(ctx,
+		"SELECT "+addressColumns+" FROM customer_addresses WHERE customer_id = $1 AND id = $2",
+		customerID, addressID)
+	a, err := scanAddress(row)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// clearDefault unsets the current default address of the same type, so address can become the default.
+func clearDefault(ctx context.Context, tx *sql.Tx, address models.Address) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE customer_addresses SET is_default = FALSE WHERE customer_id = $1 AND type = $2 AND id <> $3 AND is_default",
+		address.CustomerID, address.Type, address.ID)
+	return err
+}
+
+func (r addressRepository) CreateAddress(ctx context.Context, address models.Address) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		if err := clearDefault(ctx, tx, address); err != nil {
+			return fmt.Errorf("error clearing default address: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO customer_addresses (`+addressColumns+`)
+     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		address.ID, address.CustomerID, address.Type, address.Line1, address.Line2, address.City,
+		address.Region, address.PostalCode, address.Country, address.IsDefault)
+	if err != nil {
+		return fmt.Errorf("error inserting address: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r addressRepository) UpdateAddress(ctx context.Context, address models.Address) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		if err := clearDefault(ctx, tx, address); err != nil {
+			return fmt.Errorf("error clearing default address: %w", err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE customer_addresses SET type=$1, line1=$2, line2=$3, city=$4, region=$5, postal_code=$6, country=$7, is_default=$8
+         WHERE customer_id=$9 AND id=$10`,
+		address.Type, address.Line1, address.Line2, address.City, address.Region, address.PostalCode,
+		address.Country, address.IsDefault, address.CustomerID, address.ID)
+	if err != nil {
+		return fmt.Errorf("error updating address: %w", err)
+	}
+	if err := expectAffected(res); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r addressRepository) DeleteAddress(ctx context.Context, customerID, addressID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM customer_addresses WHERE customer_id=$1 AND id=$2", customerID, addressID)
+	if err != nil {
+		return fmt.Errorf("error deleting address: %w", err)
+	}
+	return expectAffected(res)
+}
+
+// expectAffected turns a statement that touched no rows into sql.ErrNoRows.
+func expectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// AttachmentRepository stores the metadata of customer attachments. The contents are kept in a
+// storage.BlobStore under Attachment.Key, deleting the metadata leaves them in place.
+type AttachmentRepository interface {
+	// ListAttachments returns the attachments of the customer, newest first.
+	ListAttachments(ctx context.Context, customerID uuid.UUID) ([]models.Attachment, error)
+	GetAttachment(ctx context.Context, customerID, attachmentID uuid.UUID) (*models.Attachment, error)
+	// CreateAttachment stores attachment and sets who uploaded it and when.
+	CreateAttachment(ctx context.Context, attachment *models.Attachment) error
+	DeleteAttachment(ctx context.Context, customerID, attachmentID uuid.UUID) error
+}
+
+type attachmentRepository struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+const attachmentColumns = "id, customer_id, file_name, content_type, size, sha256, storage_key, uploaded_at, uploaded_by"
+
+func scanAttachment(row rowScanner) (models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.CustomerID, &a.FileName, &a.ContentType, &a.Size, &a.SHA256, &a.Key, &a.UploadedAt, &a.UploadedBy)
+	a.UploadedAt = a.UploadedAt.UTC()
+	return a, err
+}
+
+func (r attachmentRepository) ListAttachments(ctx context.Context, customerID uuid.UUID) ([]models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+attachmentColumns+" FROM customer_attachments WHERE customer_id = $1 ORDER BY uploaded_at DESC, id DESC",
+		customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attachment rows: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func (r attachmentRepository) GetAttachment(ctx context.Context, customerID, attachmentID uuid.UUID) (*models.Attachment, error) {
+	a, err := scanAttachment(r.db.QueryRowContext(ctx,
+		"SELECT "+attachmentColumns+" FROM customer_attachments WHERE customer_id = $1 AND id = $2",
+		customerID, attachmentID))
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r attachmentRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	at, by := now(), actor.FromContext(ctx)
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO customer_attachments ("+attachmentColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		attachment.ID, attachment.CustomerID, attachment.FileName, attachment.ContentType, attachment.Size,
+		attachment.SHA256, attachment.Key, at, by)
+	if err != nil {
+		return fmt.Errorf("error inserting attachment: %w", err)
+	}
+	attachment.UploadedAt, attachment.UploadedBy = at, by
+	return nil
+}
+
+func (r attachmentRepository) DeleteAttachment(ctx context.Context, customerID, attachmentID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM customer_attachments WHERE customer_id = $1 AND id = $2", customerID, attachmentID)
+	if err != nil {
+		return fmt.Errorf("error deleting attachment: %w", err)
+	}
+	return expectAffected(res)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+)
+
+// AttributeRepository stores the definitions of the custom customer attributes.
+type AttributeRepository interface {
+	ListAttributeDefinitions(ctx context.Context) ([]models.AttributeDefinition, error)
+	GetAttributeDefinition(ctx context.Context, name string) (*models.AttributeDefinition, error)
+	// SaveAttributeDefinition creates the definition or replaces the one with the same name.
+	SaveAttributeDefinition(ctx context.Context, definition models.AttributeDefinition) error
+	// DeleteAttributeDefinition removes the definition and the values stored for it.
+	DeleteAttributeDefinition(ctx context.Context, name string) error
+}
+
+type attributeRepository struct {
+	db      conn
+	dialect dialect
+}
+
+func NewAttributeRepository(db *sql.DB) AttributeRepository {
+	return &attributeRepository{db: conn{db: db}, dialect: dialectOf(db)}
+}
+
+func scanDefinition(row rowScanner) (models.AttributeDefinition, error) {
+	var d models.AttributeDefinition
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return d, fmt.Errorf("error decoding attribute definition: %w", err)
+	}
+	return d, nil
+}
+
+func (r attributeRepository) ListAttributeDefinitions(ctx context.Context) ([]models.AttributeDefinition, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT definition FROM attribute_definitions ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var defs []models.AttributeDefinition
+	for rows.Next() {
+		d, err := scanDefinition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attribute definition rows: %w", err)
+		}
+		defs = append(defs, d)
+	}
+	return defs, rows.Err()
+}
+
+func (r attributeRepository) GetAttributeDefinition(ctx context.Context, name string) (*models.AttributeDefinition, error) {
+	d, err := scanDefinition(r.db.QueryRowContext(ctx, "SELECT definition FROM attribute_definitions WHERE name = $1", name))
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r attributeRepository) SaveAttributeDefinition(ctx context.Context, definition models.AttributeDefinition) error {
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return fmt.Errorf("error encoding attribute definition: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO attribute_definitions (name, definition) VALUES ($1, $2)
+         ON CONFLICT (name) DO UPDATE SET definition = excluded.definition`,
+		definition.Name, string(data))
+	if err != nil {
+		return fmt.Errorf("error saving attribute definition: %w", err)
+	}
+	return nil
+}
+
+func (r attributeRepository) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM attribute_definitions WHERE name = $1", name)
+	if err != nil {
+		return fmt.Errorf("error deleting attribute definition: %w", err)
+	}
+	if err := expectAffected(res); err != nil {
+		return err
+	}
+
+	// Only the customers holding a value change, and their updated_at with them.
+	removeValue := "UPDATE customers SET attributes = attributes - $1::text, updated_at = $2, updated_by = $3 WHERE attributes ? $1"
+	arg := name
+	if r.dialect == sqliteDialect {
+		removeValue = "UPDATE customers SET attributes = json_remove(attributes, $1), updated_at = $2, updated_by = $3" +
+			" WHERE json_type(attributes, $1) IS NOT NULL"
+		arg = "$." + name
+	}
+	if _, err := tx.ExecContext(ctx, removeValue, arg, now(), actor.FromContext(ctx)); err != nil {
+		return fmt.Errorf("error removing attribute values: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// AuditRepository stores the audit trail of customer writes. Entries are kept when the customer is
+// deleted and only change when its erasure redacts their values, see PrivacyRepository.
+type AuditRepository interface {
+	RecordAudit(ctx context.Context, entry models.AuditEntry) error
+	// ListAuditEntries returns the entries of the customer, oldest first.
+	ListAuditEntries(ctx context.Context, customerID uuid.UUID) ([]models.AuditEntry, error)
+}
+
+// encryptedChanges are the audited fields of encrypted customer columns. With a cipher their values
+// are encrypted like the columns, so that the audit trail does not keep them in plaintext.
+var encryptedChanges = []string{"email", "phone_number"}
+
+type auditRepository struct {
+	db     conn
+	fields fields
+}
+
+func NewAuditRepository(db *sql.DB, opts ...Option) AuditRepository {
+	o := applyOptions(opts)
+	return &auditRepository{db: conn{db: db}, fields: fields{cipher: o.cipher}}
+}
+
+// cryptChanges replaces the string values of the encrypted fields of changes by what crypt returns.
+func cryptChanges(changes map[string]models.FieldChange, crypt func(string) (string, error)) error {
+	for _, name := range encryptedChanges {
+		change, ok := changes[name]
+		if !ok {
+			continue
+		}
+		for _, value := range []*any{&change.From, &change.To} {
+			s, ok := (*value).(string)
+			if !ok {
+				continue
+			}
+			var err error
+			if *value, err = crypt(s); err != nil {
+				return err
+			}
+		}
+		changes[name] = change
+	}
+	return nil
+}
+
+func (r auditRepository) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	var changes any
+	if len(entry.Changes) > 0 {
+		recorded := entry.Changes
+		if r.fields.enabled() {
+			recorded = maps.Clone(entry.Changes)
+			err := cryptChanges(recorded, func(s string) (string, error) { return r.fields.encrypt(ctx, s) })
+			if err != nil {
+				return fmt.Errorf("error encrypting audit changes: %w", err)
+			}
+		}
+		encoded, err := json.Marshal(recorded)
+		if err != nil {
+			return fmt.Errorf("error encoding audit changes: %w", err)
+		}
+		changes = string(encoded)
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO customer_audit (id, customer_id, action, changes, actor, occurred_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		entry.ID, entry.CustomerID, entry.Action, changes, entry.Actor, entry.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("error recording audit entry: %w", err)
+	}
+	return nil
+}
+
+func (r auditRepository) ListAuditEntries(ctx context.Context, customerID uuid.UUID) ([]models.AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, customer_id, action, changes, actor, occurred_at FROM customer_audit WHERE customer_id = $1 ORDER BY occurred_at, id",
+		customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var changes []byte
+		if err := rows.Scan(&e.ID, &e.CustomerID, &e.Action, &changes, &e.Actor, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("error scanning audit rows: %w", err)
+		}
+		if changes != nil {
+			if err := json.Unmarshal(changes, &e.Changes); err != nil {
+				return nil, fmt.Errorf("error decoding audit changes: %w", err)
+			}
+			if r.fields.enabled() {
+				err := cryptChanges(e.Changes, func(s string) (string, error) { return r.fields.decrypt(ctx, s) })
+				if err != nil {
+					return nil, fmt.Errorf("error decrypting audit changes: %w", err)
+				}
+			}
+		}
+		e.OccurredAt = e.OccurredAt.UTC()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,275 @@
+// Package cache keeps the customers looked up by ID or email in memory, in front of the
+// repositories. Writes made through the wrapped repositories invalidate the customers they change,
+// and Watch invalidates the customers of the change events published in the process, which covers
+// the writes of the service that bypass the wrapped repositories. The events do not reach other
+// instances of the service, so their caches see a change only once its customer expired.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// Options configure a Cache.
+type Options struct {
+	// Size bounds the number of cached lookups, the least recently used ones are evicted first.
+	Size int
+	// TTL is how long a customer is served from the cache.
+	TTL time.Duration
+	// NegativeTTL is how long a lookup that found no customer is remembered, zero disables it.
+	NegativeTTL time.Duration
+	// ReplicaLag is how long a read replica may still return what an invalidation dropped, zero
+	// without a replica. For that long only lookups reading the primary are cached.
+	ReplicaLag time.Duration
+}
+
+// Stats count how the cache served lookups since it was created.
+type Stats struct {
+	Hits uint64 `json:"hits"`
+	// NegativeHits are the hits that found no customer, they are included in Hits.
+	NegativeHits  uint64 `json:"negative_hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+}
+
+type entry struct {
+	key string
+	// customer is nil when the lookup found no customer.
+	customer *models.Customer
+	expires  time.Time
+}
+
+// Cache is a bounded LRU cache of customer lookups with a TTL. Concurrent misses of the same lookup
+// share a single query.
+type Cache struct {
+	opts  Options
+	now   func() time.Time
+	group singleflight.Group
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	// byCustomer holds the keys of the entries of every cached customer.
+	byCustomer map[uuid.UUID]map[string]struct{}
+	// negativeEmails holds the keys of the emails that found no customer.
+	negativeEmails map[string]struct{}
+	// generation changes with every invalidation, so that lookups that started before one do not
+	// cache what they read.
+	generation uint64
+	// invalidated is when the last invalidation happened.
+	invalidated time.Time
+
+	hits, negativeHits, misses, evictions, invalidations atomic.Uint64
+}
+
+func New(opts Options) *Cache {
+	return &Cache{
+		opts:           opts,
+		now:            time.Now,
+		lru:            list.New(),
+		entries:        map[string]*list.Element{},
+		byCustomer:     map[uuid.UUID]map[string]struct{}{},
+		negativeEmails: map[string]struct{}{},
+	}
+}
+
+const emailPrefix = "email:"
+
+func idKey(id uuid.UUID) string {
+	return "id:" + id.String()
+}
+
+func emailKey(email string) string {
+	return emailPrefix + email
+}
+
+// lookup returns the customer cached under key, or loads, caches and returns it. A lookup that
+// finds no customer fails with sql.ErrNoRows, like the repositories. Read sessions reading the
+// primary bypass the cache, as it may hold what the replica returned before their write reached
+// it, and cache what they load in its place.
+func (c *Cache) lookup(ctx context.Context, key string, load func(ctx context.Context) (*models.Customer, error)) (*models.Customer, error) {
+	primary := repository.ReadsPrimary(ctx)
+	if e, ok := c.get(key); ok && !primary {
+		c.hits.Add(1)
+		if e.customer == nil {
+			c.negativeHits.Add(1)
+			return nil, sql.ErrNoRows
+		}
+		return clone(e.customer), nil
+	}
+	c.misses.Add(1)
+
+	// Lookups reading the primary must not share the query of one reading the replica.
+	flight := key
+	if primary {
+		flight = "primary:" + key
+	}
+	v, err, _ := c.group.Do(flight, func() (any, error) {
+		c.mu.Lock()
+		generation := c.generation
+		c.mu.Unlock()
+
+		// The query is shared by every caller waiting for it, so the first one going away must not
+		// cancel it.
+		customer, err := load(context.WithoutCancel(ctx))
+		switch {
+		case err == nil:
+			c.put(key, clone(customer), c.opts.TTL, generation, primary)
+		case errors.Is(err, sql.ErrNoRows) && c.opts.NegativeTTL > 0:
+			c.put(key, nil, c.opts.NegativeTTL, generation, primary)
+		}
+		return customer, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clone(v.(*models.Customer)), nil
+}
+
+func (c *Cache) get(key string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+// put caches a lookup unless an invalidation happened since it started, or, when it may have read
+// the replica, within the replica lag.
+func (c *Cache) put(key string, customer *models.Customer, ttl time.Duration, generation uint64, primary bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation || c.opts.Size < 1 {
+		return
+	}
+	if !primary && c.now().Before(c.invalidated.Add(c.opts.ReplicaLag)) {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	e := &entry{key: key, customer: customer, expires: c.now().Add(ttl)}
+	c.entries[key] = c.lru.PushFront(e)
+	if customer != nil {
+		keys, ok := c.byCustomer[customer.ID]
+		if !ok {
+			keys = map[string]struct{}{}
+			c.byCustomer[customer.ID] = keys
+		}
+		keys[key] = struct{}{}
+	} else if strings.HasPrefix(key, emailPrefix) {
+		c.negativeEmails[key] = struct{}{}
+	}
+
+	for c.lru.Len() > c.opts.Size {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// remove drops an entry. The caller holds the lock.
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.entries, e.key)
+	delete(c.negativeEmails, e.key)
+	if e.customer == nil {
+		return
+	}
+	if keys, ok := c.byCustomer[e.customer.ID]; ok {
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.byCustomer, e.customer.ID)
+		}
+	}
+}
+
+// Invalidate drops the lookups of the customer and the lookups of emails that found no customer,
+// which may have been written for the customer. Emails may be looked up in another case than they
+// are stored in, so every miss may be stale.
+func (c *Cache) Invalidate(customerID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.invalidated = c.now()
+	c.invalidations.Add(1)
+	if el, ok := c.entries[idKey(customerID)]; ok {
+		c.remove(el)
+	}
+	for key := range c.byCustomer[customerID] {
+		c.remove(c.entries[key])
+	}
+	for key := range c.negativeEmails {
+		c.remove(c.entries[key])
+	}
+}
+
+// Purge drops every lookup.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.invalidated = c.now()
+	c.invalidations.Add(1)
+	c.lru.Init()
+	c.entries = map[string]*list.Element{}
+	c.byCustomer = map[uuid.UUID]map[string]struct{}{}
+	c.negativeEmails = map[string]struct{}{}
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:          c.hits.Load(),
+		NegativeHits:  c.negativeHits.Load(),
+		Misses:        c.misses.Load(),
+		Evictions:     c.evictions.Load(),
+		Invalidations: c.invalidations.Load(),
+		Entries:       entries,
+	}
+}
+
+// clone copies customer, so that callers changing the customers they get leave the cached ones
+// alone.
+func clone(customer *models.Customer) *models.Customer {
+	if customer == nil {
+		return nil
+	}
+	c := *customer
+	c.Attributes = maps.Clone(customer.Attributes)
+	c.Addresses = slices.Clone(customer.Addresses)
+	c.Emails = slices.Clone(customer.Emails)
+	c.Phones = slices.Clone(customer.Phones)
+	c.Tags = slices.Clone(customer.Tags)
+	return &c
+}
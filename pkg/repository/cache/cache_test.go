@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingCustomers counts the lookups reaching the repository. With release set, lookups wait
+// for it to be closed.
+type countingCustomers struct {
+	repository.CustomerRepository
+	lookups atomic.Int32
+	release chan struct{}
+}
+
+func (r *countingCustomers) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	r.lookups.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.CustomerRepository.GetCustomerByID(ctx, customerID)
+}
+
+func (r *countingCustomers) GetCustomerByEmail(ctx context.Context, email string) (*models.Customer, error) {
+	r.lookups.Add(1)
+	return r.CustomerRepository.GetCustomerByEmail(ctx, email)
+}
+
+func newTestCache(t *testing.T, opts Options) (*Cache, *countingCustomers, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := New(opts)
+	c.now = func() time.Time { return now }
+	return c, &countingCustomers{CustomerRepository: memory.NewStore().Repositories().Customers}, &now
+}
+
+func createCustomer(t *testing.T, customers repository.CustomerRepository, email string) models.Customer {
+	t.Helper()
+	c := models.Customer{ID: uuid.New(), FirstName: "Jane", LastName: "Doe", Email: email}
+	require.NoError(t, customers.CreateCustomer(context.Background(), &c))
+	return c
+}
+
+func TestCache_Lookups(t *testing.T) {
+	c, backend, now := newTestCache(t, Options{Size: 10, TTL: time.Minute})
+	customers := c.Customers(backend)
+	ctx := context.Background()
+	jane := createCustomer(t, backend, "jane@example.com")
+
+	for i := 0; i < 3; i++ {
+		got, err := customers.GetCustomerByID(ctx, jane.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "jane@example.com", got.Email)
+		// Callers changing what they get leave the cache alone.
+		got.FirstName = "Changed"
+	}
+	got, err := customers.GetCustomerByEmail(ctx, "jane@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", got.FirstName)
+	assert.EqualValues(t, 2, backend.lookups.Load())
+	assert.Equal(t, Stats{Hits: 2, Misses: 2, Entries: 2}, c.Stats())
+
+	*now = now.Add(time.Minute)
+	_, err = customers.GetCustomerByID(ctx, jane.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, backend.lookups.Load(), "expired lookups are read again")
+}
+
+func TestCache_Invalidation(t *testing.T) {
+	c, backend, _ := newTestCache(t, Options{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+	customers := c.Customers(backend)
+	ctx := context.Background()
+
+	_, err := customers.GetCustomerByEmail(ctx, "jane@example.com")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = customers.GetCustomerByEmail(ctx, "jane@example.com")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, Stats{Hits: 1, NegativeHits: 1, Misses: 1, Entries: 1}, c.Stats())
+
+	jane := createCustomer(t, customers, "jane@example.com")
+	got, err := customers.GetCustomerByEmail(ctx, "jane@example.com")
+	require.NoError(t, err, "creating a customer drops the misses")
+	assert.Equal(t, jane.ID, got.ID)
+	_, err = customers.GetCustomerByID(ctx, jane.ID)
+	require.NoError(t, err)
+
+	jane.Email = "jane.doe@example.com"
+	require.NoError(t, customers.UpdateCustomer(ctx, &jane))
+	got, err = customers.GetCustomerByID(ctx, jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "jane.doe@example.com", got.Email)
+	_, err = customers.GetCustomerByEmail(ctx, "jane@example.com")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "the lookup by the previous email is dropped")
+
+	require.NoError(t, customers.DeleteCustomer(ctx, jane.ID))
+	_, err = customers.GetCustomerByID(ctx, jane.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCache_Eviction(t *testing.T) {
+	c, backend, _ := newTestCache(t, Options{Size: 2, TTL: time.Minute})
+	customers := c.Customers(backend)
+	ctx := context.Background()
+	a := createCustomer(t, backend, "a@example.com")
+	b := createCustomer(t, backend, "b@example.com")
+	d := createCustomer(t, backend, "d@example.com")
+
+	for _, id := range []uuid.UUID{a.ID, b.ID, a.ID, d.ID} {
+		_, err := customers.GetCustomerByID(ctx, id)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+	assert.Equal(t, 2, c.Stats().Entries)
+
+	// b was the least recently used.
+	backend.lookups.Store(0)
+	for _, id := range []uuid.UUID{a.ID, d.ID, b.ID} {
+		_, err := customers.GetCustomerByID(ctx, id)
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, backend.lookups.Load())
+}
+
+func TestCache_ConcurrentMisses(t *testing.T) {
+	c, backend, _ := newTestCache(t, Options{Size: 10, TTL: time.Minute})
+	jane := createCustomer(t, backend, "jane@example.com")
+	backend.release = make(chan struct{})
+	customers := c.Customers(backend)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := customers.GetCustomerByID(context.Background(), jane.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, jane.ID, got.ID)
+		}()
+	}
+	require.Eventually(t, func() bool { return c.Stats().Misses == 10 }, time.Second, time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, backend.lookups.Load())
+}
+
+func TestCache_InvalidationDuringLookup(t *testing.T) {
+	c, backend, _ := newTestCache(t, Options{Size: 10, TTL: time.Minute})
+	jane := createCustomer(t, backend, "jane@example.com")
+	backend.release = make(chan struct{})
+	customers := c.Customers(backend)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := customers.GetCustomerByID(context.Background(), jane.ID)
+		assert.NoError(t, err)
+	}()
+	require.Eventually(t, func() bool { return backend.lookups.Load() == 1 }, time.Second, time.Millisecond)
+	c.Invalidate(jane.ID)
+	close(backend.release)
+	<-done
+
+	assert.Equal(t, 0, c.Stats().Entries, "what the lookup read may predate the invalidation")
+}
+
+// laggingReplica writes to the primary and reads from a replica the writes reach only when synced,
+// unless the read session reads the primary.
+type laggingReplica struct {
+	repository.CustomerRepository
+	replica repository.CustomerRepository
+}
+
+func newLaggingReplica() *laggingReplica {
+	return &laggingReplica{
+		CustomerRepository: memory.NewStore().Repositories().Customers,
+		replica:            memory.NewStore().Repositories().Customers,
+	}
+}
+
+func (r *laggingReplica) sync(t *testing.T, id uuid.UUID) {
+	t.Helper()
+	customer, err := r.CustomerRepository.GetCustomerByID(context.Background(), id)
+	require.NoError(t, err)
+	if _, err := r.replica.GetCustomerByID(context.Background(), id); err == nil {
+		require.NoError(t, r.replica.UpdateCustomer(context.Background(), customer))
+	} else {
+		require.NoError(t, r.replica.CreateCustomer(context.Background(), customer))
+	}
+}
+
+func (r *laggingReplica) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	if repository.ReadsPrimary(ctx) {
+		return r.CustomerRepository.GetCustomerByID(ctx, customerID)
+	}
+	return r.replica.GetCustomerByID(ctx, customerID)
+}
+
+func TestCache_LaggingReplica(t *testing.T) {
+	c, _, now := newTestCache(t, Options{Size: 10, TTL: time.Minute, ReplicaLag: 5 * time.Second})
+	backend := newLaggingReplica()
+	customers := c.Customers(backend)
+	ctx := context.Background()
+	jane := createCustomer(t, customers, "jane@example.com")
+	backend.sync(t, jane.ID)
+	*now = now.Add(5 * time.Second)
+
+	jane.FirstName = "Janet"
+	writer := repository.WithReadSession(ctx, true)
+	require.NoError(t, customers.UpdateCustomer(writer, &jane))
+
+	// Another client reads the replica before the update reached it, which must not be cached.
+	got, err := customers.GetCustomerByID(repository.WithReadSession(ctx, false), jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", got.FirstName)
+	assert.Equal(t, 0, c.Stats().Entries)
+
+	got, err = customers.GetCustomerByID(writer, jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Janet", got.FirstName, "the writer reads its write")
+
+	// What the primary returned is served to everyone.
+	got, err = customers.GetCustomerByID(ctx, jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Janet", got.FirstName)
+}
+
+func TestCache_PrimaryBypassesCache(t *testing.T) {
+	c, _, _ := newTestCache(t, Options{Size: 10, TTL: time.Minute})
+	backend := newLaggingReplica()
+	customers := c.Customers(backend)
+	ctx := context.Background()
+	jane := createCustomer(t, backend.replica, "jane@example.com")
+	require.NoError(t, backend.CustomerRepository.CreateCustomer(ctx, &models.Customer{ID: jane.ID, FirstName: "Janet", LastName: "Doe", Email: jane.Email}))
+
+	got, err := customers.GetCustomerByID(ctx, jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", got.FirstName)
+
+	got, err = customers.GetCustomerByID(repository.WithReadSession(ctx, true), jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Janet", got.FirstName, "the stale cached copy is bypassed")
+}
+
+func TestCache_UnitOfWork(t *testing.T) {
+	c, _, _ := newTestCache(t, Options{Size: 10, TTL: time.Minute})
+	uow := c.UnitOfWork(memory.NewStore())
+	ctx := context.Background()
+	jane := createCustomer(t, uow.Repositories().Customers, "jane@example.com")
+
+	_, err := uow.Repositories().Customers.GetCustomerByID(ctx, jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.Stats().Entries)
+
+	err = uow.Do(ctx, func(repos repository.Repositories) error {
+		return repos.Statuses.ChangeStatus(ctx, models.StatusChange{
+			ID: uuid.New(), CustomerID: jane.ID, From: models.StatusLead, To: models.StatusActive,
+		})
+	})
+	require.NoError(t, err)
+	got, err := uow.Repositories().Customers.GetCustomerByID(ctx, jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, got.Status)
+}
+
+func TestCache_Watch(t *testing.T) {
+	c, backend, _ := newTestCache(t, Options{Size: 10, TTL: time.Minute})
+	customers := c.Customers(backend)
+	jane := createCustomer(t, backend, "jane@example.com")
+	bus := events.NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, bus)
+
+	_, err := customers.GetCustomerByID(ctx, jane.ID)
+	require.NoError(t, err)
+
+	// The service changed the customer without going through the cached repositories.
+	require.Eventually(t, func() bool {
+		bus.Publish(ctx, events.New(events.CustomerUpdated, jane.ID, &jane))
+		return c.Stats().Entries == 0
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchBuffer is how many events Watch may fall behind before its subscription is dropped.
+const watchBuffer = 256
+
+// Customers returns customers with GetCustomerByID and GetCustomerByEmail served from the cache.
+// Its writes invalidate the customers they change.
+func (c *Cache) Customers(customers repository.CustomerRepository) repository.CustomerRepository {
+	return &cachedCustomers{customerWrites: customerWrites{CustomerRepository: customers, invalidate: c.Invalidate}, cache: c}
+}
+
+// Contacts returns contacts with its writes invalidating the customers they change, whose primary
+// email and phone number mirror the contacts.
+func (c *Cache) Contacts(contacts repository.ContactRepository) repository.ContactRepository {
+	return contactWrites{ContactRepository: contacts, invalidate: c.Invalidate}
+}
+
+// Attributes returns attributes with the deletion of a definition purging the cache, as it removes
+// the values of every customer.
+func (c *Cache) Attributes(attributes repository.AttributeRepository) repository.AttributeRepository {
+	return attributeWrites{AttributeRepository: attributes, purge: c.Purge}
+}
+
+// UnitOfWork returns uow with the customer lookups of its repositories served from the cache. The
+// writes made in its transactions invalidate the customers they change, once right away and once
+// the transaction ended, which drops what lookups read in between.
+func (c *Cache) UnitOfWork(uow repository.UnitOfWork) repository.UnitOfWork {
+	return unitOfWork{UnitOfWork: uow, cache: c}
+}
+
+type unitOfWork struct {
+	repository.UnitOfWork
+	cache *Cache
+}
+
+func (u unitOfWork) Repositories() repository.Repositories {
+	repos := u.UnitOfWork.Repositories()
+	customers := repos.Customers
+	wrapWrites(&repos, u.cache.Invalidate, u.cache.Purge)
+	repos.Customers = u.cache.Customers(customers)
+	return repos
+}
+
+func (u unitOfWork) Do(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	var (
+		mu      sync.Mutex
+		written []uuid.UUID
+		purged  bool
+	)
+	invalidate := func(customerID uuid.UUID) {
+		u.cache.Invalidate(customerID)
+		mu.Lock()
+		written = append(written, customerID)
+		mu.Unlock()
+	}
+	purge := func() {
+		u.cache.Purge()
+		mu.Lock()
+		purged = true
+		mu.Unlock()
+	}
+
+	// Reads within the transaction bypass the cache, they have to see its writes.
+	err := u.UnitOfWork.Do(ctx, func(repos repository.Repositories) error {
+		wrapWrites(&repos, invalidate, purge)
+		return fn(repos)
+	})
+
+	for _, id := range written {
+		u.cache.Invalidate(id)
+	}
+	if purged {
+		u.cache.Purge()
+	}
+	return err
+}
+
+// wrapWrites replaces the repositories of repos that change customers with ones calling invalidate
+// or purge after their writes.
+func wrapWrites(repos *repository.Repositories, invalidate func(uuid.UUID), purge func()) {
+	repos.Customers = &customerWrites{CustomerRepository: repos.Customers, invalidate: invalidate}
+	if repos.Statuses != nil {
+		repos.Statuses = statusWrites{StatusRepository: repos.Statuses, invalidate: invalidate}
+	}
+	if repos.Privacy != nil {
+		repos.Privacy = privacyWrites{PrivacyRepository: repos.Privacy, invalidate: invalidate}
+	}
+	if repos.Attributes != nil {
+		repos.Attributes = attributeWrites{AttributeRepository: repos.Attributes, purge: purge}
+	}
+}
+
+// Watch invalidates the customer of every event published to subscriber until ctx is done. When
+// the subscription is dropped for falling behind, Watch subscribes again and purges the cache.
+func (c *Cache) Watch(ctx context.Context, subscriber events.Subscriber) {
+	ch, unsubscribe := subscriber.Subscribe(watchBuffer)
+	for {
+		select {
+		case <-ctx.Done():
+			unsubscribe()
+			return
+		case event, ok := <-ch:
+			if ok {
+				c.Invalidate(event.CustomerID)
+				continue
+			}
+			log.Warn("customer cache fell behind the change events, purging it")
+			ch, unsubscribe = subscriber.Subscribe(watchBuffer)
+			c.Purge()
+		}
+	}
+}
+
+type cachedCustomers struct {
+	customerWrites
+	cache *Cache
+}
+
+func (r *cachedCustomers) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	return r.cache.lookup(ctx, idKey(customerID), func(ctx context.Context) (*models.Customer, error) {
+		return r.CustomerRepository.GetCustomerByID(ctx, customerID)
+	})
+}
+
+func (r *cachedCustomers) GetCustomerByEmail(ctx context.Context, email string) (*models.Customer, error) {
+	return r.cache.lookup(ctx, emailKey(email), func(ctx context.Context) (*models.Customer, error) {
+		return r.CustomerRepository.GetCustomerByEmail(ctx, email)
+	})
+}
+
+type customerWrites struct {
+	repository.CustomerRepository
+	invalidate func(uuid.UUID)
+}
+
+func (r *customerWrites) CreateCustomer(ctx context.Context, customer *models.Customer) error {
+	err := r.CustomerRepository.CreateCustomer(ctx, customer)
+	r.invalidate(customer.ID)
+	return err
+}
+
+func (r *customerWrites) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	err := r.CustomerRepository.UpdateCustomer(ctx, customer)
+	r.invalidate(customer.ID)
+	return err
+}
+
+func (r *customerWrites) DeleteCustomer(ctx context.Context, customerID uuid.UUID) error {
+	err := r.CustomerRepository.DeleteCustomer(ctx, customerID)
+	r.invalidate(customerID)
+	return err
+}
+
+func (r *customerWrites) MarkEmailVerified(ctx context.Context, customerID uuid.UUID, email string) error {
+	err := r.CustomerRepository.MarkEmailVerified(ctx, customerID, email)
+	r.invalidate(customerID)
+	return err
+}
+
+type contactWrites struct {
+	repository.ContactRepository
+	invalidate func(uuid.UUID)
+}
+
+func (r contactWrites) CreateEmail(ctx context.Context, email models.Email) error {
+	err := r.ContactRepository.CreateEmail(ctx, email)
+	r.invalidate(email.CustomerID)
+	return err
+}
+
+func (r contactWrites) UpdateEmail(ctx context.Context, email models.Email) error {
+	err := r.ContactRepository.UpdateEmail(ctx, email)
+	r.invalidate(email.CustomerID)
+	return err
+}
+
+func (r contactWrites) DeleteEmail(ctx context.Context, customerID, emailID uuid.UUID) error {
+	err := r.ContactRepository.DeleteEmail(ctx, customerID, emailID)
+	r.invalidate(customerID)
+	return err
+}
+
+func (r contactWrites) CreatePhone(ctx context.Context, phone models.Phone) error {
+	err := r.ContactRepository.CreatePhone(ctx, phone)
+	r.invalidate(phone.CustomerID)
+	return err
+}
+
+func (r contactWrites) UpdatePhone(ctx context.Context, phone models.Phone) error {
+	err := r.ContactRepository.UpdatePhone(ctx, phone)
+	r.invalidate(phone.CustomerID)
+	return err
+}
+
+func (r contactWrites) DeletePhone(ctx context.Context, customerID, phoneID uuid.UUID) error {
+	err := r.ContactRepository.DeletePhone(ctx, customerID, phoneID)
+	r.invalidate(customerID)
+	return err
+}
+
+type statusWrites struct {
+	repository.StatusRepository
+	invalidate func(uuid.UUID)
+}
+
+func (r statusWrites) ChangeStatus(ctx context.Context, change models.StatusChange) error {
+	err := r.StatusRepository.ChangeStatus(ctx, change)
+	r.invalidate(change.CustomerID)
+	return err
+}
+
+type privacyWrites struct {
+	repository.PrivacyRepository
+	invalidate func(uuid.UUID)
+}
+
+func (r privacyWrites) EraseCustomer(ctx context.Context, erasure *models.Erasure) ([]string, error) {
+	keys, err := r.PrivacyRepository.EraseCustomer(ctx, erasure)
+	r.invalidate(erasure.CustomerID)
+	return keys, err
+}
+
+type attributeWrites struct {
+	repository.AttributeRepository
+	purge func()
+}
+
+func (r attributeWrites) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	err := r.AttributeRepository.DeleteAttributeDefinition(ctx, name)
+	r.purge()
+	return err
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// ConsentRepository stores the consents of customers. Their history is append-only, the current
+// consent of every purpose and channel is kept beside it for the contactable filter of the customer list.
+type ConsentRepository interface {
+	// ListConsents returns the current consent of every purpose and channel recorded for the
+	// customer, ordered by purpose and channel.
+	ListConsents(ctx context.Context, customerID uuid.UUID) ([]models.Consent, error)
+	// ListConsentHistory returns every consent recorded for the customer, oldest first.
+	ListConsentHistory(ctx context.Context, customerID uuid.UUID) ([]models.Consent, error)
+	// RecordConsents records the consents of a customer at once and sets their timestamps and actor.
+	// A consent keeping the state and policy version of the current one is skipped, so that a repeated
+	// request records nothing. It returns the recorded consents and fails with sql.ErrNoRows when the
+	// customer does not exist.
+	RecordConsents(ctx context.Context, customerID uuid.UUID, consents []models.Consent) ([]models.Consent, error)
+}
+
+type consentRepository struct {
+	db *sql.DB
+}
+
+func NewConsentRepository(db *sql.DB) ConsentRepository {
+	return &consentRepository{db: db}
+}
+
+const consentColumns = "id, customer_id, purpose, channel, state, source, policy_version, recorded_at, recorded_by"
+
+func scanConsent(row rowScanner) (models.Consent, error) {
+	var c models.Consent
+	var policyVersion sql.NullString
+	err := row.Scan(&c.ID, &c.CustomerID, &c.Purpose, &c.Channel, &c.State, &c.Source, &policyVersion, &c.RecordedAt, &c.RecordedBy)
+	c.PolicyVersion = policyVersion.String
+	c.RecordedAt = c.RecordedAt.UTC()
+	return c, err
+}
+
+func (r consentRepository) listConsents(ctx context.Context, query string, customerID uuid.UUID) ([]models.Consent, error) {
+	rows, err := r.db.QueryContext(ctx, query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var consents []models.Consent
+	for rows.Next() {
+		c, err := scanConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning consent rows: %w", err)
+		}
+		consents = append(consents, c)
+	}
+	return consents, rows.Err()
+}
+
+func (r consentRepository) ListConsents(ctx context.Context, customerID uuid.UUID) ([]models.Consent, error) {
+	return r.listConsents(ctx,
+		"SELECT "+consentColumns+" FROM customer_consents WHERE customer_id = $1 ORDER BY purpose, channel", customerID)
+}
+
+func (r consentRepository) ListConsentHistory(ctx context.Context, customerID uuid.UUID) ([]models.Consent, error) {
+	return r.listConsents(ctx,
+		"SELECT "+consentColumns+" FROM customer_consent_history WHERE customer_id = $1 ORDER BY recorded_at, id", customerID)
+}
+
+func (r consentRepository) RecordConsents(ctx context.Context, customerID uuid.UUID, consents []models.Consent) ([]models.Consent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1)", customerID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	at, by := now(), actor.FromContext(ctx)
+	var recorded []models.Consent
+	for _, c := range consents {
+		var state models.ConsentState
+		var policyVersion sql.NullString
+		err := tx.QueryRowContext(ctx,
+			"SELECT state, policy_version FROM customer_consents WHERE customer_id = $1 AND purpose = $2 AND channel = $3",
+			customerID, c.Purpose, c.Channel).Scan(&state, &policyVersion)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil && state == c.State && policyVersion.String == c.PolicyVersion {
+			continue
+		}
+
+		c.CustomerID, c.RecordedAt, c.RecordedBy = customerID, at, by
+		var version any
+		if c.PolicyVersion != "" {
+			version = c.PolicyVersion
+		}
+		args := []any{c.ID, c.CustomerID, c.Purpose, c.Channel, c.State, c.Source, version, c.RecordedAt, c.RecordedBy}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO customer_consent_history ("+consentColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", args...); err != nil {
+			return nil, fmt.Errorf("error recording consent: %w", err)
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO customer_consents ("+consentColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) "+
+				"ON CONFLICT (customer_id, purpose, channel) DO UPDATE SET id = excluded.id, state = excluded.state, "+
+				"source = excluded.source, policy_version = excluded.policy_version, "+
+				"recorded_at = excluded.recorded_at, recorded_by = excluded.recorded_by", args...)
+		if err != nil {
+			return nil, fmt.Errorf("error updating current consent: %w", err)
+		}
+		recorded = append(recorded, c)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
@@ -0,0 +1,428 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrDuplicate is returned when a write would break a uniqueness constraint, such as an email in use.
+	ErrDuplicate = errors.New("duplicate value")
+	// ErrPrimaryContact is returned when a write would leave a customer without a primary email.
+	ErrPrimaryContact = errors.New("a customer must keep a primary email")
+)
+
+// duplicate marks unique constraint violations of both drivers with ErrDuplicate.
+func duplicate(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %w", ErrDuplicate, err)
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		return fmt.Errorf("%w: %w", ErrDuplicate, err)
+	}
+	return err
+}
+
+type ContactRepository interface {
+	ListEmails(ctx context.Context, customerID uuid.UUID) ([]models.Email, error)
+	ListEmailsByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Email, error)
+	GetEmail(ctx context.Context, customerID, emailID uuid.UUID) (*models.Email, error)
+	CreateEmail(ctx context.Context, email models.Email) error
+	UpdateEmail(ctx context.Context, email models.Email) error
+	DeleteEmail(ctx context.Context, customerID, emailID uuid.UUID) error
+
+	ListPhones(ctx context.Context, customerID uuid.UUID) ([]models.Phone, error)
+	ListPhonesByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Phone, error)
+	GetPhone(ctx context.Context, customerID, phoneID uuid.UUID) (*models.Phone, error)
+	CreatePhone(ctx context.Context, phone models.Phone) error
+	UpdatePhone(ctx context.Context, phone models.Phone) error
+	DeletePhone(ctx context.Context, customerID, phoneID uuid.UUID) error
+}
+
+type contactRepository struct {
+	db     *sql.DB
+	fields fields
+}
+
+func NewContactRepository(db *sql.DB, opts ...Option) ContactRepository {
+	return &contactRepository{db: db, fields: fields{cipher: applyOptions(opts).cipher}}
+}
+
+const (
+	emailColumns = "id, customer_id, type, email, is_primary, verified"
+	phoneColumns = "id, customer_id, type, number, is_primary, verified"
+)
+
+// contactTable describes one of the contact point tables and the customers column mirroring its primary row.
+type contactTable struct {
+	name, value, mirror string
+}
+
+var (
+	emailTable = contactTable{name: "customer_emails", value: "email", mirror: "email"}
+	phoneTable = contactTable{name: "customer_phones", value: "number", mirror: "phone_number"}
+)
+
+// syncPrimaryContacts makes the primary contact points match the email and phone number of customer.
+func syncPrimaryContacts(ctx context.Context, tx executor, f fields, customer models.Customer) error {
+	if err := upsertPrimary(ctx, tx, f, emailTable, customer.ID, customer.Email); err != nil {
+		return fmt.Errorf("error storing primary email: %w", err)
+	}
+
+	if customer.PhoneNumber == "" {
+		_, err := tx.ExecContext(ctx, "DELETE FROM customer_phones WHERE customer_id = $1 AND is_primary", customer.ID)
+		if err != nil {
+			return fmt.Errorf("error removing primary phone: %w", err)
+		}
+		return nil
+	}
+	if err := upsertPrimary(ctx, tx, f, phoneTable, customer.ID, customer.PhoneNumber); err != nil {
+		return fmt.Errorf("error storing primary phone: %w", err)
+	}
+	return nil
+}
+
+// upsertPrimary points the primary row of table at value. A changed value is no longer verified.
+func upsertPrimary(ctx context.Context, tx executor, f fields, table contactTable, customerID uuid.UUID, value string) error {
+	stored, index, err := f.stored(ctx, value)
+	if err != nil {
+		return err
+	}
+
+	var args []any
+	unchanged := f.matches(table.value, value, &args)
+	args = append(args, stored, index, customerID)
+	n := len(args)
+	res, err := tx.ExecContext(ctx,
+		"UPDATE "+table.name+" SET verified = verified AND "+unchanged+
+			fmt.Sprintf(", %s = $%d, %s_blind_index = $%d WHERE customer_id = $%d AND is_primary", table.value, n-2, table.value, n-1, n),
+		args...)
+	if err != nil {
+		return duplicate(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO "+table.name+" (id, customer_id, type, "+table.value+", "+table.value+"_blind_index, is_primary)"+
+			" VALUES ($1, $2, $3, $4, $5, TRUE)",
+		uuid.New(), customerID, models.ContactOther, stored, index)
+	return duplicate(err)
+}
+
+// writeContact runs write in a transaction that first demotes the other primary contact point of
+// the customer when the written one is primary, and afterwards mirrors the primary value into customers.
+func (r contactRepository) writeContact(ctx context.Context, table contactTable, customerID, id uuid.UUID, primary bool,
+	write func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if primary {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE "+table.name+" SET is_primary = FALSE WHERE customer_id = $1 AND id <> $2 AND is_primary",
+			customerID, id)
+		if err != nil {
+			return fmt.Errorf("error demoting primary contact: %w", err)
+		}
+	}
+
+	if err := write(tx); err != nil {
+		return err
+	}
+
+	var hasPrimary bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM "+table.name+" WHERE customer_id = $1 AND is_primary)", customerID).Scan(&hasPrimary)
+	if err != nil {
+		return err
+	}
+	if !hasPrimary && table == emailTable {
+		return ErrPrimaryContact
+	}
+
+	// Only a changed value touches the customer and its updated_at. Encrypted values are compared by
+	// their blind index, as the same value is encrypted differently each time.
+	ofPrimary := func(expr string) string {
+		return "(SELECT " + expr + " FROM " + table.name + " WHERE customer_id = $1 AND is_primary)"
+	}
+	index := table.value + "_blind_index"
+	mirrorIndex := table.mirror + "_blind_index"
+	_, err = tx.ExecContext(ctx,
+		"UPDATE customers SET "+table.mirror+" = COALESCE("+ofPrimary(table.value)+", ''), "+mirrorIndex+" = "+ofPrimary(index)+
+			", updated_at = $2, updated_by = $3"+
+			" WHERE id = $1 AND COALESCE("+mirrorIndex+", "+table.mirror+", '') <> "+
+			"COALESCE("+ofPrimary("COALESCE("+index+", "+table.value+")")+", '')",
+		customerID, now(), actor.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error mirroring primary contact: %w", duplicate(err))
+	}
+	return tx.Commit()
+}
+
+func scanEmail(row rowScanner) (models.Email, error) {
+	var e models.Email
+	err := row.Scan(&e.ID, &e.CustomerID, &e.Type, &e.Email, &e.IsPrimary, &e.Verified)
+	return e, err
+}
+
+func scanPhone(row rowScanner) (models.Phone, error) {
+	var p models.Phone
+	err := row.Scan(&p.ID, &p.CustomerID, &p.Type, &p.Number, &p.IsPrimary, &p.Verified)
+	return p, err
+}
+
+// queryContacts runs query and scans every row with scan.
+func queryContacts[T any](ctx context.Context, db *sql.DB, scan func(rowScanner) (T, error), query string, args ...any) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning contact rows: %w", err)
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func idArgs(ids []uuid.UUID) []any {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// decryptEmails decrypts the addresses of emails. Encrypted addresses do not sort in SQL, the
+// emails are then ordered again like ListEmails orders them.
+func (r contactRepository) decryptEmails(ctx context.Context, emails []models.Email) error {
+	for i := range emails {
+		if err := r.fields.decryptAll(ctx, &emails[i].Email); err != nil {
+			return fmt.Errorf("error decrypting email %s: %w", emails[i].ID, err)
+		}
+	}
+	if r.fields.enabled() {
+		sort.SliceStable(emails, func(i, j int) bool {
+			return contactLess(emails[i].IsPrimary, emails[i].Type, emails[i].Email, emails[j].IsPrimary, emails[j].Type, emails[j].Email)
+		})
+	}
+	return nil
+}
+
+// decryptPhones is decryptEmails for phone numbers.
+func (r contactRepository) decryptPhones(ctx context.Context, phones []models.Phone) error {
+	for i := range phones {
+		if err := r.fields.decryptAll(ctx, &phones[i].Number); err != nil {
+			return fmt.Errorf("error decrypting phone %s: %w", phones[i].ID, err)
+		}
+	}
+	if r.fields.enabled() {
+		sort.SliceStable(phones, func(i, j int) bool {
+			return contactLess(phones[i].IsPrimary, phones[i].Type, phones[i].Number, phones[j].IsPrimary, phones[j].Type, phones[j].Number)
+		})
+	}
+	return nil
+}
+
+// contactLess orders contact points primary first, then by type and value.
+func contactLess(primaryA bool, typeA models.ContactType, valueA string, primaryB bool, typeB models.ContactType, valueB string) bool {
+	if primaryA != primaryB {
+		return primaryA
+	}
+	if typeA != typeB {
+		return typeA < typeB
+	}
+	return valueA < valueB
+}
+
+func (r contactRepository) ListEmails(ctx context.Context, customerID uuid.UUID) ([]models.Email, error) {
+	emails, err := queryContacts(ctx, r.db, scanEmail,
+		"SELECT "+emailColumns+" FROM customer_emails WHERE customer_id = $1 ORDER BY is_primary DESC, type, email",
+		customerID)
+	if err != nil {
+		return nil, err
+	}
+	return emails, r.decryptEmails(ctx, emails)
+}
+
+func (r contactRepository) ListEmailsByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Email, error) {
+	out := make(map[uuid.UUID][]models.Email, len(customerIDs))
+	if len(customerIDs) == 0 {
+		return out, nil
+	}
+	emails, err := queryContacts(ctx, r.db, scanEmail,
+		"SELECT "+emailColumns+" FROM customer_emails WHERE customer_id IN ("+placeholders(1, len(customerIDs))+
+			") ORDER BY is_primary DESC, type, email", idArgs(customerIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.decryptEmails(ctx, emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		out[e.CustomerID] = append(out[e.CustomerID], e)
+	}
+	return out, nil
+}
+
+func (r contactRepository) GetEmail(ctx context.Context, customerID, emailID uuid.UUID) (*models.Email, error) {
+	e, err := scanEmail(r.db.QueryRowContext(ctx,
+		"SELECT "+emailColumns+" FROM customer_emails WHERE customer_id = $1 AND id = $2", customerID, emailID))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.fields.decryptAll(ctx, &e.Email); err != nil {
+		return nil, fmt.Errorf("error decrypting email %s: %w", e.ID, err)
+	}
+	return &e, nil
+}
+
+func (r contactRepository) CreateEmail(ctx context.Context, email models.Email) error {
+	stored, index, err := r.fields.stored(ctx, email.Email)
+	if err != nil {
+		return err
+	}
+	return r.writeContact(ctx, emailTable, email.CustomerID, email.ID, email.IsPrimary, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO customer_emails ("+emailColumns+", email_blind_index) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			email.ID, email.CustomerID, email.Type, stored, email.IsPrimary, email.Verified, index)
+		if err != nil {
+			return fmt.Errorf("error inserting email: %w", duplicate(err))
+		}
+		return nil
+	})
+}
+
+func (r contactRepository) UpdateEmail(ctx context.Context, email models.Email) error {
+	stored, index, err := r.fields.stored(ctx, email.Email)
+	if err != nil {
+		return err
+	}
+	return r.writeContact(ctx, emailTable, email.CustomerID, email.ID, email.IsPrimary, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			"UPDATE customer_emails SET type = $1, email = $2, email_blind_index = $3, is_primary = $4, verified = $5"+
+				" WHERE customer_id = $6 AND id = $7",
+			email.Type, stored, index, email.IsPrimary, email.Verified, email.CustomerID, email.ID)
+		if err != nil {
+			return fmt.Errorf("error updating email: %w", duplicate(err))
+		}
+		return expectAffected(res)
+	})
+}
+
+func (r contactRepository) DeleteEmail(ctx context.Context, customerID, emailID uuid.UUID) error {
+	return r.writeContact(ctx, emailTable, customerID, emailID, false, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM customer_emails WHERE customer_id = $1 AND id = $2", customerID, emailID)
+		if err != nil {
+			return fmt.Errorf("error deleting email: %w", err)
+		}
+		return expectAffected(res)
+	})
+}
+
+func (r contactRepository) ListPhones(ctx context.Context, customerID uuid.UUID) ([]models.Phone, error) {
+	phones, err := queryContacts(ctx, r.db, scanPhone,
+		"SELECT "+phoneColumns+" FROM customer_phones WHERE customer_id = $1 ORDER BY is_primary DESC, type, number",
+		customerID)
+	if err != nil {
+		return nil, err
+	}
+	return phones, r.decryptPhones(ctx, phones)
+}
+
+func (r contactRepository) ListPhonesByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Phone, error) {
+	out := make(map[uuid.UUID][]models.Phone, len(customerIDs))
+	if len(customerIDs) == 0 {
+		return out, nil
+	}
+	phones, err := queryContacts(ctx, r.db, scanPhone,
+		"SELECT "+phoneColumns+" FROM customer_phones WHERE customer_id IN ("+placeholders(1, len(customerIDs))+
+			") ORDER BY is_primary DESC, type, number", idArgs(customerIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.decryptPhones(ctx, phones); err != nil {
+		return nil, err
+	}
+	for _, p := range phones {
+		out[p.CustomerID] = append(out[p.CustomerID], p)
+	}
+	return out, nil
+}
+
+func (r contactRepository) GetPhone(ctx context.Context, customerID, phoneID uuid.UUID) (*models.Phone, error) {
+	p, err := scanPhone(r.db.QueryRowContext(ctx,
+		"SELECT "+phoneColumns+" FROM customer_phones WHERE customer_id = $1 AND id = $2", customerID, phoneID))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.fields.decryptAll(ctx, &p.Number); err != nil {
+		return nil, fmt.Errorf("error decrypting phone %s: %w", p.ID, err)
+	}
+	return &p, nil
+}
+
+func (r contactRepository) CreatePhone(ctx context.Context, phone models.Phone) error {
+	stored, index, err := r.fields.stored(ctx, phone.Number)
+	if err != nil {
+		return err
+	}
+	return r.writeContact(ctx, phoneTable, phone.CustomerID, phone.ID, phone.IsPrimary, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO customer_phones ("+phoneColumns+", number_blind_index) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			phone.ID, phone.CustomerID, phone.Type, stored, phone.IsPrimary, phone.Verified, index)
+		if err != nil {
+			return fmt.Errorf("error inserting phone: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r contactRepository) UpdatePhone(ctx context.Context, phone models.Phone) error {
+	stored, index, err := r.fields.stored(ctx, phone.Number)
+	if err != nil {
+		return err
+	}
+	return r.writeContact(ctx, phoneTable, phone.CustomerID, phone.ID, phone.IsPrimary, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			"UPDATE customer_phones SET type = $1, number = $2, number_blind_index = $3, is_primary = $4, verified = $5"+
+				" WHERE customer_id = $6 AND id = $7",
+			phone.Type, stored, index, phone.IsPrimary, phone.Verified, phone.CustomerID, phone.ID)
+		if err != nil {
+			return fmt.Errorf("error updating phone: %w", err)
+		}
+		return expectAffected(res)
+	})
+}
+
+func (r contactRepository) DeletePhone(ctx context.Context, customerID, phoneID uuid.UUID) error {
+	return r.writeContact(ctx, phoneTable, customerID, phoneID, false, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM customer_phones WHERE customer_id = $1 AND id = $2", customerID, phoneID)
+		if err != nil {
+			return fmt.Errorf("error deleting phone: %w", err)
+		}
+		return expectAffected(res)
+	})
+}
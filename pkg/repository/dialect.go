@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// dialect is the SQL flavour of a database. The queries are written for both, only JSON
+// handling differs between them.
+type dialect int
+
+const (
+	postgresDialect dialect = iota
+	sqliteDialect
+)
+
+func dialectOf(db *sql.DB) dialect {
+	if _, ok := db.Driver().(*sqlite3.SQLiteDriver); ok {
+		return sqliteDialect
+	}
+	return postgresDialect
+}
@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"CustomerCRUD/pkg/encryption"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// FieldCipher encrypts the emails and phone numbers of customers before they are stored, see
+// encryption.Encryptor.
+type FieldCipher interface {
+	Encrypt(ctx context.Context, plaintext string) (string, error)
+	// Decrypt returns plaintext values stored before encryption was enabled unchanged.
+	Decrypt(ctx context.Context, stored string) (string, error)
+	// BlindIndex returns a keyed hash of value for equality lookups, empty for the empty string.
+	BlindIndex(value string) string
+	// Stale reports whether a stored value has to be encrypted again with the current key.
+	Stale(stored string) bool
+}
+
+// Option configures the SQL repositories.
+type Option func(*options)
+
+type options struct {
+	cipher  FieldCipher
+	replica *Replica
+}
+
+// WithCipher encrypts emails and phone numbers with cipher. Each encrypted column has a
+// <column>_blind_index column next to it, which lookups, uniqueness and segment filters use.
+func WithCipher(cipher FieldCipher) Option {
+	return func(o *options) {
+		o.cipher = cipher
+	}
+}
+
+func applyOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// fields encrypts the values of encrypted columns when a cipher is configured, and keeps them in
+// plaintext with empty blind indexes otherwise.
+type fields struct {
+	cipher FieldCipher
+}
+
+func (f fields) enabled() bool {
+	return f.cipher != nil
+}
+
+func (f fields) encrypt(ctx context.Context, value string) (string, error) {
+	if f.cipher == nil {
+		return value, nil
+	}
+	return f.cipher.Encrypt(ctx, value)
+}
+
+func (f fields) decrypt(ctx context.Context, stored string) (string, error) {
+	if f.cipher == nil {
+		return stored, nil
+	}
+	return f.cipher.Decrypt(ctx, stored)
+}
+
+// decryptAll decrypts the values in place.
+func (f fields) decryptAll(ctx context.Context, values ...*string) error {
+	for _, v := range values {
+		var err error
+		if *v, err = f.decrypt(ctx, *v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// index returns the blind index of value to store, NULL when there is none.
+func (f fields) index(value string) any {
+	if f.cipher == nil || value == "" {
+		return nil
+	}
+	return f.cipher.BlindIndex(value)
+}
+
+// stored returns the encrypted value and blind index to store for value.
+func (f fields) stored(ctx context.Context, value string) (string, any, error) {
+	encrypted, err := f.encrypt(ctx, value)
+	if err != nil {
+		return "", nil, err
+	}
+	return encrypted, f.index(value), nil
+}
+
+// equals returns a condition selecting the rows whose column holds value and appends its arguments
+// to args. Values stored in plaintext before encryption was enabled, which have no blind index yet,
+// are compared as they are. The condition is NULL rather than false for some rows, use matches
+// where that matters.
+func (f fields) equals(column, value string, args *[]any) string {
+	if f.cipher == nil || value == "" {
+		*args = append(*args, value)
+		return fmt.Sprintf("%s = $%d", column, len(*args))
+	}
+	*args = append(*args, f.cipher.BlindIndex(value), value)
+	return fmt.Sprintf("(%s_blind_index = $%d OR %s = $%d)", column, len(*args)-1, column, len(*args))
+}
+
+// matches is equals as an expression that is never NULL, a missing value matches the empty string.
+// Unlike equals it cannot use the indexes of the columns.
+func (f fields) matches(column, value string, args *[]any) string {
+	if f.cipher == nil || value == "" {
+		*args = append(*args, value)
+		return fmt.Sprintf("COALESCE(%s, '') = $%d", column, len(*args))
+	}
+	*args = append(*args, f.cipher.BlindIndex(value), value)
+	return fmt.Sprintf("COALESCE(%s_blind_index, %s, '') IN ($%d, $%d)", column, column, len(*args)-1, len(*args))
+}
+
+// DataKeyRepository stores the wrapped data keys of encryption.Encryptor.
+type DataKeyRepository struct {
+	db *sql.DB
+}
+
+var _ encryption.DataKeyStore = (*DataKeyRepository)(nil)
+
+func NewDataKeyRepository(db *sql.DB) *DataKeyRepository {
+	return &DataKeyRepository{db: db}
+}
+
+func (r *DataKeyRepository) ListDataKeys(ctx context.Context) ([]encryption.DataKey, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, master_key_id, wrapped_key, created_at FROM encryption_data_keys ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []encryption.DataKey
+	for rows.Next() {
+		var key encryption.DataKey
+		if err := rows.Scan(&key.ID, &key.MasterKeyID, &key.WrappedKey, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning data key rows: %w", err)
+		}
+		key.CreatedAt = key.CreatedAt.UTC()
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *DataKeyRepository) CreateDataKey(ctx context.Context, key encryption.DataKey) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO encryption_data_keys (id, master_key_id, wrapped_key, created_at) VALUES ($1, $2, $3, $4)",
+		key.ID, key.MasterKeyID, key.WrappedKey, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting data key: %w", err)
+	}
+	return nil
+}
+
+func (r *DataKeyRepository) UpdateDataKey(ctx context.Context, key encryption.DataKey) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE encryption_data_keys SET master_key_id = $1, wrapped_key = $2 WHERE id = $3",
+		key.MasterKeyID, key.WrappedKey, key.ID)
+	if err != nil {
+		return fmt.Errorf("error updating data key: %w", err)
+	}
+	return expectAffected(res)
+}
+
+// encryptedTables lists the encrypted columns of every table.
+var encryptedTables = []struct {
+	name    string
+	columns []string
+}{
+	{"customers", []string{"email", "phone_number"}},
+	{"customer_emails", []string{"email"}},
+	{"customer_phones", []string{"number"}},
+}
+
+// Reencrypter encrypts the stale values of the encrypted columns again, which also encrypts and
+// indexes the plaintext values stored before encryption was enabled.
+type Reencrypter struct {
+	db     *sql.DB
+	fields fields
+}
+
+var _ encryption.Reencrypter = (*Reencrypter)(nil)
+
+func NewReencrypter(db *sql.DB, cipher FieldCipher) *Reencrypter {
+	return &Reencrypter{db: db, fields: fields{cipher: cipher}}
+}
+
+func (r *Reencrypter) Reencrypt(ctx context.Context, batchSize int) (int, error) {
+	total := 0
+	for _, table := range encryptedTables {
+		n, err := r.reencryptTable(ctx, table.name, table.columns, batchSize)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("error encrypting %s again: %w", table.name, err)
+		}
+	}
+	return total, nil
+}
+
+// reencryptTable walks table in batches ordered by ID. Every row is updated on its own and only if
+// its values did not change since they were read, so that concurrent writes are never overwritten;
+// a row skipped that way is picked up by the next run if it is still stale.
+func (r *Reencrypter) reencryptTable(ctx context.Context, table string, columns []string, batchSize int) (int, error) {
+	n := 0
+	after := uuid.Nil
+	for {
+		rows, err := r.readBatch(ctx, table, columns, after, batchSize)
+		if err != nil {
+			return n, err
+		}
+		for _, row := range rows {
+			updated, err := r.reencryptRow(ctx, table, columns, row)
+			if errors.Is(err, ErrDuplicate) {
+				// Plaintext duplicates stored before encryption was enabled cannot both be indexed.
+				log.Warnf("%s %s duplicates another row and stays in plaintext: %v", table, row.id, err)
+				continue
+			}
+			if err != nil {
+				return n, err
+			}
+			n += updated
+		}
+		if len(rows) < batchSize {
+			return n, nil
+		}
+		after = rows[len(rows)-1].id
+	}
+}
+
+type storedRow struct {
+	id     uuid.UUID
+	values []string
+}
+
+func (r *Reencrypter) readBatch(ctx context.Context, table string, columns []string, after uuid.UUID, limit int) ([]storedRow, error) {
+	result, err := r.db.QueryContext(ctx,
+		"SELECT id, "+strings.Join(columns, ", ")+" FROM "+table+" WHERE id > $1 ORDER BY id LIMIT $2", after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var rows []storedRow
+	for result.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := []any{new(uuid.UUID)}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := result.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("error scanning %s rows: %w", table, err)
+		}
+		row := storedRow{id: *dest[0].(*uuid.UUID)}
+		for _, v := range values {
+			row.values = append(row.values, v.String)
+		}
+		rows = append(rows, row)
+	}
+	return rows, result.Err()
+}
+
+// reencryptRow rewrites the stale values of row and returns how many there were.
+func (r *Reencrypter) reencryptRow(ctx context.Context, table string, columns []string, row storedRow) (int, error) {
+	var stale []string
+	var values, olds []any
+	for i, column := range columns {
+		old := row.values[i]
+		if !r.fields.cipher.Stale(old) {
+			continue
+		}
+		plain, err := r.fields.decrypt(ctx, old)
+		if err != nil {
+			return 0, fmt.Errorf("error decrypting %s of %s %s: %w", column, table, row.id, err)
+		}
+		encrypted, index, err := r.fields.stored(ctx, plain)
+		if err != nil {
+			return 0, err
+		}
+		stale = append(stale, column)
+		values = append(values, encrypted, index)
+		olds = append(olds, old)
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	// The placeholders are numbered in the order they appear, which SQLite requires.
+	assignments := make([]string, len(stale))
+	conditions := make([]string, len(stale))
+	for i, column := range stale {
+		assignments[i] = fmt.Sprintf("%s = $%d, %s_blind_index = $%d", column, 2*i+1, column, 2*i+2)
+		conditions[i] = fmt.Sprintf("COALESCE(%s, '') = $%d", column, len(values)+2+i)
+	}
+	args := append(append(values, row.id), olds...)
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE "+table+" SET "+strings.Join(assignments, ", ")+
+			fmt.Sprintf(" WHERE id = $%d AND ", len(values)+1)+strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return 0, duplicate(err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return 0, err
+	}
+	return len(stale), nil
+}
@@ -0,0 +1,238 @@
+// Package faults injects latency, errors, timeouts and partial failures into the calls of the
+// customer repository, to test how the service and its clients cope when the database degrades.
+// It is meant for non-production environments only.
+package faults
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxHang bounds how long calls hang, for callers without a deadline.
+const maxHang = time.Minute
+
+// Error kinds a rule can fail calls with.
+const (
+	// ErrorConnection is a dropped connection, which is retried.
+	ErrorConnection = "connection"
+	// ErrorSerialization is a serialization failure, which is retried, writes included.
+	ErrorSerialization = "serialization"
+	// ErrorNotFound makes the customer look missing.
+	ErrorNotFound = "not_found"
+	// ErrorInternal is an error the service knows nothing about.
+	ErrorInternal = "internal"
+)
+
+// Methods are the methods of repository.CustomerRepository faults can be injected into.
+var Methods = []string{
+	"GetAllCustomers", "ListCustomers", "FindCustomers", "CountCustomers",
+	"GetCustomerByID", "GetCustomersByIDs", "GetCustomerByEmail", "GetCustomersByEmails",
+	"CreateCustomer", "UpdateCustomer", "DeleteCustomer", "MarkEmailVerified",
+}
+
+// Rule injects faults into the calls of a method. Rates are probabilities between 0 and 1.
+type Rule struct {
+	// Method is a method of Methods, empty for every method.
+	Method string `json:"method,omitempty"`
+	// LatencyMS delays every call, by up to JitterMS more.
+	LatencyMS int `json:"latency_ms,omitempty"`
+	JitterMS  int `json:"jitter_ms,omitempty"`
+	// ErrorRate fails calls with Error, ErrorConnection by default.
+	ErrorRate float64 `json:"error_rate,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	// TimeoutRate makes calls hang until their context ends, for a minute at most.
+	TimeoutRate float64 `json:"timeout_rate,omitempty"`
+	// PartialRate fails calls halfway: reads return only part of their customers and writes fail
+	// with a connection error after they were made, as if the connection dropped before the commit
+	// was acknowledged. Within transactions the error rolls the write back.
+	PartialRate float64 `json:"partial_rate,omitempty"`
+}
+
+// Config is the set of faults to inject. A call gets the faults of every rule matching its method.
+type Config struct {
+	Rules []Rule `json:"rules"`
+	// Seed makes the injected faults reproducible, zero picks a random seed.
+	Seed uint64 `json:"seed,omitempty"`
+}
+
+// Validate reports every problem of the config at once.
+func (c Config) Validate() error {
+	var errs []error
+	for i, r := range c.Rules {
+		if r.Method != "" && !slices.Contains(Methods, r.Method) {
+			errs = append(errs, fmt.Errorf("rule %d: unknown method %q", i, r.Method))
+		}
+		if r.LatencyMS < 0 || r.JitterMS < 0 {
+			errs = append(errs, fmt.Errorf("rule %d: latency and jitter must not be negative", i))
+		}
+		for _, rate := range []float64{r.ErrorRate, r.TimeoutRate, r.PartialRate} {
+			if rate < 0 || rate > 1 {
+				errs = append(errs, fmt.Errorf("rule %d: rates must be between 0 and 1", i))
+				break
+			}
+		}
+		switch r.Error {
+		case "", ErrorConnection, ErrorSerialization, ErrorNotFound, ErrorInternal:
+		default:
+			errs = append(errs, fmt.Errorf("rule %d: unknown error %q", i, r.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stats count the faults injected since the injector was created.
+type Stats struct {
+	Rules    int    `json:"rules"`
+	Calls    uint64 `json:"calls"`
+	Delayed  uint64 `json:"delayed"`
+	Errors   uint64 `json:"errors"`
+	Timeouts uint64 `json:"timeouts"`
+	Partial  uint64 `json:"partial"`
+}
+
+// Injector holds the faults to inject, which can be changed at runtime.
+type Injector struct {
+	mu     sync.Mutex
+	config Config
+	rand   *rand.Rand
+
+	calls, delayed, failed, timedOut, partial atomic.Uint64
+}
+
+// NewInjector returns an injector without faults.
+func NewInjector() *Injector {
+	return &Injector{config: Config{Rules: []Rule{}}, rand: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+}
+
+// Set replaces the faults to inject.
+func (i *Injector) Set(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if config.Rules == nil {
+		config.Rules = []Rule{}
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.config = config
+	i.rand = rand.New(rand.NewPCG(seed, seed))
+	return nil
+}
+
+// Config returns the faults injected.
+func (i *Injector) Config() Config {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	config := i.config
+	config.Rules = slices.Clone(config.Rules)
+	return config
+}
+
+func (i *Injector) Stats() Stats {
+	i.mu.Lock()
+	rules := len(i.config.Rules)
+	i.mu.Unlock()
+	return Stats{
+		Rules:    rules,
+		Calls:    i.calls.Load(),
+		Delayed:  i.delayed.Load(),
+		Errors:   i.failed.Load(),
+		Timeouts: i.timedOut.Load(),
+		Partial:  i.partial.Load(),
+	}
+}
+
+// fault is what is injected into a single call.
+type fault struct {
+	delay   time.Duration
+	err     error
+	hang    bool
+	partial bool
+}
+
+// plan draws the faults of a call of method.
+func (i *Injector) plan(method string) fault {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var f fault
+	for _, r := range i.config.Rules {
+		if r.Method != "" && r.Method != method {
+			continue
+		}
+		f.delay += time.Duration(r.LatencyMS) * time.Millisecond
+		if r.JitterMS > 0 {
+			f.delay += time.Duration(i.rand.IntN(r.JitterMS+1)) * time.Millisecond
+		}
+		if f.err == nil && i.rand.Float64() < r.ErrorRate {
+			f.err = injectedError(r.Error)
+		}
+		f.hang = f.hang || i.rand.Float64() < r.TimeoutRate
+		f.partial = f.partial || i.rand.Float64() < r.PartialRate
+	}
+	return f
+}
+
+// before delays the call and fails it when its fault says so. Partial failures are left to the
+// caller, which knows how to fail halfway.
+func (i *Injector) before(ctx context.Context, method string) (fault, error) {
+	i.calls.Add(1)
+	f := i.plan(method)
+
+	if f.delay > 0 {
+		i.delayed.Add(1)
+		timer := time.NewTimer(f.delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return f, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if f.hang {
+		i.timedOut.Add(1)
+		ctx, cancel := context.WithTimeout(ctx, maxHang)
+		defer cancel()
+		<-ctx.Done()
+		return f, ctx.Err()
+	}
+	if f.err != nil {
+		i.failed.Add(1)
+		return f, f.err
+	}
+	if f.partial {
+		i.partial.Add(1)
+	}
+	return f, nil
+}
+
+func injectedError(kind string) error {
+	switch kind {
+	case ErrorSerialization:
+		return &pq.Error{Code: "40001", Message: "injected fault: could not serialize access"}
+	case ErrorNotFound:
+		return fmt.Errorf("injected fault: %w", sql.ErrNoRows)
+	case ErrorInternal:
+		return errors.New("injected fault: internal error")
+	default:
+		return connectionError()
+	}
+}
+
+func connectionError() error {
+	return &pq.Error{Code: "08006", Message: "injected fault: connection failure"}
+}
@@ -0,0 +1,126 @@
+package faults
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/repository/resilient"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{Rules: []Rule{{Method: "GetCustomerByID", ErrorRate: 1, Error: ErrorNotFound}, {LatencyMS: 10}}}.Validate())
+
+	err := Config{Rules: []Rule{
+		{Method: "DropDatabase"},
+		{LatencyMS: -1, ErrorRate: 1.5, Error: "meteor"},
+	}}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `rule 0: unknown method "DropDatabase"`)
+	assert.Contains(t, err.Error(), "rule 1: latency and jitter must not be negative")
+	assert.Contains(t, err.Error(), "rule 1: rates must be between 0 and 1")
+	assert.Contains(t, err.Error(), `rule 1: unknown error "meteor"`)
+}
+
+func TestInjector_Errors(t *testing.T) {
+	i := NewInjector()
+	repo := &mocks.CustomerRepository{}
+	customers := i.Customers(repo)
+	ctx := context.Background()
+	id := uuid.New()
+	repo.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id}, nil)
+
+	_, err := customers.GetCustomerByID(ctx, id)
+	require.NoError(t, err, "no faults are injected by default")
+
+	require.NoError(t, i.Set(Config{Rules: []Rule{{Method: "GetCustomerByID", ErrorRate: 1, Error: ErrorNotFound}}}))
+	_, err = customers.GetCustomerByID(ctx, id)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, i.Set(Config{Rules: []Rule{{ErrorRate: 1}}}))
+	_, err = customers.GetCustomerByID(ctx, id)
+	assert.True(t, resilient.Transient(err), "connection errors are transient")
+	assert.False(t, resilient.RolledBack(err))
+
+	require.NoError(t, i.Set(Config{}))
+	_, err = customers.GetCustomerByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Calls: 4, Errors: 2}, i.Stats())
+	repo.AssertNumberOfCalls(t, "GetCustomerByID", 2)
+}
+
+func TestInjector_ErrorRate(t *testing.T) {
+	i := NewInjector()
+	repo := &mocks.CustomerRepository{}
+	repo.On("CountCustomers", mock.Anything, mock.Anything).Return(10, nil)
+	customers := i.Customers(repo)
+	require.NoError(t, i.Set(Config{Rules: []Rule{{Method: "CountCustomers", ErrorRate: 0.3}}, Seed: 42}))
+
+	failed := 0
+	for n := 0; n < 1000; n++ {
+		if _, err := customers.CountCustomers(context.Background(), repository.CustomerQuery{}); err != nil {
+			failed++
+		}
+	}
+	assert.InDelta(t, 300, failed, 60)
+}
+
+func TestInjector_LatencyAndTimeouts(t *testing.T) {
+	i := NewInjector()
+	repo := &mocks.CustomerRepository{}
+	repo.On("GetAllCustomers", mock.Anything).Return([]models.Customer{}, nil)
+	customers := i.Customers(repo)
+
+	require.NoError(t, i.Set(Config{Rules: []Rule{{Method: "GetAllCustomers", LatencyMS: 20}}}))
+	start := time.Now()
+	_, err := customers.GetAllCustomers(context.Background())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	require.NoError(t, i.Set(Config{Rules: []Rule{{TimeoutRate: 1}}}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = customers.GetAllCustomers(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, uint64(1), i.Stats().Timeouts)
+	repo.AssertNumberOfCalls(t, "GetAllCustomers", 1)
+}
+
+func TestInjector_PartialFailures(t *testing.T) {
+	i := NewInjector()
+	repo := &mocks.CustomerRepository{}
+	repo.On("GetAllCustomers", mock.Anything).Return(make([]models.Customer, 4), nil)
+	repo.On("CreateCustomer", mock.Anything, mock.Anything).Return(nil)
+	customers := i.Customers(repo)
+	require.NoError(t, i.Set(Config{Rules: []Rule{{PartialRate: 1}}}))
+
+	all, err := customers.GetAllCustomers(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	err = customers.CreateCustomer(context.Background(), &models.Customer{})
+	assert.True(t, resilient.Transient(err))
+	repo.AssertCalled(t, "CreateCustomer", mock.Anything, mock.Anything)
+}
+
+func TestInjector_UnitOfWork(t *testing.T) {
+	i := NewInjector()
+	repo := &mocks.CustomerRepository{}
+	uow := i.UnitOfWork(repository.Untransacted(repository.Repositories{Customers: repo}))
+	require.NoError(t, i.Set(Config{Rules: []Rule{{Method: "DeleteCustomer", ErrorRate: 1, Error: ErrorInternal}}}))
+
+	err := uow.Do(context.Background(), func(repos repository.Repositories) error {
+		return repos.Customers.DeleteCustomer(context.Background(), uuid.New())
+	})
+	assert.EqualError(t, err, "injected fault: internal error")
+	repo.AssertNotCalled(t, "DeleteCustomer", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,158 @@
+package faults
+
+import (
+	"context"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+)
+
+// Customers returns customers with the faults of the injector injected into its calls.
+func (i *Injector) Customers(customers repository.CustomerRepository) repository.CustomerRepository {
+	return faultyCustomers{repo: customers, inject: i}
+}
+
+// UnitOfWork returns uow with the faults of the injector injected into the customer calls of its
+// repositories, within transactions too.
+func (i *Injector) UnitOfWork(uow repository.UnitOfWork) repository.UnitOfWork {
+	return unitOfWork{UnitOfWork: uow, inject: i}
+}
+
+type unitOfWork struct {
+	repository.UnitOfWork
+	inject *Injector
+}
+
+func (u unitOfWork) Repositories() repository.Repositories {
+	repos := u.UnitOfWork.Repositories()
+	repos.Customers = u.inject.Customers(repos.Customers)
+	return repos
+}
+
+func (u unitOfWork) Do(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return u.UnitOfWork.Do(ctx, func(repos repository.Repositories) error {
+		repos.Customers = u.inject.Customers(repos.Customers)
+		return fn(repos)
+	})
+}
+
+// read injects the faults of method into a read, a partial failure returning half of its results.
+func read[T any](ctx context.Context, i *Injector, method string, call func() ([]T, error)) ([]T, error) {
+	f, err := i.before(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	result, err := call()
+	if f.partial {
+		result = result[:len(result)/2]
+	}
+	return result, err
+}
+
+// write injects the faults of method into a write, a partial failure making the write but failing
+// it nevertheless.
+func write(ctx context.Context, i *Injector, method string, call func() error) error {
+	f, err := i.before(ctx, method)
+	if err != nil {
+		return err
+	}
+	if err := call(); err != nil || !f.partial {
+		return err
+	}
+	return connectionError()
+}
+
+type faultyCustomers struct {
+	repo   repository.CustomerRepository
+	inject *Injector
+}
+
+func (r faultyCustomers) GetAllCustomers(ctx context.Context) ([]models.Customer, error) {
+	return read(ctx, r.inject, "GetAllCustomers", func() ([]models.Customer, error) {
+		return r.repo.GetAllCustomers(ctx)
+	})
+}
+
+func (r faultyCustomers) ListCustomers(ctx context.Context, opts repository.ListOptions) ([]models.Customer, error) {
+	return read(ctx, r.inject, "ListCustomers", func() ([]models.Customer, error) {
+		return r.repo.ListCustomers(ctx, opts)
+	})
+}
+
+func (r faultyCustomers) FindCustomers(ctx context.Context, query repository.CustomerQuery) ([]models.Customer, error) {
+	return read(ctx, r.inject, "FindCustomers", func() ([]models.Customer, error) {
+		return r.repo.FindCustomers(ctx, query)
+	})
+}
+
+func (r faultyCustomers) CountCustomers(ctx context.Context, query repository.CustomerQuery) (int, error) {
+	f, err := r.inject.before(ctx, "CountCustomers")
+	if err != nil {
+		return 0, err
+	}
+	count, err := r.repo.CountCustomers(ctx, query)
+	if f.partial {
+		count /= 2
+	}
+	return count, err
+}
+
+func (r faultyCustomers) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	if _, err := r.inject.before(ctx, "GetCustomerByID"); err != nil {
+		return nil, err
+	}
+	return r.repo.GetCustomerByID(ctx, customerID)
+}
+
+func (r faultyCustomers) GetCustomersByIDs(ctx context.Context, customerIDs []uuid.UUID) ([]models.Customer, error) {
+	return read(ctx, r.inject, "GetCustomersByIDs", func() ([]models.Customer, error) {
+		return r.repo.GetCustomersByIDs(ctx, customerIDs)
+	})
+}
+
+func (r faultyCustomers) GetCustomerByEmail(ctx context.Context, email string) (*models.Customer, error) {
+	if _, err := r.inject.before(ctx, "GetCustomerByEmail"); err != nil {
+		return nil, err
+	}
+	return r.repo.GetCustomerByEmail(ctx, email)
+}
+
+func (r faultyCustomers) GetCustomersByEmails(ctx context.Context, emails []string) (map[string]models.Customer, error) {
+	f, err := r.inject.before(ctx, "GetCustomersByEmails")
+	if err != nil {
+		return nil, err
+	}
+	customers, err := r.repo.GetCustomersByEmails(ctx, emails)
+	if f.partial {
+		for _, email := range emails[:len(emails)/2] {
+			delete(customers, email)
+		}
+	}
+	return customers, err
+}
+
+func (r faultyCustomers) CreateCustomer(ctx context.Context, customer *models.Customer) error {
+	return write(ctx, r.inject, "CreateCustomer", func() error {
+		return r.repo.CreateCustomer(ctx, customer)
+	})
+}
+
+func (r faultyCustomers) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	return write(ctx, r.inject, "UpdateCustomer", func() error {
+		return r.repo.UpdateCustomer(ctx, customer)
+	})
+}
+
+func (r faultyCustomers) DeleteCustomer(ctx context.Context, customerID uuid.UUID) error {
+	return write(ctx, r.inject, "DeleteCustomer", func() error {
+		return r.repo.DeleteCustomer(ctx, customerID)
+	})
+}
+
+func (r faultyCustomers) MarkEmailVerified(ctx context.Context, customerID uuid.UUID, email string) error {
+	return write(ctx, r.inject, "MarkEmailVerified", func() error {
+		return r.repo.MarkEmailVerified(ctx, customerID, email)
+	})
+}
@@ -0,0 +1,549 @@
+// Package memory keeps customers in process memory. It implements the repositories of a
+// repository.UnitOfWork for unit tests and tools that run without a database.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+)
+
+// Store holds the data of the in-memory repositories. Units of work run one at a time and are
+// rolled back by restoring the data they started with.
+type Store struct {
+	// work serializes the units of work, mu guards the data.
+	work sync.Mutex
+	mu   sync.Mutex
+	data data
+}
+
+type data struct {
+	customers     map[uuid.UUID]models.Customer
+	statusChanges []models.StatusChange
+	audit         []models.AuditEntry
+	attributes    map[string]models.AttributeDefinition
+	legalHolds    []models.LegalHold
+	erasures      map[uuid.UUID]models.Erasure
+}
+
+func (d data) clone() data {
+	out := data{
+		customers:     make(map[uuid.UUID]models.Customer, len(d.customers)),
+		statusChanges: append([]models.StatusChange(nil), d.statusChanges...),
+		audit:         append([]models.AuditEntry(nil), d.audit...),
+		attributes:    make(map[string]models.AttributeDefinition, len(d.attributes)),
+		legalHolds:    append([]models.LegalHold(nil), d.legalHolds...),
+		erasures:      make(map[uuid.UUID]models.Erasure, len(d.erasures)),
+	}
+	for id, c := range d.customers {
+		out.customers[id] = c
+	}
+	for name, def := range d.attributes {
+		out.attributes[name] = def
+	}
+	for id, e := range d.erasures {
+		out.erasures[id] = e
+	}
+	return out
+}
+
+func NewStore() *Store {
+	return &Store{data: data{
+		customers:  map[uuid.UUID]models.Customer{},
+		attributes: map[string]models.AttributeDefinition{},
+		erasures:   map[uuid.UUID]models.Erasure{},
+	}}
+}
+
+var _ repository.UnitOfWork = (*Store)(nil)
+
+func (s *Store) Repositories() repository.Repositories {
+	return repository.Repositories{
+		Customers:  customers{s},
+		Statuses:   statuses{s},
+		Audit:      audit{s},
+		Attributes: attributes{s},
+		Privacy:    privacy{s},
+	}
+}
+
+func (s *Store) Do(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	s.work.Lock()
+	defer s.work.Unlock()
+
+	s.mu.Lock()
+	snapshot := s.data.clone()
+	s.mu.Unlock()
+
+	if err := fn(s.Repositories()); err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// copyCustomer detaches the attributes of c from the stored customer.
+func copyCustomer(c models.Customer) models.Customer {
+	if c.Attributes != nil {
+		attributes := make(map[string]any, len(c.Attributes))
+		for k, v := range c.Attributes {
+			attributes[k] = v
+		}
+		c.Attributes = attributes
+	}
+	return c
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+type customers struct {
+	s *Store
+}
+
+func (r customers) sorted() []models.Customer {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	out := make([]models.Customer, 0, len(r.s.data.customers))
+	for _, c := range r.s.data.customers {
+		out = append(out, copyCustomer(c))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID.String() < out[j].ID.String() })
+	return out
+}
+
+func (r customers) GetAllCustomers(ctx context.Context) ([]models.Customer, error) {
+	return r.sorted(), nil
+}
+
+func (r customers) ListCustomers(ctx context.Context, opts repository.ListOptions) ([]models.Customer, error) {
+	var out []models.Customer
+	for _, c := range r.sorted() {
+		if c.ID.String() > opts.After.String() && len(out) < opts.Limit {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// errUnsupportedQuery is returned for the parts of a query that need SQL.
+var errUnsupportedQuery = errors.New("attribute filters, segments, consent filters and attribute sorting are not supported in memory")
+
+func (r customers) FindCustomers(ctx context.Context, query repository.CustomerQuery) ([]models.Customer, error) {
+	if len(query.Filters) > 0 || query.Segment != nil || query.Contactable != nil || query.SortAttribute != nil {
+		return nil, errUnsupportedQuery
+	}
+
+	var out []models.Customer
+	for _, c := range r.sorted() {
+		if len(query.Statuses) > 0 && !hasStatus(query.Statuses, c.Status) {
+			continue
+		}
+		if !query.UpdatedSince.IsZero() && c.UpdatedAt.Before(query.UpdatedSince) {
+			continue
+		}
+		out = append(out, c)
+	}
+
+	if query.SortField != "" {
+		key := sortKey(query.SortField)
+		sort.SliceStable(out, func(i, j int) bool {
+			if query.Descending {
+				return key(out[j]) < key(out[i])
+			}
+			return key(out[i]) < key(out[j])
+		})
+	}
+	return out, nil
+}
+
+func hasStatus(statuses []models.Status, status models.Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKey returns a string ordering customers like the column of repository.SortFields does.
+func sortKey(field string) func(models.Customer) string {
+	switch field {
+	case "first_name":
+		return func(c models.Customer) string { return c.FirstName }
+	case "last_name":
+		return func(c models.Customer) string { return c.LastName }
+	case "email":
+		return func(c models.Customer) string { return c.Email }
+	case "created_at":
+		return func(c models.Customer) string { return c.CreatedAt.Format(time.RFC3339Nano) }
+	case "updated_at":
+		return func(c models.Customer) string { return c.UpdatedAt.Format(time.RFC3339Nano) }
+	}
+	return func(c models.Customer) string { return c.ID.String() }
+}
+
+func (r customers) CountCustomers(ctx context.Context, query repository.CustomerQuery) (int, error) {
+	found, err := r.FindCustomers(ctx, query)
+	return len(found), err
+}
+
+func (r customers) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.data.customers[customerID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	c = copyCustomer(c)
+	return &c, nil
+}
+
+func (r customers) GetCustomersByIDs(ctx context.Context, customerIDs []uuid.UUID) ([]models.Customer, error) {
+	var out []models.Customer
+	for _, id := range customerIDs {
+		if c, err := r.GetCustomerByID(ctx, id); err == nil {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func (r customers) GetCustomerByEmail(ctx context.Context, email string) (*models.Customer, error) {
+	for _, c := range r.sorted() {
+		if c.Email == email {
+			return &c, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r customers) GetCustomersByEmails(ctx context.Context, emails []string) (map[string]models.Customer, error) {
+	out := map[string]models.Customer{}
+	for _, email := range emails {
+		if c, err := r.GetCustomerByEmail(ctx, email); err == nil {
+			out[email] = *c
+		}
+	}
+	return out, nil
+}
+
+// emailTaken reports whether another customer uses the email.
+func (r customers) emailTaken(email string, except uuid.UUID) bool {
+	for id, c := range r.s.data.customers {
+		if id != except && c.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (r customers) CreateCustomer(ctx context.Context, customer *models.Customer) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.data.customers[customer.ID]; ok || r.emailTaken(customer.Email, customer.ID) {
+		return repository.ErrDuplicate
+	}
+	if customer.Status == "" {
+		customer.Status = models.InitialStatus
+	}
+	at, by := now(), actor.FromContext(ctx)
+	customer.CreatedAt, customer.UpdatedAt = at, at
+	customer.CreatedBy, customer.UpdatedBy = by, by
+
+	r.s.data.customers[customer.ID] = copyCustomer(*customer)
+	r.s.data.statusChanges = append(r.s.data.statusChanges, models.StatusChange{
+		ID:         uuid.New(),
+		CustomerID: customer.ID,
+		To:         customer.Status,
+		Reason:     "Customer created",
+		ChangedAt:  at,
+		ChangedBy:  by,
+	})
+	return nil
+}
+
+func (r customers) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.data.customers[customer.ID]
+	if !ok {
+		return nil
+	}
+	if r.emailTaken(customer.Email, customer.ID) {
+		return repository.ErrDuplicate
+	}
+
+	if stored.Email != customer.Email {
+		stored.EmailVerified = false
+	}
+	stored.FirstName, stored.MiddleName, stored.LastName = customer.FirstName, customer.MiddleName, customer.LastName
+	stored.Email, stored.PhoneNumber = customer.Email, customer.PhoneNumber
+	if customer.Attributes != nil {
+		stored.Attributes = customer.Attributes
+	}
+	stored.UpdatedAt, stored.UpdatedBy = now(), actor.FromContext(ctx)
+
+	r.s.data.customers[customer.ID] = copyCustomer(stored)
+	*customer = copyCustomer(stored)
+	return nil
+}
+
+func (r customers) DeleteCustomer(ctx context.Context, customerID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.data.customers, customerID)
+	delete(r.s.data.erasures, customerID)
+	kept := r.s.data.statusChanges[:0]
+	for _, c := range r.s.data.statusChanges {
+		if c.CustomerID != customerID {
+			kept = append(kept, c)
+		}
+	}
+	r.s.data.statusChanges = kept
+	holds := r.s.data.legalHolds[:0]
+	for _, h := range r.s.data.legalHolds {
+		if h.CustomerID != customerID {
+			holds = append(holds, h)
+		}
+	}
+	r.s.data.legalHolds = holds
+	return nil
+}
+
+func (r customers) MarkEmailVerified(ctx context.Context, customerID uuid.UUID, email string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.data.customers[customerID]
+	if !ok || stored.Email != email {
+		return sql.ErrNoRows
+	}
+	stored.EmailVerified = true
+	stored.UpdatedAt, stored.UpdatedBy = now(), actor.FromContext(ctx)
+	r.s.data.customers[customerID] = stored
+	return nil
+}
+
+type statuses struct {
+	s *Store
+}
+
+func (r statuses) ChangeStatus(ctx context.Context, change models.StatusChange) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.data.customers[change.CustomerID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if c.Status != change.From {
+		return repository.ErrStatusConflict
+	}
+	c.Status = change.To
+	c.UpdatedAt, c.UpdatedBy = change.ChangedAt, change.ChangedBy
+	r.s.data.customers[c.ID] = c
+	r.s.data.statusChanges = append(r.s.data.statusChanges, change)
+	return nil
+}
+
+func (r statuses) ListStatusChanges(ctx context.Context, customerID uuid.UUID) ([]models.StatusChange, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var out []models.StatusChange
+	for _, c := range r.s.data.statusChanges {
+		if c.CustomerID == customerID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+type audit struct {
+	s *Store
+}
+
+func (r audit) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.data.audit = append(r.s.data.audit, entry)
+	return nil
+}
+
+func (r audit) ListAuditEntries(ctx context.Context, customerID uuid.UUID) ([]models.AuditEntry, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var out []models.AuditEntry
+	for _, e := range r.s.data.audit {
+		if e.CustomerID == customerID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+type attributes struct {
+	s *Store
+}
+
+func (r attributes) ListAttributeDefinitions(ctx context.Context) ([]models.AttributeDefinition, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	out := make([]models.AttributeDefinition, 0, len(r.s.data.attributes))
+	for _, def := range r.s.data.attributes {
+		out = append(out, def)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r attributes) GetAttributeDefinition(ctx context.Context, name string) (*models.AttributeDefinition, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	def, ok := r.s.data.attributes[name]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &def, nil
+}
+
+func (r attributes) SaveAttributeDefinition(ctx context.Context, definition models.AttributeDefinition) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.data.attributes[definition.Name] = definition
+	return nil
+}
+
+func (r attributes) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.data.attributes[name]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.s.data.attributes, name)
+	for id, c := range r.s.data.customers {
+		if _, ok := c.Attributes[name]; ok {
+			c = copyCustomer(c)
+			delete(c.Attributes, name)
+			c.UpdatedAt, c.UpdatedBy = now(), actor.FromContext(ctx)
+			r.s.data.customers[id] = c
+		}
+	}
+	return nil
+}
+
+// privacy keeps legal holds and erasures. The store holds no addresses, contacts, notes or
+// attachments, so erasing a customer only touches the customer, its status changes and its audit trail.
+type privacy struct {
+	s *Store
+}
+
+func (r privacy) ListLegalHolds(ctx context.Context, customerID uuid.UUID) ([]models.LegalHold, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var out []models.LegalHold
+	for _, h := range r.s.data.legalHolds {
+		if h.CustomerID == customerID {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (r privacy) PlaceLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.data.customers[hold.CustomerID]; !ok {
+		return sql.ErrNoRows
+	}
+	hold.PlacedAt, hold.PlacedBy = now(), actor.FromContext(ctx)
+	r.s.data.legalHolds = append(r.s.data.legalHolds, *hold)
+	return nil
+}
+
+func (r privacy) ReleaseLegalHold(ctx context.Context, customerID, holdID uuid.UUID) (*models.LegalHold, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i, h := range r.s.data.legalHolds {
+		if h.ID == holdID && h.CustomerID == customerID && h.Active() {
+			at := now()
+			h.ReleasedAt, h.ReleasedBy = &at, actor.FromContext(ctx)
+			r.s.data.legalHolds[i] = h
+			return &h, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r privacy) GetErasure(ctx context.Context, customerID uuid.UUID) (*models.Erasure, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	e, ok := r.s.data.erasures[customerID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &e, nil
+}
+
+func (r privacy) EraseCustomer(ctx context.Context, erasure *models.Erasure) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.data.customers[erasure.CustomerID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if _, ok := r.s.data.erasures[erasure.CustomerID]; ok {
+		return nil, repository.ErrDuplicate
+	}
+	at, by := now(), actor.FromContext(ctx)
+
+	anonymized := models.AnonymizedCustomer(c.ID)
+	c.FirstName, c.MiddleName, c.LastName = anonymized.FirstName, "", anonymized.LastName
+	c.Email, c.PhoneNumber, c.Attributes = anonymized.Email, "", nil
+	c.UpdatedAt, c.UpdatedBy = at, by
+	r.s.data.customers[c.ID] = c
+
+	for i, change := range r.s.data.statusChanges {
+		if change.CustomerID == c.ID {
+			r.s.data.statusChanges[i].Reason = models.Erased
+		}
+	}
+	for i, entry := range r.s.data.audit {
+		if entry.CustomerID == c.ID {
+			r.s.data.audit[i].Changes = models.RedactChanges(entry.Changes)
+		}
+	}
+
+	erasure.ErasedAt, erasure.ErasedBy = at, by
+	r.s.data.erasures[c.ID] = *erasure
+	return nil, nil
+}
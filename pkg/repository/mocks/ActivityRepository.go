@@ -0,0 +1,283 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	repository "CustomerCRUD/pkg/repository"
+	context "context"
+
+	uuid "github.com/google/uuid"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ActivityRepository is an autogenerated mock type for the ActivityRepository type
+type ActivityRepository struct {
+	mock.Mock
+}
+
+// CreateInteraction provides a mock function with given fields: ctx, interaction
+func (_m *ActivityRepository) CreateInteraction(ctx context.Context, interaction *models.Interaction) error {
+	ret := _m.Called(ctx, interaction)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateInteraction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Interaction) error); ok {
+		r0 = rf(ctx, interaction)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateNote provides a mock function with given fields: ctx, note
+func (_m *ActivityRepository) CreateNote(ctx context.Context, note *models.Note) error {
+	ret := _m.Called(ctx, note)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateNote")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Note) error); ok {
+		r0 = rf(ctx, note)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteNote provides a mock function with given fields: ctx, customerID, noteID
+func (_m *ActivityRepository) DeleteNote(ctx context.Context, customerID uuid.UUID, noteID uuid.UUID) error {
+	ret := _m.Called(ctx, customerID, noteID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteNote")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r0 = rf(ctx, customerID, noteID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetInteraction provides a mock function with given fields: ctx, customerID, interactionID
+func (_m *ActivityRepository) GetInteraction(ctx context.Context, customerID uuid.UUID, interactionID uuid.UUID) (*models.Interaction, error) {
+	ret := _m.Called(ctx, customerID, interactionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInteraction")
+	}
+
+	var r0 *models.Interaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) (*models.Interaction, error)); ok {
+		return rf(ctx, customerID, interactionID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) *models.Interaction); ok {
+		r0 = rf(ctx, customerID, interactionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Interaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID, interactionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNote provides a mock function with given fields: ctx, customerID, noteID
+func (_m *ActivityRepository) GetNote(ctx context.Context, customerID uuid.UUID, noteID uuid.UUID) (*models.Note, error) {
+	ret := _m.Called(ctx, customerID, noteID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNote")
+	}
+
+	var r0 *models.Note
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) (*models.Note, error)); ok {
+		return rf(ctx, customerID, noteID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) *models.Note); ok {
+		r0 = rf(ctx, customerID, noteID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Note)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID, noteID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListInteractions provides a mock function with given fields: ctx, customerID
+func (_m *ActivityRepository) ListInteractions(ctx context.Context, customerID uuid.UUID) ([]models.Interaction, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListInteractions")
+	}
+
+	var r0 []models.Interaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.Interaction, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Interaction); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Interaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListNoteRevisions provides a mock function with given fields: ctx, customerID, noteID
+func (_m *ActivityRepository) ListNoteRevisions(ctx context.Context, customerID uuid.UUID, noteID uuid.UUID) ([]models.NoteRevision, error) {
+	ret := _m.Called(ctx, customerID, noteID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListNoteRevisions")
+	}
+
+	var r0 []models.NoteRevision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) ([]models.NoteRevision, error)); ok {
+		return rf(ctx, customerID, noteID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) []models.NoteRevision); ok {
+		r0 = rf(ctx, customerID, noteID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.NoteRevision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID, noteID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListNotes provides a mock function with given fields: ctx, query
+func (_m *ActivityRepository) ListNotes(ctx context.Context, query repository.NoteQuery) ([]models.Note, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListNotes")
+	}
+
+	var r0 []models.Note
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.NoteQuery) ([]models.Note, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.NoteQuery) []models.Note); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Note)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.NoteQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTimeline provides a mock function with given fields: ctx, query
+func (_m *ActivityRepository) ListTimeline(ctx context.Context, query repository.TimelineQuery) ([]models.TimelineEntry, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTimeline")
+	}
+
+	var r0 []models.TimelineEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.TimelineQuery) ([]models.TimelineEntry, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.TimelineQuery) []models.TimelineEntry); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.TimelineEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.TimelineQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateNote provides a mock function with given fields: ctx, note
+func (_m *ActivityRepository) UpdateNote(ctx context.Context, note *models.Note) error {
+	ret := _m.Called(ctx, note)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateNote")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Note) error); ok {
+		r0 = rf(ctx, note)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewActivityRepository creates a new instance of ActivityRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewActivityRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ActivityRepository {
+	mock := &ActivityRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,175 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// AddressRepository is an autogenerated mock type for the AddressRepository type
+type AddressRepository struct {
+	mock.Mock
+}
+
+// CreateAddress provides a mock function with given fields: ctx, address
+func (_m *AddressRepository) CreateAddress(ctx context.Context, address models.Address) error {
+	ret := _m.Called(ctx, address)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAddress")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Address) error); ok {
+		r0 = rf(ctx, address)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteAddress provides a mock function with given fields: ctx, customerID, addressID
+func (_m *AddressRepository) DeleteAddress(ctx context.Context, customerID uuid.UUID, addressID uuid.UUID) error {
+	ret := _m.Called(ctx, customerID, addressID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAddress")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r0 = rf(ctx, customerID, addressID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAddress provides a mock function with given fields: ctx, customerID, addressID
+func (_m *AddressRepository) GetAddress(ctx context.Context, customerID uuid.UUID, addressID uuid.UUID) (*models.Address, error) {
+	ret := _m.Called(ctx, customerID, addressID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAddress")
+	}
+
+	var r0 *models.Address
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) (*models.Address, error)); ok {
+		return rf(ctx, customerID, addressID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) *models.Address); ok {
+		r0 = rf(ctx, customerID, addressID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Address)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID, addressID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAddresses provides a mock function with given fields: ctx, customerID
+func (_m *AddressRepository) ListAddresses(ctx context.Context, customerID uuid.UUID) ([]models.Address, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAddresses")
+	}
+
+	var r0 []models.Address
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.Address, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Address); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Address)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAddressesByCustomerIDs provides a mock function with given fields: ctx, customerIDs
+func (_m *AddressRepository) ListAddressesByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Address, error) {
+	ret := _m.Called(ctx, customerIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAddressesByCustomerIDs")
+	}
+
+	var r0 map[uuid.UUID][]models.Address
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) (map[uuid.UUID][]models.Address, error)); ok {
+		return rf(ctx, customerIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) map[uuid.UUID][]models.Address); ok {
+		r0 = rf(ctx, customerIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[uuid.UUID][]models.Address)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, customerIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateAddress provides a mock function with given fields: ctx, address
+func (_m *AddressRepository) UpdateAddress(ctx context.Context, address models.Address) error {
+	ret := _m.Called(ctx, address)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAddress")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Address) error); ok {
+		r0 = rf(ctx, address)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAddressRepository creates a new instance of AddressRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAddressRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AddressRepository {
+	mock := &AddressRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,127 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// AttachmentRepository is an autogenerated mock type for the AttachmentRepository type
+type AttachmentRepository struct {
+	mock.Mock
+}
+
+// CreateAttachment provides a mock function with given fields: ctx, attachment
+func (_m *AttachmentRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	ret := _m.Called(ctx, attachment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAttachment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Attachment) error); ok {
+		r0 = rf(ctx, attachment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteAttachment provides a mock function with given fields: ctx, customerID, attachmentID
+func (_m *AttachmentRepository) DeleteAttachment(ctx context.Context, customerID uuid.UUID, attachmentID uuid.UUID) error {
+	ret := _m.Called(ctx, customerID, attachmentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAttachment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r0 = rf(ctx, customerID, attachmentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAttachment provides a mock function with given fields: ctx, customerID, attachmentID
+func (_m *AttachmentRepository) GetAttachment(ctx context.Context, customerID uuid.UUID, attachmentID uuid.UUID) (*models.Attachment, error) {
+	ret := _m.Called(ctx, customerID, attachmentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAttachment")
+	}
+
+	var r0 *models.Attachment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) (*models.Attachment, error)); ok {
+		return rf(ctx, customerID, attachmentID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) *models.Attachment); ok {
+		r0 = rf(ctx, customerID, attachmentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Attachment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID, attachmentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAttachments provides a mock function with given fields: ctx, customerID
+func (_m *AttachmentRepository) ListAttachments(ctx context.Context, customerID uuid.UUID) ([]models.Attachment, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAttachments")
+	}
+
+	var r0 []models.Attachment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.Attachment, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Attachment); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Attachment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAttachmentRepository creates a new instance of AttachmentRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAttachmentRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AttachmentRepository {
+	mock := &AttachmentRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
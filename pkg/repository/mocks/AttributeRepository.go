@@ -0,0 +1,125 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AttributeRepository is an autogenerated mock type for the AttributeRepository type
+type AttributeRepository struct {
+	mock.Mock
+}
+
+// DeleteAttributeDefinition provides a mock function with given fields: ctx, name
+func (_m *AttributeRepository) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAttributeDefinition")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAttributeDefinition provides a mock function with given fields: ctx, name
+func (_m *AttributeRepository) GetAttributeDefinition(ctx context.Context, name string) (*models.AttributeDefinition, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAttributeDefinition")
+	}
+
+	var r0 *models.AttributeDefinition
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.AttributeDefinition, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.AttributeDefinition); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.AttributeDefinition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAttributeDefinitions provides a mock function with given fields: ctx
+func (_m *AttributeRepository) ListAttributeDefinitions(ctx context.Context) ([]models.AttributeDefinition, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAttributeDefinitions")
+	}
+
+	var r0 []models.AttributeDefinition
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.AttributeDefinition, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.AttributeDefinition); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.AttributeDefinition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveAttributeDefinition provides a mock function with given fields: ctx, definition
+func (_m *AttributeRepository) SaveAttributeDefinition(ctx context.Context, definition models.AttributeDefinition) error {
+	ret := _m.Called(ctx, definition)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveAttributeDefinition")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.AttributeDefinition) error); ok {
+		r0 = rf(ctx, definition)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAttributeRepository creates a new instance of AttributeRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAttributeRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AttributeRepository {
+	mock := &AttributeRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,79 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// AuditRepository is an autogenerated mock type for the AuditRepository type
+type AuditRepository struct {
+	mock.Mock
+}
+
+// ListAuditEntries provides a mock function with given fields: ctx, customerID
+func (_m *AuditRepository) ListAuditEntries(ctx context.Context, customerID uuid.UUID) ([]models.AuditEntry, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAuditEntries")
+	}
+
+	var r0 []models.AuditEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.AuditEntry, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.AuditEntry); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.AuditEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordAudit provides a mock function with given fields: ctx, entry
+func (_m *AuditRepository) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	ret := _m.Called(ctx, entry)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordAudit")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.AuditEntry) error); ok {
+		r0 = rf(ctx, entry)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAuditRepository creates a new instance of AuditRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAuditRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AuditRepository {
+	mock := &AuditRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
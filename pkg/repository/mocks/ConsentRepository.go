@@ -0,0 +1,121 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// ConsentRepository is an autogenerated mock type for the ConsentRepository type
+type ConsentRepository struct {
+	mock.Mock
+}
+
+// ListConsentHistory provides a mock function with given fields: ctx, customerID
+func (_m *ConsentRepository) ListConsentHistory(ctx context.Context, customerID uuid.UUID) ([]models.Consent, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListConsentHistory")
+	}
+
+	var r0 []models.Consent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.Consent, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Consent); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Consent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListConsents provides a mock function with given fields: ctx, customerID
+func (_m *ConsentRepository) ListConsents(ctx context.Context, customerID uuid.UUID) ([]models.Consent, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListConsents")
+	}
+
+	var r0 []models.Consent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.Consent, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Consent); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Consent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordConsents provides a mock function with given fields: ctx, customerID, consents
+func (_m *ConsentRepository) RecordConsents(ctx context.Context, customerID uuid.UUID, consents []models.Consent) ([]models.Consent, error) {
+	ret := _m.Called(ctx, customerID, consents)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordConsents")
+	}
+
+	var r0 []models.Consent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, []models.Consent) ([]models.Consent, error)); ok {
+		return rf(ctx, customerID, consents)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, []models.Consent) []models.Consent); ok {
+		r0 = rf(ctx, customerID, consents)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Consent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, []models.Consent) error); ok {
+		r1 = rf(ctx, customerID, consents)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewConsentRepository creates a new instance of ConsentRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewConsentRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ConsentRepository {
+	mock := &ConsentRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,319 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// ContactRepository is an autogenerated mock type for the ContactRepository type
+type ContactRepository struct {
+	mock.Mock
+}
+
+// CreateEmail provides a mock function with given fields: ctx, email
+func (_m *ContactRepository) CreateEmail(ctx context.Context, email models.Email) error {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEmail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Email) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePhone provides a mock function with given fields: ctx, phone
+func (_m *ContactRepository) CreatePhone(ctx context.Context, phone models.Phone) error {
+	ret := _m.Called(ctx, phone)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePhone")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Phone) error); ok {
+		r0 = rf(ctx, phone)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteEmail provides a mock function with given fields: ctx, customerID, emailID
+func (_m *ContactRepository) DeleteEmail(ctx context.Context, customerID uuid.UUID, emailID uuid.UUID) error {
+	ret := _m.Called(ctx, customerID, emailID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteEmail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r0 = rf(ctx, customerID, emailID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeletePhone provides a mock function with given fields: ctx, customerID, phoneID
+func (_m *ContactRepository) DeletePhone(ctx context.Context, customerID uuid.UUID, phoneID uuid.UUID) error {
+	ret := _m.Called(ctx, customerID, phoneID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePhone")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r0 = rf(ctx, customerID, phoneID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetEmail provides a mock function with given fields: ctx, customerID, emailID
+func (_m *ContactRepository) GetEmail(ctx context.Context, customerID uuid.UUID, emailID uuid.UUID) (*models.Email, error) {
+	ret := _m.Called(ctx, customerID, emailID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEmail")
+	}
+
+	var r0 *models.Email
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) (*models.Email, error)); ok {
+		return rf(ctx, customerID, emailID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) *models.Email); ok {
+		r0 = rf(ctx, customerID, emailID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Email)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID, emailID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPhone provides a mock function with given fields: ctx, customerID, phoneID
+func (_m *ContactRepository) GetPhone(ctx context.Context, customerID uuid.UUID, phoneID uuid.UUID) (*models.Phone, error) {
+	ret := _m.Called(ctx, customerID, phoneID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPhone")
+	}
+
+	var r0 *models.Phone
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) (*models.Phone, error)); ok {
+		return rf(ctx, customerID, phoneID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) *models.Phone); ok {
+		r0 = rf(ctx, customerID, phoneID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Phone)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID, phoneID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListEmails provides a mock function with given fields: ctx, customerID
+func (_m *ContactRepository) ListEmails(ctx context.Context, customerID uuid.UUID) ([]models.Email, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEmails")
+	}
+
+	var r0 []models.Email
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.Email, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Email); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Email)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListEmailsByCustomerIDs provides a mock function with given fields: ctx, customerIDs
+func (_m *ContactRepository) ListEmailsByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Email, error) {
+	ret := _m.Called(ctx, customerIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEmailsByCustomerIDs")
+	}
+
+	var r0 map[uuid.UUID][]models.Email
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) (map[uuid.UUID][]models.Email, error)); ok {
+		return rf(ctx, customerIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) map[uuid.UUID][]models.Email); ok {
+		r0 = rf(ctx, customerIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[uuid.UUID][]models.Email)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, customerIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPhones provides a mock function with given fields: ctx, customerID
+func (_m *ContactRepository) ListPhones(ctx context.Context, customerID uuid.UUID) ([]models.Phone, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPhones")
+	}
+
+	var r0 []models.Phone
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.Phone, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.Phone); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Phone)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPhonesByCustomerIDs provides a mock function with given fields: ctx, customerIDs
+func (_m *ContactRepository) ListPhonesByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]models.Phone, error) {
+	ret := _m.Called(ctx, customerIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPhonesByCustomerIDs")
+	}
+
+	var r0 map[uuid.UUID][]models.Phone
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) (map[uuid.UUID][]models.Phone, error)); ok {
+		return rf(ctx, customerIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) map[uuid.UUID][]models.Phone); ok {
+		r0 = rf(ctx, customerIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[uuid.UUID][]models.Phone)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, customerIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateEmail provides a mock function with given fields: ctx, email
+func (_m *ContactRepository) UpdateEmail(ctx context.Context, email models.Email) error {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateEmail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Email) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdatePhone provides a mock function with given fields: ctx, phone
+func (_m *ContactRepository) UpdatePhone(ctx context.Context, phone models.Phone) error {
+	ret := _m.Called(ctx, phone)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePhone")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Phone) error); ok {
+		r0 = rf(ctx, phone)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewContactRepository creates a new instance of ContactRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewContactRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ContactRepository {
+	mock := &ContactRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -4,11 +4,11 @@ package mocks
 
 import (
 	models "CustomerCRUD/pkg/models"
+	repository "CustomerCRUD/pkg/repository"
 	context "context"
 
-	mock "github.com/stretchr/testify/mock"
-
 	uuid "github.com/google/uuid"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // CustomerRepository is an autogenerated mock type for the CustomerRepository type
@@ -16,8 +16,36 @@ type CustomerRepository struct {
 	mock.Mock
 }
 
+// CountCustomers provides a mock function with given fields: ctx, query
+func (_m *CustomerRepository) CountCustomers(ctx context.Context, query repository.CustomerQuery) (int, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountCustomers")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.CustomerQuery) (int, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.CustomerQuery) int); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.CustomerQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateCustomer provides a mock function with given fields: ctx, customer
-func (_m *CustomerRepository) CreateCustomer(ctx context.Context, customer models.Customer) error {
+func (_m *CustomerRepository) CreateCustomer(ctx context.Context, customer *models.Customer) error {
 	ret := _m.Called(ctx, customer)
 
 	if len(ret) == 0 {
@@ -25,7 +53,7 @@ func (_m *CustomerRepository) CreateCustomer(ctx context.Context, customer model
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, models.Customer) error); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Customer) error); ok {
 		r0 = rf(ctx, customer)
 	} else {
 		r0 = ret.Error(0)
@@ -52,6 +80,36 @@ func (_m *CustomerRepository) DeleteCustomer(ctx context.Context, customerID uui
 	return r0
 }
 
+// FindCustomers provides a mock function with given fields: ctx, query
+func (_m *CustomerRepository) FindCustomers(ctx context.Context, query repository.CustomerQuery) ([]models.Customer, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindCustomers")
+	}
+
+	var r0 []models.Customer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.CustomerQuery) ([]models.Customer, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.CustomerQuery) []models.Customer); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Customer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.CustomerQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllCustomers provides a mock function with given fields: ctx
 func (_m *CustomerRepository) GetAllCustomers(ctx context.Context) ([]models.Customer, error) {
 	ret := _m.Called(ctx)
@@ -142,8 +200,116 @@ func (_m *CustomerRepository) GetCustomerByID(ctx context.Context, customerID uu
 	return r0, r1
 }
 
+// GetCustomersByEmails provides a mock function with given fields: ctx, emails
+func (_m *CustomerRepository) GetCustomersByEmails(ctx context.Context, emails []string) (map[string]models.Customer, error) {
+	ret := _m.Called(ctx, emails)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCustomersByEmails")
+	}
+
+	var r0 map[string]models.Customer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string]models.Customer, error)); ok {
+		return rf(ctx, emails)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]models.Customer); ok {
+		r0 = rf(ctx, emails)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]models.Customer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, emails)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCustomersByIDs provides a mock function with given fields: ctx, customerIDs
+func (_m *CustomerRepository) GetCustomersByIDs(ctx context.Context, customerIDs []uuid.UUID) ([]models.Customer, error) {
+	ret := _m.Called(ctx, customerIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCustomersByIDs")
+	}
+
+	var r0 []models.Customer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) ([]models.Customer, error)); ok {
+		return rf(ctx, customerIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) []models.Customer); ok {
+		r0 = rf(ctx, customerIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Customer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, customerIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListCustomers provides a mock function with given fields: ctx, opts
+func (_m *CustomerRepository) ListCustomers(ctx context.Context, opts repository.ListOptions) ([]models.Customer, error) {
+	ret := _m.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListCustomers")
+	}
+
+	var r0 []models.Customer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.ListOptions) ([]models.Customer, error)); ok {
+		return rf(ctx, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.ListOptions) []models.Customer); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Customer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.ListOptions) error); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkEmailVerified provides a mock function with given fields: ctx, customerID, email
+func (_m *CustomerRepository) MarkEmailVerified(ctx context.Context, customerID uuid.UUID, email string) error {
+	ret := _m.Called(ctx, customerID, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkEmailVerified")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) error); ok {
+		r0 = rf(ctx, customerID, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateCustomer provides a mock function with given fields: ctx, customer
-func (_m *CustomerRepository) UpdateCustomer(ctx context.Context, customer models.Customer) error {
+func (_m *CustomerRepository) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
 	ret := _m.Called(ctx, customer)
 
 	if len(ret) == 0 {
@@ -151,7 +317,7 @@ func (_m *CustomerRepository) UpdateCustomer(ctx context.Context, customer model
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, models.Customer) error); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Customer) error); ok {
 		r0 = rf(ctx, customer)
 	} else {
 		r0 = ret.Error(0)
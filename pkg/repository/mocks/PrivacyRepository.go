@@ -0,0 +1,169 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// PrivacyRepository is an autogenerated mock type for the PrivacyRepository type
+type PrivacyRepository struct {
+	mock.Mock
+}
+
+// EraseCustomer provides a mock function with given fields: ctx, erasure
+func (_m *PrivacyRepository) EraseCustomer(ctx context.Context, erasure *models.Erasure) ([]string, error) {
+	ret := _m.Called(ctx, erasure)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EraseCustomer")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Erasure) ([]string, error)); ok {
+		return rf(ctx, erasure)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Erasure) []string); ok {
+		r0 = rf(ctx, erasure)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Erasure) error); ok {
+		r1 = rf(ctx, erasure)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetErasure provides a mock function with given fields: ctx, customerID
+func (_m *PrivacyRepository) GetErasure(ctx context.Context, customerID uuid.UUID) (*models.Erasure, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetErasure")
+	}
+
+	var r0 *models.Erasure
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (*models.Erasure, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.Erasure); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Erasure)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLegalHolds provides a mock function with given fields: ctx, customerID
+func (_m *PrivacyRepository) ListLegalHolds(ctx context.Context, customerID uuid.UUID) ([]models.LegalHold, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListLegalHolds")
+	}
+
+	var r0 []models.LegalHold
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.LegalHold, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.LegalHold); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.LegalHold)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PlaceLegalHold provides a mock function with given fields: ctx, hold
+func (_m *PrivacyRepository) PlaceLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	ret := _m.Called(ctx, hold)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PlaceLegalHold")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.LegalHold) error); ok {
+		r0 = rf(ctx, hold)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReleaseLegalHold provides a mock function with given fields: ctx, customerID, holdID
+func (_m *PrivacyRepository) ReleaseLegalHold(ctx context.Context, customerID uuid.UUID, holdID uuid.UUID) (*models.LegalHold, error) {
+	ret := _m.Called(ctx, customerID, holdID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseLegalHold")
+	}
+
+	var r0 *models.LegalHold
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) (*models.LegalHold, error)); ok {
+		return rf(ctx, customerID, holdID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID) *models.LegalHold); ok {
+		r0 = rf(ctx, customerID, holdID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LegalHold)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID, holdID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewPrivacyRepository creates a new instance of PrivacyRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPrivacyRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PrivacyRepository {
+	mock := &PrivacyRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
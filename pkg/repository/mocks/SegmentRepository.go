@@ -0,0 +1,145 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// SegmentRepository is an autogenerated mock type for the SegmentRepository type
+type SegmentRepository struct {
+	mock.Mock
+}
+
+// CreateSegment provides a mock function with given fields: ctx, segment
+func (_m *SegmentRepository) CreateSegment(ctx context.Context, segment models.Segment) error {
+	ret := _m.Called(ctx, segment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSegment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Segment) error); ok {
+		r0 = rf(ctx, segment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteSegment provides a mock function with given fields: ctx, segmentID
+func (_m *SegmentRepository) DeleteSegment(ctx context.Context, segmentID uuid.UUID) error {
+	ret := _m.Called(ctx, segmentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteSegment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, segmentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetSegment provides a mock function with given fields: ctx, segmentID
+func (_m *SegmentRepository) GetSegment(ctx context.Context, segmentID uuid.UUID) (*models.Segment, error) {
+	ret := _m.Called(ctx, segmentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSegment")
+	}
+
+	var r0 *models.Segment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (*models.Segment, error)); ok {
+		return rf(ctx, segmentID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *models.Segment); ok {
+		r0 = rf(ctx, segmentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Segment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, segmentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSegments provides a mock function with given fields: ctx
+func (_m *SegmentRepository) ListSegments(ctx context.Context) ([]models.Segment, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSegments")
+	}
+
+	var r0 []models.Segment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Segment, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Segment); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Segment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateSegment provides a mock function with given fields: ctx, segment
+func (_m *SegmentRepository) UpdateSegment(ctx context.Context, segment models.Segment) error {
+	ret := _m.Called(ctx, segment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSegment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Segment) error); ok {
+		r0 = rf(ctx, segment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewSegmentRepository creates a new instance of SegmentRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSegmentRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *SegmentRepository {
+	mock := &SegmentRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
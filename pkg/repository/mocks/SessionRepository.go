@@ -0,0 +1,175 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	uuid "github.com/google/uuid"
+)
+
+// SessionRepository is an autogenerated mock type for the SessionRepository type
+type SessionRepository struct {
+	mock.Mock
+}
+
+// ConsumeLoginToken provides a mock function with given fields: ctx, tokenHash
+func (_m *SessionRepository) ConsumeLoginToken(ctx context.Context, tokenHash string) (*models.LoginToken, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumeLoginToken")
+	}
+
+	var r0 *models.LoginToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.LoginToken, error)); ok {
+		return rf(ctx, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.LoginToken); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginToken)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountLoginTokens provides a mock function with given fields: ctx, customerID, since
+func (_m *SessionRepository) CountLoginTokens(ctx context.Context, customerID uuid.UUID, since time.Time) (int, error) {
+	ret := _m.Called(ctx, customerID, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountLoginTokens")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, time.Time) (int, error)); ok {
+		return rf(ctx, customerID, since)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, time.Time) int); ok {
+		r0 = rf(ctx, customerID, since)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, time.Time) error); ok {
+		r1 = rf(ctx, customerID, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateLoginToken provides a mock function with given fields: ctx, token
+func (_m *SessionRepository) CreateLoginToken(ctx context.Context, token models.LoginToken) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateLoginToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.LoginToken) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateSession provides a mock function with given fields: ctx, session
+func (_m *SessionRepository) CreateSession(ctx context.Context, session models.PortalSession) error {
+	ret := _m.Called(ctx, session)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.PortalSession) error); ok {
+		r0 = rf(ctx, session)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteSession provides a mock function with given fields: ctx, tokenHash
+func (_m *SessionRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	ret := _m.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetSession provides a mock function with given fields: ctx, tokenHash
+func (_m *SessionRepository) GetSession(ctx context.Context, tokenHash string) (*models.PortalSession, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSession")
+	}
+
+	var r0 *models.PortalSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.PortalSession, error)); ok {
+		return rf(ctx, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.PortalSession); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PortalSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewSessionRepository creates a new instance of SessionRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSessionRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *SessionRepository {
+	mock := &SessionRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
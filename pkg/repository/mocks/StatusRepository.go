@@ -0,0 +1,79 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// StatusRepository is an autogenerated mock type for the StatusRepository type
+type StatusRepository struct {
+	mock.Mock
+}
+
+// ChangeStatus provides a mock function with given fields: ctx, change
+func (_m *StatusRepository) ChangeStatus(ctx context.Context, change models.StatusChange) error {
+	ret := _m.Called(ctx, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangeStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.StatusChange) error); ok {
+		r0 = rf(ctx, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListStatusChanges provides a mock function with given fields: ctx, customerID
+func (_m *StatusRepository) ListStatusChanges(ctx context.Context, customerID uuid.UUID) ([]models.StatusChange, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListStatusChanges")
+	}
+
+	var r0 []models.StatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]models.StatusChange, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []models.StatusChange); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewStatusRepository creates a new instance of StatusRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewStatusRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *StatusRepository {
+	mock := &StatusRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
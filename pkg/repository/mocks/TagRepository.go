@@ -0,0 +1,157 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "CustomerCRUD/pkg/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	uuid "github.com/google/uuid"
+)
+
+// TagRepository is an autogenerated mock type for the TagRepository type
+type TagRepository struct {
+	mock.Mock
+}
+
+// AddTags provides a mock function with given fields: ctx, customerIDs, tags
+func (_m *TagRepository) AddTags(ctx context.Context, customerIDs []uuid.UUID, tags []string) error {
+	ret := _m.Called(ctx, customerIDs, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddTags")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID, []string) error); ok {
+		r0 = rf(ctx, customerIDs, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListCustomerTags provides a mock function with given fields: ctx, customerID
+func (_m *TagRepository) ListCustomerTags(ctx context.Context, customerID uuid.UUID) ([]string, error) {
+	ret := _m.Called(ctx, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListCustomerTags")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]string, error)); ok {
+		return rf(ctx, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []string); ok {
+		r0 = rf(ctx, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTags provides a mock function with given fields: ctx
+func (_m *TagRepository) ListTags(ctx context.Context) ([]models.Tag, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTags")
+	}
+
+	var r0 []models.Tag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Tag, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Tag); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Tag)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTagsByCustomerIDs provides a mock function with given fields: ctx, customerIDs
+func (_m *TagRepository) ListTagsByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	ret := _m.Called(ctx, customerIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTagsByCustomerIDs")
+	}
+
+	var r0 map[uuid.UUID][]string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) (map[uuid.UUID][]string, error)); ok {
+		return rf(ctx, customerIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) map[uuid.UUID][]string); ok {
+		r0 = rf(ctx, customerIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[uuid.UUID][]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, customerIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveTags provides a mock function with given fields: ctx, customerIDs, tags
+func (_m *TagRepository) RemoveTags(ctx context.Context, customerIDs []uuid.UUID, tags []string) error {
+	ret := _m.Called(ctx, customerIDs, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveTags")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID, []string) error); ok {
+		r0 = rf(ctx, customerIDs, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewTagRepository creates a new instance of TagRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewTagRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *TagRepository {
+	mock := &TagRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// PrivacyRepository keeps the legal holds of customers and erases their personal data. Whether a
+// hold blocks an erasure is up to the service layer.
+type PrivacyRepository interface {
+	// ListLegalHolds returns the holds of the customer, released ones included, oldest first.
+	ListLegalHolds(ctx context.Context, customerID uuid.UUID) ([]models.LegalHold, error)
+	// PlaceLegalHold stores hold and sets who placed it and when.
+	PlaceLegalHold(ctx context.Context, hold *models.LegalHold) error
+	// ReleaseLegalHold ends the hold and returns it. It fails with sql.ErrNoRows when the hold does
+	// not exist or was already released.
+	ReleaseLegalHold(ctx context.Context, customerID, holdID uuid.UUID) (*models.LegalHold, error)
+
+	// GetErasure returns the erasure of the customer, sql.ErrNoRows when it was never erased.
+	GetErasure(ctx context.Context, customerID uuid.UUID) (*models.Erasure, error)
+	// EraseCustomer replaces the personal data of the customer in place and records erasure, setting
+	// who erased it and when. The customer keeps its ID, status, tags and timestamps. Its addresses,
+	// emails, phones, attachments, consents and portal sessions are deleted, the bodies of its notes and
+	// interactions, the reasons of its status changes and the values of its audit changes are replaced
+	// by models.Erased. It returns the storage keys of the deleted attachments, whose contents are left
+	// to the caller, and fails with sql.ErrNoRows when the customer does not exist.
+	EraseCustomer(ctx context.Context, erasure *models.Erasure) (attachmentKeys []string, err error)
+}
+
+type privacyRepository struct {
+	db     conn
+	fields fields
+}
+
+func NewPrivacyRepository(db *sql.DB, opts ...Option) PrivacyRepository {
+	return &privacyRepository{db: conn{db: db}, fields: fields{cipher: applyOptions(opts).cipher}}
+}
+
+const legalHoldColumns = "id, customer_id, reason, placed_at, placed_by, released_at, released_by"
+
+func scanLegalHold(row rowScanner) (models.LegalHold, error) {
+	var h models.LegalHold
+	var releasedAt sql.NullTime
+	var releasedBy sql.NullString
+	err := row.Scan(&h.ID, &h.CustomerID, &h.Reason, &h.PlacedAt, &h.PlacedBy, &releasedAt, &releasedBy)
+	h.PlacedAt = h.PlacedAt.UTC()
+	if releasedAt.Valid {
+		at := releasedAt.Time.UTC()
+		h.ReleasedAt = &at
+	}
+	h.ReleasedBy = releasedBy.String
+	return h, err
+}
+
+func (r privacyRepository) ListLegalHolds(ctx context.Context, customerID uuid.UUID) ([]models.LegalHold, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+legalHoldColumns+" FROM customer_legal_holds WHERE customer_id = $1 ORDER BY placed_at, id",
+		customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holds []models.LegalHold
+	for rows.Next() {
+		h, err := scanLegalHold(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning legal hold rows: %w", err)
+		}
+		holds = append(holds, h)
+	}
+	return holds, rows.Err()
+}
+
+func (r privacyRepository) PlaceLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	at, by := now(), actor.FromContext(ctx)
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO customer_legal_holds (id, customer_id, reason, placed_at, placed_by) VALUES ($1, $2, $3, $4, $5)",
+		hold.ID, hold.CustomerID, hold.Reason, at, by)
+	if err != nil {
+		return fmt.Errorf("error placing legal hold: %w", err)
+	}
+	hold.PlacedAt, hold.PlacedBy = at, by
+	return nil
+}
+
+func (r privacyRepository) ReleaseLegalHold(ctx context.Context, customerID, holdID uuid.UUID) (*models.LegalHold, error) {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE customer_legal_holds SET released_at = $1, released_by = $2 WHERE customer_id = $3 AND id = $4 AND released_at IS NULL",
+		now(), actor.FromContext(ctx), customerID, holdID)
+	if err != nil {
+		return nil, fmt.Errorf("error releasing legal hold: %w", err)
+	}
+	if err := expectAffected(res); err != nil {
+		return nil, err
+	}
+
+	h, err := scanLegalHold(r.db.QueryRowContext(ctx,
+		"SELECT "+legalHoldColumns+" FROM customer_legal_holds WHERE id = $1", holdID))
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r privacyRepository) GetErasure(ctx context.Context, customerID uuid.UUID) (*models.Erasure, error) {
+	var e models.Erasure
+	var reference sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT customer_id, reference, erased_at, erased_by FROM customer_erasures WHERE customer_id = $1",
+		customerID).Scan(&e.CustomerID, &reference, &e.ErasedAt, &e.ErasedBy)
+	if err != nil {
+		return nil, err
+	}
+	e.Reference = reference.String
+	e.ErasedAt = e.ErasedAt.UTC()
+	return &e, nil
+}
+
+func (r privacyRepository) EraseCustomer(ctx context.Context, erasure *models.Erasure) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	at, by := now(), actor.FromContext(ctx)
+	id := erasure.CustomerID
+	anonymized := models.AnonymizedCustomer(id)
+	email, emailIndex, err := r.fields.stored(ctx, anonymized.Email)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx,
+		`UPDATE customers SET first_name = $1, middle_name = NULL, last_name = $2, email = $3, email_blind_index = $4,
+		 phone_number = NULL, phone_number_blind_index = NULL, attributes = '{}', updated_at = $5, updated_by = $6
+		 WHERE id = $7`,
+		anonymized.FirstName, anonymized.LastName, email, emailIndex, at, by, id)
+	if err != nil {
+		return nil, fmt.Errorf("error anonymizing customer: %w", err)
+	}
+	if err := expectAffected(res); err != nil {
+		return nil, err
+	}
+
+	keys, err := attachmentKeys(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deletions := []string{
+		"DELETE FROM customer_addresses WHERE customer_id = $1",
+		"DELETE FROM customer_emails WHERE customer_id = $1",
+		"DELETE FROM customer_phones WHERE customer_id = $1",
+		"DELETE FROM customer_attachments WHERE customer_id = $1",
+		"DELETE FROM customer_consents WHERE customer_id = $1",
+		"DELETE FROM customer_consent_history WHERE customer_id = $1",
+		"DELETE FROM portal_sessions WHERE customer_id = $1",
+		"DELETE FROM portal_login_tokens WHERE customer_id = $1",
+	}
+	for _, stmt := range deletions {
+		if _, err := tx.ExecContext(ctx, stmt, id); err != nil {
+			return nil, fmt.Errorf("error erasing customer data: %w", err)
+		}
+	}
+	redactions := []string{
+		"UPDATE customer_notes SET body = $1 WHERE customer_id = $2",
+		"UPDATE customer_note_revisions SET body = $1 WHERE note_id IN (SELECT id FROM customer_notes WHERE customer_id = $2)",
+		"UPDATE customer_interactions SET body = $1 WHERE customer_id = $2",
+		"UPDATE customer_status_changes SET reason = $1 WHERE customer_id = $2",
+	}
+	for _, stmt := range redactions {
+		if _, err := tx.ExecContext(ctx, stmt, models.Erased, id); err != nil {
+			return nil, fmt.Errorf("error erasing customer data: %w", err)
+		}
+	}
+
+	if err := redactAudit(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	var reference any
+	if erasure.Reference != "" {
+		reference = erasure.Reference
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO customer_erasures (customer_id, reference, erased_at, erased_by) VALUES ($1, $2, $3, $4)",
+		id, reference, at, by)
+	if err != nil {
+		return nil, fmt.Errorf("error recording erasure: %w", duplicate(err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	erasure.ErasedAt, erasure.ErasedBy = at, by
+	return keys, nil
+}
+
+func attachmentKeys(ctx context.Context, tx executor, customerID uuid.UUID) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT storage_key FROM customer_attachments WHERE customer_id = $1", customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("error scanning attachment rows: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// redactAudit replaces the recorded values of the audit entries of the customer, see
+// models.RedactChanges. The entries themselves are kept.
+func redactAudit(ctx context.Context, tx executor, customerID uuid.UUID) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, changes FROM customer_audit WHERE customer_id = $1 AND changes IS NOT NULL", customerID)
+	if err != nil {
+		return err
+	}
+	redacted := map[uuid.UUID]string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var encoded []byte
+		var changes map[string]models.FieldChange
+		if err := rows.Scan(&id, &encoded); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning audit rows: %w", err)
+		}
+		if err := json.Unmarshal(encoded, &changes); err != nil {
+			rows.Close()
+			return fmt.Errorf("error decoding audit changes: %w", err)
+		}
+		encoded, err := json.Marshal(models.RedactChanges(changes))
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("error encoding audit changes: %w", err)
+		}
+		redacted[id] = string(encoded)
+	}
+	// The statements below cannot run while the rows are still being read on the same connection.
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return err
+	}
+
+	for id, changes := range redacted {
+		if _, err := tx.ExecContext(ctx, "UPDATE customer_audit SET changes = $1 WHERE id = $2", changes, id); err != nil {
+			return fmt.Errorf("error redacting audit entry: %w", err)
+		}
+	}
+	return nil
+}
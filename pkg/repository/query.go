@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/segment"
+)
+
+// FilterOp compares a custom attribute to the value of a filter.
+type FilterOp string
+
+const (
+	OpEqual          FilterOp = "eq"
+	OpGreater        FilterOp = "gt"
+	OpGreaterOrEqual FilterOp = "gte"
+	OpLess           FilterOp = "lt"
+	OpLessOrEqual    FilterOp = "lte"
+)
+
+var filterOperators = map[FilterOp]string{
+	OpEqual:          "=",
+	OpGreater:        ">",
+	OpGreaterOrEqual: ">=",
+	OpLess:           "<",
+	OpLessOrEqual:    "<=",
+}
+
+func (op FilterOp) Valid() bool {
+	_, ok := filterOperators[op]
+	return ok
+}
+
+// AttributeFilter keeps the customers whose attribute compares to Value with Op. Value has the
+// JSON form of the attribute type, see models.AttributeDefinition.ParseValue.
+type AttributeFilter struct {
+	Attribute models.AttributeDefinition
+	Op        FilterOp
+	Value     any
+}
+
+// SortFields are the customer columns FindCustomers can sort by.
+var SortFields = map[string]bool{
+	"id": true, "first_name": true, "last_name": true, "email": true, "created_at": true, "updated_at": true,
+}
+
+// CustomerQuery selects and orders customers by their custom attributes, segment filters and status.
+// Customers missing the sort attribute come last, ties are broken by ID.
+type CustomerQuery struct {
+	Filters []AttributeFilter
+	// Segment, when set, also keeps only the customers matching the parsed segment filter.
+	Segment segment.Expr
+	// Statuses, when not empty, keeps the customers in one of them.
+	Statuses []models.Status
+	// UpdatedSince, when set, keeps the customers created or updated at or after it, for incremental syncs.
+	UpdatedSince time.Time
+	// Contactable, when set, keeps the customers who currently consent to be contacted for a purpose.
+	Contactable *ContactableFilter
+	// SortAttribute, when set, takes precedence over SortField.
+	SortAttribute *models.AttributeDefinition
+	// SortField is one of SortFields, the ID when empty.
+	SortField  string
+	Descending bool
+}
+
+// ContactableFilter selects the customers whose current consent for Purpose is granted, on Channel
+// or, when it is empty, on any channel.
+type ContactableFilter struct {
+	Purpose string
+	Channel models.ConsentChannel
+}
+
+// attributeExpr returns the SQL expression reading attribute from the JSON column, typed for comparison.
+func (d dialect) attributeExpr(attribute models.AttributeDefinition) string {
+	if d == sqliteDialect {
+		// json_extract returns native SQLite values, booleans as 0 and 1 like bound Go booleans.
+		return "json_extract(attributes, '$." + attribute.Name + "')"
+	}
+
+	text := "(attributes->>'" + attribute.Name + "')"
+	switch {
+	case attribute.Type.Numeric():
+		return text + "::numeric"
+	case attribute.Type == models.AttributeBoolean:
+		return text + "::boolean"
+	default:
+		// ISO dates compare correctly as text.
+		return text
+	}
+}
+
+// where returns the WHERE clause selecting the customers of query, empty when it selects them all.
+func (r customerRepository) where(query CustomerQuery) (string, []any, error) {
+	var conditions []string
+	var args []any
+	for _, f := range query.Filters {
+		op, ok := filterOperators[f.Op]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown filter operator %q", f.Op)
+		}
+
+		if f.Op == OpEqual && r.dialect == postgresDialect {
+			// Containment can use the GIN index on the attributes.
+			value, err := json.Marshal(map[string]any{f.Attribute.Name: f.Value})
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, string(value))
+			conditions = append(conditions, fmt.Sprintf("attributes @> $%d::jsonb", len(args)))
+			continue
+		}
+
+		args = append(args, f.Value)
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", r.dialect.attributeExpr(f.Attribute), op, len(args)))
+	}
+
+	if query.Segment != nil {
+		condition, err := segmentCondition(query.Segment, r.fields, &args)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if len(query.Statuses) > 0 {
+		for _, status := range query.Statuses {
+			args = append(args, status)
+		}
+		conditions = append(conditions, "status IN ("+placeholders(len(args)-len(query.Statuses)+1, len(query.Statuses))+")")
+	}
+
+	if !query.UpdatedSince.IsZero() {
+		args = append(args, query.UpdatedSince.UTC())
+		conditions = append(conditions, fmt.Sprintf("updated_at >= $%d", len(args)))
+	}
+
+	if c := query.Contactable; c != nil {
+		args = append(args, c.Purpose, models.ConsentGranted)
+		condition := fmt.Sprintf("EXISTS (SELECT 1 FROM customer_consents WHERE customer_id = customers.id AND purpose = $%d AND state = $%d",
+			len(args)-1, len(args))
+		if c.Channel != "" {
+			args = append(args, c.Channel)
+			condition += fmt.Sprintf(" AND channel = $%d", len(args))
+		}
+		conditions = append(conditions, condition+")")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+func (r customerRepository) FindCustomers(ctx context.Context, query CustomerQuery) ([]models.Customer, error) {
+	where, args, err := r.where(query)
+	if err != nil {
+		return nil, err
+	}
+	sql := "SELECT " + customerColumns + " FROM customers" + where
+
+	direction := "ASC"
+	if query.Descending {
+		direction = "DESC"
+	}
+	switch {
+	case query.SortAttribute != nil:
+		sql += " ORDER BY " + r.dialect.attributeExpr(*query.SortAttribute) + " " + direction + " NULLS LAST, id"
+	case query.SortField == "" || query.SortField == "id":
+		sql += " ORDER BY id " + direction
+	case query.SortField == "email" && r.fields.enabled():
+		// Ciphertexts do not sort like the addresses, they are sorted once decrypted.
+		customers, err := r.queryCustomers(ctx, sql+" ORDER BY id", args...)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(customers, func(i, j int) bool {
+			if query.Descending {
+				i, j = j, i
+			}
+			return customers[i].Email < customers[j].Email
+		})
+		return customers, nil
+	case SortFields[query.SortField]:
+		sql += " ORDER BY " + query.SortField + " " + direction + ", id"
+	default:
+		return nil, fmt.Errorf("unknown sort field %q", query.SortField)
+	}
+
+	return r.queryCustomers(ctx, sql, args...)
+}
+
+// CountCustomers counts the customers FindCustomers would return, the sort order is ignored. Like
+// getCustomer it reads with QueryContext, so that the count fails over to the primary too.
+func (r customerRepository) CountCustomers(ctx context.Context, query CustomerQuery) (int, error) {
+	where, args, err := r.where(query)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT COUNT(*) FROM customers"+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("error counting customers: %w", err)
+	}
+	defer rows.Close()
+
+	var n int
+	if rows.Next() {
+		err = rows.Scan(&n)
+	} else if err = rows.Err(); err == nil {
+		err = errors.New("no count returned")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error counting customers: %w", err)
+	}
+	return n, rows.Err()
+}
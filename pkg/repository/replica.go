@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Replica is a read replica of the database. Repositories configured WithReplica send the reads
+// made outside transactions to it, and everything else to the primary. While the replica is
+// unhealthy, or once a read session wrote, reads go to the primary as well.
+type Replica struct {
+	db        *sql.DB
+	unhealthy atomic.Bool
+
+	reads, primaryReads, failovers atomic.Uint64
+}
+
+// ReplicaStats count where reads were sent since the replica was created.
+type ReplicaStats struct {
+	Healthy      bool   `json:"healthy"`
+	Reads        uint64 `json:"reads"`
+	PrimaryReads uint64 `json:"primary_reads"`
+	// Failovers are the reads that failed on the replica and were retried on the primary.
+	Failovers uint64 `json:"failovers"`
+}
+
+func NewReplica(db *sql.DB) *Replica {
+	return &Replica{db: db}
+}
+
+// WithReplica sends the reads of the repositories to replica.
+func WithReplica(replica *Replica) Option {
+	return func(o *options) {
+		o.replica = replica
+	}
+}
+
+// Check pings the replica. Reads are sent to the primary from a failed check until a check passes.
+func (r *Replica) Check(ctx context.Context) error {
+	err := r.db.PingContext(ctx)
+	if err != nil {
+		if !r.unhealthy.Swap(true) {
+			log.Warnf("read replica is unhealthy, reading from the primary: %v", err)
+		}
+		return err
+	}
+	if r.unhealthy.Swap(false) {
+		log.Info("read replica is healthy again")
+	}
+	return nil
+}
+
+// Watch checks the replica every interval until ctx is done.
+func (r *Replica) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			r.Check(checkCtx)
+			cancel()
+		}
+	}
+}
+
+func (r *Replica) Stats() ReplicaStats {
+	return ReplicaStats{
+		Healthy:      !r.unhealthy.Load(),
+		Reads:        r.reads.Load(),
+		PrimaryReads: r.primaryReads.Load(),
+		Failovers:    r.failovers.Load(),
+	}
+}
+
+// reader returns the database the reads made with ctx go to.
+func (r *Replica) reader(ctx context.Context, primary *sql.DB) *sql.DB {
+	if r.unhealthy.Load() || ReadsPrimary(ctx) {
+		r.primaryReads.Add(1)
+		return primary
+	}
+	r.reads.Add(1)
+	return r.db
+}
+
+// failover takes the replica out of rotation after a read failed with err, unless the read failed
+// because its context ended. It reports whether the read should be retried on the primary.
+func (r *Replica) failover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	r.failovers.Add(1)
+	if !r.unhealthy.Swap(true) {
+		log.Warnf("read from the replica failed, reading from the primary until it is healthy: %v", err)
+	}
+	return true
+}
+
+type readSessionKey struct{}
+
+// readSession remembers whether the reads of a session have to go to the primary.
+type readSession struct {
+	primary, wrote atomic.Bool
+}
+
+// WithReadSession returns ctx starting a read session. Once a write is made with the session,
+// its reads go to the primary, which sees the write before the replica does. primary sends them
+// there from the start, for clients that wrote recently.
+func WithReadSession(ctx context.Context, primary bool) context.Context {
+	s := &readSession{}
+	s.primary.Store(primary)
+	return context.WithValue(ctx, readSessionKey{}, s)
+}
+
+// ReadsPrimary reports whether the read session of ctx reads from the primary, because it
+// started there or wrote.
+func ReadsPrimary(ctx context.Context) bool {
+	s, ok := ctx.Value(readSessionKey{}).(*readSession)
+	return ok && (s.primary.Load() || s.wrote.Load())
+}
+
+// Wrote reports whether a write was made with the read session of ctx.
+func Wrote(ctx context.Context) bool {
+	s, ok := ctx.Value(readSessionKey{}).(*readSession)
+	return ok && s.wrote.Load()
+}
+
+// wrote moves the read session of ctx to the primary.
+func wrote(ctx context.Context) {
+	if s, ok := ctx.Value(readSessionKey{}).(*readSession); ok {
+		s.wrote.Store(true)
+	}
+}
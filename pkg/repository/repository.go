@@ -3,9 +3,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/config"
 	"CustomerCRUD/pkg/models"
 	"CustomerCRUD/utils"
 
@@ -15,19 +20,102 @@ import (
 
 type CustomerRepository interface {
 	GetAllCustomers(ctx context.Context) ([]models.Customer, error)
+	ListCustomers(ctx context.Context, opts ListOptions) ([]models.Customer, error)
+	FindCustomers(ctx context.Context, query CustomerQuery) ([]models.Customer, error)
+	CountCustomers(ctx context.Context, query CustomerQuery) (int, error)
 	GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error)
+	GetCustomersByIDs(ctx context.Context, customerIDs []uuid.UUID) ([]models.Customer, error)
 	GetCustomerByEmail(ctx context.Context, email string) (*models.Customer, error)
-	CreateCustomer(ctx context.Context, customer models.Customer) error
-	UpdateCustomer(ctx context.Context, customer models.Customer) error
+	GetCustomersByEmails(ctx context.Context, emails []string) (map[string]models.Customer, error)
+	// CreateCustomer stores customer and fills in its initial status, timestamps and actors.
+	CreateCustomer(ctx context.Context, customer *models.Customer) error
+	// UpdateCustomer stores customer and fills in the fields it keeps, such as the status and the
+	// creation timestamp, from the stored customer.
+	UpdateCustomer(ctx context.Context, customer *models.Customer) error
 	DeleteCustomer(ctx context.Context, customerID uuid.UUID) error
+	// MarkEmailVerified marks email, the current email of the customer, as verified. It fails with
+	// sql.ErrNoRows when the customer does not exist or its email is no longer email.
+	MarkEmailVerified(ctx context.Context, customerID uuid.UUID, email string) error
+}
+
+// ListOptions selects a page of customers ordered by ID. After is the ID of the last
+// customer of the previous page, the zero UUID starts from the beginning.
+type ListOptions struct {
+	Limit int
+	After uuid.UUID
 }
 
 type customerRepository struct {
-	db *sql.DB
+	db      conn
+	dialect dialect
+	fields  fields
 }
 
-func (r customerRepository) GetAllCustomers(ctx context.Context) ([]models.Customer, error) {
-	rows, err := r.db.Query("SELECT * FROM customers")
+// customerColumns reads email_verified from the primary email, which holds the verified flag.
+const customerColumns = "id, first_name, middle_name, last_name, email, phone_number, status, attributes, created_at, updated_at, created_by, updated_by, " +
+	"COALESCE((SELECT e.verified FROM customer_emails e WHERE e.customer_id = customers.id AND e.is_primary), FALSE)"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCustomer(row rowScanner) (models.Customer, error) {
+	var c models.Customer
+	var middleName, phoneNumber, createdBy, updatedBy sql.NullString
+	var attributes []byte
+	err := row.Scan(&c.ID, &c.FirstName, &middleName, &c.LastName, &c.Email, &phoneNumber, &c.Status, &attributes,
+		&c.CreatedAt, &c.UpdatedAt, &createdBy, &updatedBy, &c.EmailVerified)
+	if err != nil {
+		return c, err
+	}
+	c.MiddleName = middleName.String
+	c.PhoneNumber = phoneNumber.String
+	c.CreatedAt = c.CreatedAt.UTC()
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	c.CreatedBy = createdBy.String
+	c.UpdatedBy = updatedBy.String
+	if err := json.Unmarshal(attributes, &c.Attributes); err != nil {
+		return c, fmt.Errorf("error decoding attributes: %w", err)
+	}
+	if len(c.Attributes) == 0 {
+		c.Attributes = nil
+	}
+	return c, nil
+}
+
+// now returns the time recorded for a change, in UTC and at the microsecond precision of Postgres
+// so that the value held by the caller matches the stored one.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// encodeAttributes encodes attributes for a JSON column. It returns a string rather than
+// bytes, which pq would send as bytea.
+func encodeAttributes(attributes map[string]any) (string, error) {
+	if attributes == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return "", fmt.Errorf("error encoding attributes: %w", err)
+	}
+	return string(data), nil
+}
+
+// scan reads a customer row and decrypts its email and phone number.
+func (r customerRepository) scan(ctx context.Context, row rowScanner) (models.Customer, error) {
+	c, err := scanCustomer(row)
+	if err != nil {
+		return c, err
+	}
+	if err := r.fields.decryptAll(ctx, &c.Email, &c.PhoneNumber); err != nil {
+		return c, fmt.Errorf("error decrypting customer %s: %w", c.ID, err)
+	}
+	return c, nil
+}
+
+func (r customerRepository) queryCustomers(ctx context.Context, query string, args ...any) ([]models.Customer, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -35,69 +123,282 @@ func (r customerRepository) GetAllCustomers(ctx context.Context) ([]models.Custo
 
 	var customers []models.Customer
 	for rows.Next() {
-		var c models.Customer
-		err := rows.Scan(&c.ID, &c.FirstName, &c.MiddleName, &c.LastName, &c.Email, &c.PhoneNumber)
+		c, err := r.scan(ctx, rows)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning customer rows: %w", err)
 		}
 		customers = append(customers, c)
 	}
-	return customers, nil
+	return customers, rows.Err()
 }
 
-func (r customerRepository) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
-	var c models.Customer
-	query := "SELECT * FROM customers WHERE id = $1"
-	err := r.db.QueryRow(query, customerID).Scan(&c.ID, &c.FirstName, &c.MiddleName, &c.LastName, &c.Email, &c.PhoneNumber)
+// getCustomer returns the first customer of query, failing with sql.ErrNoRows without one. Unlike
+// QueryRowContext it fails over to the primary when the replica fails.
+func (r customerRepository) getCustomer(ctx context.Context, query string, args ...any) (*models.Customer, error) {
+	customers, err := r.queryCustomers(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &c, nil
+	if len(customers) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &customers[0], nil
 }
 
+// placeholders returns "$from, $from+1, ..." for n query arguments.
+func placeholders(from, n int) string {
+	p := make([]string, n)
+	for i := range p {
+		p[i] = fmt.Sprintf("$%d", from+i)
+	}
+	return strings.Join(p, ", ")
+}
+
+func (r customerRepository) GetAllCustomers(ctx context.Context) ([]models.Customer, error) {
+	return r.queryCustomers(ctx, "SELECT "+customerColumns+" FROM customers")
+}
+
+func (r customerRepository) ListCustomers(ctx context.Context, opts ListOptions) ([]models.Customer, error) {
+	return r.queryCustomers(ctx,
+		"SELECT "+customerColumns+" FROM customers WHERE id > $1 ORDER BY id LIMIT $2",
+		opts.After, opts.Limit)
+}
+
+func (r customerRepository) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	return r.getCustomer(ctx, "SELECT "+customerColumns+" FROM customers WHERE id = $1", customerID)
+}
+
+func (r customerRepository) GetCustomersByIDs(ctx context.Context, customerIDs []uuid.UUID) ([]models.Customer, error) {
+	if len(customerIDs) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(customerIDs))
+	for i, id := range customerIDs {
+		args[i] = id
+	}
+	return r.queryCustomers(ctx,
+		"SELECT "+customerColumns+" FROM customers WHERE id IN ("+placeholders(1, len(args))+")", args...)
+}
+
+// GetCustomerByEmail finds the customer owning email, which may be any of its email addresses.
 func (r customerRepository) GetCustomerByEmail(ctx context.Context, email string) (*models.Customer, error) {
-	var c models.Customer
-	query := "SELECT * FROM customers WHERE email = $1"
-	err := r.db.QueryRow(query, email).Scan(&c.ID, &c.FirstName, &c.MiddleName, &c.LastName, &c.Email, &c.PhoneNumber)
+	var args []any
+	query := "SELECT " + customerColumns + " FROM customers WHERE " + r.fields.equals("email", email, &args) +
+		" OR id = (SELECT customer_id FROM customer_emails WHERE " + r.fields.equals("email", email, &args) + ")"
+	return r.getCustomer(ctx, query, args...)
+}
+
+// GetCustomersByEmails returns the customers owning emails keyed by the email they were found by.
+func (r customerRepository) GetCustomersByEmails(ctx context.Context, emails []string) (map[string]models.Customer, error) {
+	out := make(map[string]models.Customer, len(emails))
+	if len(emails) == 0 {
+		return out, nil
+	}
+	var args []any
+	conditions := make([]string, len(emails))
+	for i, email := range emails {
+		conditions[i] = r.fields.equals("email", email, &args)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT email, customer_id FROM customer_emails WHERE "+strings.Join(conditions, " OR "), args...)
 	if err != nil {
 		return nil, err
 	}
-	return &c, nil
+	defer rows.Close()
+
+	owners := map[string]uuid.UUID{}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var email string
+		var id uuid.UUID
+		if err := rows.Scan(&email, &id); err != nil {
+			return nil, fmt.Errorf("error scanning email rows: %w", err)
+		}
+		if email, err = r.fields.decrypt(ctx, email); err != nil {
+			return nil, fmt.Errorf("error decrypting email of customer %s: %w", id, err)
+		}
+		owners[email] = id
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	customers, err := r.GetCustomersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Customer, len(customers))
+	for _, c := range customers {
+		byID[c.ID] = c
+	}
+	for email, id := range owners {
+		if c, ok := byID[id]; ok {
+			out[email] = c
+		}
+	}
+	return out, nil
 }
 
-func (r customerRepository) CreateCustomer(ctx context.Context, customer models.Customer) error {
-	_, err := r.db.Exec(
-		`INSERT INTO customers (id, first_name, middle_name, last_name, email, phone_number)
-     VALUES ($1, $2, $3, $4, $5, $6)`,
-		customer.ID, customer.FirstName, customer.MiddleName, customer.LastName, customer.Email, customer.PhoneNumber)
+// CreateCustomer stores the customer together with its email and phone number as primary contact points.
+// Customers without a status start with models.InitialStatus.
+func (r customerRepository) CreateCustomer(ctx context.Context, customer *models.Customer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	attributes, err := encodeAttributes(customer.Attributes)
+	if err != nil {
+		return err
+	}
+
+	status := customer.Status
+	if status == "" {
+		status = models.InitialStatus
+	}
+
+	email, emailIndex, err := r.fields.stored(ctx, customer.Email)
+	if err != nil {
+		return err
+	}
+	phone, phoneIndex, err := r.fields.stored(ctx, customer.PhoneNumber)
+	if err != nil {
+		return err
+	}
 
+	at, by := now(), actor.FromContext(ctx)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO customers (id, first_name, middle_name, last_name, email, phone_number, status, attributes,
+                            created_at, updated_at, created_by, updated_by, email_blind_index, phone_number_blind_index)
+     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9, $10, $10, $11, $12)`,
+		customer.ID, customer.FirstName, customer.MiddleName, customer.LastName, email, phone, status,
+		attributes, at, by, emailIndex, phoneIndex)
 	if err != nil {
-		return fmt.Errorf("error inserting customer rows: %w", err)
+		return fmt.Errorf("error inserting customer rows: %w", duplicate(err))
+	}
+
+	if err := syncPrimaryContacts(ctx, tx, r.fields, *customer); err != nil {
+		return err
 	}
+	err = insertStatusChange(ctx, tx, models.StatusChange{
+		ID:         uuid.New(),
+		CustomerID: customer.ID,
+		To:         status,
+		Reason:     "Customer created",
+		ChangedAt:  at,
+		ChangedBy:  by,
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	customer.Status = status
+	customer.CreatedAt, customer.UpdatedAt = at, at
+	customer.CreatedBy, customer.UpdatedBy = by, by
 	return nil
 }
 
-func (r customerRepository) UpdateCustomer(ctx context.Context, customer models.Customer) error {
-	_, err := r.db.Exec(
-		`UPDATE customers SET first_name=$1, middle_name=$2, last_name=$3, email=$4, phone_number=$5
-         WHERE id=$6`,
-		customer.FirstName, customer.MiddleName, customer.LastName, customer.Email, customer.PhoneNumber, customer.ID)
+// UpdateCustomer replaces the customer, keeping its status, and its attributes when they are nil. A changed email
+// or phone number replaces the primary contact point, which then has to be verified again.
+func (r customerRepository) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	// NULL keeps the stored attributes.
+	var attributes any
+	if customer.Attributes != nil {
+		encoded, err := encodeAttributes(customer.Attributes)
+		if err != nil {
+			return err
+		}
+		attributes = encoded
+	}
+
+	email, emailIndex, err := r.fields.stored(ctx, customer.Email)
 	if err != nil {
-		return fmt.Errorf("error updating customer: %w", err)
+		return err
+	}
+	phone, phoneIndex, err := r.fields.stored(ctx, customer.PhoneNumber)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE customers SET first_name=$1, middle_name=$2, last_name=$3, email=$4, phone_number=$5,
+         attributes=COALESCE($6, attributes), updated_at=$7, updated_by=$8, email_blind_index=$9,
+         phone_number_blind_index=$10
+         WHERE id=$11`,
+		customer.FirstName, customer.MiddleName, customer.LastName, email, phone, attributes,
+		now(), actor.FromContext(ctx), emailIndex, phoneIndex, customer.ID)
+	if err != nil {
+		return fmt.Errorf("error updating customer: %w", duplicate(err))
 	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return nil
+	}
+
+	if err := syncPrimaryContacts(ctx, tx, r.fields, *customer); err != nil {
+		return err
+	}
+	stored, err := r.scan(ctx, tx.QueryRowContext(ctx, "SELECT "+customerColumns+" FROM customers WHERE id = $1", customer.ID))
+	if err != nil {
+		return fmt.Errorf("error reading updated customer: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*customer = stored
 	return nil
 }
 
 func (r customerRepository) DeleteCustomer(ctx context.Context, customerID uuid.UUID) error {
-	_, err := r.db.Exec("DELETE FROM customers WHERE id=$1", customerID)
+	_, err := r.db.ExecContext(ctx, "DELETE FROM customers WHERE id=$1", customerID)
 	if err != nil {
 		return fmt.Errorf("error deleting customer: %w", err)
 	}
 	return nil
 }
 
-func NewCustomerRepository(db *sql.DB) CustomerRepository {
-	return &customerRepository{db: db}
+// MarkEmailVerified sets the verified flag of the primary email and touches the customer, whose
+// email_verified changes.
+func (r customerRepository) MarkEmailVerified(ctx context.Context, customerID uuid.UUID, email string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	args := []any{customerID}
+	res, err := tx.ExecContext(ctx,
+		"UPDATE customer_emails SET verified = TRUE WHERE customer_id = $1 AND is_primary AND "+r.fields.equals("email", email, &args),
+		args...)
+	if err != nil {
+		return fmt.Errorf("error verifying email: %w", err)
+	}
+	if err := expectAffected(res); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE customers SET updated_at = $1, updated_by = $2 WHERE id = $3",
+		now(), actor.FromContext(ctx), customerID)
+	if err != nil {
+		return fmt.Errorf("error updating customer: %w", err)
+	}
+	return tx.Commit()
+}
+
+func NewCustomerRepository(db *sql.DB, opts ...Option) CustomerRepository {
+	o := applyOptions(opts)
+	return &customerRepository{db: conn{db: db, replica: o.replica}, dialect: dialectOf(db), fields: fields{cipher: o.cipher}}
 }
 
 func GetDB(isLocalDb bool, connStrEnvVar string) (*sql.DB, error) {
@@ -108,3 +409,37 @@ func GetDB(isLocalDb bool, connStrEnvVar string) (*sql.DB, error) {
 		return sql.Open("postgres", connStr)
 	}
 }
+
+// Open connects to the database described by cfg and applies its connection pool settings.
+func Open(cfg config.DatabaseConfig) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	if cfg.Local {
+		db, err = utils.GetLocalDB()
+	} else {
+		db, err = sql.Open("postgres", cfg.DSN)
+	}
+	if err != nil {
+		return nil, err
+	}
+	setPool(db, cfg.MaxOpenConns, cfg.MaxIdleConns, cfg)
+	return db, nil
+}
+
+// OpenReplica connects to the read replica of cfg, with its own connection limits and the
+// lifetimes of the primary.
+func OpenReplica(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.ReplicaDSN)
+	if err != nil {
+		return nil, err
+	}
+	setPool(db, cfg.ReplicaMaxOpenConns, cfg.ReplicaMaxIdleConns, cfg)
+	return db, nil
+}
+
+func setPool(db *sql.DB, maxOpen, maxIdle int, cfg config.DatabaseConfig) {
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
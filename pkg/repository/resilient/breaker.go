@@ -0,0 +1,135 @@
+package resilient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets every call through.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails every call fast.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single call through, which closes the breaker when it succeeds and
+	// opens it again when it fails.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerOptions configure a Breaker.
+type BreakerOptions struct {
+	// Failures is the number of consecutive failed calls that opens the breaker.
+	Failures int
+	// OpenFor is how long the breaker stays open before letting a call through again.
+	OpenFor time.Duration
+}
+
+// Breaker is a circuit breaker. It opens after sustained transient failures of the database, so
+// that callers fail fast with ErrCircuitOpen instead of waiting for the database to time out.
+type Breaker struct {
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	opened   time.Time
+	// probing is set while the call of the half-open breaker is in flight.
+	probing bool
+
+	opens, rejected uint64
+}
+
+func NewBreaker(opts BreakerOptions) *Breaker {
+	return &Breaker{opts: opts, now: time.Now, state: BreakerClosed}
+}
+
+// outcome is how a call affects the breaker.
+type outcome int
+
+const (
+	succeeded outcome = iota
+	failed
+	// abandoned calls ended because their caller went away, which tells nothing of the database.
+	abandoned
+)
+
+// allow reports whether a call may go through, which has to report its outcome with done.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && !b.now().Before(b.opened.Add(b.opts.OpenFor)) {
+		b.state = BreakerHalfOpen
+	}
+	if b.state == BreakerOpen || (b.state == BreakerHalfOpen && b.probing) {
+		b.rejected++
+		return ErrCircuitOpen
+	}
+	if b.state == BreakerHalfOpen {
+		b.probing = true
+	}
+	return nil
+}
+
+func (b *Breaker) done(o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	probe := b.state == BreakerHalfOpen && b.probing
+	if probe {
+		b.probing = false
+	}
+	switch o {
+	case succeeded:
+		if b.state != BreakerClosed {
+			log.Info("database circuit breaker closed")
+		}
+		b.state, b.failures = BreakerClosed, 0
+	case failed:
+		b.failures++
+		if probe || (b.state == BreakerClosed && b.failures >= b.opts.Failures) {
+			if b.state == BreakerClosed {
+				log.Warnf("database circuit breaker opened after %d failed calls", b.failures)
+			}
+			b.state, b.opened = BreakerOpen, b.now()
+			b.opens++
+		}
+	}
+}
+
+// State returns the state of the breaker, which turns half-open once it was open for OpenFor.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && !b.now().Before(b.opened.Add(b.opts.OpenFor)) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// RetryAfter returns how long the breaker stays open, zero when it is not open.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerOpen {
+		return 0
+	}
+	return max(b.opened.Add(b.opts.OpenFor).Sub(b.now()), 0)
+}
+
+// Check is a readiness check failing while the breaker is open.
+func (b *Breaker) Check(ctx context.Context) error {
+	if retryAfter := b.RetryAfter(); retryAfter > 0 {
+		return fmt.Errorf("%w, retrying in %s", ErrCircuitOpen, retryAfter.Round(time.Second))
+	}
+	return nil
+}
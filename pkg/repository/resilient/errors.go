@@ -0,0 +1,74 @@
+package resilient
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrCircuitOpen is returned without calling the database while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+// Postgres error codes of failures that go away when retried.
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	queryCanceled        = "57014"
+	adminShutdown        = "57P01"
+	crashShutdown        = "57P02"
+	cannotConnectNow     = "57P03"
+	tooManyConnections   = "53300"
+	// Class 08 are connection exceptions.
+	connectionException = "08"
+)
+
+// Transient reports whether err is a failure of the database that may go away when the call is
+// made again, such as a dropped connection or a serialization failure. Errors of the call itself,
+// such as sql.ErrNoRows or a constraint violation, are not.
+func Transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if RolledBack(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case queryCanceled, adminShutdown, crashShutdown, cannotConnectNow, tooManyConnections:
+			return true
+		}
+		return pqErr.Code.Class() == connectionException
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// RolledBack reports whether err is a transient failure that certainly left the database
+// unchanged, so that even writes can be made again: a serialization failure or deadlock, a
+// connection that was found broken before the statement was sent, or a locked SQLite database.
+func RolledBack(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package resilient
+
+import (
+	"context"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+)
+
+// Customers returns customers with its calls made by the guard. Reads are retried on every
+// transient failure, writes only on the ones that left the database unchanged, since a write whose
+// connection dropped may have been committed.
+func (g *Guard) Customers(customers repository.CustomerRepository) repository.CustomerRepository {
+	return guardedCustomers{repo: customers, guard: g}
+}
+
+// UnitOfWork returns uow with the customer reads of its repositories made by the guard, and its
+// transactions run again as a whole when they fail with an error that rolled them back. The calls
+// within a transaction are not retried on their own, as a failed statement aborts the transaction.
+func (g *Guard) UnitOfWork(uow repository.UnitOfWork) repository.UnitOfWork {
+	return unitOfWork{UnitOfWork: uow, guard: g}
+}
+
+type unitOfWork struct {
+	repository.UnitOfWork
+	guard *Guard
+}
+
+func (u unitOfWork) Repositories() repository.Repositories {
+	repos := u.UnitOfWork.Repositories()
+	repos.Customers = u.guard.Customers(repos.Customers)
+	return repos
+}
+
+func (u unitOfWork) Do(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return u.guard.do(ctx, RolledBack, func(ctx context.Context) error {
+		return u.UnitOfWork.Do(ctx, fn)
+	})
+}
+
+type guardedCustomers struct {
+	repo  repository.CustomerRepository
+	guard *Guard
+}
+
+func (r guardedCustomers) GetAllCustomers(ctx context.Context) ([]models.Customer, error) {
+	return call(r.guard, ctx, Transient, func(ctx context.Context) ([]models.Customer, error) {
+		return r.repo.GetAllCustomers(ctx)
+	})
+}
+
+func (r guardedCustomers) ListCustomers(ctx context.Context, opts repository.ListOptions) ([]models.Customer, error) {
+	return call(r.guard, ctx, Transient, func(ctx context.Context) ([]models.Customer, error) {
+		return r.repo.ListCustomers(ctx, opts)
+	})
+}
+
+func (r guardedCustomers) FindCustomers(ctx context.Context, query repository.CustomerQuery) ([]models.Customer, error) {
+	return call(r.guard, ctx, Transient, func(ctx context.Context) ([]models.Customer, error) {
+		return r.repo.FindCustomers(ctx, query)
+	})
+}
+
+func (r guardedCustomers) CountCustomers(ctx context.Context, query repository.CustomerQuery) (int, error) {
+	return call(r.guard, ctx, Transient, func(ctx context.Context) (int, error) {
+		return r.repo.CountCustomers(ctx, query)
+	})
+}
+
+func (r guardedCustomers) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	return call(r.guard, ctx, Transient, func(ctx context.Context) (*models.Customer, error) {
+		return r.repo.GetCustomerByID(ctx, customerID)
+	})
+}
+
+func (r guardedCustomers) GetCustomersByIDs(ctx context.Context, customerIDs []uuid.UUID) ([]models.Customer, error) {
+	return call(r.guard, ctx, Transient, func(ctx context.Context) ([]models.Customer, error) {
+		return r.repo.GetCustomersByIDs(ctx, customerIDs)
+	})
+}
+
+func (r guardedCustomers) GetCustomerByEmail(ctx context.Context, email string) (*models.Customer, error) {
+	return call(r.guard, ctx, Transient, func(ctx context.Context) (*models.Customer, error) {
+		return r.repo.GetCustomerByEmail(ctx, email)
+	})
+}
+
+func (r guardedCustomers) GetCustomersByEmails(ctx context.Context, emails []string) (map[string]models.Customer, error) {
+	return call(r.guard, ctx, Transient, func(ctx context.Context) (map[string]models.Customer, error) {
+		return r.repo.GetCustomersByEmails(ctx, emails)
+	})
+}
+
+func (r guardedCustomers) CreateCustomer(ctx context.Context, customer *models.Customer) error {
+	return r.guard.do(ctx, RolledBack, func(ctx context.Context) error {
+		return r.repo.CreateCustomer(ctx, customer)
+	})
+}
+
+func (r guardedCustomers) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	return r.guard.do(ctx, RolledBack, func(ctx context.Context) error {
+		return r.repo.UpdateCustomer(ctx, customer)
+	})
+}
+
+func (r guardedCustomers) DeleteCustomer(ctx context.Context, customerID uuid.UUID) error {
+	return r.guard.do(ctx, RolledBack, func(ctx context.Context) error {
+		return r.repo.DeleteCustomer(ctx, customerID)
+	})
+}
+
+func (r guardedCustomers) MarkEmailVerified(ctx context.Context, customerID uuid.UUID, email string) error {
+	return r.guard.do(ctx, RolledBack, func(ctx context.Context) error {
+		return r.repo.MarkEmailVerified(ctx, customerID, email)
+	})
+}
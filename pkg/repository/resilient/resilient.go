@@ -0,0 +1,133 @@
+// Package resilient retries the database calls of the repositories that fail transiently, bounds
+// each call with a timeout, and fails them fast through a circuit breaker while the database keeps
+// failing.
+package resilient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Policy configures how calls are retried.
+type Policy struct {
+	// Attempts is the number of times a call is made at most, one disabling retries.
+	Attempts int
+	// BaseDelay is the longest wait before the first retry, doubling with every further retry up
+	// to MaxDelay. The waits are jittered between zero and that bound.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// CallTimeout bounds every attempt of a call, zero leaving it to the caller's deadline.
+	CallTimeout time.Duration
+}
+
+// Stats describe the circuit breaker and count the retries since the guard was created.
+type Stats struct {
+	State BreakerState `json:"state"`
+	// ConsecutiveFailures are the failed calls since the last successful one.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// Opens is how often the breaker opened, Rejected how many calls it failed fast.
+	Opens    uint64 `json:"opens"`
+	Rejected uint64 `json:"rejected"`
+	Retries  uint64 `json:"retries"`
+}
+
+// Guard makes calls to the database according to its policy and circuit breaker.
+type Guard struct {
+	policy  Policy
+	breaker *Breaker
+	// jitter returns a random duration in [0, n).
+	jitter func(n int64) int64
+
+	retries atomic.Uint64
+}
+
+func New(policy Policy, breaker *Breaker) *Guard {
+	return &Guard{policy: policy, breaker: breaker, jitter: rand.Int64N}
+}
+
+// Breaker returns the circuit breaker of the guard.
+func (g *Guard) Breaker() *Breaker {
+	return g.breaker
+}
+
+func (g *Guard) Stats() Stats {
+	b := g.breaker
+	state := b.State()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{
+		State:               state,
+		ConsecutiveFailures: b.failures,
+		Opens:               b.opens,
+		Rejected:            b.rejected,
+		Retries:             g.retries.Load(),
+	}
+}
+
+// do calls fn until it succeeds, fails with an error retry rejects, or runs out of attempts. It
+// does not retry when the wait would outlast the deadline of ctx. Transient failures count
+// against the circuit breaker, which fails the call with ErrCircuitOpen while it is open.
+func (g *Guard) do(ctx context.Context, retry func(error) bool, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		if err := g.breaker.allow(); err != nil {
+			return err
+		}
+
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if g.policy.CallTimeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, g.policy.CallTimeout)
+		}
+		err := fn(callCtx)
+		cancel()
+
+		switch {
+		case err != nil && ctx.Err() != nil:
+			g.breaker.done(abandoned)
+			return err
+		case Transient(err):
+			g.breaker.done(failed)
+		default:
+			g.breaker.done(succeeded)
+		}
+		if err == nil || !retry(err) || attempt >= g.policy.Attempts {
+			return err
+		}
+
+		wait := g.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+		g.retries.Add(1)
+	}
+}
+
+// backoff returns the jittered wait before the retry following attempt.
+func (g *Guard) backoff(attempt int) time.Duration {
+	bound := g.policy.BaseDelay << (attempt - 1)
+	if bound > g.policy.MaxDelay || bound <= 0 {
+		bound = g.policy.MaxDelay
+	}
+	if bound <= 0 {
+		return 0
+	}
+	return time.Duration(g.jitter(int64(bound)))
+}
+
+// call is do for calls returning a value.
+func call[T any](g *Guard, ctx context.Context, retry func(error) bool, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := g.do(ctx, retry, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,211 @@
+package resilient
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	connectionFailure = &pq.Error{Code: "08006", Message: "connection failure"}
+	serialization     = &pq.Error{Code: "40001", Message: "could not serialize access"}
+)
+
+func newTestGuard(policy Policy, breaker BreakerOptions) (*Guard, *time.Time) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	g := New(policy, NewBreaker(breaker))
+	g.jitter = func(n int64) int64 { return 0 }
+	g.breaker.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		err                   error
+		transient, rolledBack bool
+	}{
+		{nil, false, false},
+		{sql.ErrNoRows, false, false},
+		{repository.ErrDuplicate, false, false},
+		{&pq.Error{Code: "23505"}, false, false},
+		{context.Canceled, false, false},
+		{fmt.Errorf("error creating customer: %w", serialization), true, true},
+		{&pq.Error{Code: "40P01"}, true, true},
+		{driver.ErrBadConn, true, true},
+		{sqlite3.Error{Code: sqlite3.ErrBusy}, true, true},
+		{connectionFailure, true, false},
+		{&pq.Error{Code: "57P01"}, true, false},
+		{io.ErrUnexpectedEOF, true, false},
+		{context.DeadlineExceeded, true, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.transient, Transient(tt.err), "Transient(%v)", tt.err)
+		assert.Equal(t, tt.rolledBack, RolledBack(tt.err), "RolledBack(%v)", tt.err)
+	}
+}
+
+func TestGuard_RetriesReads(t *testing.T) {
+	g, _ := newTestGuard(Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}, BreakerOptions{Failures: 10, OpenFor: time.Minute})
+	repo := &mocks.CustomerRepository{}
+	id := uuid.New()
+	repo.On("GetCustomerByID", mock.Anything, id).Return(nil, connectionFailure).Twice()
+	repo.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id}, nil).Once()
+
+	customer, err := g.Customers(repo).GetCustomerByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, id, customer.ID)
+	assert.Equal(t, uint64(2), g.Stats().Retries)
+	assert.Equal(t, 0, g.Stats().ConsecutiveFailures)
+	repo.AssertExpectations(t)
+}
+
+func TestGuard_RetriesWritesThatRolledBack(t *testing.T) {
+	g, _ := newTestGuard(Policy{Attempts: 3}, BreakerOptions{Failures: 10, OpenFor: time.Minute})
+	repo := &mocks.CustomerRepository{}
+	customers := g.Customers(repo)
+	ctx := context.Background()
+
+	// The connection dropped, the customer may have been created.
+	repo.On("CreateCustomer", mock.Anything, mock.Anything).Return(io.ErrUnexpectedEOF).Once()
+	assert.ErrorIs(t, customers.CreateCustomer(ctx, &models.Customer{}), io.ErrUnexpectedEOF)
+
+	repo.On("UpdateCustomer", mock.Anything, mock.Anything).Return(serialization).Once()
+	repo.On("UpdateCustomer", mock.Anything, mock.Anything).Return(nil).Once()
+	assert.NoError(t, customers.UpdateCustomer(ctx, &models.Customer{}))
+
+	repo.On("DeleteCustomer", mock.Anything, mock.Anything).Return(sql.ErrNoRows).Once()
+	assert.ErrorIs(t, customers.DeleteCustomer(ctx, uuid.New()), sql.ErrNoRows)
+	repo.AssertExpectations(t)
+}
+
+func TestGuard_CallTimeout(t *testing.T) {
+	g, _ := newTestGuard(Policy{Attempts: 2, CallTimeout: 10 * time.Millisecond}, BreakerOptions{Failures: 10, OpenFor: time.Minute})
+	calls := 0
+	err := g.do(context.Background(), Transient, func(ctx context.Context) error {
+		calls++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, calls)
+}
+
+func TestGuard_StopsAtDeadline(t *testing.T) {
+	g, _ := newTestGuard(Policy{Attempts: 5, BaseDelay: time.Second, MaxDelay: time.Second}, BreakerOptions{Failures: 10, OpenFor: time.Minute})
+	g.jitter = func(n int64) int64 { return n - 1 }
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	err := g.do(ctx, Transient, func(ctx context.Context) error {
+		calls++
+		return connectionFailure
+	})
+	assert.ErrorIs(t, err, connectionFailure)
+	assert.Equal(t, 1, calls, "the wait would outlast the deadline")
+}
+
+func TestBreaker(t *testing.T) {
+	g, now := newTestGuard(Policy{Attempts: 1}, BreakerOptions{Failures: 2, OpenFor: time.Minute})
+	ctx := context.Background()
+	calls := 0
+	fail := func(ctx context.Context) error { calls++; return connectionFailure }
+	succeed := func(ctx context.Context) error { calls++; return nil }
+	notFound := func(ctx context.Context) error { calls++; return sql.ErrNoRows }
+
+	g.do(ctx, Transient, fail)
+	g.do(ctx, Transient, notFound)
+	assert.Equal(t, BreakerClosed, g.Breaker().State(), "only consecutive transient failures count")
+
+	g.do(ctx, Transient, fail)
+	g.do(ctx, Transient, fail)
+	assert.Equal(t, BreakerOpen, g.Breaker().State())
+	assert.Error(t, g.Breaker().Check(ctx))
+	assert.Equal(t, time.Minute, g.Breaker().RetryAfter())
+
+	assert.ErrorIs(t, g.do(ctx, Transient, succeed), ErrCircuitOpen)
+	assert.Equal(t, 4, calls, "calls fail fast while the breaker is open")
+
+	*now = now.Add(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, g.Breaker().State())
+	assert.NoError(t, g.Breaker().Check(ctx))
+	assert.ErrorIs(t, g.do(ctx, Transient, fail), connectionFailure)
+	assert.Equal(t, BreakerOpen, g.Breaker().State(), "a failed probe opens the breaker again")
+
+	*now = now.Add(time.Minute)
+	assert.NoError(t, g.do(ctx, Transient, succeed))
+	assert.Equal(t, BreakerClosed, g.Breaker().State())
+	assert.Equal(t, Stats{State: BreakerClosed, Opens: 2, Rejected: 1}, g.Stats())
+}
+
+func TestBreaker_HalfOpenLetsOneCallThrough(t *testing.T) {
+	b := NewBreaker(BreakerOptions{Failures: 1, OpenFor: time.Minute})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	require.NoError(t, b.allow())
+	b.done(failed)
+	now = now.Add(time.Minute)
+
+	require.NoError(t, b.allow())
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen, "the probe is in flight")
+	b.done(abandoned)
+	assert.NoError(t, b.allow(), "an abandoned probe lets another call through")
+}
+
+// flakyUnitOfWork fails its first transactions with err.
+type flakyUnitOfWork struct {
+	repository.UnitOfWork
+	failures int
+	err      error
+	runs     int
+}
+
+func (u *flakyUnitOfWork) Do(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	u.runs++
+	if u.runs <= u.failures {
+		return u.err
+	}
+	return fn(repository.Repositories{})
+}
+
+func TestUnitOfWork_RetriesTransactions(t *testing.T) {
+	g, _ := newTestGuard(Policy{Attempts: 3}, BreakerOptions{Failures: 10, OpenFor: time.Minute})
+	ctx := context.Background()
+
+	flaky := &flakyUnitOfWork{failures: 2, err: serialization}
+	fnRuns := 0
+	err := g.UnitOfWork(flaky).Do(ctx, func(repos repository.Repositories) error {
+		fnRuns++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, flaky.runs)
+	assert.Equal(t, 1, fnRuns)
+
+	flaky = &flakyUnitOfWork{failures: 1, err: connectionFailure}
+	err = g.UnitOfWork(flaky).Do(ctx, func(repos repository.Repositories) error { return nil })
+	assert.ErrorIs(t, err, connectionFailure, "the transaction may have been committed")
+	assert.Equal(t, 1, flaky.runs)
+
+	flaky = &flakyUnitOfWork{}
+	err = g.UnitOfWork(flaky).Do(ctx, func(repos repository.Repositories) error { return errors.New("invalid") })
+	assert.EqualError(t, err, "invalid")
+	assert.Equal(t, 1, flaky.runs)
+}
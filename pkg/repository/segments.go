@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/segment"
+
+	"github.com/google/uuid"
+)
+
+// SegmentRepository stores the saved segments. Their members are found with CustomerRepository.FindCustomers.
+type SegmentRepository interface {
+	ListSegments(ctx context.Context) ([]models.Segment, error)
+	GetSegment(ctx context.Context, segmentID uuid.UUID) (*models.Segment, error)
+	CreateSegment(ctx context.Context, segment models.Segment) error
+	UpdateSegment(ctx context.Context, segment models.Segment) error
+	DeleteSegment(ctx context.Context, segmentID uuid.UUID) error
+}
+
+type segmentRepository struct {
+	db *sql.DB
+}
+
+func NewSegmentRepository(db *sql.DB) SegmentRepository {
+	return &segmentRepository{db: db}
+}
+
+const segmentColumns = "id, name, description, filter"
+
+func scanSegment(row rowScanner) (models.Segment, error) {
+	var s models.Segment
+	var description sql.NullString
+	err := row.Scan(&s.ID, &s.Name, &description, &s.Filter)
+	s.Description = description.String
+	return s, err
+}
+
+func (r segmentRepository) ListSegments(ctx context.Context) ([]models.Segment, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+segmentColumns+" FROM segments ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segments []models.Segment
+	for rows.Next() {
+		s, err := scanSegment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning segment rows: %w", err)
+		}
+		segments = append(segments, s)
+	}
+	return segments, rows.Err()
+}
+
+func (r segmentRepository) GetSegment(ctx context.Context, segmentID uuid.UUID) (*models.Segment, error) {
+	s, err := scanSegment(r.db.QueryRowContext(ctx, "SELECT "+segmentColumns+" FROM segments WHERE id = $1", segmentID))
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r segmentRepository) CreateSegment(ctx context.Context, s models.Segment) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO segments ("+segmentColumns+") VALUES ($1, $2, $3, $4)",
+		s.ID, s.Name, s.Description, s.Filter)
+	if err != nil {
+		return fmt.Errorf("error inserting segment: %w", err)
+	}
+	return nil
+}
+
+func (r segmentRepository) UpdateSegment(ctx context.Context, s models.Segment) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE segments SET name=$1, description=$2, filter=$3 WHERE id=$4",
+		s.Name, s.Description, s.Filter, s.ID)
+	if err != nil {
+		return fmt.Errorf("error updating segment: %w", err)
+	}
+	return expectAffected(res)
+}
+
+func (r segmentRepository) DeleteSegment(ctx context.Context, segmentID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM segments WHERE id=$1", segmentID)
+	if err != nil {
+		return fmt.Errorf("error deleting segment: %w", err)
+	}
+	return expectAffected(res)
+}
+
+// segmentColumnFields maps the segment fields stored on the customer row to their column.
+var segmentColumnFields = map[string]string{
+	"first_name":   "first_name",
+	"middle_name":  "middle_name",
+	"last_name":    "last_name",
+	"email":        "email",
+	"phone_number": "phone_number",
+	"status":       "status",
+}
+
+// encryptedSegmentFields are the segment fields whose column may be encrypted.
+var encryptedSegmentFields = map[string]bool{"email": true, "phone_number": true}
+
+// segmentCondition translates a parsed segment filter into a condition on the customers table,
+// appending the values it compares to args. Encrypted fields are compared through f.
+func segmentCondition(expr segment.Expr, f fields, args *[]any) (string, error) {
+	bind := func(v any) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	switch e := expr.(type) {
+	case segment.And:
+		return binaryCondition(e.Left, "AND", e.Right, f, args)
+	case segment.Or:
+		return binaryCondition(e.Left, "OR", e.Right, f, args)
+	case segment.Not:
+		inner, err := segmentCondition(e.Expr, f, args)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + inner, nil
+	case segment.HasTag:
+		return "EXISTS (SELECT 1 FROM customer_tags t WHERE t.customer_id = customers.id AND t.tag = " + bind(e.Tag) + ")", nil
+	case segment.Compare:
+		if e.Op != segment.Equal && e.Op != segment.NotEqual {
+			return "", fmt.Errorf("unknown segment operator %q", e.Op)
+		}
+		if e.Field == "country" {
+			exists := "EXISTS (SELECT 1 FROM customer_addresses a WHERE a.customer_id = customers.id AND a.country = " +
+				bind(strings.ToUpper(e.Value)) + ")"
+			if e.Op == segment.NotEqual {
+				return "NOT " + exists, nil
+			}
+			return exists, nil
+		}
+		column, ok := segmentColumnFields[e.Field]
+		if !ok {
+			return "", fmt.Errorf("unknown segment field %q", e.Field)
+		}
+		if encryptedSegmentFields[e.Field] {
+			condition := f.matches(column, e.Value, args)
+			if e.Op == segment.NotEqual {
+				return "NOT " + condition, nil
+			}
+			return condition, nil
+		}
+		op := "="
+		if e.Op == segment.NotEqual {
+			op = "<>"
+		}
+		// Missing optional fields compare as empty strings, so that middle_name != "x" matches them.
+		return "COALESCE(" + column + ", '') " + op + " " + bind(e.Value), nil
+	}
+	return "", fmt.Errorf("unknown segment expression %T", expr)
+}
+
+func binaryCondition(left segment.Expr, keyword string, right segment.Expr, f fields, args *[]any) (string, error) {
+	l, err := segmentCondition(left, f, args)
+	if err != nil {
+		return "", err
+	}
+	r, err := segmentCondition(right, f, args)
+	if err != nil {
+		return "", err
+	}
+	return "(" + l + " " + keyword + " " + r + ")", nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// SessionRepository stores the magic links and sessions of the customer self-service portal. Both
+// are looked up by the hash of their token, which callers compute.
+type SessionRepository interface {
+	// CreateLoginToken stores the token of a magic link.
+	CreateLoginToken(ctx context.Context, token models.LoginToken) error
+	// CountLoginTokens returns how many magic links were created for the customer since the given time.
+	CountLoginTokens(ctx context.Context, customerID uuid.UUID, since time.Time) (int, error)
+	// ConsumeLoginToken marks the token with the hash as used and returns it. It fails with
+	// sql.ErrNoRows when there is no such token, or when it expired or was already used.
+	ConsumeLoginToken(ctx context.Context, tokenHash string) (*models.LoginToken, error)
+
+	// CreateSession stores session and removes the expired sessions and magic links of its customer.
+	CreateSession(ctx context.Context, session models.PortalSession) error
+	// GetSession returns the session with the token hash, sql.ErrNoRows when there is none or it expired.
+	GetSession(ctx context.Context, tokenHash string) (*models.PortalSession, error)
+	// DeleteSession signs the session with the token hash out. Deleting a missing session succeeds.
+	DeleteSession(ctx context.Context, tokenHash string) error
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r sessionRepository) CreateLoginToken(ctx context.Context, token models.LoginToken) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO portal_login_tokens (id, customer_id, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		token.ID, token.CustomerID, token.TokenHash, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error inserting login token: %w", err)
+	}
+	return nil
+}
+
+func (r sessionRepository) CountLoginTokens(ctx context.Context, customerID uuid.UUID, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM portal_login_tokens WHERE customer_id = $1 AND created_at > $2", customerID, since).Scan(&n)
+	return n, err
+}
+
+func (r sessionRepository) ConsumeLoginToken(ctx context.Context, tokenHash string) (*models.LoginToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var t models.LoginToken
+	at := now()
+	err = tx.QueryRowContext(ctx,
+		"SELECT id, customer_id, token_hash, created_at, expires_at FROM portal_login_tokens "+
+			"WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2", tokenHash, at).
+		Scan(&t.ID, &t.CustomerID, &t.TokenHash, &t.CreatedAt, &t.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	// The condition on used_at keeps two requests racing for the same token from both using it.
+	res, err := tx.ExecContext(ctx, "UPDATE portal_login_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL", at, t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error using login token: %w", err)
+	}
+	if err := expectAffected(res); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	t.CreatedAt, t.ExpiresAt = t.CreatedAt.UTC(), t.ExpiresAt.UTC()
+	return &t, nil
+}
+
+func (r sessionRepository) CreateSession(ctx context.Context, session models.PortalSession) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO portal_sessions (id, customer_id, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		session.ID, session.CustomerID, session.TokenHash, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error inserting session: %w", err)
+	}
+	for _, stmt := range []string{
+		"DELETE FROM portal_sessions WHERE customer_id = $1 AND expires_at <= $2",
+		"DELETE FROM portal_login_tokens WHERE customer_id = $1 AND expires_at <= $2",
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, session.CustomerID, session.CreatedAt); err != nil {
+			return fmt.Errorf("error removing expired sessions: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r sessionRepository) GetSession(ctx context.Context, tokenHash string) (*models.PortalSession, error) {
+	var s models.PortalSession
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, customer_id, token_hash, created_at, expires_at FROM portal_sessions WHERE token_hash = $1 AND expires_at > $2",
+		tokenHash, now()).
+		Scan(&s.ID, &s.CustomerID, &s.TokenHash, &s.CreatedAt, &s.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	s.CreatedAt, s.ExpiresAt = s.CreatedAt.UTC(), s.ExpiresAt.UTC()
+	return &s, nil
+}
+
+func (r sessionRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM portal_sessions WHERE token_hash = $1", tokenHash); err != nil {
+		return fmt.Errorf("error deleting session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrStatusConflict is returned when the status of a customer changed since it was read.
+var ErrStatusConflict = errors.New("customer status changed concurrently")
+
+// StatusRepository stores the lifecycle status of customers and its history. It does not know
+// which transitions are allowed, the service layer does.
+type StatusRepository interface {
+	// ChangeStatus moves the customer from change.From to change.To and records change. It fails with
+	// sql.ErrNoRows when the customer does not exist and ErrStatusConflict when it is no longer in change.From.
+	ChangeStatus(ctx context.Context, change models.StatusChange) error
+	// ListStatusChanges returns the history of the customer, oldest first.
+	ListStatusChanges(ctx context.Context, customerID uuid.UUID) ([]models.StatusChange, error)
+}
+
+type statusRepository struct {
+	db conn
+}
+
+func NewStatusRepository(db *sql.DB) StatusRepository {
+	return &statusRepository{db: conn{db: db}}
+}
+
+const statusChangeColumns = "id, customer_id, from_status, to_status, reason, changed_at, changed_by"
+
+func insertStatusChange(ctx context.Context, tx executor, change models.StatusChange) error {
+	var from, by any
+	if change.From != "" {
+		from = change.From
+	}
+	if change.ChangedBy != "" {
+		by = change.ChangedBy
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO customer_status_changes ("+statusChangeColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		change.ID, change.CustomerID, from, change.To, change.Reason, change.ChangedAt, by)
+	if err != nil {
+		return fmt.Errorf("error recording status change: %w", err)
+	}
+	return nil
+}
+
+func (r statusRepository) ChangeStatus(ctx context.Context, change models.StatusChange) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE customers SET status = $1, updated_at = $2, updated_by = $3 WHERE id = $4 AND status = $5",
+		change.To, change.ChangedAt, change.ChangedBy, change.CustomerID, change.From)
+	if err != nil {
+		return fmt.Errorf("error changing status: %w", err)
+	}
+	if err := expectAffected(res); err != nil {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1)", change.CustomerID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrStatusConflict
+		}
+		return sql.ErrNoRows
+	}
+
+	if err := insertStatusChange(ctx, tx, change); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r statusRepository) ListStatusChanges(ctx context.Context, customerID uuid.UUID) ([]models.StatusChange, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+statusChangeColumns+" FROM customer_status_changes WHERE customer_id = $1 ORDER BY changed_at, id",
+		customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.StatusChange
+	for rows.Next() {
+		var c models.StatusChange
+		var from, by sql.NullString
+		if err := rows.Scan(&c.ID, &c.CustomerID, &from, &c.To, &c.Reason, &c.ChangedAt, &by); err != nil {
+			return nil, fmt.Errorf("error scanning status change rows: %w", err)
+		}
+		c.From = models.Status(from.String)
+		c.ChangedAt = c.ChangedAt.UTC()
+		c.ChangedBy = by.String
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
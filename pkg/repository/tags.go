@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// TagRepository stores the free-form tags of customers. Tags are expected to be normalized and validated.
+type TagRepository interface {
+	// ListTags returns every tag in use with the number of customers carrying it.
+	ListTags(ctx context.Context) ([]models.Tag, error)
+	ListCustomerTags(ctx context.Context, customerID uuid.UUID) ([]string, error)
+	ListTagsByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]string, error)
+	// AddTags tags every customer with every tag, keeping the tags they already have. It fails with
+	// sql.ErrNoRows and tags nobody when one of the customers does not exist.
+	AddTags(ctx context.Context, customerIDs []uuid.UUID, tags []string) error
+	// RemoveTags removes every tag from every customer, ignoring the tags a customer does not have.
+	RemoveTags(ctx context.Context, customerIDs []uuid.UUID, tags []string) error
+}
+
+type tagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r tagRepository) ListTags(ctx context.Context) ([]models.Tag, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT tag, COUNT(*) FROM customer_tags GROUP BY tag ORDER BY tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.Name, &t.Customers); err != nil {
+			return nil, fmt.Errorf("error scanning tag rows: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func (r tagRepository) ListCustomerTags(ctx context.Context, customerID uuid.UUID) ([]string, error) {
+	byCustomer, err := r.ListTagsByCustomerIDs(ctx, []uuid.UUID{customerID})
+	if err != nil {
+		return nil, err
+	}
+	return byCustomer[customerID], nil
+}
+
+func (r tagRepository) ListTagsByCustomerIDs(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	out := make(map[uuid.UUID][]string, len(customerIDs))
+	if len(customerIDs) == 0 {
+		return out, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT customer_id, tag FROM customer_tags WHERE customer_id IN ("+placeholders(1, len(customerIDs))+
+			") ORDER BY tag", idArgs(customerIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, fmt.Errorf("error scanning tag rows: %w", err)
+		}
+		out[id] = append(out[id], tag)
+	}
+	return out, rows.Err()
+}
+
+func (r tagRepository) AddTags(ctx context.Context, customerIDs []uuid.UUID, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range customerIDs {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1)", id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+
+		for _, tag := range tags {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO customer_tags (customer_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, tag)
+			if err != nil {
+				return fmt.Errorf("error tagging customer: %w", err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (r tagRepository) RemoveTags(ctx context.Context, customerIDs []uuid.UUID, tags []string) error {
+	if len(customerIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+	args := append(idArgs(customerIDs), stringArgs(tags)...)
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM customer_tags WHERE customer_id IN ("+placeholders(1, len(customerIDs))+
+			") AND tag IN ("+placeholders(len(customerIDs)+1, len(tags))+")", args...)
+	if err != nil {
+		return fmt.Errorf("error untagging customers: %w", err)
+	}
+	return nil
+}
+
+func stringArgs(values []string) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
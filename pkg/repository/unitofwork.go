@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Repositories are the repositories the service layer writes through. Audit, Attributes and
+// Privacy may be nil, the audit trail is then not written, custom attributes are not validated and
+// legal holds are neither checked nor available.
+type Repositories struct {
+	Customers  CustomerRepository
+	Statuses   StatusRepository
+	Audit      AuditRepository
+	Attributes AttributeRepository
+	Privacy    PrivacyRepository
+}
+
+// UnitOfWork groups writes to several repositories so that they succeed or fail together.
+type UnitOfWork interface {
+	// Repositories returns the repositories outside of any transaction, for reads.
+	Repositories() Repositories
+	// Do runs fn with repositories sharing a single transaction, which is committed when fn
+	// returns nil and rolled back otherwise.
+	Do(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// executor runs statements, either directly on the database or inside a transaction.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn is the database a repository runs on. Within a unit of work tx is set and every
+// statement, including the ones of transactions the repository starts itself, runs in it.
+// Outside of one, queries go to the replica when there is one.
+type conn struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	replica *Replica
+}
+
+func (c conn) executor() executor {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.db
+}
+
+// reader returns where a query made with ctx runs.
+func (c conn) reader(ctx context.Context) executor {
+	if c.tx == nil && c.replica != nil {
+		return c.replica.reader(ctx, c.db)
+	}
+	return c.executor()
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	wrote(ctx)
+	return c.executor().ExecContext(ctx, query, args...)
+}
+
+// QueryContext retries the queries that fail on the replica on the primary. The queries of
+// QueryRowContext fail when scanned, after which they cannot be retried, so frequent single-row
+// reads go through QueryContext.
+func (c conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	db := c.reader(ctx)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil && db != c.executor() && c.replica.failover(ctx, err) {
+		return c.db.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.reader(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction, or joins the one of the unit of work.
+func (c conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*txn, error) {
+	if c.tx != nil {
+		return &txn{Tx: c.tx, joined: true}, nil
+	}
+	wrote(ctx)
+	tx, err := c.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &txn{Tx: tx}, nil
+}
+
+// txn is a transaction started by a repository. A joined transaction belongs to a unit of work,
+// which alone commits or rolls it back.
+type txn struct {
+	*sql.Tx
+	joined bool
+}
+
+func (t *txn) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *txn) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+type sqlUnitOfWork struct {
+	db      *sql.DB
+	replica *Replica
+	dialect dialect
+	fields  fields
+}
+
+// NewUnitOfWork returns a unit of work running the customer, status, audit, attribute and privacy
+// repositories in database transactions.
+func NewUnitOfWork(db *sql.DB, opts ...Option) UnitOfWork {
+	o := applyOptions(opts)
+	return &sqlUnitOfWork{db: db, replica: o.replica, dialect: dialectOf(db), fields: fields{cipher: o.cipher}}
+}
+
+func (u sqlUnitOfWork) repositories(c conn) Repositories {
+	return Repositories{
+		Customers:  &customerRepository{db: c, dialect: u.dialect, fields: u.fields},
+		Statuses:   &statusRepository{db: c},
+		Audit:      &auditRepository{db: c, fields: u.fields},
+		Attributes: &attributeRepository{db: c, dialect: u.dialect},
+		Privacy:    &privacyRepository{db: c, fields: u.fields},
+	}
+}
+
+func (u sqlUnitOfWork) Repositories() Repositories {
+	return u.repositories(conn{db: u.db, replica: u.replica})
+}
+
+func (u sqlUnitOfWork) Do(ctx context.Context, fn func(repos Repositories) error) error {
+	wrote(ctx)
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(u.repositories(conn{db: u.db, tx: tx})); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Untransacted returns a unit of work that runs every function directly against repos. It suits
+// repositories without transactions, such as test doubles, where a failing function leaves the
+// writes it made so far in place.
+func Untransacted(repos Repositories) UnitOfWork {
+	return untransacted{repos: repos}
+}
+
+type untransacted struct {
+	repos Repositories
+}
+
+func (u untransacted) Repositories() Repositories {
+	return u.repos
+}
+
+func (u untransacted) Do(_ context.Context, fn func(repos Repositories) error) error {
+	return fn(u.repos)
+}
@@ -0,0 +1,112 @@
+package segment
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenTag
+	tokenString
+	tokenOp
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the byte offset of the token in the filter.
+	pos int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return fmt.Sprintf("%q", t.text)
+	case tokenTag:
+		return "tag:" + t.text
+	}
+	return t.text
+}
+
+func (t token) errorf(format string, args ...any) error {
+	return fmt.Errorf("at position %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func lex(filter string) ([]token, error) {
+	var tokens []token
+	runes := []rune(filter)
+	// offsets maps rune indexes to byte offsets for the error positions.
+	offsets := make([]int, 0, len(runes)+1)
+	for i := range filter {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(filter))
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := offsets[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenOpen, "(", start})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenClose, ")", start})
+			i++
+		case r == '=':
+			tokens = append(tokens, token{tokenOp, "=", start})
+			i++
+		case r == '!' && i+1 < len(runes) && runes[i+1] == '=':
+			tokens = append(tokens, token{tokenOp, "!=", start})
+			i += 2
+		case r == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("at position %d: unterminated string", start+1)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, b.String(), start})
+		case isWordChar(r):
+			j := i
+			for j < len(runes) && isWordChar(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			if strings.EqualFold(word, "tag") && j < len(runes) && runes[j] == ':' {
+				k := j + 1
+				for k < len(runes) && isWordChar(runes[k]) {
+					k++
+				}
+				tokens = append(tokens, token{tokenTag, string(runes[j+1 : k]), start})
+				i = k
+				continue
+			}
+			tokens = append(tokens, token{tokenWord, word, start})
+			i = j
+		default:
+			return nil, fmt.Errorf("at position %d: unexpected character %q", start+1, r)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", offsets[len(runes)]}), nil
+}
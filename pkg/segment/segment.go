@@ -0,0 +1,182 @@
+// Package segment parses the filter expressions that define the members of a customer segment.
+//
+// A filter compares customer fields to double-quoted strings and checks tags, combined with
+// AND, OR, NOT and parentheses:
+//
+//	country = "DE" AND tag:vip
+//	NOT tag:churned AND (last_name = "Smith" OR email != "bob@example.com")
+//
+// AND binds tighter than OR and keywords are case-insensitive. The country field matches the
+// customers with an address in that country, and status their lifecycle status.
+package segment
+
+import (
+	"strings"
+
+	"CustomerCRUD/pkg/models"
+)
+
+// Fields are the customer fields a filter can compare.
+var Fields = map[string]bool{
+	"first_name":   true,
+	"middle_name":  true,
+	"last_name":    true,
+	"email":        true,
+	"phone_number": true,
+	"status":       true,
+	"country":      true,
+}
+
+// Op compares a field to a value.
+type Op string
+
+const (
+	Equal    Op = "="
+	NotEqual Op = "!="
+)
+
+// Expr is a parsed filter, one of And, Or, Not, Compare and HasTag.
+type Expr interface {
+	isExpr()
+}
+
+type And struct{ Left, Right Expr }
+
+type Or struct{ Left, Right Expr }
+
+type Not struct{ Expr Expr }
+
+// Compare matches the customers whose Field compares to Value with Op.
+type Compare struct {
+	Field string
+	Op    Op
+	Value string
+}
+
+// HasTag matches the customers tagged with Tag.
+type HasTag struct{ Tag string }
+
+func (And) isExpr()     {}
+func (Or) isExpr()      {}
+func (Not) isExpr()     {}
+func (Compare) isExpr() {}
+func (HasTag) isExpr()  {}
+
+// Parse parses a filter expression. Errors point at the offending position and are meant for the client.
+func Parse(filter string) (Expr, error) {
+	tokens, err := lex(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, t.errorf("unexpected %s", t)
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	if t := p.peek(); t.kind == tokenWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	if p.keyword("NOT") {
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{expr}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenOpen:
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenClose {
+			return nil, closing.errorf("expected ) but found %s", closing)
+		}
+		return expr, nil
+	case tokenTag:
+		tag := models.NormalizeTag(t.text)
+		if err := models.ValidateTag(tag); err != nil {
+			return nil, t.errorf("%v", err)
+		}
+		return HasTag{tag}, nil
+	case tokenWord:
+		field := strings.ToLower(t.text)
+		if !Fields[field] {
+			return nil, t.errorf("unknown field %q", t.text)
+		}
+		op := p.next()
+		if op.kind != tokenOp {
+			return nil, op.errorf("expected = or != after %s but found %s", field, op)
+		}
+		value := p.next()
+		if value.kind != tokenString {
+			return nil, value.errorf("expected a double-quoted string but found %s", value)
+		}
+		return Compare{Field: field, Op: Op(op.text), Value: value.text}, nil
+	}
+	return nil, t.errorf("expected a comparison, a tag or ( but found %s", t)
+}
@@ -0,0 +1,54 @@
+package segment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   Expr
+	}{
+		{`country = "DE" AND tag:vip`, And{Compare{"country", Equal, "DE"}, HasTag{"vip"}}},
+		{`tag:VIP`, HasTag{"vip"}},
+		{`status != "closed"`, Compare{"status", NotEqual, "closed"}},
+		{
+			`tag:a OR tag:b AND NOT tag:c`,
+			Or{HasTag{"a"}, And{HasTag{"b"}, Not{HasTag{"c"}}}},
+		},
+		{
+			`not (Last_Name != "O\"Brien" or email = "a@example.com") and tag:new-customer`,
+			And{
+				Not{Or{Compare{"last_name", NotEqual, `O"Brien`}, Compare{"email", Equal, "a@example.com"}}},
+				HasTag{"new-customer"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.filter)
+		if assert.NoError(t, err, tt.filter) {
+			assert.Equal(t, tt.want, got, tt.filter)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		``:                          "at position 1: expected a comparison, a tag or ( but found end of filter",
+		`country = DE`:              "at position 11: expected a double-quoted string but found DE",
+		`city = "Berlin"`:           `at position 1: unknown field "city"`,
+		`tag:vip tag:new`:           "at position 9: unexpected tag:new",
+		`(tag:vip OR tag:new`:       "at position 20: expected ) but found end of filter",
+		`email = "a@example.com`:    "at position 9: unterminated string",
+		`tag:vip AND country > "D"`: "at position 21: unexpected character '>'",
+		`tag:`:                      `at position 1: tag "" must start with a letter or digit followed by up to 63 letters, digits, underscores or hyphens`,
+	}
+
+	for filter, want := range tests {
+		_, err := Parse(filter)
+		assert.EqualError(t, err, want, filter)
+	}
+}
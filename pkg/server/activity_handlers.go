@@ -0,0 +1,387 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// activityCustomer resolves the customer of a note, interaction or timeline route and writes the
+// error response when activity is disabled or the customer is invalid or does not exist.
+func (s *Server) activityCustomer(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if s.activity == nil {
+		http.Error(w, "Notes and interactions are disabled", http.StatusNotFound)
+		return uuid.Nil, false
+	}
+	return s.customerFromPath(w, r)
+}
+
+// pageSize reads the limit parameter, which defaults to defaultPageSize.
+func pageSize(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultPageSize, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		http.Error(w, "limit must be a number between 1 and "+strconv.Itoa(maxPageSize), http.StatusBadRequest)
+		return 0, false
+	}
+	return limit, true
+}
+
+// decodeNote reads, normalizes and validates the note in the request body.
+func decodeNote(w http.ResponseWriter, r *http.Request) (models.Note, bool) {
+	var n models.Note
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return n, false
+	}
+
+	n.Normalize()
+	if err := n.Validate(); err != nil {
+		http.Error(w, validationMessage("Invalid note: ", err), http.StatusBadRequest)
+		return n, false
+	}
+	return n, true
+}
+
+func (s *Server) ListNotes(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	notes, err := s.activity.ListNotes(r.Context(), repository.NoteQuery{CustomerID: customerID, Search: r.URL.Query().Get("q")})
+	if err != nil {
+		log.Errorf("error listing notes: %v", err)
+		http.Error(w, "Failed to retrieve notes", http.StatusInternalServerError)
+		return
+	}
+	if notes == nil {
+		notes = []models.Note{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+// SearchNotes searches the notes of all customers.
+func (s *Server) SearchNotes(w http.ResponseWriter, r *http.Request) {
+	if s.activity == nil {
+		http.Error(w, "Notes and interactions are disabled", http.StatusNotFound)
+		return
+	}
+	search := strings.TrimSpace(r.URL.Query().Get("q"))
+	if search == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	limit, ok := pageSize(w, r)
+	if !ok {
+		return
+	}
+
+	notes, err := s.activity.ListNotes(r.Context(), repository.NoteQuery{Search: search, Limit: limit})
+	if err != nil {
+		log.Errorf("error searching notes: %v", err)
+		http.Error(w, "Failed to search notes", http.StatusInternalServerError)
+		return
+	}
+	if notes == nil {
+		notes = []models.Note{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+func (s *Server) GetNote(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "noteId", "Invalid note ID")
+	if !ok {
+		return
+	}
+
+	note, err := s.activity.GetNote(r.Context(), customerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Note not found", http.StatusNotFound)
+		} else {
+			log.Errorf("error getting note: %v", err)
+			http.Error(w, "Failed to retrieve note", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+func (s *Server) CreateNote(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+	n, ok := decodeNote(w, r)
+	if !ok {
+		return
+	}
+
+	n.ID = uuid.New()
+	n.CustomerID = customerID
+	if err := s.activity.CreateNote(r.Context(), &n); err != nil {
+		log.Errorf("failed to create note: %v", err)
+		http.Error(w, "Failed to create note", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(n)
+}
+
+func (s *Server) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "noteId", "Invalid note ID")
+	if !ok {
+		return
+	}
+	n, ok := decodeNote(w, r)
+	if !ok {
+		return
+	}
+
+	n.ID = id
+	n.CustomerID = customerID
+	if err := s.activity.UpdateNote(r.Context(), &n); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Note not found", http.StatusNotFound)
+		} else {
+			log.Errorf("failed to update note: %v", err)
+			http.Error(w, "Failed to update note", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(n)
+}
+
+func (s *Server) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "noteId", "Invalid note ID")
+	if !ok {
+		return
+	}
+
+	if err := s.activity.DeleteNote(r.Context(), customerID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Note not found", http.StatusNotFound)
+		} else {
+			log.Errorf("failed to delete note: %v", err)
+			http.Error(w, "Failed to delete note", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) ListNoteRevisions(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "noteId", "Invalid note ID")
+	if !ok {
+		return
+	}
+
+	revisions, err := s.activity.ListNoteRevisions(r.Context(), customerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Note not found", http.StatusNotFound)
+		} else {
+			log.Errorf("error listing note revisions: %v", err)
+			http.Error(w, "Failed to retrieve note revisions", http.StatusInternalServerError)
+		}
+		return
+	}
+	if revisions == nil {
+		revisions = []models.NoteRevision{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+func (s *Server) ListInteractions(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	interactions, err := s.activity.ListInteractions(r.Context(), customerID)
+	if err != nil {
+		log.Errorf("error listing interactions: %v", err)
+		http.Error(w, "Failed to retrieve interactions", http.StatusInternalServerError)
+		return
+	}
+	if interactions == nil {
+		interactions = []models.Interaction{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(interactions)
+}
+
+func (s *Server) GetInteraction(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "interactionId", "Invalid interaction ID")
+	if !ok {
+		return
+	}
+
+	interaction, err := s.activity.GetInteraction(r.Context(), customerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Interaction not found", http.StatusNotFound)
+		} else {
+			log.Errorf("error getting interaction: %v", err)
+			http.Error(w, "Failed to retrieve interaction", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(interaction)
+}
+
+func (s *Server) CreateInteraction(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	var i models.Interaction
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	i.Normalize()
+	if err := i.Validate(); err != nil {
+		http.Error(w, validationMessage("Invalid interaction: ", err), http.StatusBadRequest)
+		return
+	}
+
+	i.ID = uuid.New()
+	i.CustomerID = customerID
+	if err := s.activity.CreateInteraction(r.Context(), &i); err != nil {
+		log.Errorf("failed to create interaction: %v", err)
+		http.Error(w, "Failed to create interaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(i)
+}
+
+// TimelinePage is a page of the timeline of a customer. NextCursor is empty on the last page.
+type TimelinePage struct {
+	Entries    []models.TimelineEntry `json:"entries"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+func (s *Server) CustomerTimeline(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.activityCustomer(w, r)
+	if !ok {
+		return
+	}
+	limit, ok := pageSize(w, r)
+	if !ok {
+		return
+	}
+
+	query := repository.TimelineQuery{CustomerID: customerID, Limit: limit}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		var err error
+		if query.Before, query.BeforeID, err = decodeTimelineCursor(cursor); err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := s.activity.ListTimeline(r.Context(), query)
+	if err != nil {
+		log.Errorf("error listing timeline: %v", err)
+		http.Error(w, "Failed to retrieve timeline", http.StatusInternalServerError)
+		return
+	}
+
+	page := TimelinePage{Entries: entries}
+	if page.Entries == nil {
+		page.Entries = []models.TimelineEntry{}
+	}
+	if len(entries) == limit {
+		last := entries[len(entries)-1]
+		page.NextCursor = encodeTimelineCursor(last.At, last.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// encodeTimelineCursor makes an opaque cursor continuing after the entry at at with ID id.
+func encodeTimelineCursor(at time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+func decodeTimelineCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	at, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return time.Time{}, uuid.Nil, errors.New("cursor without ID")
+	}
+	before, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	beforeID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return before, beforeID, nil
+}
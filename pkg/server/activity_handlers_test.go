@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func serve(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Actor", "agent@example.com")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCreateNote(t *testing.T) {
+	customers, activity := &mocks.CustomerRepository{}, &mocks.ActivityRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetActivityRepository(activity) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	activity.On("CreateNote", mock.Anything, mock.MatchedBy(func(n *models.Note) bool {
+		return n.CustomerID == customerID && n.Body == "Asked about the refund" && n.ID != uuid.Nil
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Note).Author = "agent@example.com"
+	}).Return(nil)
+
+	rr := serve(s, "POST", "/customers/"+customerID.String()+"/notes", `{"body":"  Asked about the refund "}`)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var created models.Note
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, "agent@example.com", created.Author)
+	activity.AssertExpectations(t)
+}
+
+func TestCreateNote_BlankBody(t *testing.T) {
+	customers, activity := &mocks.CustomerRepository{}, &mocks.ActivityRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetActivityRepository(activity) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+
+	rr := serve(s, "POST", "/customers/"+customerID.String()+"/notes", `{"body":"   "}`)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid note: body is required\n", rr.Body.String())
+	activity.AssertNotCalled(t, "CreateNote", mock.Anything, mock.Anything)
+}
+
+func TestUpdateNote_NotFound(t *testing.T) {
+	customers, activity := &mocks.CustomerRepository{}, &mocks.ActivityRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetActivityRepository(activity) })
+	customerID, noteID := uuid.New(), uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	activity.On("UpdateNote", mock.Anything, mock.Anything).Return(sql.ErrNoRows)
+
+	rr := serve(s, "PUT", "/customers/"+customerID.String()+"/notes/"+noteID.String(), `{"body":"Edited"}`)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Note not found\n", rr.Body.String())
+}
+
+func TestListNotes_Search(t *testing.T) {
+	customers, activity := &mocks.CustomerRepository{}, &mocks.ActivityRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetActivityRepository(activity) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	activity.On("ListNotes", mock.Anything, repository.NoteQuery{CustomerID: customerID, Search: "refund"}).
+		Return([]models.Note{{ID: uuid.New(), CustomerID: customerID, Body: "Refund approved"}}, nil)
+
+	rr := serve(s, "GET", "/customers/"+customerID.String()+"/notes?q=refund", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var notes []models.Note
+	if err := json.Unmarshal(rr.Body.Bytes(), &notes); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	assert.Len(t, notes, 1)
+	activity.AssertExpectations(t)
+}
+
+func TestSearchNotes(t *testing.T) {
+	activity := &mocks.ActivityRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetActivityRepository(activity) })
+
+	activity.On("ListNotes", mock.Anything, repository.NoteQuery{Search: "refund", Limit: 10}).Return(nil, nil)
+
+	rr := serve(s, "GET", "/notes?q=refund&limit=10", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "[]\n", rr.Body.String())
+
+	rr = serve(s, "GET", "/notes?q=refund&limit=1000", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	activity.AssertExpectations(t)
+}
+
+func TestCreateInteraction_InvalidType(t *testing.T) {
+	customers, activity := &mocks.CustomerRepository{}, &mocks.ActivityRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetActivityRepository(activity) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+
+	rr := serve(s, "POST", "/customers/"+customerID.String()+"/interactions", `{"type":"fax","body":"Sent the contract"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	activity.AssertNotCalled(t, "CreateInteraction", mock.Anything, mock.Anything)
+}
+
+func TestCreateInteraction(t *testing.T) {
+	customers, activity := &mocks.CustomerRepository{}, &mocks.ActivityRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetActivityRepository(activity) })
+	customerID := uuid.New()
+	occurredAt := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	activity.On("CreateInteraction", mock.Anything, mock.MatchedBy(func(i *models.Interaction) bool {
+		return i.CustomerID == customerID && i.Type == models.InteractionCall && i.OccurredAt.Equal(occurredAt)
+	})).Return(nil)
+
+	rr := serve(s, "POST", "/customers/"+customerID.String()+"/interactions",
+		`{"type":"call","body":"Walked through the invoice","occurred_at":"2024-05-01T09:30:00Z"}`)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	activity.AssertExpectations(t)
+}
+
+func TestCustomerTimeline_Pagination(t *testing.T) {
+	customers, activity := &mocks.CustomerRepository{}, &mocks.ActivityRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetActivityRepository(activity) })
+	customerID := uuid.New()
+	first := models.TimelineEntry{ID: uuid.New(), Kind: models.TimelineNote, At: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)}
+	second := models.TimelineEntry{ID: uuid.New(), Kind: models.TimelineAudit, At: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Type: "created"}
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	activity.On("ListTimeline", mock.Anything, repository.TimelineQuery{CustomerID: customerID, Limit: 1}).
+		Return([]models.TimelineEntry{first}, nil)
+	activity.On("ListTimeline", mock.Anything, repository.TimelineQuery{CustomerID: customerID, Before: first.At, BeforeID: first.ID, Limit: 1}).
+		Return([]models.TimelineEntry{second}, nil)
+	activity.On("ListTimeline", mock.Anything, repository.TimelineQuery{CustomerID: customerID, Before: second.At, BeforeID: second.ID, Limit: 1}).
+		Return(nil, nil)
+
+	var seen []uuid.UUID
+	path := "/customers/" + customerID.String() + "/timeline?limit=1"
+	for {
+		rr := serve(s, "GET", path, "")
+		if !assert.Equal(t, http.StatusOK, rr.Code) {
+			return
+		}
+		var page TimelinePage
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatalf("Failed to parse response body: %v", err)
+		}
+		for _, e := range page.Entries {
+			seen = append(seen, e.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/customers/" + customerID.String() + "/timeline?limit=1&cursor=" + page.NextCursor
+	}
+
+	assert.Equal(t, []uuid.UUID{first.ID, second.ID}, seen)
+	activity.AssertExpectations(t)
+}
+
+func TestCustomerTimeline_InvalidCursor(t *testing.T) {
+	customers := &mocks.CustomerRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetActivityRepository(&mocks.ActivityRepository{}) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+
+	rr := serve(s, "GET", "/customers/"+customerID.String()+"/timeline?cursor=bogus", "")
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid cursor\n", rr.Body.String())
+}
+
+func TestActivity_Disabled(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	rr := serve(s, "GET", "/customers/"+uuid.New().String()+"/timeline", "")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,14 @@
+package server
+
+import (
+	"net/http"
+
+	"CustomerCRUD/pkg/actor"
+)
+
+// identifyActor names the actor of the changes made by the request after its X-Actor header.
+func identifyActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(actor.NewContext(r.Context(), r.Header.Get(actor.Header))))
+	})
+}
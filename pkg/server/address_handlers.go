@@ -0,0 +1,166 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// addressCustomer resolves the customer of a nested address route and writes the error
+// response when addresses are disabled or the customer is invalid or does not exist.
+func (s *Server) addressCustomer(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if s.addresses == nil {
+		http.Error(w, "Addresses are disabled", http.StatusNotFound)
+		return uuid.Nil, false
+	}
+	return s.customerFromPath(w, r)
+}
+
+// decodeAddress reads, normalizes and validates the address in the request body.
+func decodeAddress(w http.ResponseWriter, r *http.Request) (models.Address, bool) {
+	var a models.Address
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return a, false
+	}
+
+	a.Normalize()
+	if err := a.Validate(); err != nil {
+		http.Error(w, validationMessage("Invalid address: ", err), http.StatusBadRequest)
+		return a, false
+	}
+	return a, true
+}
+
+func (s *Server) ListAddresses(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.addressCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	addresses, err := s.addresses.ListAddresses(r.Context(), customerID)
+	if err != nil {
+		log.Errorf("error listing addresses: %v", err)
+		http.Error(w, "Failed to retrieve addresses", http.StatusInternalServerError)
+		return
+	}
+	if addresses == nil {
+		addresses = []models.Address{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(addresses)
+}
+
+func (s *Server) GetAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.addressCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "addressId", "Invalid address ID")
+	if !ok {
+		return
+	}
+
+	address, err := s.addresses.GetAddress(r.Context(), customerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Address not found", http.StatusNotFound)
+		} else {
+			log.Errorf("error getting address: %v", err)
+			http.Error(w, "Failed to retrieve address", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(address)
+}
+
+func (s *Server) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.addressCustomer(w, r)
+	if !ok {
+		return
+	}
+	if s.erased(w, r, customerID) {
+		return
+	}
+	a, ok := decodeAddress(w, r)
+	if !ok {
+		return
+	}
+
+	a.ID = uuid.New()
+	a.CustomerID = customerID
+	if err := s.addresses.CreateAddress(r.Context(), a); err != nil {
+		log.Errorf("failed to create address: %v", err)
+		http.Error(w, "Failed to create address", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
+
+func (s *Server) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.addressCustomer(w, r)
+	if !ok {
+		return
+	}
+	if s.erased(w, r, customerID) {
+		return
+	}
+	id, ok := pathID(w, r, "addressId", "Invalid address ID")
+	if !ok {
+		return
+	}
+	a, ok := decodeAddress(w, r)
+	if !ok {
+		return
+	}
+
+	a.ID = id
+	a.CustomerID = customerID
+	if err := s.addresses.UpdateAddress(r.Context(), a); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Address not found", http.StatusNotFound)
+		} else {
+			log.Errorf("failed to update address: %v", err)
+			http.Error(w, "Failed to update address", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+func (s *Server) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.addressCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "addressId", "Invalid address ID")
+	if !ok {
+		return
+	}
+
+	if err := s.addresses.DeleteAddress(r.Context(), customerID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Address not found", http.StatusNotFound)
+		} else {
+			log.Errorf("failed to delete address: %v", err)
+			http.Error(w, "Failed to delete address", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateAddress(t *testing.T) {
+	customers, addresses := &mocks.CustomerRepository{}, &mocks.AddressRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAddressRepository(addresses) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	addresses.On("CreateAddress", mock.Anything, mock.MatchedBy(func(a models.Address) bool {
+		return a.CustomerID == customerID && a.Country == "NL" && a.PostalCode == "1012 AB" && a.ID != uuid.Nil
+	})).Return(nil)
+
+	body := `{"type":"shipping","line1":"Dam 1","city":"Amsterdam","postal_code":"1012 ab","country":"nl","is_default":true}`
+	req, err := http.NewRequest("POST", "/customers/"+customerID.String()+"/addresses", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, status)
+	}
+
+	var created models.Address
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, customerID, created.CustomerID)
+	assert.True(t, created.IsDefault)
+
+	customers.AssertExpectations(t)
+	addresses.AssertExpectations(t)
+}
+
+func TestCreateAddress_InvalidPostalCode(t *testing.T) {
+	customers, addresses := &mocks.CustomerRepository{}, &mocks.AddressRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAddressRepository(addresses) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+
+	body := `{"type":"billing","line1":"1 Main St","city":"Springfield","postal_code":"ABCDE","country":"US"}`
+	req, err := http.NewRequest("POST", "/customers/"+customerID.String()+"/addresses", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid address: postal code \"ABCDE\" is not valid for US\n", rr.Body.String())
+
+	addresses.AssertNotCalled(t, "CreateAddress", mock.Anything, mock.Anything)
+}
+
+func TestListAddresses_CustomerNotFound(t *testing.T) {
+	customers, addresses := &mocks.CustomerRepository{}, &mocks.AddressRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAddressRepository(addresses) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(nil, sql.ErrNoRows)
+
+	req, err := http.NewRequest("GET", "/customers/"+customerID.String()+"/addresses", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Customer not found\n", rr.Body.String())
+
+	addresses.AssertNotCalled(t, "ListAddresses", mock.Anything, mock.Anything)
+}
+
+func TestUpdateAddress_NotFound(t *testing.T) {
+	customers, addresses := &mocks.CustomerRepository{}, &mocks.AddressRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAddressRepository(addresses) })
+	customerID, addressID := uuid.New(), uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	addresses.On("UpdateAddress", mock.Anything, mock.AnythingOfType("models.Address")).Return(sql.ErrNoRows)
+
+	body := `{"type":"other","line1":"Unter den Linden 1","city":"Berlin","postal_code":"10117","country":"DE"}`
+	req, err := http.NewRequest("PUT", "/customers/"+customerID.String()+"/addresses/"+addressID.String(), bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Address not found\n", rr.Body.String())
+
+	addresses.AssertExpectations(t)
+}
+
+func TestDeleteAddress(t *testing.T) {
+	customers, addresses := &mocks.CustomerRepository{}, &mocks.AddressRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAddressRepository(addresses) })
+	customerID, addressID := uuid.New(), uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	addresses.On("DeleteAddress", mock.Anything, customerID, addressID).Return(nil)
+
+	req, err := http.NewRequest("DELETE", "/customers/"+customerID.String()+"/addresses/"+addressID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	addresses.AssertExpectations(t)
+}
+
+func TestGetCustomerByID_IncludeAddresses(t *testing.T) {
+	customers, addresses := &mocks.CustomerRepository{}, &mocks.AddressRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAddressRepository(addresses) })
+	customerID := uuid.New()
+	address := models.Address{
+		ID: uuid.New(), CustomerID: customerID, Type: models.AddressBilling,
+		Line1: "1 Main St", City: "Springfield", PostalCode: "12345", Country: "US",
+	}
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID, FirstName: "John"}, nil)
+	addresses.On("ListAddressesByCustomerIDs", mock.Anything, []uuid.UUID{customerID}).
+		Return(map[uuid.UUID][]models.Address{customerID: {address}}, nil)
+
+	req, err := http.NewRequest("GET", "/customers/"+customerID.String()+"?include=addresses", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var customer models.Customer
+	if err := json.Unmarshal(rr.Body.Bytes(), &customer); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, []models.Address{address}, customer.Addresses)
+
+	addresses.AssertExpectations(t)
+}
+
+func TestGetAllCustomers_UnknownInclude(t *testing.T) {
+	customers := &mocks.CustomerRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAddressRepository(&mocks.AddressRepository{}) })
+
+	req, err := http.NewRequest("GET", "/customers?include=orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	customers.AssertNotCalled(t, "GetAllCustomers", mock.Anything)
+}
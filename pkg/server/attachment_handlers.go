@@ -0,0 +1,334 @@
+package server
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/storage"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// multipartOverhead is allowed on top of the attachment size for the multipart envelope.
+const multipartOverhead = 64 << 10
+
+// maxFileNameLength caps the stored file names, in characters.
+const maxFileNameLength = 255
+
+// AttachmentPolicy limits what can be uploaded as an attachment.
+type AttachmentPolicy struct {
+	// MaxSize is the largest attachment accepted, in bytes.
+	MaxSize int64
+	// AllowedTypes are the media types accepted after sniffing the content. Empty allows all.
+	AllowedTypes []string
+}
+
+func (p AttachmentPolicy) allows(contentType string) bool {
+	if len(p.AllowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range p.AllowedTypes {
+		if strings.EqualFold(allowed, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentCustomer resolves the customer of an attachment route and writes the error
+// response when attachments are disabled or the customer is invalid or does not exist.
+func (s *Server) attachmentCustomer(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if s.attachments == nil {
+		http.Error(w, "Attachments are disabled", http.StatusNotFound)
+		return uuid.Nil, false
+	}
+	return s.customerFromPath(w, r)
+}
+
+// attachmentFromPath loads the attachment of a nested route and writes the error response when
+// it cannot.
+func (s *Server) attachmentFromPath(w http.ResponseWriter, r *http.Request) (*models.Attachment, bool) {
+	customerID, ok := s.attachmentCustomer(w, r)
+	if !ok {
+		return nil, false
+	}
+	id, ok := pathID(w, r, "attachmentId", "Invalid attachment ID")
+	if !ok {
+		return nil, false
+	}
+
+	attachment, err := s.attachments.GetAttachment(r.Context(), customerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+		} else {
+			log.Errorf("error getting attachment: %v", err)
+			http.Error(w, "Failed to retrieve attachment", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return attachment, true
+}
+
+func attachmentKey(customerID, attachmentID uuid.UUID) string {
+	return "customers/" + customerID.String() + "/attachments/" + attachmentID.String()
+}
+
+// cleanFileName keeps the base name of an uploaded file without control characters. Browsers
+// on Windows may send the full path.
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if runes := []rune(name); len(runes) > maxFileNameLength {
+		name = string(runes[:maxFileNameLength])
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+var errAttachmentTooLarge = errors.New("attachment too large")
+
+// upload is an uploaded file spooled to a temporary file, so that it can be checked before it
+// is stored and its size is known to the blob store.
+type upload struct {
+	file        *os.File
+	size        int64
+	sha256      string
+	contentType string
+}
+
+// spoolUpload copies content to a temporary file, hashing it on the way, and sniffs its type.
+// It fails with errAttachmentTooLarge when content holds more than maxSize bytes.
+func spoolUpload(content io.Reader, maxSize int64) (*upload, error) {
+	f, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	u := &upload{file: f}
+
+	hash := sha256.New()
+	u.size, err = io.Copy(io.MultiWriter(f, hash), io.LimitReader(content, maxSize+1))
+	if err == nil && u.size > maxSize {
+		err = errAttachmentTooLarge
+	}
+	if err != nil {
+		u.Close()
+		return nil, err
+	}
+	u.sha256 = hex.EncodeToString(hash.Sum(nil))
+
+	head := make([]byte, 512)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		u.Close()
+		return nil, err
+	}
+	u.contentType = http.DetectContentType(head[:n])
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		u.Close()
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *upload) Close() {
+	u.file.Close()
+	os.Remove(u.file.Name())
+}
+
+func (s *Server) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.attachmentCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	attachments, err := s.attachments.ListAttachments(r.Context(), customerID)
+	if err != nil {
+		log.Errorf("error listing attachments: %v", err)
+		http.Error(w, "Failed to retrieve attachments", http.StatusInternalServerError)
+		return
+	}
+	if attachments == nil {
+		attachments = []models.Attachment{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+func (s *Server) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := s.attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// UploadAttachment stores the file of the multipart field named file.
+func (s *Server) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.attachmentCustomer(w, r)
+	if !ok {
+		return
+	}
+	tooLarge := fmt.Sprintf("Attachment exceeds the limit of %d bytes", s.attachmentPolicy.MaxSize)
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.attachmentPolicy.MaxSize+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Content-Type must be multipart/form-data", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var u *upload
+	var fileName string
+	for u == nil {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			http.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Invalid multipart payload", http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		fileName = cleanFileName(part.FileName())
+		if u, err = spoolUpload(part, s.attachmentPolicy.MaxSize); err != nil {
+			if errors.Is(err, errAttachmentTooLarge) || errors.As(err, &maxBytesErr) {
+				http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+			} else {
+				log.Errorf("error spooling attachment: %v", err)
+				http.Error(w, "Failed to upload attachment", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+	defer u.Close()
+
+	if u.size == 0 {
+		http.Error(w, "file is empty", http.StatusBadRequest)
+		return
+	}
+	if !s.attachmentPolicy.allows(u.contentType) {
+		http.Error(w, fmt.Sprintf("Attachments of type %s are not allowed", u.contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	id := uuid.New()
+	attachment := models.Attachment{
+		ID:          id,
+		CustomerID:  customerID,
+		FileName:    fileName,
+		ContentType: u.contentType,
+		Size:        u.size,
+		SHA256:      u.sha256,
+		Key:         attachmentKey(customerID, id),
+	}
+	if err := s.blobs.Put(r.Context(), attachment.Key, u.file, u.size, u.contentType); err != nil {
+		log.Errorf("failed to store attachment content: %v", err)
+		http.Error(w, "Failed to upload attachment", http.StatusInternalServerError)
+		return
+	}
+	if err := s.attachments.CreateAttachment(r.Context(), &attachment); err != nil {
+		log.Errorf("failed to create attachment: %v", err)
+		if err := s.blobs.Delete(r.Context(), attachment.Key); err != nil {
+			log.Warnf("failed to remove content of attachment %s: %v", attachment.ID, err)
+		}
+		http.Error(w, "Failed to upload attachment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// DownloadAttachment serves the content of the attachment, including single and multiple
+// byte ranges and conditional requests on its checksum.
+func (s *Server) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := s.attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	blob, err := s.blobs.Open(r.Context(), attachment.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Errorf("content of attachment %s is missing", attachment.ID)
+			http.Error(w, "Attachment content not found", http.StatusNotFound)
+		} else {
+			log.Errorf("error opening attachment content: %v", err)
+			http.Error(w, "Failed to retrieve attachment", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer blob.Close()
+
+	checksum, _ := hex.DecodeString(attachment.SHA256)
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(checksum)+":")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", attachment.UploadedAt, blob)
+}
+
+func (s *Server) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := s.attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.attachments.DeleteAttachment(r.Context(), attachment.CustomerID, attachment.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+		} else {
+			log.Errorf("failed to delete attachment: %v", err)
+			http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		}
+		return
+	}
+	// The attachment is gone for clients at this point, leftover content only costs storage.
+	if err := s.blobs.Delete(r.Context(), attachment.Key); err != nil {
+		log.Warnf("failed to remove content of attachment %s: %v", attachment.ID, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var pdfContent = []byte("%PDF-1.7\n1 0 obj << /Type /Catalog >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n")
+
+func newAttachmentTestServer(t *testing.T, policy AttachmentPolicy) (*Server, *mocks.CustomerRepository, *mocks.AttachmentRepository, *storage.LocalStore) {
+	customers := &mocks.CustomerRepository{}
+	attachments := &mocks.AttachmentRepository{}
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	s := newTestServer(customers, func(s *Server) { s.SetAttachmentStore(attachments, blobs, policy) })
+	return s, customers, attachments, blobs
+}
+
+func uploadRequest(t *testing.T, path, fileName string, content []byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", fileName)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req, _ := http.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Actor", "agent@example.com")
+	return req
+}
+
+func TestUploadAttachment(t *testing.T) {
+	s, customers, attachments, blobs := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 1 << 10, AllowedTypes: []string{"application/pdf"}})
+	customerID := uuid.New()
+	checksum := sha256.Sum256(pdfContent)
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	attachments.On("CreateAttachment", mock.Anything, mock.MatchedBy(func(a *models.Attachment) bool {
+		return a.CustomerID == customerID && a.FileName == "contract.pdf" && a.Size == int64(len(pdfContent))
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Attachment).UploadedBy = "agent@example.com"
+	}).Return(nil)
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, uploadRequest(t, "/customers/"+customerID.String()+"/attachments", `C:\Users\agent\contract.pdf`, pdfContent))
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created models.Attachment
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "application/pdf", created.ContentType)
+	assert.Equal(t, hex.EncodeToString(checksum[:]), created.SHA256)
+	assert.Equal(t, "agent@example.com", created.UploadedBy)
+	assert.NotContains(t, rr.Body.String(), "customers/", "the storage key is internal")
+
+	blob, err := blobs.Open(context.Background(), attachmentKey(customerID, created.ID))
+	require.NoError(t, err)
+	defer blob.Close()
+	stored, _ := io.ReadAll(blob)
+	assert.Equal(t, pdfContent, stored)
+	attachments.AssertExpectations(t)
+}
+
+func TestUploadAttachment_TooLarge(t *testing.T) {
+	s, customers, attachments, _ := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 16})
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, uploadRequest(t, "/customers/"+customerID.String()+"/attachments", "contract.pdf", pdfContent))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, "Attachment exceeds the limit of 16 bytes\n", rr.Body.String())
+	attachments.AssertNotCalled(t, "CreateAttachment", mock.Anything, mock.Anything)
+}
+
+func TestUploadAttachment_DisallowedType(t *testing.T) {
+	s, customers, attachments, _ := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 1 << 10, AllowedTypes: []string{"application/pdf"}})
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+
+	// The declared name and type do not matter, the content is sniffed.
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, uploadRequest(t, "/customers/"+customerID.String()+"/attachments", "invoice.pdf", []byte("<html><script>alert(1)</script></html>")))
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	assert.Equal(t, "Attachments of type text/html; charset=utf-8 are not allowed\n", rr.Body.String())
+	attachments.AssertNotCalled(t, "CreateAttachment", mock.Anything, mock.Anything)
+}
+
+func TestUploadAttachment_MissingFile(t *testing.T) {
+	s, customers, _, _ := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 1 << 10})
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("note", "no file here")
+	form.Close()
+	req, _ := http.NewRequest("POST", "/customers/"+customerID.String()+"/attachments", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "file is required\n", rr.Body.String())
+}
+
+func TestUploadAttachment_MetadataFailureRemovesContent(t *testing.T) {
+	s, customers, attachments, blobs := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 1 << 10})
+	customerID := uuid.New()
+	var key string
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	attachments.On("CreateAttachment", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		key = args.Get(1).(*models.Attachment).Key
+	}).Return(errors.New("connection reset"))
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, uploadRequest(t, "/customers/"+customerID.String()+"/attachments", "contract.pdf", pdfContent))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	_, err := blobs.Open(context.Background(), key)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func storedAttachment(t *testing.T, blobs storage.BlobStore, customerID uuid.UUID, content []byte) *models.Attachment {
+	id := uuid.New()
+	checksum := sha256.Sum256(content)
+	a := &models.Attachment{
+		ID:          id,
+		CustomerID:  customerID,
+		FileName:    "contract.pdf",
+		ContentType: "application/pdf",
+		Size:        int64(len(content)),
+		SHA256:      hex.EncodeToString(checksum[:]),
+		UploadedAt:  time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		UploadedBy:  "agent@example.com",
+		Key:         attachmentKey(customerID, id),
+	}
+	require.NoError(t, blobs.Put(context.Background(), a.Key, bytes.NewReader(content), a.Size, a.ContentType))
+	return a
+}
+
+func TestDownloadAttachment(t *testing.T) {
+	s, customers, attachments, blobs := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 1 << 10})
+	customerID := uuid.New()
+	a := storedAttachment(t, blobs, customerID, pdfContent)
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	attachments.On("GetAttachment", mock.Anything, customerID, a.ID).Return(a, nil)
+
+	rr := serve(s, "GET", "/customers/"+customerID.String()+"/attachments/"+a.ID.String()+"/content", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, pdfContent, rr.Body.Bytes())
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=contract.pdf`, rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, `"`+a.SHA256+`"`, rr.Header().Get("ETag"))
+	assert.Equal(t, "bytes", rr.Header().Get("Accept-Ranges"))
+}
+
+func TestDownloadAttachment_Range(t *testing.T) {
+	s, customers, attachments, blobs := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 1 << 10})
+	customerID := uuid.New()
+	a := storedAttachment(t, blobs, customerID, pdfContent)
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	attachments.On("GetAttachment", mock.Anything, customerID, a.ID).Return(a, nil)
+
+	req, _ := http.NewRequest("GET", "/customers/"+customerID.String()+"/attachments/"+a.ID.String()+"/content", nil)
+	req.Header.Set("Range", "bytes=0-7")
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "%PDF-1.7", rr.Body.String())
+	assert.Equal(t, "bytes 0-7/"+strconv.Itoa(len(pdfContent)), rr.Header().Get("Content-Range"))
+
+	req.Header.Set("Range", "bytes=5000-")
+	rr = httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rr.Code)
+}
+
+func TestDownloadAttachment_NotFound(t *testing.T) {
+	s, customers, attachments, _ := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 1 << 10})
+	customerID, attachmentID := uuid.New(), uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	attachments.On("GetAttachment", mock.Anything, customerID, attachmentID).Return(nil, sql.ErrNoRows)
+
+	rr := serve(s, "GET", "/customers/"+customerID.String()+"/attachments/"+attachmentID.String()+"/content", "")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Attachment not found\n", rr.Body.String())
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	s, customers, attachments, blobs := newAttachmentTestServer(t, AttachmentPolicy{MaxSize: 1 << 10})
+	customerID := uuid.New()
+	a := storedAttachment(t, blobs, customerID, pdfContent)
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	attachments.On("GetAttachment", mock.Anything, customerID, a.ID).Return(a, nil)
+	attachments.On("DeleteAttachment", mock.Anything, customerID, a.ID).Return(nil)
+
+	rr := serve(s, "DELETE", "/customers/"+customerID.String()+"/attachments/"+a.ID.String(), "")
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	_, err := blobs.Open(context.Background(), a.Key)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	attachments.AssertExpectations(t)
+}
+
+func TestAttachments_Disabled(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	rr := serve(s, "GET", "/customers/"+uuid.NewString()+"/attachments", "")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Body.String(), "Attachments are disabled"))
+}
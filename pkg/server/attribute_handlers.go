@@ -0,0 +1,145 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (s *Server) attributesEnabled(w http.ResponseWriter) bool {
+	if s.attributes == nil {
+		http.Error(w, "Attributes are disabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func (s *Server) ListAttributes(w http.ResponseWriter, r *http.Request) {
+	if !s.attributesEnabled(w) {
+		return
+	}
+
+	defs, err := s.attributes.ListAttributeDefinitions(r.Context())
+	if err != nil {
+		log.Errorf("error listing attribute definitions: %v", err)
+		http.Error(w, "Failed to retrieve attributes", http.StatusInternalServerError)
+		return
+	}
+	if defs == nil {
+		defs = []models.AttributeDefinition{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(defs)
+}
+
+// AttributesJSONSchema serves the attributes object of a customer as a JSON Schema, for UIs to render forms from.
+func (s *Server) AttributesJSONSchema(w http.ResponseWriter, r *http.Request) {
+	if !s.attributesEnabled(w) {
+		return
+	}
+
+	defs, err := s.attributes.ListAttributeDefinitions(r.Context())
+	if err != nil {
+		log.Errorf("error listing attribute definitions: %v", err)
+		http.Error(w, "Failed to retrieve attributes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(models.AttributesJSONSchema(defs))
+}
+
+func (s *Server) GetAttribute(w http.ResponseWriter, r *http.Request) {
+	if !s.attributesEnabled(w) {
+		return
+	}
+
+	def, err := s.attributes.GetAttributeDefinition(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Attribute not found", http.StatusNotFound)
+		} else {
+			log.Errorf("error getting attribute definition: %v", err)
+			http.Error(w, "Failed to retrieve attribute", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(def)
+}
+
+// PutAttribute creates or replaces an attribute definition. Values stored before a definition
+// is tightened are only checked again when the customer is next written.
+func (s *Server) PutAttribute(w http.ResponseWriter, r *http.Request) {
+	if !s.attributesEnabled(w) {
+		return
+	}
+
+	var def models.AttributeDefinition
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	def.Name = mux.Vars(r)["name"]
+
+	if err := def.Validate(); err != nil {
+		http.Error(w, validationMessage("Invalid attribute definition: ", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.attributes.SaveAttributeDefinition(r.Context(), def); err != nil {
+		log.Errorf("failed to save attribute definition: %v", err)
+		http.Error(w, "Failed to save attribute", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(def)
+}
+
+// DeleteAttribute removes an attribute definition together with the values of every customer.
+func (s *Server) DeleteAttribute(w http.ResponseWriter, r *http.Request) {
+	if !s.attributesEnabled(w) {
+		return
+	}
+
+	if err := s.attributes.DeleteAttributeDefinition(r.Context(), mux.Vars(r)["name"]); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Attribute not found", http.StatusNotFound)
+		} else {
+			log.Errorf("failed to delete attribute definition: %v", err)
+			http.Error(w, "Failed to delete attribute", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientError carries a message that can be shown to the client as is.
+type clientError struct {
+	message string
+}
+
+func (e clientError) Error() string {
+	return e.message
+}
+
+// attributeError writes a 400 response when err reports invalid custom attributes.
+func attributeError(w http.ResponseWriter, err error) bool {
+	var attrErr *models.AttributeError
+	if !errors.As(err, &attrErr) {
+		return false
+	}
+	http.Error(w, "Invalid attributes: "+strings.Join(attrErr.Problems, "; "), http.StatusBadRequest)
+	return true
+}
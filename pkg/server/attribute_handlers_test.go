@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	loyaltyTier = models.AttributeDefinition{Name: "loyalty_tier", Label: "Loyalty tier", Type: models.AttributeString, Enum: []any{"gold", "silver"}}
+	birthday    = models.AttributeDefinition{Name: "birthday", Label: "Birthday", Type: models.AttributeDate}
+)
+
+func TestGetAllCustomers_AttributeFilters(t *testing.T) {
+	customers, attributes := &mocks.CustomerRepository{}, &mocks.AttributeRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAttributeRepository(attributes) })
+	expected := []models.Customer{{ID: uuid.New(), FirstName: "Gold", Attributes: map[string]any{"loyalty_tier": "gold"}}}
+
+	attributes.On("ListAttributeDefinitions", mock.Anything).Return([]models.AttributeDefinition{birthday, loyaltyTier}, nil)
+	customers.On("FindCustomers", mock.Anything, repository.CustomerQuery{
+		Filters: []repository.AttributeFilter{
+			{Attribute: birthday, Op: repository.OpGreaterOrEqual, Value: "1990-01-01"},
+			{Attribute: loyaltyTier, Op: repository.OpEqual, Value: "gold"},
+		},
+		SortAttribute: &birthday,
+		Descending:    true,
+	}).Return(expected, nil)
+
+	req, err := http.NewRequest("GET", "/customers?attr.loyalty_tier=gold&attr.birthday.gte=1990-01-01&sort=-attr.birthday", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var result []models.Customer
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, expected, result)
+
+	customers.AssertExpectations(t)
+}
+
+func TestGetAllCustomers_UnknownAttribute(t *testing.T) {
+	customers, attributes := &mocks.CustomerRepository{}, &mocks.AttributeRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAttributeRepository(attributes) })
+
+	attributes.On("ListAttributeDefinitions", mock.Anything).Return([]models.AttributeDefinition{loyaltyTier}, nil)
+
+	req, err := http.NewRequest("GET", "/customers?attr.nickname=bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Unknown attribute \"nickname\"\n", rr.Body.String())
+	customers.AssertNotCalled(t, "FindCustomers", mock.Anything, mock.Anything)
+}
+
+func TestPutAttribute(t *testing.T) {
+	attributes := &mocks.AttributeRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetAttributeRepository(attributes) })
+
+	attributes.On("SaveAttributeDefinition", mock.Anything, loyaltyTier).Return(nil)
+
+	body := `{"label":"Loyalty tier","type":"string","enum":["gold","silver"]}`
+	req, err := http.NewRequest("PUT", "/attributes/loyalty_tier", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	attributes.AssertExpectations(t)
+}
+
+func TestPutAttribute_Invalid(t *testing.T) {
+	attributes := &mocks.AttributeRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetAttributeRepository(attributes) })
+
+	body := `{"label":"Birthday","type":"date","minimum":0}`
+	req, err := http.NewRequest("PUT", "/attributes/birthday", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid attribute definition: minimum and maximum only apply to number and integer attributes\n", rr.Body.String())
+	attributes.AssertNotCalled(t, "SaveAttributeDefinition", mock.Anything, mock.Anything)
+}
+
+func TestAttributesJSONSchema(t *testing.T) {
+	attributes := &mocks.AttributeRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetAttributeRepository(attributes) })
+
+	attributes.On("ListAttributeDefinitions", mock.Anything).Return([]models.AttributeDefinition{birthday, loyaltyTier}, nil)
+
+	req, err := http.NewRequest("GET", "/attributes/json-schema", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var schema struct {
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &schema); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, "date", schema.Properties["birthday"]["format"])
+	assert.Equal(t, []any{"gold", "silver"}, schema.Properties["loyalty_tier"]["enum"])
+}
+
+func TestCreateCustomer_InvalidAttributes(t *testing.T) {
+	customers := &mocks.CustomerRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetAttributeRepository(&mocks.AttributeRepository{}) })
+
+	customers.On("CreateCustomer", mock.Anything, mock.AnythingOfType("*models.Customer")).
+		Return(&models.AttributeError{Problems: []string{"loyalty_tier must be one of [gold silver]"}})
+
+	body := `{"first_name":"A","last_name":"B","email":"a@example.com","attributes":{"loyalty_tier":"bronze"}}`
+	req, err := http.NewRequest("POST", "/customers", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid attributes: loyalty_tier must be one of [gold silver]\n", rr.Body.String())
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"CustomerCRUD/pkg/repository/resilient"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// databaseFreeRoutes serve without the database, so they keep working while the circuit breaker
+// is open.
+var databaseFreeRoutes = map[string]bool{
+	"/healthz":      true,
+	"/readyz":       true,
+	"/metrics":      true,
+	"/openapi.json": true,
+	"/docs/":        true,
+	// The faults can be cleared while the breaker is open.
+	"/admin/faults": true,
+}
+
+// SetCircuitBreaker fails requests fast with 503 while breaker is open, instead of letting them
+// wait for the database. Without it requests are always served.
+func (s *Server) SetCircuitBreaker(breaker *resilient.Breaker) {
+	s.breaker = breaker
+}
+
+// failFast responds 503 to the requests needing the database while the circuit breaker is open.
+func (s *Server) failFast(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.breaker == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if path, _ := route.GetPathTemplate(); databaseFreeRoutes[path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if retryAfter := s.breaker.RetryAfter(); retryAfter > 0 {
+			writeUnavailable(w, retryAfter)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeUnavailable tells the client to retry once the database is expected to be back.
+func writeUnavailable(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", headerSeconds(retryAfter))
+	http.Error(w, "Database unavailable, please retry later", http.StatusServiceUnavailable)
+}
+
+// internalError logs err and responds 500 with message, or 503 when the circuit breaker failed the
+// call fast.
+func internalError(w http.ResponseWriter, err error, logMessage, message string) {
+	if errors.Is(err, resilient.ErrCircuitOpen) {
+		writeUnavailable(w, time.Second)
+		return
+	}
+	log.Errorf("%s: %v", logMessage, err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
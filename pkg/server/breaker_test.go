@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/repository/resilient"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	guard := resilient.New(resilient.Policy{Attempts: 1}, resilient.NewBreaker(resilient.BreakerOptions{Failures: 1, OpenFor: time.Minute}))
+	mockRepo := &mocks.CustomerRepository{}
+	mockRepo.On("GetCustomerByID", mock.Anything, mock.Anything).Return(nil, &pq.Error{Code: "08006"}).Once()
+	s := newTestServer(guard.Customers(mockRepo), func(s *Server) { s.SetCircuitBreaker(guard.Breaker()) })
+
+	rr := getWith(t, s, "/customers/"+uuid.NewString(), nil)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	// The failure opened the breaker, the database is not called anymore.
+	rr = getWith(t, s, "/customers/"+uuid.NewString(), nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "Database unavailable, please retry later\n", rr.Body.String())
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	mockRepo.AssertExpectations(t)
+
+	assert.Equal(t, http.StatusOK, getWith(t, s, "/healthz", nil).Code)
+	assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", nil).Code)
+}
+
+func TestCircuitBreaker_OpenedDuringRequest(t *testing.T) {
+	guard := resilient.New(resilient.Policy{Attempts: 2}, resilient.NewBreaker(resilient.BreakerOptions{Failures: 1, OpenFor: time.Minute}))
+	mockRepo := &mocks.CustomerRepository{}
+	mockRepo.On("GetCustomerByID", mock.Anything, mock.Anything).Return(nil, &pq.Error{Code: "08006"}).Once()
+	s := newTestServer(guard.Customers(mockRepo), func(s *Server) { s.SetCircuitBreaker(guard.Breaker()) })
+
+	rr := getWith(t, s, "/customers/"+uuid.NewString(), nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "the retry is failed fast")
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/unsubscribe"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// unsubscribeActor records the consents revoked through unsubscribe links, which are not
+// authenticated and so cannot trust the X-Actor header.
+const unsubscribeActor = "unsubscribe link"
+
+// consentRequest is one consent of the body of the endpoint recording consents.
+type consentRequest struct {
+	Purpose       string                `json:"purpose"`
+	Channel       models.ConsentChannel `json:"channel"`
+	State         models.ConsentState   `json:"state"`
+	Source        string                `json:"source"`
+	PolicyVersion string                `json:"policy_version"`
+}
+
+// unsubscribeTokenRequest is the body of the endpoint issuing unsubscribe tokens.
+type unsubscribeTokenRequest struct {
+	Purpose string                `json:"purpose"`
+	Channel models.ConsentChannel `json:"channel"`
+}
+
+// UnsubscribeToken is the response of the endpoint issuing unsubscribe tokens. URL is relative to
+// the public address of the service.
+type UnsubscribeToken struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+func (s *Server) consentsEnabled(w http.ResponseWriter) bool {
+	if s.consents == nil {
+		http.Error(w, "Consents are disabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func (s *Server) unsubscribeEnabled(w http.ResponseWriter) bool {
+	if s.consents == nil || s.unsubscribe == nil {
+		http.Error(w, "Unsubscribe links are disabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// parseContactable parses the contactable filter of the customer list, a purpose optionally
+// followed by a colon and a channel.
+func parseContactable(raw string) (*repository.ContactableFilter, error) {
+	purpose, channel, _ := strings.Cut(raw, ":")
+	filter := &repository.ContactableFilter{
+		Purpose: models.NormalizePurpose(purpose),
+		Channel: models.ConsentChannel(strings.ToLower(strings.TrimSpace(channel))),
+	}
+	if err := models.ValidatePurpose(filter.Purpose); err != nil {
+		return nil, err
+	}
+	if filter.Channel != "" && !filter.Channel.Valid() {
+		return nil, fmt.Errorf("channel %q is not email, sms, phone or post", filter.Channel)
+	}
+	return filter, nil
+}
+
+func (s *Server) ListConsents(w http.ResponseWriter, r *http.Request) {
+	if !s.consentsEnabled(w) {
+		return
+	}
+	customerID, ok := s.customerFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	consents, err := s.consents.ListConsents(r.Context(), customerID)
+	if err != nil {
+		log.Errorf("error listing consents: %v", err)
+		http.Error(w, "Failed to retrieve consents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nonNil(consents))
+}
+
+func (s *Server) ListConsentHistory(w http.ResponseWriter, r *http.Request) {
+	if !s.consentsEnabled(w) {
+		return
+	}
+	customerID, ok := s.customerFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := s.consents.ListConsentHistory(r.Context(), customerID)
+	if err != nil {
+		log.Errorf("error listing consent history: %v", err)
+		http.Error(w, "Failed to retrieve consent history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nonNil(history))
+}
+
+// PutConsents records the given consents of the customer and responds with all its current ones.
+// The purposes and channels that are not given keep their consent.
+func (s *Server) PutConsents(w http.ResponseWriter, r *http.Request) {
+	if !s.consentsEnabled(w) {
+		return
+	}
+	customerID, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	var req []consentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	consents, err := newConsents(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.recordConsents(w, r.Context(), customerID, consents)
+}
+
+// newConsents validates the consents of a request.
+func newConsents(req []consentRequest) ([]models.Consent, error) {
+	if len(req) == 0 {
+		return nil, clientError{"Invalid consents: at least one consent is required"}
+	}
+
+	consents := make([]models.Consent, len(req))
+	seen := map[string]bool{}
+	for i, c := range req {
+		consent := models.Consent{
+			ID:            uuid.New(),
+			Purpose:       c.Purpose,
+			Channel:       c.Channel,
+			State:         c.State,
+			Source:        c.Source,
+			PolicyVersion: c.PolicyVersion,
+		}
+		consent.Normalize()
+		if err := consent.Validate(); err != nil {
+			return nil, clientError{fmt.Sprintf("Invalid consent %d: %v", i, err)}
+		}
+		key := consent.Purpose + ":" + string(consent.Channel)
+		if seen[key] {
+			return nil, clientError{fmt.Sprintf("Invalid consent %d: %s is given more than once", i, key)}
+		}
+		seen[key] = true
+		consents[i] = consent
+	}
+	return consents, nil
+}
+
+// recordConsents records the consents of the customer and responds with all its current ones.
+func (s *Server) recordConsents(w http.ResponseWriter, ctx context.Context, customerID uuid.UUID, consents []models.Consent) {
+	if _, err := s.consents.RecordConsents(ctx, customerID, consents); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		} else {
+			log.Errorf("error recording consents: %v", err)
+			http.Error(w, "Failed to record consents", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	current, err := s.consents.ListConsents(ctx, customerID)
+	if err != nil {
+		log.Errorf("error listing consents: %v", err)
+		http.Error(w, "Failed to retrieve consents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nonNil(current))
+}
+
+// IssueUnsubscribeToken signs a token for the unsubscribe link of a message sent to the customer.
+func (s *Server) IssueUnsubscribeToken(w http.ResponseWriter, r *http.Request) {
+	if !s.unsubscribeEnabled(w) {
+		return
+	}
+	customerID, ok := s.customerFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req unsubscribeTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	sub := unsubscribe.Subscription{
+		CustomerID: customerID,
+		Purpose:    models.NormalizePurpose(req.Purpose),
+		Channel:    models.ConsentChannel(strings.ToLower(strings.TrimSpace(string(req.Channel)))),
+	}
+	if err := models.ValidatePurpose(sub.Purpose); err != nil {
+		http.Error(w, "Invalid unsubscribe token: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if sub.Channel != "" && !sub.Channel.Valid() {
+		http.Error(w, fmt.Sprintf("Invalid unsubscribe token: channel %q is not email, sms, phone or post", sub.Channel), http.StatusBadRequest)
+		return
+	}
+
+	token := s.unsubscribe.Sign(sub)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UnsubscribeToken{Token: token, URL: "/unsubscribe?token=" + url.QueryEscape(token)})
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+<form method="post" action="/unsubscribe?token={{.}}">
+<p>Do you want to stop receiving these messages?</p>
+<button type="submit">Unsubscribe</button>
+</form>
+</body>
+</html>
+`))
+
+// UnsubscribePage asks for a confirmation before unsubscribing, so that mail scanners following
+// the link do not unsubscribe anybody.
+func (s *Server) UnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	if !s.unsubscribeEnabled(w) {
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if _, err := s.unsubscribe.Verify(token); err != nil {
+		http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	unsubscribePage.Execute(w, token)
+}
+
+// Unsubscribe revokes the consent named by the token of an unsubscribe link, without
+// authentication. It also serves the one-click unsubscribe of mail clients, RFC 8058. A token
+// without a channel revokes every granted channel of its purpose.
+func (s *Server) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	if !s.unsubscribeEnabled(w) {
+		return
+	}
+	sub, err := s.unsubscribe.Verify(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+		return
+	}
+	ctx := actor.NewContext(r.Context(), unsubscribeActor)
+
+	current, err := s.consents.ListConsents(ctx, sub.CustomerID)
+	if err != nil {
+		log.Errorf("error listing consents: %v", err)
+		http.Error(w, "Failed to unsubscribe, please try again later", http.StatusInternalServerError)
+		return
+	}
+	var revocations []models.Consent
+	revoke := func(channel models.ConsentChannel) {
+		revocations = append(revocations, models.Consent{
+			ID:      uuid.New(),
+			Purpose: sub.Purpose,
+			Channel: channel,
+			State:   models.ConsentRevoked,
+			Source:  models.UnsubscribeSource,
+		})
+	}
+	if sub.Channel != "" {
+		// Recorded even without a previous grant, as an explicit opt-out.
+		revoke(sub.Channel)
+	} else {
+		for _, c := range current {
+			if c.Purpose == sub.Purpose && c.Granted() {
+				revoke(c.Channel)
+			}
+		}
+	}
+
+	if len(revocations) > 0 {
+		if _, err := s.consents.RecordConsents(ctx, sub.CustomerID, revocations); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Customer not found", http.StatusNotFound)
+			} else {
+				log.Errorf("error revoking consents: %v", err)
+				http.Error(w, "Failed to unsubscribe, please try again later", http.StatusInternalServerError)
+			}
+			return
+		}
+		log.Infof("customer %s unsubscribed from %s", sub.CustomerID, sub.Purpose)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "You have been unsubscribed.")
+}
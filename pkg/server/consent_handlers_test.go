@@ -0,0 +1,261 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/unsubscribe"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testUnsubscribeSecret = "a secret of at least thirty-two characters"
+
+// withConsents attaches consents and an unsubscribe signer to a test server.
+func withConsents(consents *mocks.ConsentRepository) func(*Server) {
+	return func(s *Server) {
+		s.SetConsentRepository(consents)
+		s.SetUnsubscribeSigner(unsubscribe.NewSigner(testUnsubscribeSecret))
+	}
+}
+
+func TestPutConsents(t *testing.T) {
+	consents := &mocks.ConsentRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, withConsents(consents))
+	id := uuid.New()
+
+	consents.On("RecordConsents", mock.Anything, id, mock.MatchedBy(func(c []models.Consent) bool {
+		return len(c) == 2 && c[0].ID != uuid.Nil &&
+			c[0].Purpose == "marketing" && c[0].Channel == models.ChannelEmail && c[0].State == models.ConsentGranted &&
+			c[0].PolicyVersion == "2026-03" && c[1].Channel == models.ChannelSMS && c[1].State == models.ConsentRevoked
+	})).Return([]models.Consent{}, nil)
+	current := []models.Consent{{ID: uuid.New(), CustomerID: id, Purpose: "marketing", Channel: models.ChannelEmail, State: models.ConsentGranted}}
+	consents.On("ListConsents", mock.Anything, id).Return(current, nil)
+
+	body := `[{"purpose": "Marketing", "channel": "email", "state": "granted", "source": "signup_form", "policy_version": "2026-03"},
+		{"purpose": "marketing", "channel": "sms", "state": "revoked", "source": "signup_form"}]`
+	req, err := http.NewRequest("PUT", "/customers/"+id.String()+"/consents", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var got []models.Consent
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, current, got)
+	consents.AssertExpectations(t)
+}
+
+func TestPutConsents_Invalid(t *testing.T) {
+	consents := &mocks.ConsentRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, withConsents(consents))
+	id := uuid.New()
+
+	for name, tc := range map[string]struct{ body, message string }{
+		"grant without policy": {
+			`[{"purpose": "marketing", "channel": "email", "state": "granted", "source": "form"}]`,
+			"Invalid consent 0: policy_version is required when consent is granted\n",
+		},
+		"duplicate": {
+			`[{"purpose": "marketing", "channel": "email", "state": "revoked", "source": "form"},
+			  {"purpose": "Marketing", "channel": "email", "state": "revoked", "source": "call"}]`,
+			"Invalid consent 1: marketing:email is given more than once\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("PUT", "/customers/"+id.String()+"/consents", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			s.Router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, tc.message, rr.Body.String())
+		})
+	}
+	consents.AssertNotCalled(t, "RecordConsents", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPutConsents_CustomerNotFound(t *testing.T) {
+	consents := &mocks.ConsentRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, withConsents(consents))
+	id := uuid.New()
+
+	consents.On("RecordConsents", mock.Anything, id, mock.Anything).Return(nil, sql.ErrNoRows)
+
+	body := `[{"purpose": "marketing", "channel": "post", "state": "revoked", "source": "letter"}]`
+	req, err := http.NewRequest("PUT", "/customers/"+id.String()+"/consents", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestListConsentHistory(t *testing.T) {
+	customers, consents := &mocks.CustomerRepository{}, &mocks.ConsentRepository{}
+	s := newTestServer(customers, withConsents(consents))
+	id := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id}, nil)
+	consents.On("ListConsentHistory", mock.Anything, id).Return(nil, nil)
+
+	req, err := http.NewRequest("GET", "/customers/"+id.String()+"/consents/history", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+}
+
+func TestConsentsDisabled(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	req, err := http.NewRequest("GET", "/customers/"+uuid.NewString()+"/consents", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Consents are disabled\n", rr.Body.String())
+}
+
+func issueUnsubscribeToken(t *testing.T, s *Server, id uuid.UUID, body string) UnsubscribeToken {
+	req, err := http.NewRequest("POST", "/customers/"+id.String()+"/unsubscribe-tokens", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var token UnsubscribeToken
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &token))
+	return token
+}
+
+func TestUnsubscribe(t *testing.T) {
+	customers, consents := &mocks.CustomerRepository{}, &mocks.ConsentRepository{}
+	s := newTestServer(customers, withConsents(consents))
+	id := uuid.New()
+	customers.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id}, nil)
+
+	token := issueUnsubscribeToken(t, s, id, `{"purpose": "marketing"}`)
+	assert.Equal(t, "/unsubscribe?token="+url.QueryEscape(token.Token), token.URL)
+
+	// Opening the link only asks for a confirmation.
+	req, err := http.NewRequest("GET", token.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `<form method="post"`)
+	consents.AssertNotCalled(t, "RecordConsents", mock.Anything, mock.Anything, mock.Anything)
+
+	consents.On("ListConsents", mock.Anything, id).Return([]models.Consent{
+		{Purpose: "marketing", Channel: models.ChannelEmail, State: models.ConsentGranted},
+		{Purpose: "marketing", Channel: models.ChannelPhone, State: models.ConsentRevoked},
+		{Purpose: "marketing", Channel: models.ChannelSMS, State: models.ConsentGranted},
+		{Purpose: "billing", Channel: models.ChannelEmail, State: models.ConsentGranted},
+	}, nil)
+	consents.On("RecordConsents", mock.Anything, id, mock.MatchedBy(func(c []models.Consent) bool {
+		return len(c) == 2 && c[0].Channel == models.ChannelEmail && c[1].Channel == models.ChannelSMS &&
+			c[0].Purpose == "marketing" && c[0].State == models.ConsentRevoked && c[0].Source == models.UnsubscribeSource
+	})).Return([]models.Consent{}, nil)
+
+	// Mail clients unsubscribe in one click, RFC 8058, without authentication.
+	req, err = http.NewRequest("POST", token.URL, strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "You have been unsubscribed.\n", rr.Body.String())
+	consents.AssertExpectations(t)
+}
+
+func TestUnsubscribe_InvalidToken(t *testing.T) {
+	consents := &mocks.ConsentRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, withConsents(consents))
+	forged := unsubscribe.NewSigner("another secret of at least thirty-two characters").
+		Sign(unsubscribe.Subscription{CustomerID: uuid.New(), Purpose: "marketing"})
+
+	req, err := http.NewRequest("POST", "/unsubscribe?token="+url.QueryEscape(forged), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid unsubscribe link\n", rr.Body.String())
+	consents.AssertNotCalled(t, "ListConsents", mock.Anything, mock.Anything)
+}
+
+func TestGetAllCustomers_Contactable(t *testing.T) {
+	customers := &mocks.CustomerRepository{}
+	s := newTestServer(customers, withConsents(&mocks.ConsentRepository{}))
+
+	customers.On("FindCustomers", mock.Anything, repository.CustomerQuery{
+		Contactable: &repository.ContactableFilter{Purpose: "marketing", Channel: models.ChannelEmail},
+	}).Return([]models.Customer{}, nil)
+
+	req, err := http.NewRequest("GET", "/customers?contactable=Marketing:email", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	customers.AssertExpectations(t)
+
+	req, err = http.NewRequest("GET", "/customers?contactable=marketing:fax", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
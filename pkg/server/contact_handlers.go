@@ -0,0 +1,291 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// contactCustomer resolves the customer of a nested email or phone route and writes the error
+// response when contacts are disabled or the customer is invalid or does not exist.
+func (s *Server) contactCustomer(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if s.contacts == nil {
+		http.Error(w, "Contacts are disabled", http.StatusNotFound)
+		return uuid.Nil, false
+	}
+	return s.customerFromPath(w, r)
+}
+
+// contactError writes the response for an error returned by a contact repository write.
+func contactError(w http.ResponseWriter, err error, notFound, failed string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, notFound, http.StatusNotFound)
+	case errors.Is(err, repository.ErrDuplicate):
+		http.Error(w, "Email already in use", http.StatusConflict)
+	case errors.Is(err, repository.ErrPrimaryContact):
+		http.Error(w, "A customer must keep a primary email, make another email primary first", http.StatusConflict)
+	default:
+		log.Errorf("%s: %v", failed, err)
+		http.Error(w, failed, http.StatusInternalServerError)
+	}
+}
+
+func decodeEmail(w http.ResponseWriter, r *http.Request) (models.Email, bool) {
+	var e models.Email
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return e, false
+	}
+
+	e.Normalize()
+	if err := e.Validate(); err != nil {
+		http.Error(w, validationMessage("Invalid email: ", err), http.StatusBadRequest)
+		return e, false
+	}
+	return e, true
+}
+
+func decodePhone(w http.ResponseWriter, r *http.Request) (models.Phone, bool) {
+	var p models.Phone
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return p, false
+	}
+
+	p.Normalize()
+	if err := p.Validate(); err != nil {
+		http.Error(w, validationMessage("Invalid phone: ", err), http.StatusBadRequest)
+		return p, false
+	}
+	return p, true
+}
+
+func (s *Server) ListEmails(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	emails, err := s.contacts.ListEmails(r.Context(), customerID)
+	if err != nil {
+		log.Errorf("error listing emails: %v", err)
+		http.Error(w, "Failed to retrieve emails", http.StatusInternalServerError)
+		return
+	}
+	if emails == nil {
+		emails = []models.Email{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(emails)
+}
+
+func (s *Server) GetEmail(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "emailId", "Invalid email ID")
+	if !ok {
+		return
+	}
+
+	email, err := s.contacts.GetEmail(r.Context(), customerID, id)
+	if err != nil {
+		contactError(w, err, "Email not found", "Failed to retrieve email")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(email)
+}
+
+func (s *Server) CreateEmail(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+	if s.erased(w, r, customerID) {
+		return
+	}
+	e, ok := decodeEmail(w, r)
+	if !ok {
+		return
+	}
+
+	e.ID = uuid.New()
+	e.CustomerID = customerID
+	if err := s.contacts.CreateEmail(r.Context(), e); err != nil {
+		contactError(w, err, "Email not found", "Failed to create email")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+func (s *Server) UpdateEmail(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+	if s.erased(w, r, customerID) {
+		return
+	}
+	id, ok := pathID(w, r, "emailId", "Invalid email ID")
+	if !ok {
+		return
+	}
+	e, ok := decodeEmail(w, r)
+	if !ok {
+		return
+	}
+
+	e.ID = id
+	e.CustomerID = customerID
+	if err := s.contacts.UpdateEmail(r.Context(), e); err != nil {
+		contactError(w, err, "Email not found", "Failed to update email")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+func (s *Server) DeleteEmail(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "emailId", "Invalid email ID")
+	if !ok {
+		return
+	}
+
+	if err := s.contacts.DeleteEmail(r.Context(), customerID, id); err != nil {
+		contactError(w, err, "Email not found", "Failed to delete email")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) ListPhones(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	phones, err := s.contacts.ListPhones(r.Context(), customerID)
+	if err != nil {
+		log.Errorf("error listing phones: %v", err)
+		http.Error(w, "Failed to retrieve phones", http.StatusInternalServerError)
+		return
+	}
+	if phones == nil {
+		phones = []models.Phone{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(phones)
+}
+
+func (s *Server) GetPhone(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "phoneId", "Invalid phone ID")
+	if !ok {
+		return
+	}
+
+	phone, err := s.contacts.GetPhone(r.Context(), customerID, id)
+	if err != nil {
+		contactError(w, err, "Phone not found", "Failed to retrieve phone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(phone)
+}
+
+func (s *Server) CreatePhone(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+	if s.erased(w, r, customerID) {
+		return
+	}
+	p, ok := decodePhone(w, r)
+	if !ok {
+		return
+	}
+
+	p.ID = uuid.New()
+	p.CustomerID = customerID
+	if err := s.contacts.CreatePhone(r.Context(), p); err != nil {
+		contactError(w, err, "Phone not found", "Failed to create phone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (s *Server) UpdatePhone(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+	if s.erased(w, r, customerID) {
+		return
+	}
+	id, ok := pathID(w, r, "phoneId", "Invalid phone ID")
+	if !ok {
+		return
+	}
+	p, ok := decodePhone(w, r)
+	if !ok {
+		return
+	}
+
+	p.ID = id
+	p.CustomerID = customerID
+	if err := s.contacts.UpdatePhone(r.Context(), p); err != nil {
+		contactError(w, err, "Phone not found", "Failed to update phone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func (s *Server) DeletePhone(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := s.contactCustomer(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "phoneId", "Invalid phone ID")
+	if !ok {
+		return
+	}
+
+	if err := s.contacts.DeletePhone(r.Context(), customerID, id); err != nil {
+		contactError(w, err, "Phone not found", "Failed to delete phone")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
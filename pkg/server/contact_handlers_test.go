@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateEmail(t *testing.T) {
+	customers, contacts := &mocks.CustomerRepository{}, &mocks.ContactRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetContactRepository(contacts) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	contacts.On("CreateEmail", mock.Anything, mock.MatchedBy(func(e models.Email) bool {
+		return e.CustomerID == customerID && e.Email == "work@example.com" && e.IsPrimary
+	})).Return(nil)
+
+	body := `{"type":"work","email":"work@example.com","is_primary":true}`
+	req, err := http.NewRequest("POST", "/customers/"+customerID.String()+"/emails", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, status)
+	}
+
+	contacts.AssertExpectations(t)
+}
+
+func TestCreateEmail_Duplicate(t *testing.T) {
+	customers, contacts := &mocks.CustomerRepository{}, &mocks.ContactRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetContactRepository(contacts) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	contacts.On("CreateEmail", mock.Anything, mock.AnythingOfType("models.Email")).
+		Return(fmt.Errorf("error inserting email: %w", repository.ErrDuplicate))
+
+	body := `{"type":"home","email":"taken@example.com"}`
+	req, err := http.NewRequest("POST", "/customers/"+customerID.String()+"/emails", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "Email already in use\n", rr.Body.String())
+}
+
+func TestDeleteEmail_Primary(t *testing.T) {
+	customers, contacts := &mocks.CustomerRepository{}, &mocks.ContactRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetContactRepository(contacts) })
+	customerID, emailID := uuid.New(), uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	contacts.On("DeleteEmail", mock.Anything, customerID, emailID).Return(repository.ErrPrimaryContact)
+
+	req, err := http.NewRequest("DELETE", "/customers/"+customerID.String()+"/emails/"+emailID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	contacts.AssertExpectations(t)
+}
+
+func TestCreatePhone_Normalized(t *testing.T) {
+	customers, contacts := &mocks.CustomerRepository{}, &mocks.ContactRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetContactRepository(contacts) })
+	customerID := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	contacts.On("CreatePhone", mock.Anything, mock.MatchedBy(func(p models.Phone) bool {
+		return p.Number == "+442079460000"
+	})).Return(nil)
+
+	body := `{"type":"mobile","number":"+44 (20) 7946-0000"}`
+	req, err := http.NewRequest("POST", "/customers/"+customerID.String()+"/phones", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, status)
+	}
+
+	var created models.Phone
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, "+442079460000", created.Number)
+
+	contacts.AssertExpectations(t)
+}
+
+func TestListPhones_Disabled(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	req, err := http.NewRequest("GET", "/customers/"+uuid.NewString()+"/phones", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Contacts are disabled\n", rr.Body.String())
+}
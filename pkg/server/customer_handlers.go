@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/resilient"
+	"CustomerCRUD/pkg/service"
+	"CustomerCRUD/pkg/verification"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -16,10 +21,28 @@ import (
 func (s *Server) GetAllCustomers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	customers, err := s.repository.GetAllCustomers(ctx)
+	query, search, err := s.customerQuery(ctx, r)
+	var clientErr clientError
+	if errors.As(err, &clientErr) {
+		http.Error(w, clientErr.message, http.StatusBadRequest)
+		return
+	}
+
+	var customers []models.Customer
+	if err == nil {
+		if search {
+			customers, err = s.customers.FindCustomers(ctx, query)
+		} else {
+			customers, err = s.customers.GetAllCustomers(ctx)
+		}
+	}
 	if err != nil {
-		log.Errorf("error getting customers: %v", err)
-		http.Error(w, "Problem when retrieving customers, please try again later", http.StatusInternalServerError)
+		internalError(w, err, "error getting customers", "Problem when retrieving customers, please try again later")
+		return
+	}
+
+	if err := s.embedIncludes(ctx, r, customers); err != nil {
+		internalError(w, err, "error getting included resources", "Problem when retrieving customers, please try again later")
 		return
 	}
 
@@ -31,17 +54,23 @@ func (s *Server) GetCustomerByEmail(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	email := mux.Vars(r)["email"]
-	customer, err := s.repository.GetCustomerByEmail(ctx, email)
+	customer, err := s.customers.GetCustomerByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "Customer not found", http.StatusNotFound)
 		} else {
-			log.Errorf("error getting customer by email: %v", err)
-			http.Error(w, "Failed to retrieve customer", http.StatusInternalServerError)
+			internalError(w, err, "error getting customer by email", "Failed to retrieve customer")
 		}
 		return
 	}
 
+	customers := []models.Customer{*customer}
+	if err := s.embedIncludes(ctx, r, customers); err != nil {
+		internalError(w, err, "error getting included resources", "Failed to retrieve customer")
+		return
+	}
+	customer = &customers[0]
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(customer)
 }
@@ -57,17 +86,23 @@ func (s *Server) GetCustomerByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	customer, err := s.repository.GetCustomerByID(ctx, id)
+	customer, err := s.customers.GetCustomerByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "Customer not found", http.StatusNotFound)
 		} else {
-			log.Errorf("error getting customer by ID: %v", err)
-			http.Error(w, "Failed to retrieve customer", http.StatusInternalServerError)
+			internalError(w, err, "error getting customer by ID", "Failed to retrieve customer")
 		}
 		return
 	}
 
+	customers := []models.Customer{*customer}
+	if err := s.embedIncludes(ctx, r, customers); err != nil {
+		internalError(w, err, "error getting included resources", "Failed to retrieve customer")
+		return
+	}
+	customer = &customers[0]
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(customer)
 }
@@ -81,16 +116,8 @@ func (s *Server) CreateCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if c.Email == "" || c.FirstName == "" || c.LastName == "" {
-		http.Error(w, "First name, last name, and email are required", http.StatusBadRequest)
-		return
-	}
-
-	c.ID = uuid.New()
-	err := s.repository.CreateCustomer(ctx, c)
-	if err != nil {
-		log.Errorf("Failed to create customer: %s", err)
-		http.Error(w, "Failed to create customer", http.StatusInternalServerError)
+	if err := s.customers.CreateCustomer(ctx, &c); err != nil {
+		writeServiceError(w, err, "Invalid customer: ", "Failed to create customer")
 		return
 	}
 
@@ -116,10 +143,8 @@ func (s *Server) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 	}
 	c.ID = id
 
-	err = s.repository.UpdateCustomer(ctx, c)
-	if err != nil {
-		log.Errorf("failed to update customer: %v", err)
-		http.Error(w, "Failed to update customer", http.StatusInternalServerError)
+	if err := s.customers.UpdateCustomer(ctx, &c); err != nil {
+		writeServiceError(w, err, "Invalid customer: ", "Failed to update customer")
 		return
 	}
 
@@ -138,12 +163,82 @@ func (s *Server) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.repository.DeleteCustomer(ctx, id)
-	if err != nil {
-		log.Errorf("failed to delete customer: %v", err)
-		http.Error(w, "Failed to delete customer", http.StatusInternalServerError)
+	if err := s.customers.DeleteCustomer(ctx, id); err != nil {
+		writeServiceError(w, err, "Invalid customer: ", "Failed to delete customer")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (s *Server) CustomerAuditTrail(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	entries, err := s.customers.AuditTrail(r.Context(), id)
+	if err != nil {
+		internalError(w, err, "error getting customer audit trail", "Failed to retrieve customer audit trail")
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// writeServiceError writes the response for an error returned by a customer service write.
+// Validation errors are prefixed with invalid, unexpected errors are logged and answered with failed.
+func writeServiceError(w http.ResponseWriter, err error, invalid, failed string) {
+	if attributeError(w, err) {
+		return
+	}
+
+	var validationErr *service.ValidationError
+	var transitionErr *service.TransitionError
+	var holdErr *service.LegalHoldError
+	var limitErr *verification.RateLimitError
+	switch {
+	case errors.Is(err, models.ErrRequiredFields):
+		http.Error(w, "First name, last name, and email are required", http.StatusBadRequest)
+	case errors.As(err, &validationErr):
+		http.Error(w, validationMessage(invalid, err), http.StatusBadRequest)
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "Not allowed to change this customer", http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Customer not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDuplicate):
+		http.Error(w, "Email already in use", http.StatusConflict)
+	case errors.As(err, &transitionErr):
+		http.Error(w, "Status change not allowed: "+transitionErr.Error(), http.StatusConflict)
+	case errors.Is(err, repository.ErrStatusConflict):
+		http.Error(w, "The customer status changed in the meantime, please retry", http.StatusConflict)
+	case errors.As(err, &holdErr):
+		http.Error(w, "Customer is under legal hold, see its legal holds", http.StatusConflict)
+	case errors.Is(err, service.ErrAlreadyErased):
+		http.Error(w, "Customer is already erased", http.StatusConflict)
+	case errors.Is(err, service.ErrErased):
+		http.Error(w, "Customer was erased", http.StatusGone)
+	case errors.Is(err, service.ErrVerificationDisabled):
+		http.Error(w, "Email verification is disabled", http.StatusNotFound)
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		http.Error(w, "Email is already verified", http.StatusConflict)
+	case errors.Is(err, service.ErrEmailChanged):
+		http.Error(w, "The email changed since this verification link was sent", http.StatusConflict)
+	case errors.Is(err, verification.ErrInvalidToken):
+		http.Error(w, "Invalid verification link", http.StatusBadRequest)
+	case errors.Is(err, verification.ErrExpiredToken):
+		http.Error(w, "Verification link expired, please request a new one", http.StatusBadRequest)
+	case errors.As(err, &limitErr):
+		w.Header().Set("Retry-After", headerSeconds(limitErr.RetryAfter))
+		http.Error(w, "Verification email sent too recently, please retry later", http.StatusTooManyRequests)
+	case errors.Is(err, resilient.ErrCircuitOpen):
+		writeUnavailable(w, time.Second)
+	default:
+		log.Errorf("%s: %v", failed, err)
+		http.Error(w, failed, http.StatusInternalServerError)
+	}
+}
@@ -3,16 +3,22 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/events"
 	"CustomerCRUD/pkg/models"
 	"CustomerCRUD/pkg/repository"
 	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/service"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -20,10 +26,16 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-func newTestServer(mockRepo repository.CustomerRepository) *Server {
-	return &Server{
-		repository: mockRepo,
+// newTestServer returns a server over the customers repository, changed by the options, e.g. to
+// attach another repository, and then with its routes set up.
+func newTestServer(customers repository.CustomerRepository, options ...func(*Server)) *Server {
+	uow := repository.Untransacted(repository.Repositories{Customers: customers})
+	s := NewServer(service.NewCustomerService(uow, events.NewBus()))
+	for _, option := range options {
+		option(s)
 	}
+	s.SetupRoutes()
+	return s
 }
 
 func TestGetAllCustomers(t *testing.T) {
@@ -325,7 +337,7 @@ func TestCreateCustomer(t *testing.T) {
 		Email:     "bob.builder@example.com",
 	}
 
-	mockRepo.On("CreateCustomer", mock.Anything, mock.MatchedBy(func(c models.Customer) bool {
+	mockRepo.On("CreateCustomer", mock.Anything, mock.MatchedBy(func(c *models.Customer) bool {
 		return c.FirstName == inputCustomer.FirstName &&
 			c.LastName == inputCustomer.LastName &&
 			c.Email == inputCustomer.Email &&
@@ -431,7 +443,7 @@ func TestCreateCustomer_Error(t *testing.T) {
 		Email:     "error.case@example.com",
 	}
 
-	mockRepo.On("CreateCustomer", mock.Anything, mock.AnythingOfType("models.Customer")).Return(errors.New("database error"))
+	mockRepo.On("CreateCustomer", mock.Anything, mock.AnythingOfType("*models.Customer")).Return(errors.New("database error"))
 
 	body, _ := json.Marshal(inputCustomer)
 	req, err := http.NewRequest("POST", "/customers", bytes.NewBuffer(body))
@@ -456,6 +468,42 @@ func TestCreateCustomer_Error(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestCreateCustomer_DuplicateEmail(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	s := newTestServer(mockRepo)
+
+	inputCustomer := models.Customer{
+		FirstName: "Taken",
+		LastName:  "Email",
+		Email:     "taken@example.com",
+	}
+
+	mockRepo.On("CreateCustomer", mock.Anything, mock.AnythingOfType("*models.Customer")).
+		Return(fmt.Errorf("error inserting customer rows: %w", repository.ErrDuplicate))
+
+	body, _ := json.Marshal(inputCustomer)
+	req, err := http.NewRequest("POST", "/customers", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.CreateCustomer)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, status)
+	}
+
+	expectedBody := "Email already in use\n"
+	if rr.Body.String() != expectedBody {
+		t.Errorf("Expected body %q, got %q", expectedBody, rr.Body.String())
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
 func TestUpdateCustomer(t *testing.T) {
 	mockRepo := &mocks.CustomerRepository{}
 	s := newTestServer(mockRepo)
@@ -468,7 +516,8 @@ func TestUpdateCustomer(t *testing.T) {
 		Email:     "updated.user@example.com",
 	}
 
-	mockRepo.On("UpdateCustomer", mock.Anything, updatedCustomer).Return(nil)
+	mockRepo.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id, FirstName: "Old", LastName: "User", Email: "old.user@example.com"}, nil)
+	mockRepo.On("UpdateCustomer", mock.Anything, &updatedCustomer).Return(nil)
 
 	body, _ := json.Marshal(updatedCustomer)
 	req, err := http.NewRequest("PUT", "/customers/"+id.String(), bytes.NewBuffer(body))
@@ -563,7 +612,8 @@ func TestUpdateCustomer_Error(t *testing.T) {
 		Email:     "error.case@example.com",
 	}
 
-	mockRepo.On("UpdateCustomer", mock.Anything, updatedCustomer).Return(errors.New("database error"))
+	mockRepo.On("GetCustomerByID", mock.Anything, id).Return(&updatedCustomer, nil)
+	mockRepo.On("UpdateCustomer", mock.Anything, &updatedCustomer).Return(errors.New("database error"))
 
 	body, _ := json.Marshal(updatedCustomer)
 	req, err := http.NewRequest("PUT", "/customers/"+id.String(), bytes.NewBuffer(body))
@@ -594,6 +644,7 @@ func TestDeleteCustomer(t *testing.T) {
 	s := newTestServer(mockRepo)
 
 	id := uuid.New()
+	mockRepo.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id}, nil)
 	mockRepo.On("DeleteCustomer", mock.Anything, id).Return(nil)
 
 	req, err := http.NewRequest("DELETE", "/customers/"+id.String(), nil)
@@ -644,6 +695,7 @@ func TestDeleteCustomer_Error(t *testing.T) {
 	s := newTestServer(mockRepo)
 
 	id := uuid.New()
+	mockRepo.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id}, nil)
 	mockRepo.On("DeleteCustomer", mock.Anything, id).Return(errors.New("database error"))
 
 	req, err := http.NewRequest("DELETE", "/customers/"+id.String(), nil)
@@ -667,3 +719,149 @@ func TestDeleteCustomer_Error(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestCreateCustomer_Actor(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	s := newTestServer(mockRepo)
+
+	mockRepo.On("CreateCustomer", mock.MatchedBy(func(ctx context.Context) bool {
+		return actor.FromContext(ctx) == "alice@example.com"
+	}), mock.AnythingOfType("*models.Customer")).Run(func(args mock.Arguments) {
+		c := args.Get(1).(*models.Customer)
+		c.CreatedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		c.UpdatedAt = c.CreatedAt
+		c.CreatedBy = actor.FromContext(args.Get(0).(context.Context))
+		c.UpdatedBy = c.CreatedBy
+	}).Return(nil)
+
+	body := `{"first_name":"Jane","last_name":"Doe","email":"jane.doe@example.com"}`
+	req, err := http.NewRequest("POST", "/customers", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Actor", "alice@example.com")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, status)
+	}
+
+	var created models.Customer
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, "alice@example.com", created.CreatedBy)
+	assert.Equal(t, "2024-05-01T12:00:00Z", created.UpdatedAt.Format(time.RFC3339))
+}
+
+func TestGetAllCustomers_UpdatedSince(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	s := newTestServer(mockRepo)
+
+	mockRepo.On("FindCustomers", mock.Anything, repository.CustomerQuery{
+		UpdatedSince: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		SortField:    "updated_at",
+	}).Return([]models.Customer{}, nil)
+
+	req, err := http.NewRequest("GET", "/customers?updated_since=2024-05-01T12:00:00Z&sort=updated_at", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+
+	req, err = http.NewRequest("GET", "/customers?updated_since=yesterday", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestCustomerAuditTrail(t *testing.T) {
+	customers := &mocks.CustomerRepository{}
+	audit := &mocks.AuditRepository{}
+	s := NewServer(service.NewCustomerService(repository.Untransacted(repository.Repositories{Customers: customers, Audit: audit}), events.NewBus()))
+	s.SetupRoutes()
+
+	id := uuid.New()
+	audit.On("ListAuditEntries", mock.Anything, id).Return([]models.AuditEntry{{
+		ID:         uuid.New(),
+		CustomerID: id,
+		Action:     models.AuditUpdated,
+		Changes:    map[string]models.FieldChange{"last_name": {From: "Doe", To: "Smith"}},
+		Actor:      "alice@example.com",
+	}}, nil)
+
+	req, err := http.NewRequest("GET", "/customers/"+id.String()+"/audit", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var entries []models.AuditEntry
+	if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	assert.Len(t, entries, 1)
+	assert.Equal(t, models.FieldChange{From: "Doe", To: "Smith"}, entries[0].Changes["last_name"])
+	audit.AssertExpectations(t)
+}
+
+func TestDeleteCustomer_Forbidden(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	s := newTestServer(mockRepo)
+	s.customers.SetAuthorizer(service.AuthorizerFunc(func(ctx context.Context, action service.Action, _ uuid.UUID) error {
+		if action == service.ActionDelete {
+			return service.ErrForbidden
+		}
+		return nil
+	}))
+
+	id := uuid.New()
+	req, err := http.NewRequest("DELETE", "/customers/"+id.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": id.String()})
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.DeleteCustomer).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "Not allowed to change this customer\n", rr.Body.String())
+	mockRepo.AssertNotCalled(t, "DeleteCustomer", mock.Anything, mock.Anything)
+}
+
+func TestDeleteCustomer_NotFound(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	s := newTestServer(mockRepo)
+
+	id := uuid.New()
+	mockRepo.On("GetCustomerByID", mock.Anything, id).Return(nil, sql.ErrNoRows)
+
+	req, err := http.NewRequest("DELETE", "/customers/"+id.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": id.String()})
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.DeleteCustomer).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockRepo.AssertNotCalled(t, "DeleteCustomer", mock.Anything, mock.Anything)
+}
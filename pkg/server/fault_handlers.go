@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/repository/faults"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetFaultInjector enables the /admin/faults endpoints, which change the faults injected into the
+// customer repository at runtime. Without it they respond 404. It must not be set in production.
+func (s *Server) SetFaultInjector(injector *faults.Injector) {
+	s.faults = injector
+}
+
+func (s *Server) faultsEnabled(w http.ResponseWriter) bool {
+	if s.faults == nil {
+		http.Error(w, "Fault injection is disabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func (s *Server) GetFaults(w http.ResponseWriter, r *http.Request) {
+	if !s.faultsEnabled(w) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.faults.Config())
+}
+
+// PutFaults replaces the injected faults.
+func (s *Server) PutFaults(w http.ResponseWriter, r *http.Request) {
+	if !s.faultsEnabled(w) {
+		return
+	}
+
+	var config faults.Config
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := s.faults.Set(config); err != nil {
+		http.Error(w, validationMessage("Invalid faults: ", err), http.StatusBadRequest)
+		return
+	}
+	log.Warnf("%s set %d fault injection rules", actor.FromContext(r.Context()), len(config.Rules))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.faults.Config())
+}
+
+// DeleteFaults stops injecting faults.
+func (s *Server) DeleteFaults(w http.ResponseWriter, r *http.Request) {
+	if !s.faultsEnabled(w) {
+		return
+	}
+	s.faults.Set(faults.Config{})
+	log.Warnf("%s cleared the fault injection rules", actor.FromContext(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"CustomerCRUD/pkg/repository/faults"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFaultTestServer() (*Server, *faults.Injector, *mocks.CustomerRepository) {
+	mockRepo := &mocks.CustomerRepository{}
+	injector := faults.NewInjector()
+	s := newTestServer(injector.Customers(mockRepo), func(s *Server) { s.SetFaultInjector(injector) })
+	return s, injector, mockRepo
+}
+
+func sendFaults(t *testing.T, s *Server, method, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := http.NewRequest(method, "/admin/faults", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestFaults_Disabled(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	rr := getWith(t, s, "/admin/faults", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Fault injection is disabled\n", rr.Body.String())
+	assert.Equal(t, http.StatusNotFound, sendFaults(t, s, "PUT", `{"rules": []}`).Code)
+}
+
+func TestPutFaults(t *testing.T) {
+	s, injector, mockRepo := newFaultTestServer()
+
+	rr := sendFaults(t, s, "PUT", `{"rules": [{"method": "GetCustomerByID", "error_rate": 1, "error": "not_found"}], "seed": 7}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	want := faults.Config{Rules: []faults.Rule{{Method: "GetCustomerByID", ErrorRate: 1, Error: faults.ErrorNotFound}}, Seed: 7}
+	assert.Equal(t, want, injector.Config())
+
+	rr = getWith(t, s, "/admin/faults", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var got faults.Config
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, want, got)
+
+	// The customer looks missing without the database being called.
+	rr = getWith(t, s, "/customers/"+uuid.NewString(), nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockRepo.AssertNotCalled(t, "GetCustomerByID")
+
+	rr = sendFaults(t, s, "DELETE", "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, injector.Config().Rules)
+}
+
+func TestPutFaults_Invalid(t *testing.T) {
+	s, injector, _ := newFaultTestServer()
+
+	rr := sendFaults(t, s, "PUT", `{"rules": [{"method": "DropDatabase", "error_rate": 2}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "body /rules/0/error_rate: must be at most 1")
+	assert.Contains(t, rr.Body.String(), "body /rules/0/method: must be one of")
+
+	rr = sendFaults(t, s, "PUT", `{"rules": "all"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Request does not match the API specification: body /rules: must be of type array\n", rr.Body.String())
+	assert.Empty(t, injector.Config().Rules)
+}
@@ -0,0 +1,16 @@
+package server
+
+import "net/http"
+
+// MountGraphQL serves the GraphQL API with h at /graphql. Without it the endpoint responds 404.
+func (s *Server) MountGraphQL(h http.Handler) {
+	s.graphqlHandler = h
+}
+
+func (s *Server) GraphQL(w http.ResponseWriter, r *http.Request) {
+	if s.graphqlHandler == nil {
+		http.Error(w, "GraphQL is disabled", http.StatusNotFound)
+		return
+	}
+	s.graphqlHandler.ServeHTTP(w, r)
+}
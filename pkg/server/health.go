@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// readinessTimeout bounds how long all readiness checks together may take.
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck reports whether a dependency of the service is usable.
+type ReadinessCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// AddReadinessCheck registers a check that has to pass for /readyz to report ready.
+func (s *Server) AddReadinessCheck(name string, check ReadinessCheck) {
+	s.readinessChecks = append(s.readinessChecks, namedCheck{name: name, check: check})
+}
+
+// Healthz is the liveness probe. It only tells that the process is able to serve requests.
+func (s *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Readyz is the readiness probe. It runs every registered readiness check and
+// reports not ready while any of them fails or while the server is shutting down.
+func (s *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	checks := make(map[string]string, len(s.readinessChecks))
+
+	if s.shuttingDown.Load() {
+		status = http.StatusServiceUnavailable
+		checks["server"] = "shutting down"
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	for _, c := range s.readinessChecks {
+		if err := c.check(ctx); err != nil {
+			log.Warnf("readiness check %s failed: %v", c.name, err)
+			status = http.StatusServiceUnavailable
+			checks[c.name] = err.Error()
+			continue
+		}
+		checks[c.name] = "ok"
+	}
+
+	result := "ok"
+	if status != http.StatusOK {
+		result = "unavailable"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": result, "checks": checks})
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+func TestHealthz(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	req, err := http.NewRequest("GET", "/healthz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.Healthz)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+	s.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.Readyz)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+}
+
+func TestReadyz_CheckFails(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+	s.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+	s.AddReadinessCheck("migrations", func(ctx context.Context) error { return errors.New("schema version is 0, expected 1") })
+
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.Readyz)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+
+	var body struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+
+	assert.Equal(t, "unavailable", body.Status)
+	assert.Equal(t, "ok", body.Checks["database"])
+	assert.Equal(t, "schema version is 0, expected 1", body.Checks["migrations"])
+}
+
+func TestReadyz_ShuttingDown(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+	s.shuttingDown.Store(true)
+
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.Readyz)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestListenAndServe_Shutdown(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServe(ctx, "127.0.0.1:0", Timeouts{Shutdown: time.Second})
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected server to stop after context cancellation")
+	}
+	assert.True(t, s.shuttingDown.Load())
+}
+
+func TestListenAndServe_Drain(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServe(ctx, "127.0.0.1:0", Timeouts{Shutdown: time.Second, Drain: 300 * time.Millisecond})
+	}()
+
+	cancel()
+	assert.Eventually(t, s.shuttingDown.Load, time.Second, 10*time.Millisecond)
+
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	select {
+	case <-done:
+		t.Fatal("Expected server to keep serving during the drain delay")
+	case <-time.After(100 * time.Millisecond):
+	}
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected server to stop after the drain delay")
+	}
+}
+
+func TestMultiplexGRPC_StreamsOutliveWriteTimeout(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+	s.MultiplexGRPC(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, message := range []string{"first", "second"} {
+			if _, err := io.WriteString(w, message); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(300 * time.Millisecond)
+		}
+	}))
+
+	ts := httptest.NewUnstartedServer(s.handler())
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Post(ts.URL+"/customer.v1.CustomerService/WatchCustomers", "application/grpc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "firstsecond", string(body))
+}
+
+func TestMetrics(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+	s.AddMetrics("customer_cache", func() any { return map[string]int{"hits": 3} })
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.Metrics)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	assert.JSONEq(t, `{"customer_cache": {"hits": 3}}`, rr.Body.String())
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Timeouts groups the HTTP server timeouts together with the grace period
+// given to in-flight requests when the server is shutting down.
+type Timeouts struct {
+	Read     time.Duration
+	Write    time.Duration
+	Idle     time.Duration
+	Shutdown time.Duration
+	// Drain is how long the server keeps serving while reporting not ready before it shuts down,
+	// so that load balancers stop sending it traffic. It has no default, zero shuts down at once.
+	Drain time.Duration
+}
+
+// DefaultTimeouts are used for every zero value in the Timeouts passed to ListenAndServe.
+var DefaultTimeouts = Timeouts{
+	Read:     10 * time.Second,
+	Write:    15 * time.Second,
+	Idle:     60 * time.Second,
+	Shutdown: 20 * time.Second,
+}
+
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Read <= 0 {
+		t.Read = DefaultTimeouts.Read
+	}
+	if t.Write <= 0 {
+		t.Write = DefaultTimeouts.Write
+	}
+	if t.Idle <= 0 {
+		t.Idle = DefaultTimeouts.Idle
+	}
+	if t.Shutdown <= 0 {
+		t.Shutdown = DefaultTimeouts.Shutdown
+	}
+	return t
+}
+
+// ListenAndServe serves the router on addr until ctx is cancelled. On cancellation the
+// server stops reporting ready, keeps serving for the drain delay, then stops accepting
+// new connections and waits up to the shutdown grace period for in-flight requests to finish.
+func (s *Server) ListenAndServe(ctx context.Context, addr string, timeouts Timeouts) error {
+	timeouts = timeouts.withDefaults()
+
+	httpServer := &http.Server{
+		Addr:         addr,
+		Handler:      s.handler(),
+		ReadTimeout:  timeouts.Read,
+		WriteTimeout: timeouts.Write,
+		IdleTimeout:  timeouts.Idle,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.shuttingDown.Store(true)
+	if timeouts.Drain > 0 {
+		log.Infof("reporting not ready for %s before shutting down", timeouts.Drain)
+		select {
+		case err := <-serveErr:
+			return err
+		case <-time.After(timeouts.Drain):
+		}
+	}
+	log.Infof("shutting down, waiting up to %s for in-flight requests", timeouts.Shutdown)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeouts.Shutdown)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// MultiplexGRPC serves gRPC requests with h on the HTTP port. Requests are told apart by
+// their protocol and content type, which needs cleartext HTTP/2 (h2c) to be enabled. The read
+// and write timeouts do not apply to gRPC requests, so that streams like WatchCustomers stay open.
+func (s *Server) MultiplexGRPC(h http.Handler) {
+	s.grpcHandler = h
+}
+
+func (s *Server) handler() http.Handler {
+	if s.grpcHandler == nil {
+		return s.Router
+	}
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			rc := http.NewResponseController(w)
+			if err := errors.Join(rc.SetReadDeadline(time.Time{}), rc.SetWriteDeadline(time.Time{})); err != nil {
+				log.Warnf("error lifting the timeouts of gRPC request %s: %v", r.URL.Path, err)
+			}
+			s.grpcHandler.ServeHTTP(w, r)
+			return
+		}
+		s.Router.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
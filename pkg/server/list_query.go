@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+)
+
+// attributeFilterPrefix starts the query parameters filtering customers by a custom attribute,
+// as in attr.loyalty_tier=gold or attr.birthday.gte=1990-01-01.
+const attributeFilterPrefix = "attr."
+
+// customerQuery builds the query of the list endpoint from its attribute filters, updated_since,
+// status, contactable and sort parameters. It returns false when the request has none of them.
+// Errors are meant for the client.
+func (s *Server) customerQuery(ctx context.Context, r *http.Request) (repository.CustomerQuery, bool, error) {
+	var query repository.CustomerQuery
+	params := r.URL.Query()
+
+	var filterKeys []string
+	for key := range params {
+		if strings.HasPrefix(key, attributeFilterPrefix) {
+			filterKeys = append(filterKeys, key)
+		}
+	}
+	sort.Strings(filterKeys)
+	sortBy := params.Get("sort")
+	updatedSince := params.Get("updated_since")
+	statuses := params.Get("status")
+	contactable := params.Get("contactable")
+	if len(filterKeys) == 0 && sortBy == "" && updatedSince == "" && statuses == "" && contactable == "" {
+		return query, false, nil
+	}
+
+	if updatedSince != "" {
+		since, err := time.Parse(time.RFC3339Nano, updatedSince)
+		if err != nil {
+			return query, true, clientError{"updated_since must be a date-time such as 2024-05-01T12:00:00Z"}
+		}
+		query.UpdatedSince = since
+	}
+	if statuses != "" {
+		for _, raw := range strings.Split(statuses, ",") {
+			status := models.Status(strings.ToLower(strings.TrimSpace(raw)))
+			if !status.Valid() {
+				return query, true, clientError{fmt.Sprintf("Unknown status %q", raw)}
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+	if contactable != "" {
+		filter, err := parseContactable(contactable)
+		if err != nil {
+			return query, true, clientError{"Invalid contactable filter: " + err.Error()}
+		}
+		query.Contactable = filter
+	}
+
+	defs := map[string]models.AttributeDefinition{}
+	if s.attributes != nil {
+		list, err := s.attributes.ListAttributeDefinitions(ctx)
+		if err != nil {
+			return query, true, err
+		}
+		for _, d := range list {
+			defs[d.Name] = d
+		}
+	}
+
+	for _, key := range filterKeys {
+		name, op := strings.TrimPrefix(key, attributeFilterPrefix), repository.OpEqual
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name, op = name[:i], repository.FilterOp(name[i+1:])
+		}
+		def, ok := defs[name]
+		if !ok {
+			return query, true, clientError{fmt.Sprintf("Unknown attribute %q", name)}
+		}
+		if !op.Valid() {
+			return query, true, clientError{fmt.Sprintf("Unknown operator %q, use eq, gt, gte, lt or lte", op)}
+		}
+		for _, raw := range params[key] {
+			value, err := def.ParseValue(raw)
+			if err != nil {
+				return query, true, clientError{fmt.Sprintf("Invalid value for attribute %s: %v", name, err)}
+			}
+			query.Filters = append(query.Filters, repository.AttributeFilter{Attribute: def, Op: op, Value: value})
+		}
+	}
+
+	if strings.HasPrefix(sortBy, "-") {
+		query.Descending = true
+		sortBy = sortBy[1:]
+	}
+	if name, ok := strings.CutPrefix(sortBy, attributeFilterPrefix); ok {
+		def, ok := defs[name]
+		if !ok {
+			return query, true, clientError{fmt.Sprintf("Unknown attribute %q", name)}
+		}
+		query.SortAttribute = &def
+	} else if sortBy != "" {
+		if !repository.SortFields[sortBy] {
+			return query, true, clientError{fmt.Sprintf("Cannot sort by %q", sortBy)}
+		}
+		query.SortField = sortBy
+	}
+	return query, true, nil
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type namedMetrics struct {
+	name   string
+	source func() any
+}
+
+// AddMetrics registers source, whose value /metrics reports under name.
+func (s *Server) AddMetrics(name string, source func() any) {
+	s.metrics = append(s.metrics, namedMetrics{name: name, source: source})
+}
+
+// Metrics reports the current value of every registered metrics source.
+func (s *Server) Metrics(w http.ResponseWriter, r *http.Request) {
+	metrics := make(map[string]any, len(s.metrics))
+	for _, m := range s.metrics {
+		metrics[m.name] = m.source()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// customerFromPath resolves the customer of a nested route and writes the error response
+// when the ID is invalid or the customer does not exist.
+func (s *Server) customerFromPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	if _, err := s.customers.GetCustomerByID(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		} else {
+			internalError(w, err, "error getting customer by ID", "Failed to retrieve customer")
+		}
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// erased writes a 410 response when the customer was erased, as writing personal data to it would
+// undo the erasure.
+func (s *Server) erased(w http.ResponseWriter, r *http.Request, customerID uuid.UUID) bool {
+	err := s.customers.CheckNotErased(r.Context(), customerID)
+	if err == nil {
+		return false
+	}
+	writeServiceError(w, err, "", "Failed to retrieve customer")
+	return true
+}
+
+// pathID parses the UUID path variable name and responds with message when it is invalid.
+func pathID(w http.ResponseWriter, r *http.Request, name, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)[name])
+	if err != nil {
+		http.Error(w, message, http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// validationMessage puts the errors joined by a Validate method on a single line.
+func validationMessage(prefix string, err error) string {
+	return prefix + strings.ReplaceAll(err.Error(), "\n", "; ")
+}
+
+// includes returns the related resources requested with the comma separated include parameter.
+func includes(r *http.Request) map[string]bool {
+	out := map[string]bool{}
+	for _, v := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out[v] = true
+		}
+	}
+	return out
+}
+
+// embedIncludes loads the related resources requested by r into customers. Resources whose
+// repository is not configured are left out.
+func (s *Server) embedIncludes(ctx context.Context, r *http.Request, customers []models.Customer) error {
+	include := includes(r)
+	if len(include) == 0 || len(customers) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(customers))
+	for i, c := range customers {
+		ids[i] = c.ID
+	}
+
+	if include["addresses"] && s.addresses != nil {
+		byCustomer, err := s.addresses.ListAddressesByCustomerIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range customers {
+			customers[i].Addresses = byCustomer[customers[i].ID]
+		}
+	}
+	if include["emails"] && s.contacts != nil {
+		byCustomer, err := s.contacts.ListEmailsByCustomerIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range customers {
+			customers[i].Emails = byCustomer[customers[i].ID]
+		}
+	}
+	if include["phones"] && s.contacts != nil {
+		byCustomer, err := s.contacts.ListPhonesByCustomerIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range customers {
+			customers[i].Phones = byCustomer[customers[i].ID]
+		}
+	}
+	if include["tags"] && s.tags != nil {
+		byCustomer, err := s.tags.ListTagsByCustomerIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range customers {
+			customers[i].Tags = byCustomer[customers[i].ID]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"CustomerCRUD/api"
+	"CustomerCRUD/pkg/openapi"
+
+	"github.com/gorilla/mux"
+	"github.com/swaggest/swgui/v5emb"
+)
+
+// maxBodyBytes caps the request bodies read by the validation middleware.
+const maxBodyBytes = 1 << 20
+
+// apiSpec is parsed once, a broken document fails every test of the package.
+var apiSpec = mustLoadSpec()
+
+func mustLoadSpec() *openapi.Document {
+	doc, err := openapi.Load(api.Spec)
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// OpenAPISpec serves the OpenAPI document describing the API.
+func (s *Server) OpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(api.Spec)
+}
+
+func swaggerUI() http.Handler {
+	return v5emb.New("Customer service", "/openapi.json", "/docs/")
+}
+
+// validateRequest rejects requests whose query parameters or JSON body do not match the
+// OpenAPI document. Routes missing from the document are passed through untouched.
+func validateRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		op, ok := apiSpec.Operation(r.Method, path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		violations, err := queryViolations(r, path, op)
+		if err != nil {
+			http.Error(w, "Invalid API specification", http.StatusInternalServerError)
+			return
+		}
+
+		if op.RequestBody != nil {
+			status, msg, bodyViolations := validateBody(r, op.RequestBody)
+			if status != 0 {
+				http.Error(w, msg, status)
+				return
+			}
+			violations = append(violations, bodyViolations...)
+		}
+
+		if len(violations) > 0 {
+			http.Error(w, "Request does not match the API specification: "+strings.Join(violations, "; "), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func queryViolations(r *http.Request, path string, op *openapi.Operation) ([]string, error) {
+	params, err := apiSpec.OperationParameters(path, op)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	query := r.URL.Query()
+	for _, p := range params {
+		if p.In != "query" {
+			continue
+		}
+		raw, ok := query[p.Name]
+		if !ok {
+			if p.Required {
+				violations = append(violations, fmt.Sprintf("query parameter %s is required", p.Name))
+			}
+			continue
+		}
+		for _, v := range apiSpec.Validate(openapi.ParseParameter(raw[0], p.Schema), p.Schema) {
+			violations = append(violations, "query parameter "+p.Name+" "+strings.TrimPrefix(v, "/: "))
+		}
+	}
+	return violations, nil
+}
+
+// validateBody returns a status and message when the body cannot be validated at all,
+// otherwise the schema violations. The body is restored for the next handler.
+func validateBody(r *http.Request, body *openapi.RequestBody) (int, string, []string) {
+	media, ok := body.Content["application/json"]
+	if !ok {
+		return 0, "", nil
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return http.StatusUnsupportedMediaType, "Content-Type must be application/json", nil
+		}
+	}
+
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		return http.StatusBadRequest, "Invalid request payload", nil
+	}
+	if len(data) > maxBodyBytes {
+		return http.StatusRequestEntityTooLarge, "Request body too large", nil
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return http.StatusBadRequest, "Request body is required", nil
+		}
+		return 0, "", nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return http.StatusBadRequest, "Invalid request payload", nil
+	}
+
+	var violations []string
+	for _, v := range apiSpec.Validate(value, media.Schema) {
+		violations = append(violations, "body "+v)
+	}
+	return 0, "", violations
+}
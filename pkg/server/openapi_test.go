@@ -0,0 +1,309 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/cache"
+	"CustomerCRUD/pkg/repository/faults"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/repository/resilient"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// jsonFields returns the JSON names of the fields of a model.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func schemaProperties(t *testing.T, name string) []string {
+	schema, ok := apiSpec.Components.Schemas[name]
+	require.True(t, ok, "schema %s is missing from the spec", name)
+
+	var names []string
+	for prop := range schema.Properties {
+		names = append(names, prop)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestOpenAPI_RoutesMatchSpec(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	registered := map[string]bool{}
+	err := s.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Prefix routes such as the Swagger UI are not API operations.
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			registered[method+" "+path] = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	documented := map[string]bool{}
+	for path, item := range apiSpec.Paths {
+		for method := range item.Operations {
+			documented[method+" "+path] = true
+		}
+	}
+
+	for route := range registered {
+		assert.True(t, documented[route], "route %s is not documented in api/openapi.json", route)
+	}
+	for route := range documented {
+		assert.True(t, registered[route], "route %s is documented but not registered in SetupRoutes", route)
+	}
+}
+
+func TestOpenAPI_CustomerSchemaMatchesModel(t *testing.T) {
+	fields := jsonFields(reflect.TypeOf(models.Customer{}))
+	assert.Equal(t, fields, schemaProperties(t, "Customer"), "Customer schema drifted from models.Customer")
+
+	// Embedded resources are read only, they are managed through their own endpoints.
+	var writable []string
+	for _, f := range fields {
+		if !apiSpec.Components.Schemas["Customer"].Properties[f].ReadOnly {
+			writable = append(writable, f)
+		}
+	}
+	assert.Equal(t, writable, schemaProperties(t, "CustomerInput"), "CustomerInput schema drifted from models.Customer")
+}
+
+func TestOpenAPI_NestedSchemasMatchModels(t *testing.T) {
+	nested := map[string]reflect.Type{
+		"Address":     reflect.TypeOf(models.Address{}),
+		"Email":       reflect.TypeOf(models.Email{}),
+		"Phone":       reflect.TypeOf(models.Phone{}),
+		"Segment":     reflect.TypeOf(models.Segment{}),
+		"Note":        reflect.TypeOf(models.Note{}),
+		"Interaction": reflect.TypeOf(models.Interaction{}),
+	}
+
+	for name, model := range nested {
+		fields := jsonFields(model)
+		assert.Equal(t, fields, schemaProperties(t, name), "%s schema drifted from models.%s", name, name)
+		assert.Equal(t, fields, schemaProperties(t, name+"Input"), "%sInput schema drifted from models.%s", name, name)
+	}
+}
+
+func TestOpenAPI_AttributeDefinitionSchemaMatchesModel(t *testing.T) {
+	fields := jsonFields(reflect.TypeOf(models.AttributeDefinition{}))
+
+	assert.Equal(t, fields, schemaProperties(t, "AttributeDefinition"), "AttributeDefinition schema drifted from models.AttributeDefinition")
+}
+
+func TestOpenAPI_StatusChangeSchemaMatchesModel(t *testing.T) {
+	fields := jsonFields(reflect.TypeOf(models.StatusChange{}))
+
+	assert.Equal(t, fields, schemaProperties(t, "StatusChange"), "StatusChange schema drifted from models.StatusChange")
+}
+
+func TestOpenAPI_TimelineSchemasMatchModels(t *testing.T) {
+	assert.Equal(t, jsonFields(reflect.TypeOf(models.NoteRevision{})), schemaProperties(t, "NoteRevision"), "NoteRevision schema drifted from models.NoteRevision")
+	assert.Equal(t, jsonFields(reflect.TypeOf(models.TimelineEntry{})), schemaProperties(t, "TimelineEntry"), "TimelineEntry schema drifted from models.TimelineEntry")
+	assert.Equal(t, jsonFields(reflect.TypeOf(TimelinePage{})), schemaProperties(t, "TimelinePage"), "TimelinePage schema drifted from TimelinePage")
+}
+
+func TestOpenAPI_AttachmentSchemaMatchesModel(t *testing.T) {
+	fields := jsonFields(reflect.TypeOf(models.Attachment{}))
+
+	assert.Equal(t, fields, schemaProperties(t, "Attachment"), "Attachment schema drifted from models.Attachment")
+}
+
+func TestOpenAPI_PrivacySchemasMatchModels(t *testing.T) {
+	assert.Equal(t, jsonFields(reflect.TypeOf(models.LegalHold{})), schemaProperties(t, "LegalHold"), "LegalHold schema drifted from models.LegalHold")
+	assert.Equal(t, jsonFields(reflect.TypeOf(legalHoldRequest{})), schemaProperties(t, "LegalHoldRequest"), "LegalHoldRequest schema drifted from legalHoldRequest")
+	assert.Equal(t, jsonFields(reflect.TypeOf(models.Erasure{})), schemaProperties(t, "Erasure"), "Erasure schema drifted from models.Erasure")
+	assert.Equal(t, jsonFields(reflect.TypeOf(erasureRequest{})), schemaProperties(t, "ErasureRequest"), "ErasureRequest schema drifted from erasureRequest")
+}
+
+func TestOpenAPI_AuditEntrySchemaMatchesModel(t *testing.T) {
+	assert.Equal(t, jsonFields(reflect.TypeOf(models.AuditEntry{})), schemaProperties(t, "AuditEntry"), "AuditEntry schema drifted from models.AuditEntry")
+	assert.Equal(t, jsonFields(reflect.TypeOf(models.FieldChange{})), schemaProperties(t, "FieldChange"), "FieldChange schema drifted from models.FieldChange")
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	req, err := http.NewRequest("GET", "/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, "3.1.0", doc["openapi"])
+}
+
+func TestSwaggerUI(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	req, err := http.NewRequest("GET", "/docs/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	assert.Contains(t, rr.Body.String(), "/openapi.json")
+}
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		contentType  string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "unknown property",
+			body:         `{"first_name":"A","last_name":"B","email":"a@example.com","nickname":"C"}`,
+			contentType:  "application/json",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `Request does not match the API specification: body /: unknown property "nickname"` + "\n",
+		},
+		{
+			name:         "invalid email and missing last name",
+			body:         `{"first_name":"A","email":"not-an-email"}`,
+			contentType:  "application/json",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `Request does not match the API specification: body /: missing required property "last_name"; body /email: must be a valid email address` + "\n",
+		},
+		{
+			name:         "wrong type",
+			body:         `{"first_name":1,"last_name":"B","email":"a@example.com"}`,
+			contentType:  "application/json",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Request does not match the API specification: body /first_name: must be of type string\n",
+		},
+		{
+			name:         "wrong content type",
+			body:         `first_name=A`,
+			contentType:  "application/x-www-form-urlencoded",
+			expectedCode: http.StatusUnsupportedMediaType,
+			expectedBody: "Content-Type must be application/json\n",
+		},
+		{
+			name:         "empty body",
+			contentType:  "application/json",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Request body is required\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mocks.CustomerRepository{}
+			s := newTestServer(mockRepo)
+
+			req, err := http.NewRequest("POST", "/customers", bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rr := httptest.NewRecorder()
+			s.Router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, tt.expectedBody, rr.Body.String())
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestValidateRequest_Valid(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	s := newTestServer(mockRepo)
+
+	mockRepo.On("CreateCustomer", mock.Anything, mock.AnythingOfType("*models.Customer")).Return(nil)
+
+	body := `{"first_name":"Valid","last_name":"Request","email":"valid.request@example.com"}`
+	req, err := http.NewRequest("POST", "/customers", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, status)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOpenAPI_ConsentSchemasMatchModels(t *testing.T) {
+	assert.Equal(t, jsonFields(reflect.TypeOf(models.Consent{})), schemaProperties(t, "Consent"), "Consent schema drifted from models.Consent")
+	assert.Equal(t, jsonFields(reflect.TypeOf(consentRequest{})), schemaProperties(t, "ConsentRequest"), "ConsentRequest schema drifted from consentRequest")
+	assert.Equal(t, jsonFields(reflect.TypeOf(unsubscribeTokenRequest{})), schemaProperties(t, "UnsubscribeTokenRequest"), "UnsubscribeTokenRequest schema drifted from unsubscribeTokenRequest")
+	assert.Equal(t, jsonFields(reflect.TypeOf(UnsubscribeToken{})), schemaProperties(t, "UnsubscribeToken"), "UnsubscribeToken schema drifted from UnsubscribeToken")
+}
+
+func TestOpenAPI_PortalSchemasMatchModels(t *testing.T) {
+	assert.Equal(t, jsonFields(reflect.TypeOf(portalLoginRequest{})), schemaProperties(t, "PortalLoginRequest"), "PortalLoginRequest schema drifted from portalLoginRequest")
+	assert.Equal(t, jsonFields(reflect.TypeOf(portalSessionRequest{})), schemaProperties(t, "PortalSessionRequest"), "PortalSessionRequest schema drifted from portalSessionRequest")
+	assert.Equal(t, jsonFields(reflect.TypeOf(PortalSignIn{})), schemaProperties(t, "PortalSignIn"), "PortalSignIn schema drifted from PortalSignIn")
+	assert.Equal(t, jsonFields(reflect.TypeOf(PortalProfile{})), schemaProperties(t, "PortalProfile"), "PortalProfile schema drifted from PortalProfile")
+	assert.Equal(t, jsonFields(reflect.TypeOf(profileUpdate{})), schemaProperties(t, "PortalProfileUpdate"), "PortalProfileUpdate schema drifted from profileUpdate")
+	assert.Equal(t, jsonFields(reflect.TypeOf(portalConsentRequest{})), schemaProperties(t, "PortalConsentRequest"), "PortalConsentRequest schema drifted from portalConsentRequest")
+}
+
+func TestOpenAPI_MetricsSchemasMatchModels(t *testing.T) {
+	assert.Equal(t, jsonFields(reflect.TypeOf(cache.Stats{})), schemaProperties(t, "CacheStats"), "CacheStats schema drifted from cache.Stats")
+	assert.Equal(t, jsonFields(reflect.TypeOf(repository.ReplicaStats{})), schemaProperties(t, "ReplicaStats"), "ReplicaStats schema drifted from repository.ReplicaStats")
+	assert.Equal(t, jsonFields(reflect.TypeOf(resilient.Stats{})), schemaProperties(t, "BreakerStats"), "BreakerStats schema drifted from resilient.Stats")
+	assert.Equal(t, jsonFields(reflect.TypeOf(faults.Stats{})), schemaProperties(t, "FaultStats"), "FaultStats schema drifted from faults.Stats")
+}
+
+func TestOpenAPI_FaultSchemasMatchModels(t *testing.T) {
+	assert.Equal(t, jsonFields(reflect.TypeOf(faults.Config{})), schemaProperties(t, "FaultConfig"), "FaultConfig schema drifted from faults.Config")
+	assert.Equal(t, jsonFields(reflect.TypeOf(faults.Rule{})), schemaProperties(t, "FaultRule"), "FaultRule schema drifted from faults.Rule")
+}
@@ -0,0 +1,437 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/mail"
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// portalCookie holds the session token of a customer signed in to the portal.
+	portalCookie = "crm_portal_session"
+	// csrfHeader carries the CSRF token of the session on every portal write.
+	csrfHeader = "X-CSRF-Token"
+	// portalConsentSource is the source of the consents customers record themselves.
+	portalConsentSource = "customer_portal"
+)
+
+// PortalPolicy configures the customer self-service portal.
+type PortalPolicy struct {
+	// LoginURL is the page of the sign-in links, which posts the token of its token query parameter
+	// to POST /me/session.
+	LoginURL   string
+	LoginTTL   time.Duration
+	SessionTTL time.Duration
+	// LoginsPerHour bounds the sign-in links sent to a customer within an hour, zero disables the limit.
+	LoginsPerHour int
+	// InsecureCookie drops the Secure attribute of the session cookie, for local development over plain HTTP.
+	InsecureCookie bool
+}
+
+// portalLoginRequest is the body of the endpoint sending sign-in links.
+type portalLoginRequest struct {
+	Email string `json:"email"`
+}
+
+// portalSessionRequest is the body of the endpoint signing customers in.
+type portalSessionRequest struct {
+	Token string `json:"token"`
+}
+
+// PortalSignIn is the response of the endpoint signing customers in. The session token itself is
+// only set as an HttpOnly cookie, CSRFToken has to be sent in the X-CSRF-Token header of every write.
+type PortalSignIn struct {
+	CSRFToken string    `json:"csrf_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PortalProfile is what customers see of themselves in the portal.
+type PortalProfile struct {
+	ID            uuid.UUID `json:"id"`
+	FirstName     string    `json:"first_name"`
+	MiddleName    string    `json:"middle_name,omitempty"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	PhoneNumber   string    `json:"phone_number,omitempty"`
+}
+
+func newPortalProfile(c *models.Customer) PortalProfile {
+	return PortalProfile{
+		ID:            c.ID,
+		FirstName:     c.FirstName,
+		MiddleName:    c.MiddleName,
+		LastName:      c.LastName,
+		Email:         c.Email,
+		EmailVerified: c.EmailVerified,
+		PhoneNumber:   c.PhoneNumber,
+	}
+}
+
+// profileUpdate is the body of the endpoint updating the own profile. These are the only fields
+// customers can change themselves, missing ones are kept. The email is changed through support,
+// which takes care of its verification.
+type profileUpdate struct {
+	FirstName   *string `json:"first_name"`
+	MiddleName  *string `json:"middle_name"`
+	LastName    *string `json:"last_name"`
+	PhoneNumber *string `json:"phone_number"`
+}
+
+// portalConsentRequest is one consent of the body of the endpoint recording the own consents.
+// Their source is always customer_portal.
+type portalConsentRequest struct {
+	Purpose       string                `json:"purpose"`
+	Channel       models.ConsentChannel `json:"channel"`
+	State         models.ConsentState   `json:"state"`
+	PolicyVersion string                `json:"policy_version"`
+}
+
+type portalSessionKey struct{}
+
+func (s *Server) portalEnabled(w http.ResponseWriter) bool {
+	if s.sessions == nil {
+		http.Error(w, "The customer portal is disabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// portalActor names the customer signed in to the portal as the actor of its changes.
+func portalActor(customerID uuid.UUID) string {
+	return "customer:" + customerID.String()
+}
+
+// newPortalToken returns a random token that is safe to use in URLs and cookies.
+func newPortalToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashPortalToken returns the hash sign-in links and sessions are stored by, so that a leaked
+// database does not hand out sessions.
+func hashPortalToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// portalCSRFToken derives the CSRF token of a session from its token. Other sites can neither
+// read the cookie nor the token.
+func portalCSRFToken(sessionToken string) string {
+	mac := hmac.New(sha256.New, []byte(sessionToken))
+	mac.Write([]byte("csrf"))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) sessionCookie(value string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     portalCookie,
+		Value:    value,
+		Path:     "/me",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   !s.portalPolicy.InsecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+// portalAuth runs next for the customer signed in with the session cookie. Writes also need the
+// CSRF token of the session in the X-CSRF-Token header. Changes are recorded for the customer,
+// whatever the X-Actor header says.
+func (s *Server) portalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.portalEnabled(w) {
+			return
+		}
+		cookie, err := r.Cookie(portalCookie)
+		if err != nil {
+			http.Error(w, "Not signed in", http.StatusUnauthorized)
+			return
+		}
+		session, err := s.sessions.GetSession(r.Context(), hashPortalToken(cookie.Value))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.SetCookie(w, s.sessionCookie("", time.Time{}))
+			http.Error(w, "Not signed in", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Errorf("error reading portal session: %v", err)
+			http.Error(w, "Failed to check the session", http.StatusInternalServerError)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead &&
+			!hmac.Equal([]byte(r.Header.Get(csrfHeader)), []byte(portalCSRFToken(cookie.Value))) {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		ctx := actor.NewContext(r.Context(), portalActor(session.CustomerID))
+		ctx = context.WithValue(ctx, portalSessionKey{}, session)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// portalSession returns the session of a request passed by portalAuth.
+func portalSession(r *http.Request) *models.PortalSession {
+	return r.Context().Value(portalSessionKey{}).(*models.PortalSession)
+}
+
+// PortalLogin mails a sign-in link to the customer with the email. It responds 202 whether or not
+// the email belongs to a customer, so that it cannot be used to find out.
+func (s *Server) PortalLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.portalEnabled(w) {
+		return
+	}
+	var req portalLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.sendLoginLink(r.Context(), strings.TrimSpace(req.Email)); err != nil {
+		log.Errorf("error sending portal sign-in link: %v", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) sendLoginLink(ctx context.Context, email string) error {
+	customer, err := s.customers.GetCustomerByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	policy := s.portalPolicy
+	at := time.Now().UTC()
+	if policy.LoginsPerHour > 0 {
+		n, err := s.sessions.CountLoginTokens(ctx, customer.ID, at.Add(-time.Hour))
+		if err != nil {
+			return err
+		}
+		if n >= policy.LoginsPerHour {
+			log.Warnf("portal sign-in link of customer %s not sent, %d were sent within the last hour", customer.ID, n)
+			return nil
+		}
+	}
+
+	token, err := newPortalToken()
+	if err != nil {
+		return err
+	}
+	err = s.sessions.CreateLoginToken(ctx, models.LoginToken{
+		ID:         uuid.New(),
+		CustomerID: customer.ID,
+		TokenHash:  hashPortalToken(token),
+		CreatedAt:  at,
+		ExpiresAt:  at.Add(policy.LoginTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(policy.LoginURL)
+	if err != nil {
+		return fmt.Errorf("invalid portal login URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return s.portalMailer.Send(ctx, mail.Message{
+		To:      customer.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Hello %s,\n\nopen this link to sign in and manage your details:\n\n%s\n\n"+
+			"The link expires in %s and works once. If you did not ask to sign in, you can ignore this email.\n",
+			customer.FirstName, link, policy.LoginTTL),
+	})
+}
+
+// CreatePortalSession signs a customer in with the token of a sign-in link and sets the session
+// cookie. The body is JSON, which other sites cannot post without a CORS preflight.
+func (s *Server) CreatePortalSession(w http.ResponseWriter, r *http.Request) {
+	if !s.portalEnabled(w) {
+		return
+	}
+	var req portalSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	login, err := s.sessions.ConsumeLoginToken(ctx, hashPortalToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Invalid or expired sign-in link", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Errorf("error using portal sign-in link: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := newPortalToken()
+	if err != nil {
+		log.Errorf("error generating portal session token: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	at := time.Now().UTC()
+	session := models.PortalSession{
+		ID:         uuid.New(),
+		CustomerID: login.CustomerID,
+		TokenHash:  hashPortalToken(token),
+		CreatedAt:  at,
+		ExpiresAt:  at.Add(s.portalPolicy.SessionTTL),
+	}
+	if err := s.sessions.CreateSession(ctx, session); err != nil {
+		log.Errorf("error creating portal session: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	log.Infof("customer %s signed in to the portal", login.CustomerID)
+
+	http.SetCookie(w, s.sessionCookie(token, session.ExpiresAt))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(PortalSignIn{CSRFToken: portalCSRFToken(token), ExpiresAt: session.ExpiresAt})
+}
+
+// DeletePortalSession signs the customer out.
+func (s *Server) DeletePortalSession(w http.ResponseWriter, r *http.Request) {
+	if err := s.sessions.DeleteSession(r.Context(), portalSession(r).TokenHash); err != nil {
+		log.Errorf("error deleting portal session: %v", err)
+		http.Error(w, "Failed to sign out", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, s.sessionCookie("", time.Time{}))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) GetPortalProfile(w http.ResponseWriter, r *http.Request) {
+	customer, err := s.customers.GetCustomerByID(r.Context(), portalSession(r).CustomerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errorf("error getting portal profile: %v", err)
+		http.Error(w, "Failed to retrieve profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPortalProfile(customer))
+}
+
+// UpdatePortalProfile changes the fields of profileUpdate given in the body and keeps the others.
+func (s *Server) UpdatePortalProfile(w http.ResponseWriter, r *http.Request) {
+	var req profileUpdate
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field") {
+			http.Error(w, "Invalid profile: only first_name, middle_name, last_name and phone_number can be changed", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		}
+		return
+	}
+
+	ctx := r.Context()
+	customer, err := s.customers.GetCustomerByID(ctx, portalSession(r).CustomerID)
+	if err != nil {
+		writeServiceError(w, err, "Invalid profile: ", "Failed to update profile")
+		return
+	}
+	for field, value := range map[*string]*string{
+		&customer.FirstName:   req.FirstName,
+		&customer.MiddleName:  req.MiddleName,
+		&customer.LastName:    req.LastName,
+		&customer.PhoneNumber: req.PhoneNumber,
+	} {
+		if value != nil {
+			*field = *value
+		}
+	}
+	// Nil attributes are kept as they are stored.
+	customer.Attributes = nil
+
+	if err := s.customers.UpdateCustomer(ctx, customer); err != nil {
+		writeServiceError(w, err, "Invalid profile: ", "Failed to update profile")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPortalProfile(customer))
+}
+
+func (s *Server) ListPortalConsents(w http.ResponseWriter, r *http.Request) {
+	if !s.consentsEnabled(w) {
+		return
+	}
+	consents, err := s.consents.ListConsents(r.Context(), portalSession(r).CustomerID)
+	if err != nil {
+		log.Errorf("error listing consents: %v", err)
+		http.Error(w, "Failed to retrieve consents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nonNil(consents))
+}
+
+// PutPortalConsents records the consents customers give or revoke themselves, with the source
+// customer_portal, and responds with all their current ones.
+func (s *Server) PutPortalConsents(w http.ResponseWriter, r *http.Request) {
+	if !s.consentsEnabled(w) {
+		return
+	}
+	var req []portalConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	requests := make([]consentRequest, len(req))
+	for i, c := range req {
+		requests[i] = consentRequest{
+			Purpose:       c.Purpose,
+			Channel:       c.Channel,
+			State:         c.State,
+			Source:        portalConsentSource,
+			PolicyVersion: c.PolicyVersion,
+		}
+	}
+	consents, err := newConsents(requests)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.recordConsents(w, r.Context(), portalSession(r).CustomerID, consents)
+}
@@ -0,0 +1,281 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/mail"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type portalTestServer struct {
+	*Server
+	customers *mocks.CustomerRepository
+	sessions  *mocks.SessionRepository
+	consents  *mocks.ConsentRepository
+	outbox    *mail.Outbox
+}
+
+func newPortalTestServer() portalTestServer {
+	p := portalTestServer{
+		customers: &mocks.CustomerRepository{},
+		sessions:  &mocks.SessionRepository{},
+		consents:  &mocks.ConsentRepository{},
+		outbox:    mail.NewOutbox(),
+	}
+	p.Server = newTestServer(p.customers)
+	p.SetConsentRepository(p.consents)
+	p.SetPortal(p.sessions, p.outbox, PortalPolicy{
+		LoginURL:      "https://app.example.com/sign-in?lang=en",
+		LoginTTL:      15 * time.Minute,
+		SessionTTL:    time.Hour,
+		LoginsPerHour: 3,
+	})
+	p.SetupRoutes()
+	return p
+}
+
+// signIn makes token a valid session of the customer and returns its cookie.
+func (p portalTestServer) signIn(customerID uuid.UUID, token string) *http.Cookie {
+	p.sessions.On("GetSession", mock.Anything, hashPortalToken(token)).Return(&models.PortalSession{
+		ID:         uuid.New(),
+		CustomerID: customerID,
+		TokenHash:  hashPortalToken(token),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}, nil)
+	return &http.Cookie{Name: portalCookie, Value: token}
+}
+
+func (p portalTestServer) serve(t *testing.T, method, path, body string, cookie *http.Cookie, csrf string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := http.NewRequest(method, path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	if csrf != "" {
+		req.Header.Set(csrfHeader, csrf)
+	}
+	rr := httptest.NewRecorder()
+	p.Router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPortalLogin(t *testing.T) {
+	p := newPortalTestServer()
+	customer := models.Customer{ID: uuid.New(), FirstName: "Jane", Email: "jane@example.com"}
+	p.customers.On("GetCustomerByEmail", mock.Anything, "jane@example.com").Return(&customer, nil)
+	p.sessions.On("CountLoginTokens", mock.Anything, customer.ID, mock.Anything).Return(0, nil)
+	var stored models.LoginToken
+	p.sessions.On("CreateLoginToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(models.LoginToken)
+	}).Return(nil)
+
+	rr := p.serve(t, "POST", "/me/login", `{"email": "jane@example.com"}`, nil, "")
+
+	assert.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	require.Len(t, p.outbox.Messages(), 1)
+	msg := p.outbox.Messages()[0]
+	assert.Equal(t, "jane@example.com", msg.To)
+	start := strings.Index(msg.Body, "https://")
+	require.NotEqual(t, -1, start)
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	require.NoError(t, err)
+	assert.Equal(t, "en", link.Query().Get("lang"))
+	token := link.Query().Get("token")
+	assert.Equal(t, customer.ID, stored.CustomerID)
+	assert.Equal(t, hashPortalToken(token), stored.TokenHash, "only the hash of the token is stored")
+	assert.Equal(t, 15*time.Minute, stored.ExpiresAt.Sub(stored.CreatedAt))
+}
+
+func TestPortalLogin_NothingSent(t *testing.T) {
+	p := newPortalTestServer()
+	limited := models.Customer{ID: uuid.New(), Email: "jane@example.com"}
+	p.customers.On("GetCustomerByEmail", mock.Anything, "nobody@example.com").Return(nil, sql.ErrNoRows)
+	p.customers.On("GetCustomerByEmail", mock.Anything, "jane@example.com").Return(&limited, nil)
+	p.sessions.On("CountLoginTokens", mock.Anything, limited.ID, mock.Anything).Return(3, nil)
+
+	// Both respond like a sent link, so that the endpoint does not tell which emails are known.
+	for _, email := range []string{"nobody@example.com", "jane@example.com"} {
+		rr := p.serve(t, "POST", "/me/login", `{"email": "`+email+`"}`, nil, "")
+		assert.Equal(t, http.StatusAccepted, rr.Code, email)
+	}
+	assert.Empty(t, p.outbox.Messages())
+	p.sessions.AssertNotCalled(t, "CreateLoginToken", mock.Anything, mock.Anything)
+}
+
+func TestCreatePortalSession(t *testing.T) {
+	p := newPortalTestServer()
+	customerID := uuid.New()
+	p.sessions.On("ConsumeLoginToken", mock.Anything, hashPortalToken("link-token")).
+		Return(&models.LoginToken{ID: uuid.New(), CustomerID: customerID}, nil)
+	var session models.PortalSession
+	p.sessions.On("CreateSession", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		session = args.Get(1).(models.PortalSession)
+	}).Return(nil)
+
+	rr := p.serve(t, "POST", "/me/session", `{"token": "link-token"}`, nil, "")
+
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, portalCookie, cookie.Name)
+	assert.Equal(t, "/me", cookie.Path)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.Equal(t, customerID, session.CustomerID)
+	assert.Equal(t, hashPortalToken(cookie.Value), session.TokenHash)
+
+	var got PortalSignIn
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, portalCSRFToken(cookie.Value), got.CSRFToken)
+	assert.True(t, got.ExpiresAt.Equal(session.ExpiresAt))
+}
+
+func TestCreatePortalSession_InvalidToken(t *testing.T) {
+	p := newPortalTestServer()
+	p.sessions.On("ConsumeLoginToken", mock.Anything, hashPortalToken("used")).Return(nil, sql.ErrNoRows)
+
+	rr := p.serve(t, "POST", "/me/session", `{"token": "used"}`, nil, "")
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid or expired sign-in link\n", rr.Body.String())
+	p.sessions.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+}
+
+func TestPortalAuth(t *testing.T) {
+	p := newPortalTestServer()
+	cookie := p.signIn(uuid.New(), "session-token")
+	p.sessions.On("GetSession", mock.Anything, hashPortalToken("expired")).Return(nil, sql.ErrNoRows)
+
+	rr := p.serve(t, "GET", "/me", "", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "Not signed in\n", rr.Body.String())
+
+	rr = p.serve(t, "GET", "/me", "", &http.Cookie{Name: portalCookie, Value: "expired"}, "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	require.Len(t, rr.Result().Cookies(), 1)
+	assert.Equal(t, -1, rr.Result().Cookies()[0].MaxAge, "the stale cookie is removed")
+
+	for _, csrf := range []string{"", portalCSRFToken("another session")} {
+		rr = p.serve(t, "PATCH", "/me", `{"phone_number": "+15550100"}`, cookie, csrf)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, "Invalid CSRF token\n", rr.Body.String())
+	}
+	p.customers.AssertNotCalled(t, "UpdateCustomer", mock.Anything, mock.Anything)
+}
+
+func TestPortalDisabled(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	req, err := http.NewRequest("GET", "/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "The customer portal is disabled\n", rr.Body.String())
+}
+
+func TestGetPortalProfile(t *testing.T) {
+	p := newPortalTestServer()
+	customer := models.Customer{ID: uuid.New(), FirstName: "Jane", LastName: "Doe", Email: "jane@example.com",
+		Status: models.StatusActive, Attributes: map[string]any{"tier": "gold"}}
+	cookie := p.signIn(customer.ID, "session-token")
+	p.customers.On("GetCustomerByID", mock.Anything, customer.ID).Return(&customer, nil)
+
+	rr := p.serve(t, "GET", "/me", "", cookie, "")
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"id": "`+customer.ID.String()+`", "first_name": "Jane", "last_name": "Doe",
+		"email": "jane@example.com", "email_verified": false}`, rr.Body.String())
+}
+
+func TestUpdatePortalProfile(t *testing.T) {
+	p := newPortalTestServer()
+	id := uuid.New()
+	stored := func() *models.Customer {
+		return &models.Customer{ID: id, FirstName: "Jnae", LastName: "Doe", Email: "jane@example.com", PhoneNumber: "+15550199"}
+	}
+	cookie := p.signIn(id, "session-token")
+	p.customers.On("GetCustomerByID", mock.Anything, id).Return(stored(), nil).Once()
+	p.customers.On("GetCustomerByID", mock.Anything, id).Return(stored(), nil).Once()
+	p.customers.On("UpdateCustomer", mock.Anything, mock.MatchedBy(func(c *models.Customer) bool {
+		return c.FirstName == "Jane" && c.LastName == "Doe" && c.Email == "jane@example.com" && c.PhoneNumber == "+15550100"
+	})).Return(nil)
+
+	rr := p.serve(t, "PATCH", "/me", `{"first_name": "Jane", "phone_number": "+15550100"}`, cookie, portalCSRFToken("session-token"))
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var got PortalProfile
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, "Jane", got.FirstName)
+	assert.Equal(t, "+15550100", got.PhoneNumber)
+	p.customers.AssertExpectations(t)
+}
+
+func TestUpdatePortalProfile_RestrictedFields(t *testing.T) {
+	p := newPortalTestServer()
+	cookie := p.signIn(uuid.New(), "session-token")
+
+	for _, body := range []string{`{"email": "someone@example.com"}`, `{"status": "active"}`, `{"attributes": {"tier": "gold"}}`} {
+		rr := p.serve(t, "PATCH", "/me", body, cookie, portalCSRFToken("session-token"))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+	p.customers.AssertNotCalled(t, "UpdateCustomer", mock.Anything, mock.Anything)
+}
+
+func TestPutPortalConsents(t *testing.T) {
+	p := newPortalTestServer()
+	id := uuid.New()
+	cookie := p.signIn(id, "session-token")
+	p.consents.On("RecordConsents", mock.Anything, id, mock.MatchedBy(func(c []models.Consent) bool {
+		return len(c) == 1 && c[0].Source == portalConsentSource && c[0].State == models.ConsentRevoked
+	})).Return([]models.Consent{}, nil)
+	p.consents.On("ListConsents", mock.Anything, id).Return([]models.Consent{}, nil)
+
+	rr := p.serve(t, "PUT", "/me/consents", `[{"purpose": "marketing", "channel": "email", "state": "revoked"}]`,
+		cookie, portalCSRFToken("session-token"))
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	p.consents.AssertExpectations(t)
+
+	// Customers cannot claim another source.
+	rr = p.serve(t, "PUT", "/me/consents", `[{"purpose": "marketing", "channel": "email", "state": "revoked", "source": "support"}]`,
+		cookie, portalCSRFToken("session-token"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestDeletePortalSession(t *testing.T) {
+	p := newPortalTestServer()
+	cookie := p.signIn(uuid.New(), "session-token")
+	p.sessions.On("DeleteSession", mock.Anything, hashPortalToken("session-token")).Return(nil)
+
+	rr := p.serve(t, "DELETE", "/me/session", "", cookie, portalCSRFToken("session-token"))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	require.Len(t, rr.Result().Cookies(), 1)
+	assert.Equal(t, -1, rr.Result().Cookies()[0].MaxAge)
+	p.sessions.AssertExpectations(t)
+}
@@ -0,0 +1,340 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// legalHoldRequest is the body of the endpoint placing a legal hold.
+type legalHoldRequest struct {
+	Reason string `json:"reason"`
+}
+
+// erasureRequest is the body of the erasure endpoint.
+type erasureRequest struct {
+	Reference string `json:"reference"`
+}
+
+func (s *Server) ListLegalHolds(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	holds, err := s.customers.LegalHolds(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errorf("error listing legal holds: %v", err)
+		http.Error(w, "Failed to retrieve legal holds", http.StatusInternalServerError)
+		return
+	}
+	if holds == nil {
+		holds = []models.LegalHold{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holds)
+}
+
+func (s *Server) PlaceLegalHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	var req legalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	hold := models.LegalHold{CustomerID: id, Reason: req.Reason}
+	if err := s.customers.PlaceLegalHold(r.Context(), &hold); err != nil {
+		writeServiceError(w, err, "Invalid legal hold: ", "Failed to place legal hold")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// ReleaseLegalHold ends a legal hold. The hold is kept with the time and actor of its release.
+func (s *Server) ReleaseLegalHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+	holdID, ok := pathID(w, r, "holdId", "Invalid legal hold ID")
+	if !ok {
+		return
+	}
+
+	hold, err := s.customers.ReleaseLegalHold(r.Context(), id, holdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Active legal hold not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "Invalid legal hold: ", "Failed to release legal hold")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// EraseCustomer anonymizes the personal data of the customer in place, unless it is under legal hold.
+func (s *Server) EraseCustomer(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	// The body is optional.
+	var req erasureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	erasure := models.Erasure{CustomerID: id, Reference: req.Reference}
+	if err := s.customers.Erase(r.Context(), &erasure); err != nil {
+		writeServiceError(w, err, "Invalid erasure: ", "Failed to erase customer")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(erasure)
+}
+
+func (s *Server) GetErasure(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	erasure, err := s.customers.Erasure(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Customer was not erased", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errorf("error getting erasure: %v", err)
+		http.Error(w, "Failed to retrieve erasure", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(erasure)
+}
+
+// ExportManifest describes a GDPR export, it is stored as export.json.
+type ExportManifest struct {
+	CustomerID uuid.UUID `json:"customer_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	ExportedBy string    `json:"exported_by"`
+	// Files lists the JSON documents of the export. Attachment contents are stored under
+	// attachments/<id>/<file name>.
+	Files []string `json:"files"`
+}
+
+// ExportedNote is a note of a GDPR export with its previous bodies.
+type ExportedNote struct {
+	models.Note
+	Revisions []models.NoteRevision `json:"revisions"`
+}
+
+// exportDocument is a JSON document of a GDPR export.
+type exportDocument struct {
+	name    string
+	content any
+}
+
+// collectExport loads everything stored about the customer. Related resources whose repository is
+// not configured are left out.
+func (s *Server) collectExport(ctx context.Context, customer *models.Customer) ([]exportDocument, []models.Attachment, error) {
+	id := customer.ID
+	var err error
+	if s.contacts != nil {
+		if customer.Emails, err = s.contacts.ListEmails(ctx, id); err != nil {
+			return nil, nil, fmt.Errorf("error listing emails: %w", err)
+		}
+		if customer.Phones, err = s.contacts.ListPhones(ctx, id); err != nil {
+			return nil, nil, fmt.Errorf("error listing phones: %w", err)
+		}
+	}
+	if s.tags != nil {
+		if customer.Tags, err = s.tags.ListCustomerTags(ctx, id); err != nil {
+			return nil, nil, fmt.Errorf("error listing tags: %w", err)
+		}
+	}
+	docs := []exportDocument{{"customer.json", customer}}
+
+	if s.addresses != nil {
+		addresses, err := s.addresses.ListAddresses(ctx, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error listing addresses: %w", err)
+		}
+		docs = append(docs, exportDocument{"addresses.json", nonNil(addresses)})
+	}
+
+	if s.activity != nil {
+		notes, err := s.activity.ListNotes(ctx, repository.NoteQuery{CustomerID: id})
+		if err != nil {
+			return nil, nil, fmt.Errorf("error listing notes: %w", err)
+		}
+		exported := make([]ExportedNote, len(notes))
+		for i, note := range notes {
+			revisions, err := s.activity.ListNoteRevisions(ctx, id, note.ID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error listing note revisions: %w", err)
+			}
+			exported[i] = ExportedNote{Note: note, Revisions: nonNil(revisions)}
+		}
+		interactions, err := s.activity.ListInteractions(ctx, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error listing interactions: %w", err)
+		}
+		docs = append(docs, exportDocument{"notes.json", exported}, exportDocument{"interactions.json", nonNil(interactions)})
+	}
+
+	statusHistory, err := s.customers.StatusHistory(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing status changes: %w", err)
+	}
+	audit, err := s.customers.AuditTrail(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing audit entries: %w", err)
+	}
+	docs = append(docs, exportDocument{"status_history.json", nonNil(statusHistory)}, exportDocument{"audit.json", nonNil(audit)})
+
+	if s.consents != nil {
+		consents, err := s.consents.ListConsentHistory(ctx, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error listing consents: %w", err)
+		}
+		docs = append(docs, exportDocument{"consents.json", nonNil(consents)})
+	}
+
+	var attachments []models.Attachment
+	if s.attachments != nil {
+		if attachments, err = s.attachments.ListAttachments(ctx, id); err != nil {
+			return nil, nil, fmt.Errorf("error listing attachments: %w", err)
+		}
+		docs = append(docs, exportDocument{"attachments.json", nonNil(attachments)})
+	}
+	return docs, attachments, nil
+}
+
+// nonNil keeps empty lists from being exported as null.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// GDPRExport answers a data subject access request with a ZIP archive of everything stored about
+// the customer: JSON documents listed in export.json and the contents of its attachments.
+func (s *Server) GDPRExport(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	customer, err := s.customers.GetCustomerByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errorf("error getting customer by ID: %v", err)
+		http.Error(w, "Failed to export customer", http.StatusInternalServerError)
+		return
+	}
+	docs, attachments, err := s.collectExport(ctx, customer)
+	if err != nil {
+		log.Errorf("error collecting the export of customer %s: %v", id, err)
+		http.Error(w, "Failed to export customer", http.StatusInternalServerError)
+		return
+	}
+
+	manifest := ExportManifest{CustomerID: id, ExportedAt: time.Now().UTC(), ExportedBy: actor.FromContext(ctx)}
+	for _, doc := range docs {
+		manifest.Files = append(manifest.Files, doc.name)
+	}
+	docs = append([]exportDocument{{"export.json", manifest}}, docs...)
+	log.Infof("exporting the personal data of customer %s for %s", id, manifest.ExportedBy)
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="customer-%s.zip"`, id))
+	w.Header().Set("Cache-Control", "no-store")
+
+	// The status is sent with the first bytes of the archive. A failure after that aborts the
+	// response, so that clients never take a truncated archive for a complete one.
+	archive := zip.NewWriter(w)
+	for _, doc := range docs {
+		if err := writeExportDocument(archive, doc, manifest.ExportedAt); err != nil {
+			log.Errorf("error writing %s of the export of customer %s: %v", doc.name, id, err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+	for _, attachment := range attachments {
+		if err := s.writeExportAttachment(ctx, archive, attachment); err != nil {
+			log.Errorf("error writing attachment %s of the export of customer %s: %v", attachment.ID, id, err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		log.Errorf("error finishing the export of customer %s: %v", id, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func writeExportDocument(archive *zip.Writer, doc exportDocument, at time.Time) error {
+	f, err := archive.CreateHeader(&zip.FileHeader{Name: doc.name, Method: zip.Deflate, Modified: at})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc.content)
+}
+
+func (s *Server) writeExportAttachment(ctx context.Context, archive *zip.Writer, attachment models.Attachment) error {
+	blob, err := s.blobs.Open(ctx, attachment.Key)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	f, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     "attachments/" + attachment.ID.String() + "/" + attachment.FileName,
+		Method:   zip.Deflate,
+		Modified: attachment.UploadedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, blob)
+	return err
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/memory"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/service"
+	"CustomerCRUD/pkg/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newPrivacyTestServer serves customers kept in memory, which supports legal holds and erasure.
+func newPrivacyTestServer(t *testing.T) (*Server, *service.CustomerService, models.Customer) {
+	customers := service.NewCustomerService(memory.NewStore(), events.NewBus())
+	c := models.Customer{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}
+	require.NoError(t, customers.CreateCustomer(context.Background(), &c))
+
+	s := NewServer(customers)
+	s.SetupRoutes()
+	return s, customers, c
+}
+
+func TestLegalHoldBlocksErasure(t *testing.T) {
+	s, _, c := newPrivacyTestServer(t)
+	base := "/customers/" + c.ID.String()
+
+	rr := serve(s, "POST", base+"/legal-holds", `{"reason":"Pending litigation"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var hold models.LegalHold
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &hold))
+	assert.Equal(t, "agent@example.com", hold.PlacedBy)
+
+	rr = serve(s, "POST", base+"/erasure", "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "Customer is under legal hold, see its legal holds\n", rr.Body.String())
+
+	rr = serve(s, "DELETE", base, "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = serve(s, "POST", base+"/legal-holds/"+hold.ID.String()+"/release", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = serve(s, "POST", base+"/legal-holds/"+hold.ID.String()+"/release", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = serve(s, "GET", base+"/legal-holds", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var holds []models.LegalHold
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &holds))
+	require.Len(t, holds, 1)
+	assert.NotNil(t, holds[0].ReleasedAt)
+}
+
+func TestPlaceLegalHold_MissingReason(t *testing.T) {
+	s, _, c := newPrivacyTestServer(t)
+
+	rr := serve(s, "POST", "/customers/"+c.ID.String()+"/legal-holds", `{"reason":" "}`)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid legal hold: reason is required\n", rr.Body.String())
+}
+
+func TestEraseCustomer(t *testing.T) {
+	s, _, c := newPrivacyTestServer(t)
+	base := "/customers/" + c.ID.String()
+
+	rr := serve(s, "GET", base+"/erasure", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = serve(s, "POST", base+"/erasure", `{"reference":"TICKET-42"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var erasure models.Erasure
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &erasure))
+	assert.Equal(t, "TICKET-42", erasure.Reference)
+	assert.Equal(t, "agent@example.com", erasure.ErasedBy)
+
+	rr = serve(s, "GET", base, "")
+	assert.NotContains(t, rr.Body.String(), "jane")
+
+	rr = serve(s, "GET", base+"/erasure", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = serve(s, "POST", base+"/erasure", "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "Customer is already erased\n", rr.Body.String())
+}
+
+func TestErasedCustomerTakesNoPersonalData(t *testing.T) {
+	s, _, c := newPrivacyTestServer(t)
+	addresses := &mocks.AddressRepository{}
+	s.SetAddressRepository(addresses)
+	base := "/customers/" + c.ID.String()
+	require.Equal(t, http.StatusOK, serve(s, "POST", base+"/erasure", "").Code)
+
+	rr := serve(s, "PUT", base, `{"first_name":"Jane","last_name":"Doe","email":"jane@example.com"}`)
+	assert.Equal(t, http.StatusGone, rr.Code)
+	assert.Equal(t, "Customer was erased\n", rr.Body.String())
+
+	rr = serve(s, "POST", base+"/addresses", `{"type":"shipping","line1":"Dam 1","city":"Amsterdam","postal_code":"1012 ab","country":"nl"}`)
+	assert.Equal(t, http.StatusGone, rr.Code)
+	addresses.AssertExpectations(t)
+
+	rr = serve(s, "GET", base, "")
+	assert.NotContains(t, rr.Body.String(), "jane")
+}
+
+func TestGDPRExport(t *testing.T) {
+	s, _, c := newPrivacyTestServer(t)
+	activity := &mocks.ActivityRepository{}
+	attachments := &mocks.AttachmentRepository{}
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	s.SetActivityRepository(activity)
+	s.SetAttachmentStore(attachments, blobs, AttachmentPolicy{MaxSize: 1 << 10})
+
+	note := models.Note{ID: uuid.New(), CustomerID: c.ID, Body: "Prefers calls", Author: "agent@example.com"}
+	activity.On("ListNotes", mock.Anything, repository.NoteQuery{CustomerID: c.ID}).Return([]models.Note{note}, nil)
+	activity.On("ListNoteRevisions", mock.Anything, c.ID, note.ID).Return([]models.NoteRevision{{ID: uuid.New(), NoteID: note.ID, Body: "Prefers email"}}, nil)
+	activity.On("ListInteractions", mock.Anything, c.ID).Return(nil, nil)
+	attachment := storedAttachment(t, blobs, c.ID, pdfContent)
+	attachments.On("ListAttachments", mock.Anything, c.ID).Return([]models.Attachment{*attachment}, nil)
+
+	rr := serve(s, "GET", "/customers/"+c.ID.String()+"/gdpr-export", "")
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+
+	var manifest ExportManifest
+	require.NoError(t, json.Unmarshal(files["export.json"], &manifest))
+	assert.Equal(t, c.ID, manifest.CustomerID)
+	assert.Equal(t, "agent@example.com", manifest.ExportedBy)
+	assert.Equal(t, []string{"customer.json", "notes.json", "interactions.json", "status_history.json", "audit.json", "attachments.json"}, manifest.Files)
+	for _, name := range manifest.Files {
+		assert.Contains(t, files, name)
+	}
+
+	var customer models.Customer
+	require.NoError(t, json.Unmarshal(files["customer.json"], &customer))
+	assert.Equal(t, "jane@example.com", customer.Email)
+	var notes []ExportedNote
+	require.NoError(t, json.Unmarshal(files["notes.json"], &notes))
+	require.Len(t, notes, 1)
+	assert.Equal(t, "Prefers email", notes[0].Revisions[0].Body)
+	assert.Equal(t, "[]\n", string(files["interactions.json"]))
+	assert.Equal(t, pdfContent, files["attachments/"+attachment.ID.String()+"/contract.pdf"])
+}
+
+func TestGDPRExport_NotFound(t *testing.T) {
+	s, _, _ := newPrivacyTestServer(t)
+
+	rr := serve(s, "GET", "/customers/"+uuid.NewString()+"/gdpr-export", "")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
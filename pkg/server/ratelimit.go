@@ -0,0 +1,128 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"CustomerCRUD/pkg/ratelimit"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// apiKeyHeader carries the API key of an integration, which its rate limit and daily quota are
+// kept for.
+const apiKeyHeader = "X-API-Key"
+
+// unlimitedRoutes are never rate limited, so that probes keep working while clients are limited.
+var unlimitedRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// SetRateLimiter limits the requests of every client with limiter. trustProxy takes the client IP
+// from the last X-Forwarded-For entry, which only a proxy in front of the service can be trusted to
+// set. Without a limiter requests are not limited.
+func (s *Server) SetRateLimiter(limiter *ratelimit.Limiter, trustProxy bool) {
+	s.limiter = limiter
+	s.trustProxy = trustProxy
+}
+
+// APIKeyID identifies an API key without revealing it. Quotas are configured by it.
+func APIKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) rateLimitRequest(r *http.Request) ratelimit.Request {
+	req := ratelimit.Request{Method: r.Method}
+	if route := mux.CurrentRoute(r); route != nil {
+		req.Route, _ = route.GetPathTemplate()
+	}
+	req.Client, req.APIKeyID = s.client(r)
+	return req
+}
+
+// client identifies the client of r by its API key when the rate limiter knows it, else by its IP
+// address, and returns the ID of that API key. Unknown API keys and actors are not trusted, as a
+// client changing them with every request would get a fresh bucket each time.
+func (s *Server) client(r *http.Request) (client, apiKeyID string) {
+	if key := r.Header.Get(apiKeyHeader); key != "" && s.limiter != nil {
+		if id := APIKeyID(key); s.limiter.Knows(id) {
+			return "key:" + id, id
+		}
+	}
+	return "ip:" + s.clientIP(r), ""
+}
+
+func (s *Server) clientIP(r *http.Request) string {
+	if s.trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit responds 429 to the requests of clients that exceed their rate limit or daily quota,
+// and tells clients their limit in the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers. Requests are let through when the limiter fails.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		req := s.rateLimitRequest(r)
+		if unlimitedRoutes[req.Route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		d, err := s.limiter.Allow(r.Context(), req)
+		if err != nil {
+			log.Errorf("error applying rate limit: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if d.Limited {
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(d.Limit.Burst))
+			h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			h.Set("RateLimit-Reset", headerSeconds(d.Reset))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s;burst=%d", d.Limit.Requests, headerSeconds(d.Limit.Period), d.Limit.Burst))
+		}
+		if d.Allowed {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", headerSeconds(d.RetryAfter))
+		if d.QuotaExceeded {
+			log.Warnf("daily quota of API key %s exceeded", req.APIKeyID[:12])
+			http.Error(w, "Daily quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+	})
+}
+
+// headerSeconds renders d as whole seconds, rounded up so that clients do not retry too early.
+func headerSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/ratelimit"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRateLimitTestServer(policy ratelimit.Policy, trustProxy bool) *Server {
+	return newTestServer(&mocks.CustomerRepository{}, func(s *Server) {
+		s.SetRateLimiter(ratelimit.NewLimiter(policy, ratelimit.NewMemoryStore()), trustProxy)
+	})
+}
+
+func getWith(t *testing.T, s *Server, path string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.RemoteAddr = "192.0.2.1:1234"
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newRateLimitTestServer(ratelimit.Policy{
+		Default: ratelimit.Limit{Requests: 100, Period: time.Minute, Burst: 100},
+		Routes: []ratelimit.Route{
+			{Method: "GET", Path: "/openapi.json", Limit: ratelimit.Limit{Requests: 1, Period: time.Minute, Burst: 2}},
+		},
+		APIKeys: []string{APIKeyID("partner")},
+	}, false)
+
+	rr := getWith(t, s, "/openapi.json", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", rr.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "1;w=60;burst=2", rr.Header().Get("RateLimit-Policy"))
+
+	getWith(t, s, "/openapi.json", nil)
+	rr = getWith(t, s, "/openapi.json", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "Too many requests\n", rr.Body.String())
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+
+	// Configured API keys and the probes are not affected.
+	assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", map[string]string{"X-API-Key": "partner"}).Code)
+	rr = getWith(t, s, "/healthz", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+
+	// Unknown API keys and actors are not trusted, the client is still told apart by its IP address.
+	assert.Equal(t, http.StatusTooManyRequests, getWith(t, s, "/openapi.json", map[string]string{"X-API-Key": "made-up"}).Code)
+	assert.Equal(t, http.StatusTooManyRequests, getWith(t, s, "/openapi.json", map[string]string{"X-Actor": "batch-job"}).Code)
+}
+
+func TestRateLimit_TrustProxy(t *testing.T) {
+	policy := ratelimit.Policy{Default: ratelimit.Limit{Requests: 1, Period: time.Hour, Burst: 1}}
+	forwarded := func(ip string) map[string]string {
+		return map[string]string{"X-Forwarded-For": "203.0.113.9, " + ip}
+	}
+
+	s := newRateLimitTestServer(policy, true)
+	assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", forwarded("198.51.100.1")).Code)
+	assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", forwarded("198.51.100.2")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, getWith(t, s, "/openapi.json", forwarded("198.51.100.2")).Code)
+
+	// Without a trusted proxy the header is ignored, as clients could send any.
+	s = newRateLimitTestServer(policy, false)
+	assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", forwarded("198.51.100.1")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, getWith(t, s, "/openapi.json", forwarded("198.51.100.2")).Code)
+}
+
+func TestRateLimit_DailyQuota(t *testing.T) {
+	s := newRateLimitTestServer(ratelimit.Policy{
+		Default:    ratelimit.Limit{Requests: 100, Period: time.Second, Burst: 100},
+		APIKeys:    []string{APIKeyID("partner")},
+		DailyQuota: 1,
+		Quotas:     map[string]int{APIKeyID("internal"): 0},
+	}, false)
+	partner := map[string]string{"X-API-Key": "partner"}
+
+	assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", partner).Code)
+	rr := getWith(t, s, "/openapi.json", partner)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "Daily quota exceeded\n", rr.Body.String())
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", map[string]string{"X-API-Key": "internal"}).Code)
+	}
+	// Requests without a configured API key have no quota.
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", nil).Code)
+		assert.Equal(t, http.StatusOK, getWith(t, s, "/openapi.json", map[string]string{"X-API-Key": "made-up"}).Code)
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"CustomerCRUD/pkg/repository"
+)
+
+// stickinessSweep is how often the clients whose stickiness expired are forgotten.
+const stickinessSweep = time.Minute
+
+// SetReplicaStickiness lets clients read their own writes when reads go to a read replica: for
+// window after a client wrote, its requests read from the primary. Without it only the request
+// making a write reads from the primary afterwards.
+func (s *Server) SetReplicaStickiness(window time.Duration) {
+	s.stickiness = &stickiness{window: window, now: time.Now, until: map[string]time.Time{}}
+}
+
+// stickiness remembers until when clients read from the primary.
+type stickiness struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	until map[string]time.Time
+	swept time.Time
+}
+
+func (st *stickiness) sticky(client string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.now().Before(st.until[client])
+}
+
+func (st *stickiness) wrote(client string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := st.now()
+	st.until[client] = now.Add(st.window)
+	if now.Sub(st.swept) < stickinessSweep {
+		return
+	}
+	st.swept = now
+	for c, until := range st.until {
+		if !now.Before(until) {
+			delete(st.until, c)
+		}
+	}
+}
+
+// readYourWrites starts the read session of every request. Requests that may write read from the
+// primary, as do the requests of clients that wrote within the stickiness window.
+func (s *Server) readYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write := r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+		if s.stickiness == nil {
+			next.ServeHTTP(w, r.WithContext(repository.WithReadSession(r.Context(), write)))
+			return
+		}
+
+		client, _ := s.client(r)
+		ctx := repository.WithReadSession(r.Context(), write || s.stickiness.sticky(client))
+		next.ServeHTTP(w, r.WithContext(ctx))
+		if write || repository.Wrote(ctx) {
+			s.stickiness.wrote(client)
+		}
+	})
+}
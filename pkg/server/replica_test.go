@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+	s.SetReplicaStickiness(5 * time.Second)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.stickiness.now = func() time.Time { return now }
+
+	var readsPrimary bool
+	handler := s.readYourWrites(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readsPrimary = repository.ReadsPrimary(r.Context())
+	}))
+	serve := func(method, ip string) bool {
+		t.Helper()
+		req, err := http.NewRequest(method, "/customers", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = ip + ":1234"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return readsPrimary
+	}
+
+	assert.False(t, serve("GET", "192.0.2.1"), "reads go to the replica")
+	assert.True(t, serve("POST", "192.0.2.1"), "writes read from the primary")
+	assert.True(t, serve("GET", "192.0.2.1"), "the client reads its writes")
+	assert.False(t, serve("GET", "192.0.2.2"), "other clients read from the replica")
+
+	now = now.Add(5 * time.Second)
+	assert.False(t, serve("GET", "192.0.2.1"), "stickiness expires")
+}
+
+func TestReadYourWrites_WithoutStickiness(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	var readsPrimary bool
+	handler := s.readYourWrites(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readsPrimary = repository.ReadsPrimary(r.Context())
+	}))
+	for _, tc := range []struct {
+		method string
+		want   bool
+	}{{"GET", false}, {"PUT", true}, {"GET", false}} {
+		req, err := http.NewRequest(tc.method, "/customers", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, tc.want, readsPrimary, tc.method)
+	}
+}
@@ -6,13 +6,118 @@ import (
 
 func (s *Server) SetupRoutes() {
 	s.Router = mux.NewRouter()
+	s.Router.Use(identifyActor, s.rateLimit, s.failFast, s.readYourWrites, validateRequest)
+
+	s.Router.HandleFunc("/healthz", s.Healthz).Methods("GET")
+	s.Router.HandleFunc("/readyz", s.Readyz).Methods("GET")
+	s.Router.HandleFunc("/metrics", s.Metrics).Methods("GET")
+
+	s.Router.HandleFunc("/admin/faults", s.GetFaults).Methods("GET")
+	s.Router.HandleFunc("/admin/faults", s.PutFaults).Methods("PUT")
+	s.Router.HandleFunc("/admin/faults", s.DeleteFaults).Methods("DELETE")
+
+	s.Router.HandleFunc("/openapi.json", s.OpenAPISpec).Methods("GET")
+	s.Router.PathPrefix("/docs/").Handler(swaggerUI())
 
 	s.Router.HandleFunc("/customers", s.GetAllCustomers).Methods("GET")
 	s.Router.HandleFunc("/customers", s.CreateCustomer).Methods("POST")
+	s.Router.HandleFunc("/customers/verify-email", s.VerifyEmail).Methods("POST")
 
 	s.Router.HandleFunc("/customers/{id}", s.GetCustomerByID).Methods("GET")
 	s.Router.HandleFunc("/customers/{id}", s.UpdateCustomer).Methods("PUT")
 	s.Router.HandleFunc("/customers/{id}", s.DeleteCustomer).Methods("DELETE")
 
+	s.Router.HandleFunc("/customers/{id}/addresses", s.ListAddresses).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/addresses", s.CreateAddress).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/addresses/{addressId}", s.GetAddress).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/addresses/{addressId}", s.UpdateAddress).Methods("PUT")
+	s.Router.HandleFunc("/customers/{id}/addresses/{addressId}", s.DeleteAddress).Methods("DELETE")
+
+	s.Router.HandleFunc("/customers/{id}/emails", s.ListEmails).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/emails", s.CreateEmail).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/emails/{emailId}", s.GetEmail).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/emails/{emailId}", s.UpdateEmail).Methods("PUT")
+	s.Router.HandleFunc("/customers/{id}/emails/{emailId}", s.DeleteEmail).Methods("DELETE")
+
+	s.Router.HandleFunc("/customers/{id}/phones", s.ListPhones).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/phones", s.CreatePhone).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/phones/{phoneId}", s.GetPhone).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/phones/{phoneId}", s.UpdatePhone).Methods("PUT")
+	s.Router.HandleFunc("/customers/{id}/phones/{phoneId}", s.DeletePhone).Methods("DELETE")
+
+	s.Router.HandleFunc("/customers/{id}/tags", s.ListCustomerTags).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/tags/{tag}", s.TagCustomer).Methods("PUT")
+	s.Router.HandleFunc("/customers/{id}/tags/{tag}", s.UntagCustomer).Methods("DELETE")
+
+	s.Router.HandleFunc("/customers/{id}/verification-email", s.ResendVerificationEmail).Methods("POST")
+
+	s.Router.HandleFunc("/customers/{id}/status", s.ChangeCustomerStatus).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/status/history", s.CustomerStatusHistory).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/audit", s.CustomerAuditTrail).Methods("GET")
+
+	s.Router.HandleFunc("/customers/{id}/notes", s.ListNotes).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/notes", s.CreateNote).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/notes/{noteId}", s.GetNote).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/notes/{noteId}", s.UpdateNote).Methods("PUT")
+	s.Router.HandleFunc("/customers/{id}/notes/{noteId}", s.DeleteNote).Methods("DELETE")
+	s.Router.HandleFunc("/customers/{id}/notes/{noteId}/revisions", s.ListNoteRevisions).Methods("GET")
+
+	s.Router.HandleFunc("/customers/{id}/interactions", s.ListInteractions).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/interactions", s.CreateInteraction).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/interactions/{interactionId}", s.GetInteraction).Methods("GET")
+
+	s.Router.HandleFunc("/customers/{id}/timeline", s.CustomerTimeline).Methods("GET")
+
+	s.Router.HandleFunc("/customers/{id}/attachments", s.ListAttachments).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/attachments", s.UploadAttachment).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/attachments/{attachmentId}", s.GetAttachment).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/attachments/{attachmentId}", s.DeleteAttachment).Methods("DELETE")
+	s.Router.HandleFunc("/customers/{id}/attachments/{attachmentId}/content", s.DownloadAttachment).Methods("GET")
+
+	s.Router.HandleFunc("/customers/{id}/gdpr-export", s.GDPRExport).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/erasure", s.GetErasure).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/erasure", s.EraseCustomer).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/legal-holds", s.ListLegalHolds).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/legal-holds", s.PlaceLegalHold).Methods("POST")
+	s.Router.HandleFunc("/customers/{id}/legal-holds/{holdId}/release", s.ReleaseLegalHold).Methods("POST")
+
+	s.Router.HandleFunc("/customers/{id}/consents", s.ListConsents).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/consents", s.PutConsents).Methods("PUT")
+	s.Router.HandleFunc("/customers/{id}/consents/history", s.ListConsentHistory).Methods("GET")
+	s.Router.HandleFunc("/customers/{id}/unsubscribe-tokens", s.IssueUnsubscribeToken).Methods("POST")
+
 	s.Router.HandleFunc("/customers/email/{email}", s.GetCustomerByEmail).Methods("GET")
+
+	s.Router.HandleFunc("/attributes", s.ListAttributes).Methods("GET")
+	s.Router.HandleFunc("/attributes/json-schema", s.AttributesJSONSchema).Methods("GET")
+	s.Router.HandleFunc("/attributes/{name}", s.GetAttribute).Methods("GET")
+	s.Router.HandleFunc("/attributes/{name}", s.PutAttribute).Methods("PUT")
+	s.Router.HandleFunc("/attributes/{name}", s.DeleteAttribute).Methods("DELETE")
+
+	s.Router.HandleFunc("/tags", s.ListTags).Methods("GET")
+	s.Router.HandleFunc("/tags/add", s.BulkTagCustomers).Methods("POST")
+	s.Router.HandleFunc("/tags/remove", s.BulkUntagCustomers).Methods("POST")
+
+	s.Router.HandleFunc("/segments", s.ListSegments).Methods("GET")
+	s.Router.HandleFunc("/segments", s.CreateSegment).Methods("POST")
+	s.Router.HandleFunc("/segments/{id}", s.GetSegment).Methods("GET")
+	s.Router.HandleFunc("/segments/{id}", s.UpdateSegment).Methods("PUT")
+	s.Router.HandleFunc("/segments/{id}", s.DeleteSegment).Methods("DELETE")
+	s.Router.HandleFunc("/segments/{id}/customers", s.SegmentCustomers).Methods("GET")
+	s.Router.HandleFunc("/segments/{id}/count", s.SegmentCount).Methods("GET")
+
+	s.Router.HandleFunc("/notes", s.SearchNotes).Methods("GET")
+
+	s.Router.HandleFunc("/unsubscribe", s.UnsubscribePage).Methods("GET")
+	s.Router.HandleFunc("/unsubscribe", s.Unsubscribe).Methods("POST")
+
+	s.Router.HandleFunc("/me/login", s.PortalLogin).Methods("POST")
+	s.Router.HandleFunc("/me/session", s.CreatePortalSession).Methods("POST")
+	s.Router.HandleFunc("/me/session", s.portalAuth(s.DeletePortalSession)).Methods("DELETE")
+	s.Router.HandleFunc("/me", s.portalAuth(s.GetPortalProfile)).Methods("GET")
+	s.Router.HandleFunc("/me", s.portalAuth(s.UpdatePortalProfile)).Methods("PATCH")
+	s.Router.HandleFunc("/me/consents", s.portalAuth(s.ListPortalConsents)).Methods("GET")
+	s.Router.HandleFunc("/me/consents", s.portalAuth(s.PutPortalConsents)).Methods("PUT")
+
+	s.Router.HandleFunc("/graphql", s.GraphQL).Methods("GET", "POST")
 }
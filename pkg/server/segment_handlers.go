@@ -0,0 +1,236 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/segment"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// segmentCount is the response of the membership count endpoint.
+type segmentCount struct {
+	Count int `json:"count"`
+}
+
+func (s *Server) segmentsEnabled(w http.ResponseWriter) bool {
+	if s.segments == nil {
+		http.Error(w, "Segments are disabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func segmentError(w http.ResponseWriter, err error, failed string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Segment not found", http.StatusNotFound)
+		return
+	}
+	log.Errorf("%s: %v", failed, err)
+	http.Error(w, failed, http.StatusInternalServerError)
+}
+
+func decodeSegment(w http.ResponseWriter, r *http.Request) (models.Segment, bool) {
+	var sg models.Segment
+	if err := json.NewDecoder(r.Body).Decode(&sg); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return sg, false
+	}
+
+	sg.Normalize()
+	if err := sg.Validate(); err != nil {
+		http.Error(w, validationMessage("Invalid segment: ", err), http.StatusBadRequest)
+		return sg, false
+	}
+	if _, err := segment.Parse(sg.Filter); err != nil {
+		http.Error(w, "Invalid segment filter: "+err.Error(), http.StatusBadRequest)
+		return sg, false
+	}
+	return sg, true
+}
+
+func (s *Server) ListSegments(w http.ResponseWriter, r *http.Request) {
+	if !s.segmentsEnabled(w) {
+		return
+	}
+
+	segments, err := s.segments.ListSegments(r.Context())
+	if err != nil {
+		log.Errorf("error listing segments: %v", err)
+		http.Error(w, "Failed to retrieve segments", http.StatusInternalServerError)
+		return
+	}
+	if segments == nil {
+		segments = []models.Segment{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(segments)
+}
+
+func (s *Server) GetSegment(w http.ResponseWriter, r *http.Request) {
+	if !s.segmentsEnabled(w) {
+		return
+	}
+	id, ok := pathID(w, r, "id", "Invalid segment ID")
+	if !ok {
+		return
+	}
+
+	sg, err := s.segments.GetSegment(r.Context(), id)
+	if err != nil {
+		segmentError(w, err, "Failed to retrieve segment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sg)
+}
+
+func (s *Server) CreateSegment(w http.ResponseWriter, r *http.Request) {
+	if !s.segmentsEnabled(w) {
+		return
+	}
+	sg, ok := decodeSegment(w, r)
+	if !ok {
+		return
+	}
+
+	sg.ID = uuid.New()
+	if err := s.segments.CreateSegment(r.Context(), sg); err != nil {
+		segmentError(w, err, "Failed to create segment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sg)
+}
+
+func (s *Server) UpdateSegment(w http.ResponseWriter, r *http.Request) {
+	if !s.segmentsEnabled(w) {
+		return
+	}
+	id, ok := pathID(w, r, "id", "Invalid segment ID")
+	if !ok {
+		return
+	}
+	sg, ok := decodeSegment(w, r)
+	if !ok {
+		return
+	}
+
+	sg.ID = id
+	if err := s.segments.UpdateSegment(r.Context(), sg); err != nil {
+		segmentError(w, err, "Failed to update segment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sg)
+}
+
+func (s *Server) DeleteSegment(w http.ResponseWriter, r *http.Request) {
+	if !s.segmentsEnabled(w) {
+		return
+	}
+	id, ok := pathID(w, r, "id", "Invalid segment ID")
+	if !ok {
+		return
+	}
+
+	if err := s.segments.DeleteSegment(r.Context(), id); err != nil {
+		segmentError(w, err, "Failed to delete segment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// segmentQuery loads the segment of the path and returns the customer query selecting its
+// members, narrowed by the attribute filters and ordered by the sort of the request.
+func (s *Server) segmentQuery(w http.ResponseWriter, r *http.Request) (repository.CustomerQuery, bool) {
+	var query repository.CustomerQuery
+	if !s.segmentsEnabled(w) {
+		return query, false
+	}
+	id, ok := pathID(w, r, "id", "Invalid segment ID")
+	if !ok {
+		return query, false
+	}
+
+	sg, err := s.segments.GetSegment(r.Context(), id)
+	if err != nil {
+		segmentError(w, err, "Failed to retrieve segment")
+		return query, false
+	}
+	expr, err := segment.Parse(sg.Filter)
+	if err != nil {
+		log.Errorf("stored filter of segment %s is invalid: %v", sg.ID, err)
+		http.Error(w, "Failed to evaluate segment", http.StatusInternalServerError)
+		return query, false
+	}
+
+	query, _, err = s.customerQuery(r.Context(), r)
+	var clientErr clientError
+	if errors.As(err, &clientErr) {
+		http.Error(w, clientErr.message, http.StatusBadRequest)
+		return query, false
+	}
+	if err != nil {
+		log.Errorf("error building segment query: %v", err)
+		http.Error(w, "Failed to evaluate segment", http.StatusInternalServerError)
+		return query, false
+	}
+	query.Segment = expr
+	return query, true
+}
+
+// SegmentCustomers lists the current members of a segment.
+func (s *Server) SegmentCustomers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query, ok := s.segmentQuery(w, r)
+	if !ok {
+		return
+	}
+
+	customers, err := s.customers.FindCustomers(ctx, query)
+	if err == nil {
+		err = s.embedIncludes(ctx, r, customers)
+	}
+	if err != nil {
+		log.Errorf("error getting segment customers: %v", err)
+		http.Error(w, "Failed to evaluate segment", http.StatusInternalServerError)
+		return
+	}
+	if customers == nil {
+		customers = []models.Customer{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customers)
+}
+
+// SegmentCount counts the current members of a segment.
+func (s *Server) SegmentCount(w http.ResponseWriter, r *http.Request) {
+	query, ok := s.segmentQuery(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := s.customers.CountCustomers(r.Context(), query)
+	if err != nil {
+		log.Errorf("error counting segment customers: %v", err)
+		http.Error(w, "Failed to evaluate segment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(segmentCount{Count: n})
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/segment"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateSegment(t *testing.T) {
+	segments := &mocks.SegmentRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetSegmentRepository(segments) })
+
+	segments.On("CreateSegment", mock.Anything, mock.MatchedBy(func(sg models.Segment) bool {
+		return sg.ID != uuid.Nil && sg.Name == "German VIPs" && sg.Filter == `country = "DE" AND tag:vip`
+	})).Return(nil)
+
+	body := `{"name":" German VIPs ","filter":"country = \"DE\" AND tag:vip"}`
+	req, err := http.NewRequest("POST", "/segments", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, status)
+	}
+	segments.AssertExpectations(t)
+}
+
+func TestCreateSegment_InvalidFilter(t *testing.T) {
+	segments := &mocks.SegmentRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetSegmentRepository(segments) })
+
+	body := `{"name":"Broken","filter":"country = DE"}`
+	req, err := http.NewRequest("POST", "/segments", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid segment filter: at position 11: expected a double-quoted string but found DE\n", rr.Body.String())
+	segments.AssertNotCalled(t, "CreateSegment", mock.Anything, mock.Anything)
+}
+
+func TestSegmentCustomers(t *testing.T) {
+	customers, segments := &mocks.CustomerRepository{}, &mocks.SegmentRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetSegmentRepository(segments) })
+	sg := models.Segment{ID: uuid.New(), Name: "German VIPs", Filter: `country = "DE" AND tag:vip`}
+	expected := []models.Customer{{ID: uuid.New(), FirstName: "Ann", LastName: "Adler"}}
+
+	segments.On("GetSegment", mock.Anything, sg.ID).Return(&sg, nil)
+	customers.On("FindCustomers", mock.Anything, repository.CustomerQuery{
+		Segment:   segment.And{Left: segment.Compare{Field: "country", Op: segment.Equal, Value: "DE"}, Right: segment.HasTag{Tag: "vip"}},
+		SortField: "last_name",
+	}).Return(expected, nil)
+
+	req, err := http.NewRequest("GET", "/segments/"+sg.ID.String()+"/customers?sort=last_name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var result []models.Customer
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestSegmentCount(t *testing.T) {
+	customers, segments := &mocks.CustomerRepository{}, &mocks.SegmentRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetSegmentRepository(segments) })
+	sg := models.Segment{ID: uuid.New(), Name: "VIPs", Filter: "tag:vip"}
+
+	segments.On("GetSegment", mock.Anything, sg.ID).Return(&sg, nil)
+	customers.On("CountCustomers", mock.Anything, repository.CustomerQuery{Segment: segment.HasTag{Tag: "vip"}}).Return(42, nil)
+
+	req, err := http.NewRequest("GET", "/segments/"+sg.ID.String()+"/count", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"count":42}`, rr.Body.String())
+}
@@ -1,18 +1,115 @@
 package server
 
 import (
+	"net/http"
+	"sync/atomic"
+
+	"CustomerCRUD/pkg/mail"
+	"CustomerCRUD/pkg/ratelimit"
 	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/faults"
+	"CustomerCRUD/pkg/repository/resilient"
+	"CustomerCRUD/pkg/service"
+	"CustomerCRUD/pkg/storage"
+	"CustomerCRUD/pkg/unsubscribe"
 
 	"github.com/gorilla/mux"
 )
 
 type Server struct {
 	Router     *mux.Router
-	repository repository.CustomerRepository // TODO: Abstract service layer
+	customers  *service.CustomerService
+	addresses  repository.AddressRepository
+	contacts   repository.ContactRepository
+	attributes repository.AttributeRepository
+	tags       repository.TagRepository
+	segments   repository.SegmentRepository
+	activity   repository.ActivityRepository
+	consents   repository.ConsentRepository
+
+	unsubscribe *unsubscribe.Signer
+
+	sessions     repository.SessionRepository
+	portalMailer mail.Mailer
+	portalPolicy PortalPolicy
+
+	attachments      repository.AttachmentRepository
+	blobs            storage.BlobStore
+	attachmentPolicy AttachmentPolicy
+
+	limiter    *ratelimit.Limiter
+	trustProxy bool
+	stickiness *stickiness
+	breaker    *resilient.Breaker
+	faults     *faults.Injector
+
+	readinessChecks []namedCheck
+	metrics         []namedMetrics
+	shuttingDown    atomic.Bool
+	grpcHandler     http.Handler
+	graphqlHandler  http.Handler
 }
 
-func NewServer(repository repository.CustomerRepository) *Server {
+func NewServer(customers *service.CustomerService) *Server {
 	return &Server{
-		repository: repository,
+		customers: customers,
 	}
 }
+
+// SetAddressRepository enables the nested address endpoints. Without it they respond 404.
+func (s *Server) SetAddressRepository(addresses repository.AddressRepository) {
+	s.addresses = addresses
+}
+
+// SetContactRepository enables the nested email and phone endpoints. Without it they respond 404.
+func (s *Server) SetContactRepository(contacts repository.ContactRepository) {
+	s.contacts = contacts
+}
+
+// SetAttributeRepository enables the custom attribute registry and the attribute filters of the
+// customer list. Without it the registry responds 404.
+func (s *Server) SetAttributeRepository(attributes repository.AttributeRepository) {
+	s.attributes = attributes
+}
+
+// SetTagRepository enables the tag endpoints and ?include=tags. Without it they respond 404.
+func (s *Server) SetTagRepository(tags repository.TagRepository) {
+	s.tags = tags
+}
+
+// SetSegmentRepository enables the segment endpoints. Without it they respond 404.
+func (s *Server) SetSegmentRepository(segments repository.SegmentRepository) {
+	s.segments = segments
+}
+
+// SetActivityRepository enables the note, interaction and timeline endpoints. Without it they respond 404.
+func (s *Server) SetActivityRepository(activity repository.ActivityRepository) {
+	s.activity = activity
+}
+
+// SetConsentRepository enables the consent endpoints. Without it they respond 404.
+func (s *Server) SetConsentRepository(consents repository.ConsentRepository) {
+	s.consents = consents
+}
+
+// SetUnsubscribeSigner enables unsubscribe links, whose tokens signer signs and verifies. They also
+// need the consent repository, without either they respond 404.
+func (s *Server) SetUnsubscribeSigner(signer *unsubscribe.Signer) {
+	s.unsubscribe = signer
+}
+
+// SetPortal enables the customer self-service portal under /me, keeping its sign-in links and
+// sessions in sessions and mailing the links with mailer. Without it the portal responds 404.
+func (s *Server) SetPortal(sessions repository.SessionRepository, mailer mail.Mailer, policy PortalPolicy) {
+	s.sessions = sessions
+	s.portalMailer = mailer
+	s.portalPolicy = policy
+}
+
+// SetAttachmentStore enables the attachment endpoints, keeping the metadata in attachments and
+// the contents in blobs. Without it they respond 404.
+func (s *Server) SetAttachmentStore(attachments repository.AttachmentRepository, blobs storage.BlobStore, policy AttachmentPolicy) {
+	s.attachments = attachments
+	s.blobs = blobs
+	s.attachmentPolicy = policy
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"CustomerCRUD/pkg/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// statusChangeRequest is the body of the status change endpoint.
+type statusChangeRequest struct {
+	Status models.Status `json:"status"`
+	Reason string        `json:"reason"`
+}
+
+func (s *Server) ChangeCustomerStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	var req statusChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	change, err := s.customers.ChangeStatus(r.Context(), id, req.Status, req.Reason)
+	if err != nil {
+		writeServiceError(w, err, "Invalid status change: ", "Failed to change customer status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
+
+func (s *Server) CustomerStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	changes, err := s.customers.StatusHistory(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errorf("error getting customer status history: %v", err)
+		http.Error(w, "Failed to retrieve customer status history", http.StatusInternalServerError)
+		return
+	}
+	if changes == nil {
+		changes = []models.StatusChange{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newStatusTestServer() (*Server, *mocks.CustomerRepository, *mocks.StatusRepository) {
+	customers := &mocks.CustomerRepository{}
+	statuses := &mocks.StatusRepository{}
+	uow := repository.Untransacted(repository.Repositories{Customers: customers, Statuses: statuses})
+	s := NewServer(service.NewCustomerService(uow, events.NewBus()))
+	s.SetupRoutes()
+	return s, customers, statuses
+}
+
+func postStatus(s *Server, id string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/customers/"+id+"/status", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestChangeCustomerStatus(t *testing.T) {
+	s, customers, statuses := newStatusTestServer()
+	id := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id, Status: models.StatusLead}, nil)
+	statuses.On("ChangeStatus", mock.Anything, mock.MatchedBy(func(c models.StatusChange) bool {
+		return c.From == models.StatusLead && c.To == models.StatusProspect && c.Reason == "Requested a demo"
+	})).Return(nil)
+
+	rr := postStatus(s, id.String(), map[string]string{"status": "prospect", "reason": " Requested a demo "})
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var change models.StatusChange
+	if err := json.NewDecoder(rr.Body).Decode(&change); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, change.CustomerID)
+	assert.Equal(t, models.StatusLead, change.From)
+	assert.Equal(t, models.StatusProspect, change.To)
+	statuses.AssertExpectations(t)
+}
+
+func TestChangeCustomerStatus_Errors(t *testing.T) {
+	s, customers, statuses := newStatusTestServer()
+	lead, raced := uuid.New(), uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, lead).Return(&models.Customer{ID: lead, Status: models.StatusLead}, nil)
+	customers.On("GetCustomerByID", mock.Anything, raced).Return(&models.Customer{ID: raced, Status: models.StatusActive}, nil)
+	statuses.On("ChangeStatus", mock.Anything, mock.Anything).Return(repository.ErrStatusConflict)
+
+	tests := []struct {
+		name    string
+		id      string
+		body    map[string]string
+		code    int
+		message string
+	}{
+		{"unknown status", lead.String(), map[string]string{"status": "archived", "reason": "Old"}, http.StatusBadRequest,
+			"Request does not match the API specification: body /status: must be one of [lead prospect active suspended closed]\n"},
+		{"blank reason", lead.String(), map[string]string{"status": "prospect", "reason": "  "}, http.StatusBadRequest,
+			"Invalid status change: reason is required\n"},
+		{"illegal transition", lead.String(), map[string]string{"status": "suspended", "reason": "Fraud"}, http.StatusConflict,
+			"Status change not allowed: cannot change status from lead to suspended, allowed: prospect, closed\n"},
+		{"concurrent change", raced.String(), map[string]string{"status": "suspended", "reason": "Fraud"}, http.StatusConflict,
+			"The customer status changed in the meantime, please retry\n"},
+		{"invalid id", "invalid-uuid", map[string]string{"status": "prospect", "reason": "Demo"}, http.StatusBadRequest,
+			"Invalid customer ID\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postStatus(s, tt.id, tt.body)
+			assert.Equal(t, tt.code, rr.Code)
+			assert.Equal(t, tt.message, rr.Body.String())
+		})
+	}
+}
+
+func TestCustomerStatusHistory(t *testing.T) {
+	s, customers, statuses := newStatusTestServer()
+	id := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id, Status: models.StatusProspect}, nil)
+	statuses.On("ListStatusChanges", mock.Anything, id).Return([]models.StatusChange{
+		{ID: uuid.New(), CustomerID: id, To: models.StatusLead, Reason: "Customer created"},
+		{ID: uuid.New(), CustomerID: id, From: models.StatusLead, To: models.StatusProspect, Reason: "Requested a demo"},
+	}, nil)
+
+	req, err := http.NewRequest("GET", "/customers/"+id.String()+"/status/history", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var changes []models.StatusChange
+	if err := json.NewDecoder(rr.Body).Decode(&changes); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, changes, 2)
+	assert.Equal(t, models.StatusProspect, changes[1].To)
+}
+
+func TestGetAllCustomers_Status(t *testing.T) {
+	mockRepo := &mocks.CustomerRepository{}
+	s := newTestServer(mockRepo)
+
+	mockRepo.On("FindCustomers", mock.Anything, repository.CustomerQuery{
+		Statuses: []models.Status{models.StatusActive, models.StatusSuspended},
+	}).Return([]models.Customer{}, nil)
+
+	req, err := http.NewRequest("GET", "/customers?status=active,suspended", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+
+	req, err = http.NewRequest("GET", "/customers?status=archived", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxBulkTagCustomers bounds the customers of a single bulk tag or untag request.
+const maxBulkTagCustomers = 1000
+
+// bulkTagRequest is the body of the bulk tag and untag endpoints.
+type bulkTagRequest struct {
+	CustomerIDs []uuid.UUID `json:"customer_ids"`
+	Tags        []string    `json:"tags"`
+}
+
+func (s *Server) tagsEnabled(w http.ResponseWriter) bool {
+	if s.tags == nil {
+		http.Error(w, "Tags are disabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// normalizeTags normalizes, validates and deduplicates tags, keeping their order.
+func normalizeTags(tags []string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = models.NormalizeTag(tag)
+		if err := models.ValidateTag(tag); err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+func (s *Server) ListTags(w http.ResponseWriter, r *http.Request) {
+	if !s.tagsEnabled(w) {
+		return
+	}
+
+	tags, err := s.tags.ListTags(r.Context())
+	if err != nil {
+		log.Errorf("error listing tags: %v", err)
+		http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+func (s *Server) ListCustomerTags(w http.ResponseWriter, r *http.Request) {
+	if !s.tagsEnabled(w) {
+		return
+	}
+	customerID, ok := s.customerFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := s.tags.ListCustomerTags(r.Context(), customerID)
+	if err != nil {
+		log.Errorf("error listing customer tags: %v", err)
+		http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// tagFromPath returns the normalized tag of the path and responds 400 when it is invalid.
+func tagFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tag := models.NormalizeTag(mux.Vars(r)["tag"])
+	if err := models.ValidateTag(tag); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid tag: %v", err), http.StatusBadRequest)
+		return "", false
+	}
+	return tag, true
+}
+
+func (s *Server) TagCustomer(w http.ResponseWriter, r *http.Request) {
+	if !s.tagsEnabled(w) {
+		return
+	}
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+	tag, ok := tagFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.tags.AddTags(r.Context(), []uuid.UUID{id}, []string{tag}); err != nil {
+		tagError(w, err, "Failed to tag customer")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) UntagCustomer(w http.ResponseWriter, r *http.Request) {
+	if !s.tagsEnabled(w) {
+		return
+	}
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+	tag, ok := tagFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.tags.RemoveTags(r.Context(), []uuid.UUID{id}, []string{tag}); err != nil {
+		tagError(w, err, "Failed to untag customer")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeBulkTag(w http.ResponseWriter, r *http.Request) (bulkTagRequest, bool) {
+	var req bulkTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return req, false
+	}
+	if len(req.CustomerIDs) == 0 || len(req.Tags) == 0 {
+		http.Error(w, "customer_ids and tags are required", http.StatusBadRequest)
+		return req, false
+	}
+	if len(req.CustomerIDs) > maxBulkTagCustomers {
+		http.Error(w, fmt.Sprintf("At most %d customers can be tagged at once", maxBulkTagCustomers), http.StatusBadRequest)
+		return req, false
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid tag: %v", err), http.StatusBadRequest)
+		return req, false
+	}
+	req.Tags = tags
+	return req, true
+}
+
+// BulkTagCustomers tags every listed customer with every listed tag. Nobody is tagged when
+// one of the customers does not exist.
+func (s *Server) BulkTagCustomers(w http.ResponseWriter, r *http.Request) {
+	if !s.tagsEnabled(w) {
+		return
+	}
+	req, ok := decodeBulkTag(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.tags.AddTags(r.Context(), req.CustomerIDs, req.Tags); err != nil {
+		tagError(w, err, "Failed to tag customers")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BulkUntagCustomers removes every listed tag from every listed customer.
+func (s *Server) BulkUntagCustomers(w http.ResponseWriter, r *http.Request) {
+	if !s.tagsEnabled(w) {
+		return
+	}
+	req, ok := decodeBulkTag(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.tags.RemoveTags(r.Context(), req.CustomerIDs, req.Tags); err != nil {
+		tagError(w, err, "Failed to untag customers")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func tagError(w http.ResponseWriter, err error, failed string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+	log.Errorf("%s: %v", failed, err)
+	http.Error(w, failed, http.StatusInternalServerError)
+}
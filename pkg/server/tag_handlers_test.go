@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBulkTagCustomers(t *testing.T) {
+	tags := &mocks.TagRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetTagRepository(tags) })
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	tags.On("AddTags", mock.Anything, ids, []string{"vip", "newsletter"}).Return(nil)
+
+	body, _ := json.Marshal(map[string]any{"customer_ids": ids, "tags": []string{"VIP", " newsletter", "vip"}})
+	req, err := http.NewRequest("POST", "/tags/add", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	tags.AssertExpectations(t)
+}
+
+func TestBulkTagCustomers_UnknownCustomer(t *testing.T) {
+	tags := &mocks.TagRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetTagRepository(tags) })
+	ids := []uuid.UUID{uuid.New()}
+
+	tags.On("AddTags", mock.Anything, ids, []string{"vip"}).Return(sql.ErrNoRows)
+
+	body, _ := json.Marshal(map[string]any{"customer_ids": ids, "tags": []string{"vip"}})
+	req, err := http.NewRequest("POST", "/tags/add", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Customer not found\n", rr.Body.String())
+}
+
+func TestBulkUntagCustomers_InvalidTag(t *testing.T) {
+	tags := &mocks.TagRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetTagRepository(tags) })
+
+	body, _ := json.Marshal(map[string]any{"customer_ids": []uuid.UUID{uuid.New()}, "tags": []string{"not a tag"}})
+	req, err := http.NewRequest("POST", "/tags/remove", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	tags.AssertNotCalled(t, "RemoveTags", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUntagCustomer(t *testing.T) {
+	tags := &mocks.TagRepository{}
+	s := newTestServer(&mocks.CustomerRepository{}, func(s *Server) { s.SetTagRepository(tags) })
+	id := uuid.New()
+
+	tags.On("RemoveTags", mock.Anything, []uuid.UUID{id}, []string{"vip"}).Return(nil)
+
+	req, err := http.NewRequest("DELETE", "/customers/"+id.String()+"/tags/VIP", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	tags.AssertExpectations(t)
+}
+
+func TestGetCustomerByID_IncludeTags(t *testing.T) {
+	customers, tags := &mocks.CustomerRepository{}, &mocks.TagRepository{}
+	s := newTestServer(customers, func(s *Server) { s.SetTagRepository(tags) })
+	id := uuid.New()
+
+	customers.On("GetCustomerByID", mock.Anything, id).Return(&models.Customer{ID: id, FirstName: "Ann"}, nil)
+	tags.On("ListTagsByCustomerIDs", mock.Anything, []uuid.UUID{id}).
+		Return(map[uuid.UUID][]string{id: {"newsletter", "vip"}}, nil)
+
+	req, err := http.NewRequest("GET", "/customers/"+id.String()+"?include=tags", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var result models.Customer
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Errorf("Failed to parse response body: %v", err)
+	}
+	assert.Equal(t, []string{"newsletter", "vip"}, result.Tags)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"CustomerCRUD/pkg/actor"
+)
+
+// verificationActor records the emails verified through verification links, which are not
+// authenticated and so cannot trust the X-Actor header.
+const verificationActor = "email verification link"
+
+// verifyEmailRequest is the body of the endpoint verifying emails.
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail marks the email a verification token was sent to as verified and responds with the
+// customer. The token proves the request, so it needs no authentication.
+func (s *Server) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := s.customers.VerifyEmail(actor.NewContext(r.Context(), verificationActor), req.Token)
+	if err != nil {
+		writeServiceError(w, err, "Invalid verification: ", "Failed to verify email")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer)
+}
+
+// ResendVerificationEmail sends the verification email of the customer again, within the resend limits.
+func (s *Server) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	if err := s.customers.ResendVerification(r.Context(), id); err != nil {
+		writeServiceError(w, err, "Invalid verification: ", "Failed to send verification email")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
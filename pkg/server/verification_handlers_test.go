@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/mail"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/verification"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerificationTestServer() (*Server, *mocks.CustomerRepository, *verification.Verifier, *mail.Outbox) {
+	customers := &mocks.CustomerRepository{}
+	outbox := mail.NewOutbox()
+	verifier := verification.NewVerifier(verification.Config{
+		Secret:  "a secret of at least thirty-two characters",
+		TTL:     time.Hour,
+		LinkURL: "https://app.example.com/verify-email",
+		Limits:  verification.Limits{Interval: time.Minute},
+	}, outbox)
+	s := newTestServer(customers, func(s *Server) { s.customers.SetEmailVerifier(verifier) })
+	return s, customers, verifier, outbox
+}
+
+func postVerifyEmail(t *testing.T, s *Server, token string) *httptest.ResponseRecorder {
+	body, err := json.Marshal(verifyEmailRequest{Token: token})
+	require.NoError(t, err)
+	req, err := http.NewRequest("POST", "/customers/verify-email", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	s.Router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s, customers, verifier, _ := newVerificationTestServer()
+	customer := models.Customer{ID: uuid.New(), FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}
+	verified := customer
+	verified.EmailVerified = true
+
+	customers.On("GetCustomerByID", mock.Anything, customer.ID).Return(&customer, nil).Once()
+	customers.On("MarkEmailVerified", mock.Anything, customer.ID, "jane@example.com").Return(nil)
+	customers.On("GetCustomerByID", mock.Anything, customer.ID).Return(&verified, nil).Once()
+
+	rr := postVerifyEmail(t, s, verifier.Sign(customer))
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var got models.Customer
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.True(t, got.EmailVerified)
+	customers.AssertExpectations(t)
+}
+
+func TestVerifyEmail_Rejected(t *testing.T) {
+	s, customers, verifier, _ := newVerificationTestServer()
+	customer := models.Customer{ID: uuid.New(), Email: "jane.doe@example.com"}
+	customers.On("GetCustomerByID", mock.Anything, customer.ID).Return(&customer, nil)
+
+	rr := postVerifyEmail(t, s, "not a token")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid verification link\n", rr.Body.String())
+
+	// The token was sent to the previous email of the customer.
+	rr = postVerifyEmail(t, s, verifier.Sign(models.Customer{ID: customer.ID, Email: "jane@example.com"}))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	customers.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	s, customers, _, outbox := newVerificationTestServer()
+	customer := models.Customer{ID: uuid.New(), FirstName: "Jane", Email: "jane@example.com"}
+	customers.On("GetCustomerByID", mock.Anything, customer.ID).Return(&customer, nil)
+
+	resend := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/customers/"+customer.ID.String()+"/verification-email", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		s.Router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := resend()
+	assert.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	require.Len(t, outbox.Messages(), 1)
+	assert.Equal(t, "jane@example.com", outbox.Messages()[0].To)
+
+	rr = resend()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.Len(t, outbox.Messages(), 1)
+}
+
+func TestVerificationDisabled(t *testing.T) {
+	s := newTestServer(&mocks.CustomerRepository{})
+
+	rr := postVerifyEmail(t, s, "token")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "Email verification is disabled\n", rr.Body.String())
+}
@@ -0,0 +1,274 @@
+// Package service holds the business rules that sit between the transports and the repositories.
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/storage"
+	"CustomerCRUD/pkg/verification"
+
+	"github.com/google/uuid"
+)
+
+// ValidationError is returned when a write is invalid. Its message can be shown to clients as is.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// CustomerService owns the rules of customer writes: authorization, normalization, validation,
+// the audit trail and change events. Each write runs in a unit of work, so the customer and its
+// audit entry or status history are stored together. Reads go straight to the repository, which
+// makes the service a repository.CustomerRepository itself.
+type CustomerService struct {
+	repository.CustomerRepository
+
+	uow         repository.UnitOfWork
+	publisher   events.Publisher
+	authorizer  Authorizer
+	transitions Transitions
+	blobs       storage.BlobStore
+	verifier    *verification.Verifier
+}
+
+var _ repository.CustomerRepository = (*CustomerService)(nil)
+
+// NewCustomerService returns a service writing through uow and publishing to publisher. Every
+// write is allowed and statuses follow DefaultTransitions until configured otherwise.
+func NewCustomerService(uow repository.UnitOfWork, publisher events.Publisher) *CustomerService {
+	return &CustomerService{
+		CustomerRepository: uow.Repositories().Customers,
+		uow:                uow,
+		publisher:          publisher,
+		authorizer:         AllowAll,
+		transitions:        DefaultTransitions(),
+	}
+}
+
+// SetAuthorizer replaces the authorizer deciding on every write.
+func (s *CustomerService) SetAuthorizer(authorizer Authorizer) {
+	s.authorizer = authorizer
+}
+
+// SetBlobStore sets the store holding the attachment contents, which erasures remove.
+func (s *CustomerService) SetBlobStore(blobs storage.BlobStore) {
+	s.blobs = blobs
+}
+
+// SetTransitions replaces the allowed status transitions, see NewTransitions.
+func (s *CustomerService) SetTransitions(transitions Transitions) {
+	s.transitions = transitions
+}
+
+// CreateCustomer stores a new customer with a fresh ID and the initial status.
+func (s *CustomerService) CreateCustomer(ctx context.Context, customer *models.Customer) error {
+	if err := s.authorizer.Authorize(ctx, ActionCreate, uuid.Nil); err != nil {
+		return err
+	}
+	customer.Normalize()
+	if err := customer.Validate(); err != nil {
+		return &ValidationError{err}
+	}
+
+	// The status only changes through ChangeStatus and the email is verified through VerifyEmail.
+	customer.ID, customer.Status, customer.EmailVerified = uuid.New(), "", false
+	err := s.uow.Do(ctx, func(repos repository.Repositories) error {
+		if err := validateAttributes(ctx, repos, customer.Attributes); err != nil {
+			return err
+		}
+		if err := repos.Customers.CreateCustomer(ctx, customer); err != nil {
+			return err
+		}
+		return s.audit(ctx, repos, customer.ID, models.AuditCreated, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot := *customer
+	s.publisher.Publish(ctx, events.New(events.CustomerCreated, customer.ID, &snapshot))
+	s.sendVerification(ctx, snapshot)
+	return nil
+}
+
+// UpdateCustomer replaces the customer and records the changed fields. It keeps the stored
+// attributes when customer has none and fails with sql.ErrNoRows when the customer does not exist
+// and ErrErased when it was erased. A changed email has to be verified again.
+func (s *CustomerService) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	if err := s.authorizer.Authorize(ctx, ActionUpdate, customer.ID); err != nil {
+		return err
+	}
+	customer.Normalize()
+	if err := customer.Validate(); err != nil {
+		return &ValidationError{err}
+	}
+
+	emailChanged := false
+	err := s.uow.Do(ctx, func(repos repository.Repositories) error {
+		before, err := repos.Customers.GetCustomerByID(ctx, customer.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkNotErased(ctx, repos, customer.ID); err != nil {
+			return err
+		}
+		emailChanged = before.Email != customer.Email
+		if customer.Attributes != nil {
+			if err := validateAttributes(ctx, repos, customer.Attributes); err != nil {
+				return err
+			}
+		}
+		if err := repos.Customers.UpdateCustomer(ctx, customer); err != nil {
+			return err
+		}
+
+		changes := models.CustomerChanges(*before, *customer)
+		if len(changes) == 0 {
+			return nil
+		}
+		return s.audit(ctx, repos, customer.ID, models.AuditUpdated, changes)
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot := *customer
+	s.publisher.Publish(ctx, events.New(events.CustomerUpdated, customer.ID, &snapshot))
+	if emailChanged {
+		s.sendVerification(ctx, snapshot)
+	}
+	return nil
+}
+
+// DeleteCustomer removes the customer. It fails with sql.ErrNoRows when the customer does not exist
+// and a *LegalHoldError when it is under legal hold.
+func (s *CustomerService) DeleteCustomer(ctx context.Context, customerID uuid.UUID) error {
+	if err := s.authorizer.Authorize(ctx, ActionDelete, customerID); err != nil {
+		return err
+	}
+
+	err := s.uow.Do(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Customers.GetCustomerByID(ctx, customerID); err != nil {
+			return err
+		}
+		if err := checkLegalHolds(ctx, repos, customerID); err != nil {
+			return err
+		}
+		if err := repos.Customers.DeleteCustomer(ctx, customerID); err != nil {
+			return err
+		}
+		return s.audit(ctx, repos, customerID, models.AuditDeleted, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publisher.Publish(ctx, events.New(events.CustomerDeleted, customerID, nil))
+	return nil
+}
+
+// ChangeStatus moves the customer to another status and records why. It fails with a
+// *ValidationError for an unknown status or a missing reason, a *TransitionError when the
+// lifecycle does not allow the change, sql.ErrNoRows when the customer does not exist and
+// repository.ErrStatusConflict when its status changed concurrently.
+func (s *CustomerService) ChangeStatus(ctx context.Context, customerID uuid.UUID, to models.Status, reason string) (models.StatusChange, error) {
+	if err := s.authorizer.Authorize(ctx, ActionChangeStatus, customerID); err != nil {
+		return models.StatusChange{}, err
+	}
+	change := models.StatusChange{ID: uuid.New(), CustomerID: customerID, To: to, Reason: reason}
+	change.Normalize()
+	if err := change.Validate(); err != nil {
+		return models.StatusChange{}, &ValidationError{err}
+	}
+
+	var customer *models.Customer
+	err := s.uow.Do(ctx, func(repos repository.Repositories) error {
+		var err error
+		if customer, err = repos.Customers.GetCustomerByID(ctx, customerID); err != nil {
+			return err
+		}
+		if customer.Status == change.To || !s.transitions.Allows(customer.Status, change.To) {
+			return &TransitionError{From: customer.Status, To: change.To, Allowed: s.transitions[customer.Status]}
+		}
+
+		change.From = customer.Status
+		change.ChangedAt, change.ChangedBy = now(), actor.FromContext(ctx)
+		return repos.Statuses.ChangeStatus(ctx, change)
+	})
+	if err != nil {
+		return models.StatusChange{}, err
+	}
+
+	customer.Status = change.To
+	customer.UpdatedAt, customer.UpdatedBy = change.ChangedAt, change.ChangedBy
+	event := events.New(events.CustomerStatusChanged, customerID, customer)
+	event.StatusChange = &change
+	s.publisher.Publish(ctx, event)
+	return change, nil
+}
+
+// StatusHistory returns the status changes of the customer, oldest first. It fails with
+// sql.ErrNoRows when the customer does not exist.
+func (s *CustomerService) StatusHistory(ctx context.Context, customerID uuid.UUID) ([]models.StatusChange, error) {
+	repos := s.uow.Repositories()
+	if _, err := repos.Customers.GetCustomerByID(ctx, customerID); err != nil {
+		return nil, err
+	}
+	return repos.Statuses.ListStatusChanges(ctx, customerID)
+}
+
+// AuditTrail returns the audit entries of the customer, oldest first. The trail of a deleted
+// customer is still available.
+func (s *CustomerService) AuditTrail(ctx context.Context, customerID uuid.UUID) ([]models.AuditEntry, error) {
+	repos := s.uow.Repositories()
+	if repos.Audit == nil {
+		return nil, nil
+	}
+	return repos.Audit.ListAuditEntries(ctx, customerID)
+}
+
+func (s *CustomerService) audit(ctx context.Context, repos repository.Repositories, customerID uuid.UUID,
+	action models.AuditAction, changes map[string]models.FieldChange) error {
+	if repos.Audit == nil {
+		return nil
+	}
+	return repos.Audit.RecordAudit(ctx, models.AuditEntry{
+		ID:         uuid.New(),
+		CustomerID: customerID,
+		Action:     action,
+		Changes:    changes,
+		Actor:      actor.FromContext(ctx),
+		OccurredAt: now(),
+	})
+}
+
+// validateAttributes checks attributes against their definitions. Without an attribute
+// repository custom attributes are not validated.
+func validateAttributes(ctx context.Context, repos repository.Repositories, attributes map[string]any) error {
+	if repos.Attributes == nil {
+		return nil
+	}
+	defs, err := repos.Attributes.ListAttributeDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading attribute definitions: %w", err)
+	}
+	return models.ValidateAttributes(defs, attributes)
+}
+
+// now matches the microsecond precision the timestamps are stored with.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
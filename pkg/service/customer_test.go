@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (*CustomerService, *memory.Store, <-chan events.Event) {
+	t.Helper()
+	store := memory.NewStore()
+	bus := events.NewBus()
+	received, unsubscribe := bus.Subscribe(10)
+	t.Cleanup(unsubscribe)
+	return NewCustomerService(store, bus), store, received
+}
+
+func createCustomer(t *testing.T, svc *CustomerService, email string) models.Customer {
+	t.Helper()
+	c := models.Customer{FirstName: "Jane", LastName: "Doe", Email: email}
+	require.NoError(t, svc.CreateCustomer(context.Background(), &c))
+	return c
+}
+
+func TestCreateCustomer(t *testing.T) {
+	svc, store, received := newTestService(t)
+	ctx := actor.NewContext(context.Background(), "crm-import")
+
+	c := models.Customer{ID: uuid.New(), FirstName: " Jane ", LastName: "Doe", Email: "jane@example.com ", Status: models.StatusActive}
+	chosen := c.ID
+	require.NoError(t, svc.CreateCustomer(ctx, &c))
+
+	assert.NotEqual(t, chosen, c.ID, "the service generates IDs")
+	assert.Equal(t, "Jane", c.FirstName)
+	assert.Equal(t, "jane@example.com", c.Email)
+	assert.Equal(t, models.InitialStatus, c.Status)
+	assert.Equal(t, "crm-import", c.CreatedBy)
+
+	event := <-received
+	assert.Equal(t, events.CustomerCreated, event.Type)
+	assert.Equal(t, c.ID, event.CustomerID)
+
+	entries, err := store.Repositories().Audit.ListAuditEntries(ctx, c.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.AuditCreated, entries[0].Action)
+	assert.Equal(t, "crm-import", entries[0].Actor)
+}
+
+func TestCreateCustomer_Invalid(t *testing.T) {
+	svc, store, _ := newTestService(t)
+	ctx := context.Background()
+
+	var validationErr *ValidationError
+	err := svc.CreateCustomer(ctx, &models.Customer{FirstName: "Jane", Email: "jane@example.com"})
+	require.ErrorAs(t, err, &validationErr)
+	assert.EqualError(t, err, "first name, last name, and email are required")
+
+	err = svc.CreateCustomer(ctx, &models.Customer{FirstName: "Jane", LastName: "Doe", Email: "Jane <jane@example.com>"})
+	assert.EqualError(t, err, "email must be a valid email address")
+
+	require.NoError(t, store.Repositories().Attributes.SaveAttributeDefinition(ctx, models.AttributeDefinition{
+		Name: "loyalty_tier", Type: models.AttributeString, Enum: []any{"gold"},
+	}))
+	err = svc.CreateCustomer(ctx, &models.Customer{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com",
+		Attributes: map[string]any{"loyalty_tier": "tin"}})
+	var attrErr *models.AttributeError
+	assert.ErrorAs(t, err, &attrErr)
+
+	all, err := svc.GetAllCustomers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestCreateCustomer_Duplicate(t *testing.T) {
+	svc, store, _ := newTestService(t)
+	first := createCustomer(t, svc, "jane@example.com")
+
+	c := models.Customer{FirstName: "Other", LastName: "Jane", Email: "jane@example.com"}
+	assert.ErrorIs(t, svc.CreateCustomer(context.Background(), &c), repository.ErrDuplicate)
+
+	entries, err := store.Repositories().Audit.ListAuditEntries(context.Background(), c.ID)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	entries, err = svc.AuditTrail(context.Background(), first.ID)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestUpdateCustomer(t *testing.T) {
+	svc, _, received := newTestService(t)
+	c := createCustomer(t, svc, "jane@example.com")
+	<-received
+
+	update := models.Customer{ID: c.ID, FirstName: "Janet", LastName: "Doe", Email: "jane@example.com", Status: models.StatusClosed}
+	require.NoError(t, svc.UpdateCustomer(actor.NewContext(context.Background(), "support"), &update))
+
+	assert.Equal(t, "Janet", update.FirstName)
+	assert.Equal(t, models.InitialStatus, update.Status, "updates keep the status")
+	assert.Equal(t, events.CustomerUpdated, (<-received).Type)
+
+	entries, err := svc.AuditTrail(context.Background(), c.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, models.AuditUpdated, entries[1].Action)
+	assert.Equal(t, "support", entries[1].Actor)
+	assert.Equal(t, map[string]models.FieldChange{"first_name": {From: "Jane", To: "Janet"}}, entries[1].Changes)
+
+	// An update changing nothing leaves no audit entry.
+	require.NoError(t, svc.UpdateCustomer(context.Background(), &update))
+	entries, err = svc.AuditTrail(context.Background(), c.ID)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestUpdateCustomer_NotFound(t *testing.T) {
+	svc, _, _ := newTestService(t)
+
+	err := svc.UpdateCustomer(context.Background(), &models.Customer{ID: uuid.New(), FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestDeleteCustomer(t *testing.T) {
+	svc, _, received := newTestService(t)
+	c := createCustomer(t, svc, "jane@example.com")
+	<-received
+
+	require.NoError(t, svc.DeleteCustomer(context.Background(), c.ID))
+	assert.Equal(t, events.CustomerDeleted, (<-received).Type)
+	assert.ErrorIs(t, svc.DeleteCustomer(context.Background(), c.ID), sql.ErrNoRows)
+
+	// The audit trail outlives the customer.
+	entries, err := svc.AuditTrail(context.Background(), c.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, models.AuditDeleted, entries[1].Action)
+}
+
+func TestAuthorizer(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	c := createCustomer(t, svc, "jane@example.com")
+
+	svc.SetAuthorizer(AuthorizerFunc(func(ctx context.Context, action Action, customerID uuid.UUID) error {
+		if action == ActionDelete && actor.FromContext(ctx) != "admin" {
+			return fmt.Errorf("%s may not delete customers: %w", actor.FromContext(ctx), ErrForbidden)
+		}
+		return nil
+	}))
+
+	err := svc.DeleteCustomer(actor.NewContext(context.Background(), "intern"), c.ID)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = svc.GetCustomerByID(context.Background(), c.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, svc.DeleteCustomer(actor.NewContext(context.Background(), "admin"), c.ID))
+}
+
+// failingAudit makes every audit write fail to show that the customer write is rolled back with it.
+type failingAudit struct {
+	repository.AuditRepository
+}
+
+func (failingAudit) RecordAudit(context.Context, models.AuditEntry) error {
+	return errors.New("audit storage unavailable")
+}
+
+type failingAuditStore struct {
+	*memory.Store
+}
+
+func (s failingAuditStore) Do(ctx context.Context, fn func(repository.Repositories) error) error {
+	return s.Store.Do(ctx, func(repos repository.Repositories) error {
+		repos.Audit = failingAudit{repos.Audit}
+		return fn(repos)
+	})
+}
+
+func TestUnitOfWork_RollsBack(t *testing.T) {
+	store := memory.NewStore()
+	svc := NewCustomerService(failingAuditStore{store}, events.NewBus())
+
+	c := models.Customer{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}
+	assert.EqualError(t, svc.CreateCustomer(context.Background(), &c), "audit storage unavailable")
+
+	_, err := svc.GetCustomerByID(context.Background(), c.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
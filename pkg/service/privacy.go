@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrAlreadyErased is returned when erasing a customer that was erased before.
+var ErrAlreadyErased = errors.New("customer is already erased")
+
+// ErrErased is returned when writing personal data to a customer that was erased, which would undo
+// the erasure.
+var ErrErased = errors.New("customer was erased")
+
+// errNoPrivacy is returned by the legal hold and erasure methods of a unit of work without a
+// privacy repository.
+var errNoPrivacy = errors.New("legal holds and erasure need a privacy repository")
+
+// LegalHoldError is returned when active legal holds block the erasure or deletion of a customer.
+type LegalHoldError struct {
+	Holds []models.LegalHold
+}
+
+func (e *LegalHoldError) Error() string {
+	reasons := make([]string, len(e.Holds))
+	for i, hold := range e.Holds {
+		reasons[i] = hold.Reason
+	}
+	return "customer is under legal hold: " + strings.Join(reasons, "; ")
+}
+
+// checkLegalHolds fails with a *LegalHoldError when the customer has active legal holds.
+func checkLegalHolds(ctx context.Context, repos repository.Repositories, customerID uuid.UUID) error {
+	if repos.Privacy == nil {
+		return nil
+	}
+	holds, err := repos.Privacy.ListLegalHolds(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	var active []models.LegalHold
+	for _, hold := range holds {
+		if hold.Active() {
+			active = append(active, hold)
+		}
+	}
+	if len(active) > 0 {
+		return &LegalHoldError{Holds: active}
+	}
+	return nil
+}
+
+// checkNotErased fails with ErrErased when the customer was erased.
+func checkNotErased(ctx context.Context, repos repository.Repositories, customerID uuid.UUID) error {
+	if repos.Privacy == nil {
+		return nil
+	}
+	_, err := repos.Privacy.GetErasure(ctx, customerID)
+	if err == nil {
+		return ErrErased
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// CheckNotErased fails with ErrErased when the customer was erased. It guards the writes of personal
+// data that bypass the service, such as those of addresses and contacts.
+func (s *CustomerService) CheckNotErased(ctx context.Context, customerID uuid.UUID) error {
+	return checkNotErased(ctx, s.uow.Repositories(), customerID)
+}
+
+// LegalHolds returns the legal holds of the customer, released ones included, oldest first. It
+// fails with sql.ErrNoRows when the customer does not exist.
+func (s *CustomerService) LegalHolds(ctx context.Context, customerID uuid.UUID) ([]models.LegalHold, error) {
+	repos := s.uow.Repositories()
+	if repos.Privacy == nil {
+		return nil, errNoPrivacy
+	}
+	if _, err := repos.Customers.GetCustomerByID(ctx, customerID); err != nil {
+		return nil, err
+	}
+	return repos.Privacy.ListLegalHolds(ctx, customerID)
+}
+
+// PlaceLegalHold places a hold with a fresh ID on the customer of hold, which blocks its erasure
+// and deletion until released. It fails with sql.ErrNoRows when the customer does not exist.
+func (s *CustomerService) PlaceLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	if err := s.authorizer.Authorize(ctx, ActionLegalHold, hold.CustomerID); err != nil {
+		return err
+	}
+	hold.Normalize()
+	if err := hold.Validate(); err != nil {
+		return &ValidationError{err}
+	}
+
+	hold.ID, hold.ReleasedAt, hold.ReleasedBy = uuid.New(), nil, ""
+	return s.uow.Do(ctx, func(repos repository.Repositories) error {
+		if repos.Privacy == nil {
+			return errNoPrivacy
+		}
+		if _, err := repos.Customers.GetCustomerByID(ctx, hold.CustomerID); err != nil {
+			return err
+		}
+		return repos.Privacy.PlaceLegalHold(ctx, hold)
+	})
+}
+
+// ReleaseLegalHold ends a hold of the customer. It fails with sql.ErrNoRows when the hold does not
+// exist or was already released.
+func (s *CustomerService) ReleaseLegalHold(ctx context.Context, customerID, holdID uuid.UUID) (*models.LegalHold, error) {
+	if err := s.authorizer.Authorize(ctx, ActionLegalHold, customerID); err != nil {
+		return nil, err
+	}
+	repos := s.uow.Repositories()
+	if repos.Privacy == nil {
+		return nil, errNoPrivacy
+	}
+	return repos.Privacy.ReleaseLegalHold(ctx, customerID, holdID)
+}
+
+// Erasure returns the erasure of the customer, sql.ErrNoRows when it was not erased.
+func (s *CustomerService) Erasure(ctx context.Context, customerID uuid.UUID) (*models.Erasure, error) {
+	repos := s.uow.Repositories()
+	if repos.Privacy == nil {
+		return nil, errNoPrivacy
+	}
+	return repos.Privacy.GetErasure(ctx, customerID)
+}
+
+// Erase anonymizes the personal data of the customer in place, see
+// repository.PrivacyRepository.EraseCustomer, and records the erasure in the audit trail. The
+// contents of its attachments are removed from the blob store and a CustomerErased event tells
+// downstream systems to erase their copies. It fails with sql.ErrNoRows when the customer does not
+// exist, a *LegalHoldError when it is under legal hold and ErrAlreadyErased when it was erased before.
+func (s *CustomerService) Erase(ctx context.Context, erasure *models.Erasure) error {
+	if err := s.authorizer.Authorize(ctx, ActionErase, erasure.CustomerID); err != nil {
+		return err
+	}
+	erasure.Normalize()
+	if err := erasure.Validate(); err != nil {
+		return &ValidationError{err}
+	}
+
+	var customer *models.Customer
+	var attachmentKeys []string
+	err := s.uow.Do(ctx, func(repos repository.Repositories) error {
+		if repos.Privacy == nil {
+			return errNoPrivacy
+		}
+		if _, err := repos.Customers.GetCustomerByID(ctx, erasure.CustomerID); err != nil {
+			return err
+		}
+		if err := checkNotErased(ctx, repos, erasure.CustomerID); errors.Is(err, ErrErased) {
+			return ErrAlreadyErased
+		} else if err != nil {
+			return err
+		}
+		if err := checkLegalHolds(ctx, repos, erasure.CustomerID); err != nil {
+			return err
+		}
+
+		var err error
+		if attachmentKeys, err = repos.Privacy.EraseCustomer(ctx, erasure); err != nil {
+			return err
+		}
+		if err := s.audit(ctx, repos, erasure.CustomerID, models.AuditErased, nil); err != nil {
+			return err
+		}
+		customer, err = repos.Customers.GetCustomerByID(ctx, erasure.CustomerID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.removeBlobs(ctx, attachmentKeys)
+	s.publisher.Publish(ctx, events.New(events.CustomerErased, erasure.CustomerID, customer))
+	return nil
+}
+
+// removeBlobs deletes the contents of erased attachments. The erasure is committed at this point, a
+// failure is logged for the leftover content to be removed by hand.
+func (s *CustomerService) removeBlobs(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	if s.blobs == nil {
+		log.Warnf("no blob store configured, the contents of %d erased attachments are left in place", len(keys))
+		return
+	}
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Errorf("failed to remove the content of an erased attachment under %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/mocks"
+	"CustomerCRUD/pkg/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErase(t *testing.T) {
+	svc, store, received := newTestService(t)
+	ctx := actor.NewContext(context.Background(), "dpo@example.com")
+	c := createCustomer(t, svc, "jane@example.com")
+	c.FirstName = "Janet"
+	require.NoError(t, svc.UpdateCustomer(ctx, &c))
+	_, err := svc.ChangeStatus(ctx, c.ID, models.StatusProspect, "Jane asked for a quote")
+	require.NoError(t, err)
+	drain(received)
+
+	erasure := models.Erasure{CustomerID: c.ID, Reference: " TICKET-42 "}
+	require.NoError(t, svc.Erase(ctx, &erasure))
+	assert.Equal(t, "TICKET-42", erasure.Reference)
+	assert.Equal(t, "dpo@example.com", erasure.ErasedBy)
+
+	erased, err := svc.GetCustomerByID(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Erased", erased.FirstName)
+	assert.Equal(t, "erased-"+c.ID.String()+"@erased.invalid", erased.Email)
+	assert.Equal(t, models.StatusProspect, erased.Status, "the status is not personal data")
+
+	history, err := svc.StatusHistory(ctx, c.ID)
+	require.NoError(t, err)
+	for _, change := range history {
+		assert.Equal(t, models.Erased, change.Reason)
+	}
+
+	entries, err := store.Repositories().Audit.ListAuditEntries(ctx, c.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, models.FieldChange{From: models.Erased, To: models.Erased}, entries[1].Changes["first_name"])
+	assert.Equal(t, models.AuditErased, entries[2].Action)
+	assert.Equal(t, "dpo@example.com", entries[2].Actor)
+	assert.Empty(t, entries[2].Changes)
+
+	event := <-received
+	assert.Equal(t, events.CustomerErased, event.Type)
+	assert.Equal(t, "Erased", event.Customer.FirstName)
+
+	assert.ErrorIs(t, svc.Erase(ctx, &models.Erasure{CustomerID: c.ID}), ErrAlreadyErased)
+	assert.ErrorIs(t, svc.Erase(ctx, &models.Erasure{CustomerID: uuid.New()}), sql.ErrNoRows)
+}
+
+func TestUpdateCustomer_Erased(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	ctx := context.Background()
+	c := createCustomer(t, svc, "jane@example.com")
+	require.NoError(t, svc.Erase(ctx, &models.Erasure{CustomerID: c.ID}))
+
+	c.Email = "jane@example.com"
+	assert.ErrorIs(t, svc.UpdateCustomer(ctx, &c), ErrErased)
+	assert.ErrorIs(t, svc.CheckNotErased(ctx, c.ID), ErrErased)
+
+	erased, err := svc.GetCustomerByID(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "erased-"+c.ID.String()+"@erased.invalid", erased.Email)
+
+	other := createCustomer(t, svc, "john@example.com")
+	assert.NoError(t, svc.CheckNotErased(ctx, other.ID))
+}
+
+func TestErase_InvalidReference(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	c := createCustomer(t, svc, "jane@example.com")
+
+	err := svc.Erase(context.Background(), &models.Erasure{CustomerID: c.ID, Reference: strings.Repeat("x", 201)})
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestLegalHold(t *testing.T) {
+	svc, _, received := newTestService(t)
+	ctx := actor.NewContext(context.Background(), "legal@example.com")
+	c := createCustomer(t, svc, "jane@example.com")
+	drain(received)
+
+	hold := models.LegalHold{CustomerID: c.ID, Reason: " Pending litigation "}
+	require.NoError(t, svc.PlaceLegalHold(ctx, &hold))
+	assert.Equal(t, "Pending litigation", hold.Reason)
+	assert.Equal(t, "legal@example.com", hold.PlacedBy)
+
+	err := svc.Erase(ctx, &models.Erasure{CustomerID: c.ID})
+	var holdErr *LegalHoldError
+	require.ErrorAs(t, err, &holdErr)
+	assert.EqualError(t, err, "customer is under legal hold: Pending litigation")
+	assert.ErrorAs(t, svc.DeleteCustomer(ctx, c.ID), &holdErr)
+	assert.Empty(t, received, "nothing changed")
+
+	released, err := svc.ReleaseLegalHold(ctx, c.ID, hold.ID)
+	require.NoError(t, err)
+	assert.False(t, released.Active())
+	assert.Equal(t, "legal@example.com", released.ReleasedBy)
+	_, err = svc.ReleaseLegalHold(ctx, c.ID, hold.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "a hold is released once")
+
+	holds, err := svc.LegalHolds(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Len(t, holds, 1, "released holds are kept")
+	assert.NoError(t, svc.Erase(ctx, &models.Erasure{CustomerID: c.ID}))
+}
+
+func TestPlaceLegalHold_Invalid(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	c := createCustomer(t, svc, "jane@example.com")
+
+	err := svc.PlaceLegalHold(context.Background(), &models.LegalHold{CustomerID: c.ID, Reason: "  "})
+	assert.EqualError(t, err, "reason is required")
+	assert.ErrorIs(t, svc.PlaceLegalHold(context.Background(), &models.LegalHold{CustomerID: uuid.New(), Reason: "Audit"}), sql.ErrNoRows)
+}
+
+func TestErase_RemovesAttachmentContents(t *testing.T) {
+	ctx := context.Background()
+	customerID := uuid.New()
+	customers := &mocks.CustomerRepository{}
+	privacy := &mocks.PrivacyRepository{}
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, blobs.Put(ctx, "customers/1/attachments/2", strings.NewReader("scan"), 4, "image/png"))
+
+	customers.On("GetCustomerByID", mock.Anything, customerID).Return(&models.Customer{ID: customerID}, nil)
+	privacy.On("GetErasure", mock.Anything, customerID).Return(nil, sql.ErrNoRows)
+	privacy.On("ListLegalHolds", mock.Anything, customerID).Return(nil, nil)
+	privacy.On("EraseCustomer", mock.Anything, mock.Anything).Return([]string{"customers/1/attachments/2"}, nil)
+
+	svc := NewCustomerService(repository.Untransacted(repository.Repositories{Customers: customers, Privacy: privacy}), events.NewBus())
+	svc.SetBlobStore(blobs)
+	require.NoError(t, svc.Erase(ctx, &models.Erasure{CustomerID: customerID}))
+
+	_, err = blobs.Open(ctx, "customers/1/attachments/2")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func drain(received <-chan events.Event) {
+	for len(received) > 0 {
+		<-received
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"CustomerCRUD/pkg/models"
+)
+
+// Transitions maps every status to the statuses a customer may move to from it.
+type Transitions map[models.Status][]models.Status
+
+// DefaultTransitions returns the lifecycle used unless the configuration overrides it.
+func DefaultTransitions() Transitions {
+	return Transitions{
+		models.StatusLead:      {models.StatusProspect, models.StatusClosed},
+		models.StatusProspect:  {models.StatusActive, models.StatusLead, models.StatusClosed},
+		models.StatusActive:    {models.StatusSuspended, models.StatusClosed},
+		models.StatusSuspended: {models.StatusActive, models.StatusClosed},
+		models.StatusClosed:    {},
+	}
+}
+
+// NewTransitions builds the lifecycle from the default one, replacing the transitions of every
+// status present in overrides. It fails on unknown statuses.
+func NewTransitions(overrides map[string][]string) (Transitions, error) {
+	t := DefaultTransitions()
+	for from, targets := range overrides {
+		status := models.Status(strings.ToLower(from))
+		if !status.Valid() {
+			return nil, fmt.Errorf("unknown status %q in lifecycle transitions", from)
+		}
+		allowed := make([]models.Status, 0, len(targets))
+		for _, target := range targets {
+			to := models.Status(strings.ToLower(target))
+			if !to.Valid() {
+				return nil, fmt.Errorf("unknown status %q in the transitions from %s", target, status)
+			}
+			allowed = append(allowed, to)
+		}
+		t[status] = allowed
+	}
+	return t, nil
+}
+
+// Allows reports whether a customer may move from one status to the other.
+func (t Transitions) Allows(from, to models.Status) bool {
+	for _, allowed := range t[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when the lifecycle does not allow a status change.
+type TransitionError struct {
+	From    models.Status
+	To      models.Status
+	Allowed []models.Status
+}
+
+func (e *TransitionError) Error() string {
+	if e.From == e.To {
+		return fmt.Sprintf("customer is already %s", e.From)
+	}
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("cannot change status from %s to %s, %s is final", e.From, e.To, e.From)
+	}
+	allowed := make([]string, len(e.Allowed))
+	for i, status := range e.Allowed {
+		allowed[i] = string(status)
+	}
+	return fmt.Sprintf("cannot change status from %s to %s, allowed: %s", e.From, e.To, strings.Join(allowed, ", "))
+}
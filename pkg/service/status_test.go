@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"CustomerCRUD/pkg/actor"
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransitions(t *testing.T) {
+	transitions, err := NewTransitions(map[string][]string{"Closed": {"active"}})
+	require.NoError(t, err)
+	assert.True(t, transitions.Allows(models.StatusClosed, models.StatusActive))
+	assert.True(t, transitions.Allows(models.StatusLead, models.StatusProspect))
+	assert.False(t, transitions.Allows(models.StatusLead, models.StatusActive))
+
+	_, err = NewTransitions(map[string][]string{"archived": {"active"}})
+	assert.Error(t, err)
+	_, err = NewTransitions(map[string][]string{"active": {"archived"}})
+	assert.Error(t, err)
+}
+
+func TestChangeStatus(t *testing.T) {
+	svc, _, received := newTestService(t)
+	c := createCustomer(t, svc, "jane@example.com")
+	<-received
+
+	ctx := actor.NewContext(context.Background(), "sales")
+	change, err := svc.ChangeStatus(ctx, c.ID, " Prospect", " Requested a demo ")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusLead, change.From)
+	assert.Equal(t, models.StatusProspect, change.To)
+	assert.Equal(t, "Requested a demo", change.Reason)
+	assert.Equal(t, "sales", change.ChangedBy)
+
+	event := <-received
+	assert.Equal(t, events.CustomerStatusChanged, event.Type)
+	assert.Equal(t, models.StatusProspect, event.Customer.Status)
+	assert.Equal(t, &change, event.StatusChange)
+
+	stored, err := svc.GetCustomerByID(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusProspect, stored.Status)
+	assert.Equal(t, "sales", stored.UpdatedBy)
+
+	history, err := svc.StatusHistory(ctx, c.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "Customer created", history[0].Reason)
+	assert.Equal(t, change, history[1])
+}
+
+func TestChangeStatus_Errors(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	c := createCustomer(t, svc, "jane@example.com")
+	ctx := context.Background()
+
+	_, err := svc.ChangeStatus(ctx, c.ID, models.StatusActive, "Skipping ahead")
+	var transitionErr *TransitionError
+	require.ErrorAs(t, err, &transitionErr)
+	assert.EqualError(t, err, "cannot change status from lead to active, allowed: prospect, closed")
+
+	_, err = svc.ChangeStatus(ctx, c.ID, models.StatusLead, "Again")
+	assert.EqualError(t, err, "customer is already lead")
+
+	_, err = svc.ChangeStatus(ctx, c.ID, "archived", "Old")
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	_, err = svc.ChangeStatus(ctx, uuid.New(), models.StatusProspect, "Unknown")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = svc.ChangeStatus(ctx, c.ID, models.StatusClosed, "Not interested")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, c.ID, models.StatusActive, "Reopening")
+	assert.EqualError(t, err, "cannot change status from closed to active, closed is final")
+
+	transitions, err := NewTransitions(map[string][]string{"closed": {"active"}})
+	require.NoError(t, err)
+	svc.SetTransitions(transitions)
+	_, err = svc.ChangeStatus(ctx, c.ID, models.StatusActive, "Reopening")
+	assert.NoError(t, err)
+}
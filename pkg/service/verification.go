@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/verification"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrVerificationDisabled is returned by the email verification methods of a service without a verifier.
+	ErrVerificationDisabled = errors.New("email verification is disabled")
+	// ErrEmailAlreadyVerified is returned when resending the verification email of a verified address.
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	// ErrEmailChanged is returned for a valid verification token sent to an address the customer
+	// no longer has.
+	ErrEmailChanged = errors.New("email changed since the verification email was sent")
+)
+
+// SetEmailVerifier enables email verification: new customers and customers whose email changes
+// are sent a verification link by verifier.
+func (s *CustomerService) SetEmailVerifier(verifier *verification.Verifier) {
+	s.verifier = verifier
+}
+
+// sendVerification sends the verification email of a written customer. The write succeeded, so
+// failures are only logged and the customer can ask for the email again.
+func (s *CustomerService) sendVerification(ctx context.Context, customer models.Customer) {
+	if s.verifier == nil || customer.EmailVerified {
+		return
+	}
+	var limited *verification.RateLimitError
+	if err := s.verifier.Send(ctx, customer); errors.As(err, &limited) {
+		log.Warnf("verification email of customer %s not sent: %v", customer.ID, err)
+	} else if err != nil {
+		log.Errorf("error sending verification email of customer %s: %v", customer.ID, err)
+	}
+}
+
+// ResendVerification sends the verification email of the customer again. It fails with
+// sql.ErrNoRows when the customer does not exist, ErrEmailAlreadyVerified when its email is
+// verified and a *verification.RateLimitError when it was sent too often.
+func (s *CustomerService) ResendVerification(ctx context.Context, customerID uuid.UUID) error {
+	if s.verifier == nil {
+		return ErrVerificationDisabled
+	}
+	if err := s.authorizer.Authorize(ctx, ActionUpdate, customerID); err != nil {
+		return err
+	}
+	customer, err := s.GetCustomerByID(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	if customer.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	return s.verifier.Send(ctx, *customer)
+}
+
+// VerifyEmail marks the email a verification token was sent to as verified and returns the
+// customer. It fails with verification.ErrInvalidToken or verification.ErrExpiredToken for bad
+// tokens, sql.ErrNoRows when the customer no longer exists and ErrEmailChanged when its email
+// changed since. Verifying twice succeeds.
+func (s *CustomerService) VerifyEmail(ctx context.Context, token string) (*models.Customer, error) {
+	if s.verifier == nil {
+		return nil, ErrVerificationDisabled
+	}
+	claims, err := s.verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var customer *models.Customer
+	verified := false
+	err = s.uow.Do(ctx, func(repos repository.Repositories) error {
+		var err error
+		if customer, err = repos.Customers.GetCustomerByID(ctx, claims.CustomerID); err != nil {
+			return err
+		}
+		if !claims.For(customer.Email) {
+			return ErrEmailChanged
+		}
+		if customer.EmailVerified {
+			return nil
+		}
+		before := *customer
+		if err := repos.Customers.MarkEmailVerified(ctx, customer.ID, customer.Email); errors.Is(err, sql.ErrNoRows) {
+			return ErrEmailChanged
+		} else if err != nil {
+			return err
+		}
+		if customer, err = repos.Customers.GetCustomerByID(ctx, claims.CustomerID); err != nil {
+			return err
+		}
+		verified = true
+		return s.audit(ctx, repos, customer.ID, models.AuditUpdated, models.CustomerChanges(before, *customer))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return customer, nil
+	}
+
+	snapshot := *customer
+	s.publisher.Publish(ctx, events.New(events.CustomerUpdated, customer.ID, &snapshot))
+	return customer, nil
+}
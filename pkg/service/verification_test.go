@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/mail"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/verification"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerifyingService(t *testing.T, limits verification.Limits) (*CustomerService, *mail.Outbox, <-chan events.Event) {
+	t.Helper()
+	svc, _, received := newTestService(t)
+	outbox := mail.NewOutbox()
+	svc.SetEmailVerifier(verification.NewVerifier(verification.Config{
+		Secret:  "a secret of at least thirty-two characters",
+		TTL:     time.Hour,
+		LinkURL: "https://app.example.com/verify-email",
+		Limits:  limits,
+	}, outbox))
+	return svc, outbox, received
+}
+
+// lastToken returns the token of the last verification email sent to email.
+func lastToken(t *testing.T, outbox *mail.Outbox, email string) string {
+	t.Helper()
+	messages := outbox.Messages()
+	require.NotEmpty(t, messages)
+	msg := messages[len(messages)-1]
+	require.Equal(t, email, msg.To)
+	start := strings.Index(msg.Body, "https://")
+	require.NotEqual(t, -1, start)
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestVerifyEmail(t *testing.T) {
+	svc, outbox, received := newVerifyingService(t, verification.Limits{})
+	ctx := context.Background()
+
+	c := models.Customer{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", EmailVerified: true}
+	require.NoError(t, svc.CreateCustomer(ctx, &c))
+	assert.False(t, c.EmailVerified, "clients cannot create verified customers")
+	<-received
+
+	verified, err := svc.VerifyEmail(ctx, lastToken(t, outbox, "jane@example.com"))
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+	event := <-received
+	assert.Equal(t, events.CustomerUpdated, event.Type)
+
+	entries, err := svc.AuditTrail(ctx, c.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]models.FieldChange{"email_verified": {From: false, To: true}}, entries[1].Changes)
+
+	// Following the link again is harmless.
+	_, err = svc.VerifyEmail(ctx, lastToken(t, outbox, "jane@example.com"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, svc.ResendVerification(ctx, c.ID), ErrEmailAlreadyVerified)
+}
+
+func TestVerifyEmail_EmailChanged(t *testing.T) {
+	svc, outbox, _ := newVerifyingService(t, verification.Limits{})
+	ctx := context.Background()
+
+	c := createCustomer(t, svc, "jane@example.com")
+	oldToken := lastToken(t, outbox, "jane@example.com")
+	_, err := svc.VerifyEmail(ctx, oldToken)
+	require.NoError(t, err)
+
+	c.Email = "jane.doe@example.com"
+	require.NoError(t, svc.UpdateCustomer(ctx, &c))
+	assert.False(t, c.EmailVerified, "a changed email has to be verified again")
+	require.Len(t, outbox.Messages(), 2)
+
+	_, err = svc.VerifyEmail(ctx, oldToken)
+	assert.ErrorIs(t, err, ErrEmailChanged)
+
+	verified, err := svc.VerifyEmail(ctx, lastToken(t, outbox, "jane.doe@example.com"))
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+}
+
+func TestResendVerification(t *testing.T) {
+	svc, outbox, _ := newVerifyingService(t, verification.Limits{Interval: time.Minute})
+	ctx := context.Background()
+
+	c := createCustomer(t, svc, "jane@example.com")
+	var limited *verification.RateLimitError
+	require.ErrorAs(t, svc.ResendVerification(ctx, c.ID), &limited)
+	assert.Len(t, outbox.Messages(), 1)
+
+	// Renaming the customer sends nothing.
+	c.FirstName = "Janet"
+	require.NoError(t, svc.UpdateCustomer(ctx, &c))
+	assert.Len(t, outbox.Messages(), 1)
+}
+
+func TestVerifyEmail_Disabled(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	c := createCustomer(t, svc, "jane@example.com")
+
+	assert.ErrorIs(t, svc.ResendVerification(context.Background(), c.ID), ErrVerificationDisabled)
+	_, err := svc.VerifyEmail(context.Background(), "token")
+	assert.ErrorIs(t, err, ErrVerificationDisabled)
+}
@@ -0,0 +1,46 @@
+package signedtoken
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	codec := NewCodec("a secret of at least thirty-two characters")
+
+	token := codec.Encode("0f8fad5b-d9cb-469f-a165-70867728950e", "marketing", "")
+	assert.NotContains(t, token, "/")
+	assert.NotContains(t, token, "+")
+	assert.NotContains(t, token, "marketing")
+
+	fields, err := codec.Decode(token, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0f8fad5b-d9cb-469f-a165-70867728950e", "marketing", ""}, fields)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	codec := NewCodec("a secret of at least thirty-two characters")
+	token := codec.Encode("customer", "marketing")
+	payload, mac, _ := strings.Cut(token, ".")
+	other := NewCodec("another secret of at least thirty-two characters").Encode("other", "marketing")
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	for name, token := range map[string]string{
+		"empty":          "",
+		"no signature":   payload,
+		"other secret":   other,
+		"swapped":        otherPayload + "." + mac,
+		"bad encoding":   payload + ".!!",
+		"truncated":      token[:len(token)-4],
+		"trailing bytes": token + "AA",
+		"fields":         codec.Encode("customer", "marketing", "email"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := codec.Decode(token, 2)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps blobs as files below a directory.
+type LocalStore struct {
+	dir string
+}
+
+var _ BlobStore = (*LocalStore)(nil)
+
+// NewLocalStore returns a store writing below dir, which is created when missing.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating blob directory: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// path maps key to a file below the store directory, refusing keys that would escape it.
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || !fs.ValidPath(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file first, so that readers never see a partially written blob.
+func (s *LocalStore) Put(_ context.Context, key string, content io.Reader, size int64, _ string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("error creating blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("error creating blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing blob: %w", err)
+	}
+	if written != size {
+		return fmt.Errorf("error writing blob: got %d bytes, expected %d", written, size)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) Open(_ context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error deleting blob: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options configures an S3Store.
+type S3Options struct {
+	// Endpoint is the base URL of the service, such as https://s3.eu-central-1.amazonaws.com or
+	// the URL of a MinIO server.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// AccessKeyID and SecretAccessKey sign the requests with AWS Signature Version 4.
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket in the path rather than in the host name, which most
+	// S3-compatible services other than AWS need.
+	PathStyle bool
+	// Client sends the requests, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// S3Store keeps blobs as objects of a bucket of AWS S3 or an S3-compatible service. It speaks
+// the REST API directly, so it needs nothing but the standard library.
+type S3Store struct {
+	endpoint *url.URL
+	opts     S3Options
+	now      func() time.Time
+}
+
+var _ BlobStore = (*S3Store)(nil)
+
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", opts.Endpoint)
+	}
+	if opts.Bucket == "" || opts.Region == "" {
+		return nil, errors.New("S3 bucket and region are required")
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &S3Store{endpoint: endpoint, opts: opts, now: time.Now}, nil
+}
+
+// objectURL returns the URL of the object stored under key.
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/") + "/" + key
+	if s.opts.PathStyle {
+		path = strings.TrimSuffix(u.Path, "/") + "/" + s.opts.Bucket + "/" + key
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+	}
+	u.Path = path
+	u.RawPath = escapePath(path)
+	return &u
+}
+
+// do signs and sends a request for the object under key. Responses outside of 2xx are turned
+// into errors, ErrNotFound for a missing object.
+func (s *S3Store) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req)
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling S3: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, fmt.Errorf("S3 %s %s failed with %s: %s", method, key, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	// http.NoBody keeps an empty blob from being sent without a Content-Length.
+	if size == 0 {
+		content = http.NoBody
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, content, size, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return &s3Object{ctx: ctx, store: s, key: key, size: resp.ContentLength}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// s3Object reads an object with ranged GET requests. A request is only sent on the first read
+// after opening or seeking, so serving a range transfers just that range.
+type s3Object struct {
+	ctx    context.Context
+	store  *S3Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.body == nil {
+		if o.offset >= o.size {
+			return 0, io.EOF
+		}
+		header := http.Header{}
+		header.Set("Range", "bytes="+strconv.FormatInt(o.offset, 10)+"-")
+		resp, err := o.store.do(o.ctx, http.MethodGet, o.key, nil, 0, header)
+		if err != nil {
+			return 0, err
+		}
+		o.body = resp.Body
+	}
+
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before the start of the object")
+	}
+	if offset != o.offset {
+		o.Close()
+		o.offset = offset
+	}
+	return offset, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
+
+const (
+	amzDateFormat = "20060102T150405Z"
+	// unsignedPayload lets uploads be streamed instead of hashed up front, TLS protects them.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	emptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// sign adds an AWS Signature Version 4 Authorization header to req. The host, the range and
+// the x-amz-* headers are signed.
+func (s *S3Store) sign(req *http.Request) {
+	now := s.now().UTC()
+	date := now.Format(amzDateFormat)
+	payload := emptyPayload
+	if req.Body != nil && req.Body != http.NoBody {
+		payload = unsignedPayload
+	}
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payload,
+	}, "\n")
+
+	scope := date[:8] + "/" + s.opts.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretAccessKey), date[:8])
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.opts.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, awsEscape(name)+"="+awsEscape(v))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// escapePath escapes every segment of path the way Signature Version 4 expects.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsEscape percent-encodes everything but the unreserved characters of RFC 3986.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,23 @@
+// Package storage keeps the contents of customer attachments outside of the database.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when a blob does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores opaque blobs under keys chosen by the caller. Keys are slash separated paths
+// such as customers/<id>/attachments/<id>.
+type BlobStore interface {
+	// Put stores the size bytes read from content under key, replacing any blob stored there.
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Open returns the content of the blob. It is seekable so that ranges can be served without
+	// reading the whole blob.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the blob. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,118 @@
+package harness
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"CustomerCRUD/utils"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// Backend is a storage the service can run on.
+type Backend struct {
+	Name string
+	// open returns a fresh database for a test, nil for the in-memory backend.
+	open func(t testing.TB) *sql.DB
+}
+
+var (
+	SQLite = Backend{Name: "sqlite", open: openSQLite}
+	Memory = Backend{Name: "memory"}
+)
+
+// Backends returns the backends tests run against, Postgres only when it is configured.
+func Backends() []Backend {
+	backends := []Backend{SQLite, Memory}
+	if dsn := postgresURL(); dsn != "" {
+		backends = append(backends, Backend{Name: "postgres", open: openPostgres(dsn)})
+	}
+	return backends
+}
+
+// openSQLite creates the schema in a database file of its own for every test.
+func openSQLite(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := utils.OpenLocalDB(filepath.Join(t.TempDir(), "customers.db"))
+	if err != nil {
+		t.Fatalf("error opening SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// postgresURL is the embedded cluster started by Main, else TEST_DATABASE_URL.
+func postgresURL() string {
+	if embedded != nil {
+		return embedded.url
+	}
+	return os.Getenv("TEST_DATABASE_URL")
+}
+
+// openPostgres migrates a schema of its own for every test, dropped when the test ends, so
+// that tests neither see each other's data nor touch the tables of the database itself.
+func openPostgres(dsn string) func(t testing.TB) *sql.DB {
+	return func(t testing.TB) *sql.DB {
+		t.Helper()
+		admin, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatalf("error opening Postgres database: %v", err)
+		}
+		schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+			admin.Close()
+			t.Fatalf("error creating schema: %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+				t.Errorf("error dropping schema %s: %v", schema, err)
+			}
+			admin.Close()
+		})
+
+		schemaDSN, err := withSearchPath(dsn, schema)
+		if err != nil {
+			t.Fatalf("error parsing Postgres DSN: %v", err)
+		}
+		if err := utils.RunMigrationsFrom(schemaDSN, migrationsDir()); err != nil {
+			t.Fatalf("error running migrations: %v", err)
+		}
+		db, err := sql.Open("postgres", schemaDSN)
+		if err != nil {
+			t.Fatalf("error opening Postgres database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+}
+
+// withSearchPath makes the connections of dsn use schema, for URLs and key=value DSNs alike.
+func withSearchPath(dsn, schema string) (string, error) {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + schema, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// migrationsDir finds the migrations from the source of the harness, whatever the working
+// directory of the tests.
+func migrationsDir() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		panic("harness: cannot locate the migrations")
+	}
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "migrations")
+}
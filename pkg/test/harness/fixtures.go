@@ -0,0 +1,58 @@
+package harness
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+)
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+// CreateCustomer stores a customer with a unique name and email, changed by the options first.
+func (h *Harness) CreateCustomer(t testing.TB, options ...func(*models.Customer)) models.Customer {
+	t.Helper()
+	h.sequence++
+	customer := models.Customer{
+		FirstName: "Test",
+		LastName:  fmt.Sprintf("Customer%d", h.sequence),
+		Email:     fmt.Sprintf("customer%d@example.com", h.sequence),
+	}
+	for _, option := range options {
+		option(&customer)
+	}
+	if err := h.Customers.CreateCustomer(context.Background(), &customer); err != nil {
+		t.Fatalf("error creating customer %s: %v", customer.Email, err)
+	}
+	return customer
+}
+
+// LoadCustomers stores the customers of testdata/<name>.json, an object of customers by key, and
+// returns them by the same keys. They are stored in key order, so their creation times are too.
+func (h *Harness) LoadCustomers(t testing.TB, name string) map[string]models.Customer {
+	t.Helper()
+	data, err := fixtures.ReadFile("testdata/" + name + ".json")
+	if err != nil {
+		t.Fatalf("error reading fixture %s: %v", name, err)
+	}
+	var customers map[string]models.Customer
+	if err := json.Unmarshal(data, &customers); err != nil {
+		t.Fatalf("error decoding fixture %s: %v", name, err)
+	}
+
+	keys := make([]string, 0, len(customers))
+	for key := range customers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		customer := customers[key]
+		customers[key] = h.CreateCustomer(t, func(c *models.Customer) { *c = customer })
+	}
+	return customers
+}
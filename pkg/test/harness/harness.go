@@ -0,0 +1,148 @@
+// Package harness runs the service on an httptest.Server for integration tests. Every test gets a
+// server of its own on fresh, isolated storage, and Run repeats a test against every backend:
+// SQLite and in-memory storage always, and Postgres when TEST_DATABASE_URL is set or
+// TEST_POSTGRES=embedded starts a throwaway cluster, see Main.
+package harness
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"CustomerCRUD/pkg/events"
+	"CustomerCRUD/pkg/repository"
+	"CustomerCRUD/pkg/repository/memory"
+	"CustomerCRUD/pkg/server"
+	"CustomerCRUD/pkg/service"
+	"CustomerCRUD/pkg/storage"
+)
+
+// Harness is a running service and the storage behind it.
+type Harness struct {
+	// Backend names the storage, see Backends.
+	Backend string
+	// URL is the base URL of the server, Client talks to it.
+	URL    string
+	Client *http.Client
+	// Customers is the service behind the server, for factories and assertions that bypass HTTP.
+	Customers *service.CustomerService
+	// DB is the database of the SQL backends, nil for the in-memory one.
+	DB *sql.DB
+
+	// sequence makes the factory-made customers unique.
+	sequence int
+}
+
+// Run runs test as a subtest for every backend, each with a harness of its own.
+func Run(t *testing.T, test func(t *testing.T, h *Harness)) {
+	t.Helper()
+	for _, backend := range Backends() {
+		t.Run(backend.Name, func(t *testing.T) {
+			test(t, Start(t, backend))
+		})
+	}
+}
+
+// Start serves the service on backend until the test ends.
+func Start(t testing.TB, backend Backend) *Harness {
+	t.Helper()
+	h := &Harness{Backend: backend.Name}
+
+	var srv *server.Server
+	if backend.open == nil {
+		store := memory.NewStore()
+		h.Customers = service.NewCustomerService(store, events.NewBus())
+		srv = server.NewServer(h.Customers)
+		srv.SetAttributeRepository(store.Repositories().Attributes)
+	} else {
+		h.DB = backend.open(t)
+		h.Customers = service.NewCustomerService(repository.NewUnitOfWork(h.DB), events.NewBus())
+		srv = newSQLServer(t, h.DB, h.Customers)
+	}
+	srv.SetupRoutes()
+
+	ts := httptest.NewServer(srv.Router)
+	t.Cleanup(ts.Close)
+	h.URL, h.Client = ts.URL, ts.Client()
+	return h
+}
+
+// newSQLServer wires every repository of db into the server, like the service does.
+func newSQLServer(t testing.TB, db *sql.DB, customers *service.CustomerService) *server.Server {
+	t.Helper()
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("error creating attachment store: %v", err)
+	}
+	customers.SetBlobStore(blobs)
+
+	srv := server.NewServer(customers)
+	srv.SetAddressRepository(repository.NewAddressRepository(db))
+	srv.SetContactRepository(repository.NewContactRepository(db))
+	srv.SetAttributeRepository(repository.NewAttributeRepository(db))
+	srv.SetTagRepository(repository.NewTagRepository(db))
+	srv.SetSegmentRepository(repository.NewSegmentRepository(db))
+	srv.SetActivityRepository(repository.NewActivityRepository(db))
+	srv.SetConsentRepository(repository.NewConsentRepository(db))
+	srv.SetAttachmentStore(repository.NewAttachmentRepository(db), blobs, server.AttachmentPolicy{MaxSize: 1 << 20})
+	return srv
+}
+
+// RequireDatabase skips the test on the in-memory backend, which only stores customers, their
+// status changes, audit trail, attributes and privacy records.
+func (h *Harness) RequireDatabase(t testing.TB) {
+	t.Helper()
+	if h.DB == nil {
+		t.Skipf("the %s backend has no database", h.Backend)
+	}
+}
+
+// Response is a response of the server with its body read.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Do sends a request to the server, with body encoded as JSON unless it is nil.
+func (h *Harness) Do(t testing.TB, method, path string, body any) Response {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("error encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, h.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	read, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading response body: %v", err)
+	}
+	return Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: read}
+}
+
+// Decode decodes the JSON body of the response into v.
+func (r Response) Decode(t testing.TB, v any) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("error decoding response body %q: %v", r.Body, err)
+	}
+}
@@ -0,0 +1,106 @@
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// embedded is the Postgres cluster started by Main, if any.
+var embedded *cluster
+
+// Main runs the tests of a package from its TestMain. With TEST_POSTGRES=embedded it first starts
+// a throwaway Postgres cluster, which the postgres backend uses instead of TEST_DATABASE_URL. The
+// cluster needs the Postgres server binaries, found in TEST_POSTGRES_BIN, on the PATH or where
+// Debian installs them, and initdb refuses to run as root.
+func Main(m *testing.M) int {
+	if os.Getenv("TEST_POSTGRES") == "embedded" {
+		c, err := startCluster(os.Getenv("TEST_POSTGRES_BIN"))
+		if err != nil {
+			log.Printf("error starting embedded Postgres: %v", err)
+			return 1
+		}
+		defer c.stop()
+		embedded = c
+	}
+	return m.Run()
+}
+
+// cluster is a Postgres server running from a temporary data directory.
+type cluster struct {
+	dir   string
+	pgCtl string
+	url   string
+}
+
+func startCluster(binDir string) (*cluster, error) {
+	initdb, err := findBinary(binDir, "initdb")
+	if err != nil {
+		return nil, err
+	}
+	pgCtl, err := findBinary(binDir, "pg_ctl")
+	if err != nil {
+		return nil, err
+	}
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "postgres-*")
+	if err != nil {
+		return nil, err
+	}
+	c := &cluster{dir: dir, pgCtl: pgCtl, url: fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)}
+
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "--auth=trust", "--no-sync").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("initdb: %w: %s", err, out)
+	}
+	// Durability is of no use for a cluster deleted after the tests.
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off -c synchronous_commit=off", port, dir)
+	start := exec.Command(pgCtl, "-D", data, "-o", options, "-l", filepath.Join(dir, "postgres.log"), "-w", "start")
+	if out, err := start.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("pg_ctl start: %w: %s", err, out)
+	}
+	return c, nil
+}
+
+func (c *cluster) stop() {
+	if out, err := exec.Command(c.pgCtl, "-D", filepath.Join(c.dir, "data"), "-m", "immediate", "stop").CombinedOutput(); err != nil {
+		log.Printf("error stopping embedded Postgres: %v: %s", err, out)
+	}
+	os.RemoveAll(c.dir)
+}
+
+// findBinary looks for a Postgres binary in dir, else on the PATH, else in a version installed
+// under /usr/lib/postgresql.
+func findBinary(dir, name string) (string, error) {
+	if dir != "" {
+		return exec.LookPath(filepath.Join(dir, name))
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	matches, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql", "*", "bin", name))
+	if len(matches) == 0 {
+		return "", errors.New(name + " not found, set TEST_POSTGRES_BIN to the directory of the Postgres binaries")
+	}
+	return matches[len(matches)-1], nil
+}
+
+// freePort returns a port nothing listens on.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
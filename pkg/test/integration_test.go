@@ -1,150 +1,133 @@
-// server/integration_test.go
 package test
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 	"testing"
-	"time"
 
 	"CustomerCRUD/pkg/models"
-	"CustomerCRUD/pkg/repository"
-	"CustomerCRUD/pkg/server"
+	"CustomerCRUD/pkg/test/harness"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-var serverAddress = "localhost:8081"
-
 func TestMain(m *testing.M) {
-	// Set up the test database connection
-	testDBURL := os.Getenv("TEST_DATABASE_URL")
-	if testDBURL == "" {
-		log.Fatal("TEST_DATABASE_URL environment variable not set")
-	}
-
-	// Initialize the repository
-	db, err := repository.GetDB(false, "TEST_DATABASE_URL")
-	if err != nil {
-		log.Fatalf("Failed to connect to test database: %v", err)
-	}
-	defer db.Close()
-
-	// Run migrations
-	if err := runMigrations(testDBURL); err != nil {
-		log.Fatalf("Migration failed: %v", err)
-	}
-
-	repo := repository.NewCustomerRepository(db)
-
-	// Initialize the server
-	srv := server.NewServer(repo)
-	srv.SetupRoutes()
-
-	go func() {
-		if err := http.ListenAndServe(":8081", srv.Router); err != nil {
-			log.Fatalf("Failed to start HTTP server: %v", err)
-		}
-	}()
-
-	// Wait for the server to start
-	time.Sleep(1 * time.Second)
-
-	// Run tests
-	code := m.Run()
-
-	os.Exit(code)
+	os.Exit(harness.Main(m))
 }
 
-func runMigrations(dbURL string) error {
-	// Get the absolute path to the migrations directory
-	dir, err := filepath.Abs("../../migrations")
-	if err != nil {
-		return err
-	}
-
-	m, err := migrate.New(
-		"file://"+dir,
-		dbURL)
-	if err != nil {
-		return err
-	}
-
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return err
-	}
-
-	return nil
+func TestIntegration_CreateAndGetCustomer(t *testing.T) {
+	harness.Run(t, func(t *testing.T, h *harness.Harness) {
+		resp := h.Do(t, "POST", "/customers", map[string]string{
+			"first_name": "Integration",
+			"last_name":  "Test",
+			"email":      "integration.test@example.com",
+		})
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(resp.Body))
+		var created models.Customer
+		resp.Decode(t, &created)
+		assert.Equal(t, models.StatusLead, created.Status)
+
+		resp = h.Do(t, "GET", "/customers/"+created.ID.String(), nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var fetched models.Customer
+		resp.Decode(t, &fetched)
+		assert.Equal(t, created.ID, fetched.ID)
+		assert.Equal(t, created.Email, fetched.Email)
+		assert.WithinDuration(t, created.CreatedAt, fetched.CreatedAt, 0)
+
+		resp = h.Do(t, "DELETE", "/customers/"+created.ID.String(), nil)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp = h.Do(t, "GET", "/customers/"+created.ID.String(), nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
 }
 
-func TestIntegration_CreateAndGetCustomer(t *testing.T) {
-	baseURL := "http://" + serverAddress
-
-	// Create a new customer
-	customer := models.Customer{
-		FirstName: "Integration",
-		LastName:  "Test",
-		Email:     "integration.test@example.com",
-	}
-
-	createBody, _ := json.Marshal(customer)
-	createResp, err := http.Post(baseURL+"/customers", "application/json", bytes.NewBuffer(createBody))
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer createResp.Body.Close()
-
-	if createResp.StatusCode != http.StatusCreated {
-		fmt.Println(createResp.Body)
-		t.Fatalf("Expected status 201 Created, got %d", createResp.StatusCode)
-	}
-
-	var createdCustomer models.Customer
-	err = json.NewDecoder(createResp.Body).Decode(&createdCustomer)
-	if err != nil {
-		t.Fatalf("Failed to decode response: %v", err)
-	}
-
-	// Get the customer by ID
-	getResp, err := http.Get(baseURL + "/customers/" + createdCustomer.ID.String())
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer getResp.Body.Close()
-
-	if getResp.StatusCode != http.StatusOK {
-		t.Fatalf("Expected status 200 OK, got %d", getResp.StatusCode)
-	}
-
-	var fetchedCustomer models.Customer
-	err = json.NewDecoder(getResp.Body).Decode(&fetchedCustomer)
-	if err != nil {
-		t.Fatalf("Failed to decode response: %v", err)
-	}
-
-	if fetchedCustomer != createdCustomer {
-		t.Fatalf("Expected fetched customer to be %v, got %v", createdCustomer, fetchedCustomer)
-	}
-
-	// Clean up: delete the customer
-	req, err := http.NewRequest("DELETE", baseURL+"/customers/"+createdCustomer.ID.String(), nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-	delResp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer delResp.Body.Close()
-
-	if delResp.StatusCode != http.StatusNoContent {
-		t.Fatalf("Expected status 204 No Content, got %d", delResp.StatusCode)
-	}
+func TestIntegration_DuplicateEmail(t *testing.T) {
+	harness.Run(t, func(t *testing.T, h *harness.Harness) {
+		existing := h.CreateCustomer(t)
+
+		resp := h.Do(t, "POST", "/customers", map[string]string{"first_name": "Other", "last_name": "Customer", "email": existing.Email})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+}
+
+func TestIntegration_UpdateCustomer(t *testing.T) {
+	harness.Run(t, func(t *testing.T, h *harness.Harness) {
+		customer := h.CreateCustomer(t)
+
+		resp := h.Do(t, "PUT", "/customers/"+customer.ID.String(), map[string]string{
+			"first_name": "Renamed",
+			"last_name":  customer.LastName,
+			"email":      customer.Email,
+		})
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(resp.Body))
+
+		resp = h.Do(t, "GET", "/customers/email/"+customer.Email, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var fetched models.Customer
+		resp.Decode(t, &fetched)
+		assert.Equal(t, "Renamed", fetched.FirstName)
+	})
+}
+
+func TestIntegration_ListCustomers(t *testing.T) {
+	harness.Run(t, func(t *testing.T, h *harness.Harness) {
+		fixtures := h.LoadCustomers(t, "customers")
+
+		resp := h.Do(t, "GET", "/customers?sort=-last_name", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(resp.Body))
+		var customers []models.Customer
+		resp.Decode(t, &customers)
+		require.Len(t, customers, 3, "every test starts from an empty store")
+		assert.Equal(t, fixtures["alan"].ID, customers[0].ID)
+		assert.Equal(t, fixtures["ada"].ID, customers[1].ID)
+		assert.Equal(t, fixtures["grace"].ID, customers[2].ID)
+	})
+}
+
+func TestIntegration_StatusLifecycle(t *testing.T) {
+	harness.Run(t, func(t *testing.T, h *harness.Harness) {
+		customer := h.CreateCustomer(t)
+		path := "/customers/" + customer.ID.String() + "/status"
+
+		resp := h.Do(t, "POST", path, map[string]string{"status": "active", "reason": "Signed the contract"})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, "leads become prospects first")
+
+		resp = h.Do(t, "POST", path, map[string]string{"status": "prospect", "reason": "Asked for a quote"})
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(resp.Body))
+
+		resp = h.Do(t, "GET", "/customers?status=prospect", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(resp.Body))
+		var customers []models.Customer
+		resp.Decode(t, &customers)
+		require.Len(t, customers, 1)
+		assert.Equal(t, customer.ID, customers[0].ID)
+	})
+}
+
+func TestIntegration_Addresses(t *testing.T) {
+	harness.Run(t, func(t *testing.T, h *harness.Harness) {
+		h.RequireDatabase(t)
+		customer := h.CreateCustomer(t)
+		path := "/customers/" + customer.ID.String() + "/addresses"
+
+		resp := h.Do(t, "POST", path, models.Address{
+			Type: models.AddressBilling, Line1: "1 Main Street", City: "London", PostalCode: "sw1a 1aa", Country: "gb", IsDefault: true,
+		})
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(resp.Body))
+
+		resp = h.Do(t, "GET", "/customers/"+customer.ID.String()+"?include=addresses", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(resp.Body))
+		var fetched models.Customer
+		resp.Decode(t, &fetched)
+		require.Len(t, fetched.Addresses, 1)
+		assert.Equal(t, "SW1A 1AA", fetched.Addresses[0].PostalCode)
+
+		// Deleting the customer cascades to the addresses.
+		require.Equal(t, http.StatusNoContent, h.Do(t, "DELETE", "/customers/"+customer.ID.String(), nil).StatusCode)
+		var count int
+		require.NoError(t, h.DB.QueryRow("SELECT COUNT(*) FROM customer_addresses").Scan(&count))
+		assert.Zero(t, count)
+	})
 }
@@ -0,0 +1,55 @@
+// Package unsubscribe signs the tokens of one-click unsubscribe links, which let customers revoke a
+// consent without signing in. A token names the customer, the purpose and optionally the channel,
+// and is signed with signedtoken so that it can neither be forged nor altered. Tokens do not
+// expire: links in old messages keep working, and unsubscribing twice is harmless.
+package unsubscribe
+
+import (
+	"errors"
+
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/signedtoken"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken is returned for tokens that were not signed with the secret or were altered.
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+// Subscription is what a token unsubscribes from. An empty Channel stands for every channel.
+type Subscription struct {
+	CustomerID uuid.UUID
+	Purpose    string
+	Channel    models.ConsentChannel
+}
+
+// Signer signs and verifies tokens with a secret shared by every instance of the service.
+type Signer struct {
+	codec *signedtoken.Codec
+}
+
+func NewSigner(secret string) *Signer {
+	return &Signer{codec: signedtoken.NewCodec(secret)}
+}
+
+// Sign returns the token of sub, which is safe to use in URLs.
+func (s *Signer) Sign(sub Subscription) string {
+	return s.codec.Encode(sub.CustomerID.String(), sub.Purpose, string(sub.Channel))
+}
+
+// Verify returns the subscription of a token returned by Sign.
+func (s *Signer) Verify(token string) (Subscription, error) {
+	fields, err := s.codec.Decode(token, 3)
+	if err != nil {
+		return Subscription{}, ErrInvalidToken
+	}
+	id, err := uuid.Parse(fields[0])
+	if err != nil {
+		return Subscription{}, ErrInvalidToken
+	}
+	sub := Subscription{CustomerID: id, Purpose: fields[1], Channel: models.ConsentChannel(fields[2])}
+	if models.ValidatePurpose(sub.Purpose) != nil || (sub.Channel != "" && !sub.Channel.Valid()) {
+		return Subscription{}, ErrInvalidToken
+	}
+	return sub, nil
+}
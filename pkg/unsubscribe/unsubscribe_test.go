@@ -0,0 +1,53 @@
+package unsubscribe
+
+import (
+	"strings"
+	"testing"
+
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	signer := NewSigner("a secret of at least thirty-two characters")
+	sub := Subscription{CustomerID: uuid.New(), Purpose: "marketing", Channel: models.ChannelEmail}
+
+	token := signer.Sign(sub)
+	assert.NotContains(t, token, "/")
+	assert.NotContains(t, token, "+")
+
+	got, err := signer.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, sub, got)
+
+	every := Subscription{CustomerID: sub.CustomerID, Purpose: "newsletter"}
+	got, err = signer.Verify(signer.Sign(every))
+	require.NoError(t, err)
+	assert.Equal(t, every, got)
+}
+
+func TestVerify_Invalid(t *testing.T) {
+	signer := NewSigner("a secret of at least thirty-two characters")
+	token := signer.Sign(Subscription{CustomerID: uuid.New(), Purpose: "marketing"})
+	payload, mac, _ := strings.Cut(token, ".")
+	other := NewSigner("another secret of at least thirty-two characters").Sign(Subscription{CustomerID: uuid.New(), Purpose: "marketing"})
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	for name, token := range map[string]string{
+		"empty":          "",
+		"no signature":   payload,
+		"other secret":   other,
+		"swapped":        otherPayload + "." + mac,
+		"bad encoding":   payload + ".!!",
+		"truncated":      token[:len(token)-4],
+		"trailing bytes": token + "AA",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := signer.Verify(token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
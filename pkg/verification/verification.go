@@ -0,0 +1,191 @@
+// Package verification proves that customers own their email addresses. A Verifier mails a link
+// carrying a signed token that expires; posting the token back marks the address as verified.
+// A token names the customer and a keyed hash of the address it was sent to, so that it neither
+// exposes the address in URLs nor verifies an address the customer changed to afterwards.
+package verification
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"CustomerCRUD/pkg/mail"
+	"CustomerCRUD/pkg/models"
+	"CustomerCRUD/pkg/signedtoken"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that were not signed with the secret or were altered.
+	ErrInvalidToken = errors.New("invalid verification token")
+	// ErrExpiredToken is returned for tokens older than the TTL of the verifier.
+	ErrExpiredToken = errors.New("verification token expired")
+)
+
+// RateLimitError is returned when a customer was sent verification emails too often.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("verification email sent too recently, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Claims are what a valid token proves.
+type Claims struct {
+	CustomerID uuid.UUID
+	ExpiresAt  time.Time
+
+	emailHash string
+	verifier  *Verifier
+}
+
+// For reports whether the token was sent to email.
+func (c Claims) For(email string) bool {
+	return hmac.Equal([]byte(c.emailHash), []byte(c.verifier.hashEmail(email)))
+}
+
+// Limits bound how often a customer is sent verification emails. Zero values disable a limit.
+type Limits struct {
+	// Interval is the least time between two emails.
+	Interval time.Duration
+	// PerDay is the most emails within 24 hours.
+	PerDay int
+}
+
+// Config configures a Verifier.
+type Config struct {
+	// Secret signs the tokens and is shared by every instance of the service.
+	Secret string
+	// TTL is how long a token stays valid.
+	TTL time.Duration
+	// LinkURL is the page customers open to verify their address, which posts the token of its
+	// token query parameter to POST /customers/verify-email.
+	LinkURL string
+	Limits  Limits
+}
+
+// Verifier signs tokens and mails them to customers. The limits are kept in memory, so each
+// instance of the service applies them on its own.
+type Verifier struct {
+	cfg    Config
+	codec  *signedtoken.Codec
+	mailer mail.Mailer
+	now    func() time.Time
+
+	mu        sync.Mutex
+	sent      map[uuid.UUID][]time.Time
+	lastSweep time.Time
+}
+
+func NewVerifier(cfg Config, mailer mail.Mailer) *Verifier {
+	return &Verifier{
+		cfg:    cfg,
+		codec:  signedtoken.NewCodec(cfg.Secret),
+		mailer: mailer,
+		now:    time.Now,
+		sent:   map[uuid.UUID][]time.Time{},
+	}
+}
+
+// Sign returns a token for the current email of the customer, which is safe to use in URLs.
+func (v *Verifier) Sign(customer models.Customer) string {
+	expires := v.now().Add(v.cfg.TTL).Unix()
+	return v.codec.Encode(customer.ID.String(), v.hashEmail(customer.Email), strconv.FormatInt(expires, 10))
+}
+
+// Verify returns the claims of a token returned by Sign. It fails with ErrInvalidToken or
+// ErrExpiredToken.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	fields, err := v.codec.Decode(token, 3)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	id, err := uuid.Parse(fields[0])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	claims := Claims{CustomerID: id, ExpiresAt: time.Unix(expires, 0).UTC(), emailHash: fields[1], verifier: v}
+	if !v.now().Before(claims.ExpiresAt) {
+		return Claims{}, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+// hashEmail keys the hash of an address with the secret, so that tokens do not let anyone who
+// guesses the address confirm it.
+func (v *Verifier) hashEmail(email string) string {
+	sum := v.codec.Sum([]byte("email|" + strings.ToLower(email)))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// Send mails a verification link to the current email of the customer. It fails with a
+// *RateLimitError when the limits do not allow another email yet.
+func (v *Verifier) Send(ctx context.Context, customer models.Customer) error {
+	if err := v.reserve(customer.ID); err != nil {
+		return err
+	}
+
+	link, err := url.Parse(v.cfg.LinkURL)
+	if err != nil {
+		return fmt.Errorf("invalid verification link URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", v.Sign(customer))
+	link.RawQuery = query.Encode()
+
+	return v.mailer.Send(ctx, mail.Message{
+		To:      customer.Email,
+		Subject: "Please verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nplease confirm that %s is your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %s. If you did not expect this email, you can ignore it.\n",
+			customer.FirstName, customer.Email, link, v.cfg.TTL),
+	})
+}
+
+// reserve records an email to the customer when the limits allow it.
+func (v *Verifier) reserve(customerID uuid.UUID) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	dayAgo := now.Add(-24 * time.Hour)
+	if now.Sub(v.lastSweep) > time.Hour {
+		for id, times := range v.sent {
+			if !times[len(times)-1].After(dayAgo) {
+				delete(v.sent, id)
+			}
+		}
+		v.lastSweep = now
+	}
+
+	var recent []time.Time
+	for _, at := range v.sent[customerID] {
+		if at.After(dayAgo) {
+			recent = append(recent, at)
+		}
+	}
+	limits := v.cfg.Limits
+	if n := len(recent); n > 0 {
+		if wait := recent[n-1].Add(limits.Interval).Sub(now); limits.Interval > 0 && wait > 0 {
+			return &RateLimitError{RetryAfter: wait}
+		}
+		if limits.PerDay > 0 && n >= limits.PerDay {
+			return &RateLimitError{RetryAfter: recent[n-limits.PerDay].Sub(dayAgo)}
+		}
+	}
+	v.sent[customerID] = append(recent, now)
+	return nil
+}
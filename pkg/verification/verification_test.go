@@ -0,0 +1,121 @@
+package verification
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"CustomerCRUD/pkg/mail"
+	"CustomerCRUD/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "a secret of at least thirty-two characters"
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestVerifier(limits Limits) (*Verifier, *mail.Outbox, *clock) {
+	outbox := mail.NewOutbox()
+	v := NewVerifier(Config{Secret: testSecret, TTL: 48 * time.Hour, LinkURL: "https://app.example.com/verify-email", Limits: limits}, outbox)
+	c := &clock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	v.now = c.now
+	return v, outbox, c
+}
+
+func TestSignVerify(t *testing.T) {
+	v, _, c := newTestVerifier(Limits{})
+	customer := models.Customer{ID: uuid.New(), Email: "Jane@Example.com"}
+
+	token := v.Sign(customer)
+	assert.NotContains(t, token, "jane")
+
+	claims, err := v.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, customer.ID, claims.CustomerID)
+	assert.Equal(t, c.t.Add(48*time.Hour), claims.ExpiresAt)
+	assert.True(t, claims.For("jane@example.com"))
+	assert.False(t, claims.For("john@example.com"))
+	other := NewVerifier(Config{Secret: "another secret of at least thirty-two characters"}, nil)
+	assert.NotEqual(t, v.hashEmail(customer.Email), other.hashEmail(customer.Email), "the hash is keyed with the secret")
+
+	c.t = c.t.Add(48 * time.Hour)
+	_, err = v.Verify(token)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestVerify_Invalid(t *testing.T) {
+	v, _, _ := newTestVerifier(Limits{})
+	token := v.Sign(models.Customer{ID: uuid.New(), Email: "jane@example.com"})
+	payload, mac, _ := strings.Cut(token, ".")
+	other := NewVerifier(Config{Secret: "another secret of at least thirty-two characters", TTL: time.Hour}, nil).
+		Sign(models.Customer{ID: uuid.New(), Email: "jane@example.com"})
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	for name, token := range map[string]string{
+		"empty":        "",
+		"no signature": payload,
+		"other secret": other,
+		"swapped":      otherPayload + "." + mac,
+		"bad encoding": payload + ".!!",
+		"truncated":    token[:len(token)-4],
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.Verify(token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	v, outbox, _ := newTestVerifier(Limits{})
+	customer := models.Customer{ID: uuid.New(), FirstName: "Jane", Email: "jane@example.com"}
+
+	require.NoError(t, v.Send(context.Background(), customer))
+	messages := outbox.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "jane@example.com", messages[0].To)
+
+	start := strings.Index(messages[0].Body, "https://")
+	require.NotEqual(t, -1, start)
+	link, err := url.Parse(strings.Fields(messages[0].Body[start:])[0])
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", link.Host)
+	claims, err := v.Verify(link.Query().Get("token"))
+	require.NoError(t, err)
+	assert.Equal(t, customer.ID, claims.CustomerID)
+}
+
+func TestSend_RateLimited(t *testing.T) {
+	v, outbox, c := newTestVerifier(Limits{Interval: time.Minute, PerDay: 3})
+	customer := models.Customer{ID: uuid.New(), Email: "jane@example.com"}
+	ctx := context.Background()
+
+	require.NoError(t, v.Send(ctx, customer))
+	c.t = c.t.Add(20 * time.Second)
+	var limited *RateLimitError
+	require.ErrorAs(t, v.Send(ctx, customer), &limited)
+	assert.Equal(t, 40*time.Second, limited.RetryAfter)
+
+	// Other customers have limits of their own.
+	require.NoError(t, v.Send(ctx, models.Customer{ID: uuid.New(), Email: "john@example.com"}))
+
+	c.t = c.t.Add(time.Minute)
+	require.NoError(t, v.Send(ctx, customer))
+	c.t = c.t.Add(time.Hour)
+	require.NoError(t, v.Send(ctx, customer))
+	c.t = c.t.Add(time.Hour)
+	require.ErrorAs(t, v.Send(ctx, customer), &limited)
+	assert.Equal(t, 22*time.Hour-80*time.Second, limited.RetryAfter)
+
+	// The first email leaves the window after a day.
+	c.t = c.t.Add(limited.RetryAfter + time.Second)
+	require.NoError(t, v.Send(ctx, customer))
+	assert.Len(t, outbox.Messages(), 5)
+}
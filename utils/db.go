@@ -2,7 +2,6 @@ package utils
 
 import (
 	"database/sql"
-	"os"
 	"path/filepath"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -11,36 +10,279 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func GetLocalDB() (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", "./customers.db")
-	if err != nil {
-		return nil, err
-	}
-
-	createTableSQL := `
-        CREATE TABLE IF NOT EXISTS customers (
+// localSchema mirrors the migrations for the local SQLite database, keep both in sync.
+var localSchema = []string{
+	`CREATE TABLE IF NOT EXISTS customers (
             id UUID PRIMARY KEY,
             first_name TEXT NOT NULL,
             middle_name TEXT,
             last_name TEXT NOT NULL,
             email TEXT NOT NULL UNIQUE,
-            phone_number TEXT
-        );
-        `
+            phone_number TEXT,
+            status TEXT NOT NULL DEFAULT 'lead' CHECK (status IN ('lead', 'prospect', 'active', 'suspended', 'closed')),
+            attributes JSON NOT NULL DEFAULT '{}',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            created_by TEXT,
+            updated_by TEXT,
+            email_blind_index TEXT,
+            phone_number_blind_index TEXT
+        )`,
+	`CREATE TABLE IF NOT EXISTS customer_addresses (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            type TEXT NOT NULL CHECK (type IN ('billing', 'shipping', 'other')),
+            line1 TEXT NOT NULL,
+            line2 TEXT,
+            city TEXT NOT NULL,
+            region TEXT,
+            postal_code TEXT,
+            country TEXT NOT NULL,
+            is_default BOOLEAN NOT NULL DEFAULT FALSE
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_addresses_customer_id_idx ON customer_addresses (customer_id)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS customer_addresses_default_idx
+            ON customer_addresses (customer_id, type) WHERE is_default`,
+	`CREATE TABLE IF NOT EXISTS customer_emails (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            type TEXT NOT NULL CHECK (type IN ('work', 'home', 'mobile', 'other')),
+            email TEXT NOT NULL,
+            is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+            verified BOOLEAN NOT NULL DEFAULT FALSE,
+            email_blind_index TEXT
+        )`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS customer_emails_email_idx ON customer_emails (email)`,
+	`CREATE INDEX IF NOT EXISTS customer_emails_customer_id_idx ON customer_emails (customer_id)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS customer_emails_primary_idx ON customer_emails (customer_id) WHERE is_primary`,
+	`CREATE TABLE IF NOT EXISTS customer_phones (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            type TEXT NOT NULL CHECK (type IN ('work', 'home', 'mobile', 'other')),
+            number TEXT NOT NULL,
+            is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+            verified BOOLEAN NOT NULL DEFAULT FALSE,
+            number_blind_index TEXT
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_phones_customer_id_idx ON customer_phones (customer_id)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS customer_phones_primary_idx ON customer_phones (customer_id) WHERE is_primary`,
+	`CREATE TABLE IF NOT EXISTS attribute_definitions (
+            name TEXT PRIMARY KEY,
+            definition JSON NOT NULL
+        )`,
+	`CREATE TABLE IF NOT EXISTS customer_tags (
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            tag TEXT NOT NULL,
+            PRIMARY KEY (customer_id, tag)
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_tags_tag_idx ON customer_tags (tag, customer_id)`,
+	`CREATE TABLE IF NOT EXISTS segments (
+            id UUID PRIMARY KEY,
+            name TEXT NOT NULL,
+            description TEXT,
+            filter TEXT NOT NULL
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_addresses_country_idx ON customer_addresses (country, customer_id)`,
+	`CREATE TABLE IF NOT EXISTS customer_status_changes (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            from_status TEXT,
+            to_status TEXT NOT NULL,
+            reason TEXT NOT NULL,
+            changed_at TIMESTAMP NOT NULL,
+            changed_by TEXT
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_status_changes_customer_id_idx ON customer_status_changes (customer_id, changed_at)`,
+	`CREATE TABLE IF NOT EXISTS customer_audit (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL,
+            action TEXT NOT NULL CHECK (action IN ('created', 'updated', 'deleted', 'erased')),
+            changes JSON,
+            actor TEXT NOT NULL,
+            occurred_at TIMESTAMP NOT NULL
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_audit_customer_id_idx ON customer_audit (customer_id, occurred_at)`,
+	`CREATE TABLE IF NOT EXISTS customer_notes (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            body TEXT NOT NULL,
+            author TEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            updated_by TEXT,
+            deleted_at TIMESTAMP,
+            deleted_by TEXT
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_notes_customer_id_idx ON customer_notes (customer_id, created_at)`,
+	`CREATE TABLE IF NOT EXISTS customer_note_revisions (
+            id UUID PRIMARY KEY,
+            note_id UUID NOT NULL REFERENCES customer_notes (id) ON DELETE CASCADE,
+            body TEXT NOT NULL,
+            edited_at TIMESTAMP NOT NULL,
+            edited_by TEXT NOT NULL
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_note_revisions_note_id_idx ON customer_note_revisions (note_id, edited_at)`,
+	`CREATE TABLE IF NOT EXISTS customer_interactions (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            type TEXT NOT NULL CHECK (type IN ('call', 'email', 'meeting')),
+            body TEXT NOT NULL,
+            occurred_at TIMESTAMP NOT NULL,
+            author TEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_interactions_customer_id_idx ON customer_interactions (customer_id, occurred_at)`,
+	`CREATE TABLE IF NOT EXISTS customer_attachments (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            file_name TEXT NOT NULL,
+            content_type TEXT NOT NULL,
+            size INTEGER NOT NULL CHECK (size >= 0),
+            sha256 TEXT NOT NULL,
+            storage_key TEXT NOT NULL,
+            uploaded_at TIMESTAMP NOT NULL,
+            uploaded_by TEXT NOT NULL
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_attachments_customer_id_idx ON customer_attachments (customer_id, uploaded_at)`,
+	`CREATE TABLE IF NOT EXISTS customer_legal_holds (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            reason TEXT NOT NULL,
+            placed_at TIMESTAMP NOT NULL,
+            placed_by TEXT NOT NULL,
+            released_at TIMESTAMP,
+            released_by TEXT
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_legal_holds_customer_id_idx ON customer_legal_holds (customer_id, placed_at)`,
+	`CREATE TABLE IF NOT EXISTS customer_erasures (
+            customer_id UUID PRIMARY KEY REFERENCES customers (id) ON DELETE CASCADE,
+            reference TEXT,
+            erased_at TIMESTAMP NOT NULL,
+            erased_by TEXT NOT NULL
+        )`,
+	`CREATE TABLE IF NOT EXISTS customer_consent_history (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            purpose TEXT NOT NULL,
+            channel TEXT NOT NULL CHECK (channel IN ('email', 'sms', 'phone', 'post')),
+            state TEXT NOT NULL CHECK (state IN ('granted', 'revoked')),
+            source TEXT NOT NULL,
+            policy_version TEXT,
+            recorded_at TIMESTAMP NOT NULL,
+            recorded_by TEXT NOT NULL
+        )`,
+	`CREATE INDEX IF NOT EXISTS customer_consent_history_customer_id_idx ON customer_consent_history (customer_id, recorded_at)`,
+	`CREATE TABLE IF NOT EXISTS customer_consents (
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            purpose TEXT NOT NULL,
+            channel TEXT NOT NULL,
+            id UUID NOT NULL,
+            state TEXT NOT NULL,
+            source TEXT NOT NULL,
+            policy_version TEXT,
+            recorded_at TIMESTAMP NOT NULL,
+            recorded_by TEXT NOT NULL,
+            PRIMARY KEY (customer_id, purpose, channel)
+        )`,
+	`CREATE TABLE IF NOT EXISTS portal_login_tokens (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            token_hash TEXT NOT NULL UNIQUE,
+            created_at TIMESTAMP NOT NULL,
+            expires_at TIMESTAMP NOT NULL,
+            used_at TIMESTAMP
+        )`,
+	`CREATE INDEX IF NOT EXISTS portal_login_tokens_customer_id_idx ON portal_login_tokens (customer_id, created_at)`,
+	`CREATE TABLE IF NOT EXISTS portal_sessions (
+            id UUID PRIMARY KEY,
+            customer_id UUID NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+            token_hash TEXT NOT NULL UNIQUE,
+            created_at TIMESTAMP NOT NULL,
+            expires_at TIMESTAMP NOT NULL
+        )`,
+	`CREATE INDEX IF NOT EXISTS portal_sessions_customer_id_idx ON portal_sessions (customer_id)`,
+	`CREATE TABLE IF NOT EXISTS encryption_data_keys (
+            id TEXT PRIMARY KEY,
+            master_key_id TEXT NOT NULL,
+            wrapped_key BLOB NOT NULL,
+            created_at TIMESTAMP NOT NULL
+        )`,
+}
+
+// localColumns are added to local databases created before the column was part of localSchema.
+var localColumns = []struct{ table, column, definition string }{
+	{"customers", "attributes", `JSON NOT NULL DEFAULT '{}'`},
+	// SQLite only adds columns with constant defaults, older customers get the epoch.
+	{"customers", "created_at", `TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00'`},
+	{"customers", "updated_at", `TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00'`},
+	{"customers", "created_by", `TEXT`},
+	{"customers", "updated_by", `TEXT`},
+	// Customers stored before the lifecycle existed are active.
+	{"customers", "status", `TEXT NOT NULL DEFAULT 'active'`},
+	{"customers", "email_blind_index", `TEXT`},
+	{"customers", "phone_number_blind_index", `TEXT`},
+	{"customer_emails", "email_blind_index", `TEXT`},
+	{"customer_phones", "number_blind_index", `TEXT`},
+}
+
+// localIndexes cover columns of localColumns, so they are created once the columns exist.
+var localIndexes = []string{
+	`CREATE INDEX IF NOT EXISTS customers_updated_at_idx ON customers (updated_at, id)`,
+	`CREATE INDEX IF NOT EXISTS customers_status_idx ON customers (status)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS customers_email_blind_index_idx ON customers (email_blind_index)`,
+	`CREATE INDEX IF NOT EXISTS customers_phone_number_blind_index_idx ON customers (phone_number_blind_index)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS customer_emails_email_blind_index_idx ON customer_emails (email_blind_index)`,
+	`CREATE INDEX IF NOT EXISTS customer_phones_number_blind_index_idx ON customer_phones (number_blind_index)`,
+}
 
-	if _, err = db.Exec(createTableSQL); err != nil {
+func GetLocalDB() (*sql.DB, error) {
+	return OpenLocalDB("./customers.db")
+}
+
+// OpenLocalDB opens the SQLite database at path, creating it and its schema when needed.
+func OpenLocalDB(path string) (*sql.DB, error) {
+	// Foreign keys are off by default in SQLite and are needed for the cascading deletes.
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+	if err != nil {
 		return nil, err
 	}
+
+	for _, stmt := range localSchema {
+		if _, err = db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range localColumns {
+		if err := ensureColumn(db, c.table, c.column, c.definition); err != nil {
+			return nil, err
+		}
+	}
+	for _, stmt := range localIndexes {
+		if _, err = db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
 	return db, nil
 }
 
+// ensureColumn adds a column to a SQLite table unless it is already there.
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	var exists bool
+	err := db.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info($1) WHERE name = $2", table, column).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	return err
+}
+
 func RunMigrations(dbURL string) error {
 	// Use the MIGRATIONS_DIR environment variable, or fallback to "./migrations"
-	dir := os.Getenv("MIGRATIONS_DIR")
-	if dir == "" {
-		dir = "./migrations"
-	}
+	return RunMigrationsFrom(dbURL, MigrationsDir())
+}
 
+// RunMigrationsFrom applies every pending migration found in dir.
+func RunMigrationsFrom(dbURL, dir string) error {
 	// Get the absolute path to the migrations directory
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_.+\.up\.sql$`)
+
+// MigrationsDir returns the directory holding the SQL migrations, taken from
+// the MIGRATIONS_DIR environment variable or "./migrations" when it is unset.
+func MigrationsDir() string {
+	if dir := os.Getenv("MIGRATIONS_DIR"); dir != "" {
+		return dir
+	}
+	return "./migrations"
+}
+
+// LatestMigrationVersion returns the highest version among the up migrations in dir.
+func LatestMigrationVersion(dir string) (uint, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, e := range entries {
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		v, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration version in %s: %w", e.Name(), err)
+		}
+		if uint(v) > latest {
+			latest = uint(v)
+		}
+	}
+	return latest, nil
+}
+
+// CheckMigrationVersion verifies that the schema in db is clean and at the
+// latest version found in the migrations directory.
+func CheckMigrationVersion(ctx context.Context, db *sql.DB, dir string) error {
+	expected, err := LatestMigrationVersion(dir)
+	if err != nil {
+		return fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	var version uint
+	var dirty bool
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty", version)
+	}
+	if version != expected {
+		return fmt.Errorf("schema version is %d, expected %d", version, expected)
+	}
+	return nil
+}